@@ -36,4 +36,5 @@ require (
 	k8s.io/klog v1.0.0
 	k8s.io/kube-aggregator v0.20.0
 	k8s.io/kube-openapi v0.0.0-20201113171705-d219536bb9fd
+	modernc.org/sqlite v1.10.8
 )
@@ -63,6 +63,13 @@ func (l *AccessStore) AccessFor(user user.Info) *AccessSet {
 	return result
 }
 
+// Forget drops the cached access set with the given ID so it is computed again on the next lookup.
+func (l *AccessStore) Forget(id string) {
+	if l.cache != nil {
+		l.cache.Remove(id)
+	}
+}
+
 func (l *AccessStore) CacheKey(user user.Info) string {
 	d := sha256.New()
 
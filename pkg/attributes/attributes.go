@@ -2,6 +2,7 @@ package attributes
 
 import (
 	"fmt"
+	"net/url"
 
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/wrangler/pkg/data/convert"
@@ -184,3 +185,214 @@ func SetPreferredGroup(s *types.APISchema, ver string) {
 	}
 	s.Attributes["preferredGroup"] = ver
 }
+
+func Sensitive(s *types.APISchema) bool {
+	return convert.ToBool(s.Attributes["sensitive"])
+}
+
+func SetSensitive(s *types.APISchema, value bool) {
+	setVal(s, "sensitive", value)
+}
+
+func StreamingActions(s *types.APISchema) []string {
+	return convert.ToStringSlice(s.Attributes["streamingActions"])
+}
+
+func SetStreamingActions(s *types.APISchema, actions []string) {
+	setVal(s, "streamingActions", actions)
+}
+
+// FieldConstraint holds the enum values and pattern declared for a field in the OpenAPI schema. When Items
+// is true they apply to the elements of an array or the values of a map instead of the field itself.
+type FieldConstraint struct {
+	Enum    []string `json:"enum,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+	Items   bool     `json:"items,omitempty"`
+}
+
+func FieldConstraints(s *types.APISchema) map[string]FieldConstraint {
+	constraints, _ := s.Attributes["fieldConstraints"].(map[string]FieldConstraint)
+	return constraints
+}
+
+func SetFieldConstraints(s *types.APISchema, constraints map[string]FieldConstraint) {
+	setVal(s, "fieldConstraints", constraints)
+}
+
+func CompatibilityVersions(s *types.APISchema) []string {
+	versions, _ := s.Attributes["compatibilityVersions"].([]string)
+	return versions
+}
+
+func SetCompatibilityVersions(s *types.APISchema, versions []string) {
+	setVal(s, "compatibilityVersions", versions)
+}
+
+// ReplaceOnUpdate is whether an update of the schema replaces the object when the request does not set the
+// replace option. It defaults to true, which is the behavior of a Kubernetes update.
+func ReplaceOnUpdate(s *types.APISchema) bool {
+	replace, ok := s.Attributes["replace"].(bool)
+	return replace || !ok
+}
+
+func SetReplaceOnUpdate(s *types.APISchema, replace bool) {
+	setVal(s, "replace", replace)
+}
+
+// AsyncWrites is whether creates, updates and deletes of the schema are queued as operations even when the
+// client did not send Prefer: respond-async.
+func AsyncWrites(s *types.APISchema) bool {
+	async, _ := s.Attributes["asyncWrites"].(bool)
+	return async
+}
+
+func SetAsyncWrites(s *types.APISchema, async bool) {
+	setVal(s, "asyncWrites", async)
+}
+
+// FieldPromotions are computed top-level fields added to listed and watched objects, keyed by field name with the
+// JSONPath expression of the value, for example "ready": "status.conditions[?type==Ready].status".
+func FieldPromotions(s *types.APISchema) map[string]string {
+	promotions, _ := s.Attributes["fieldPromotions"].(map[string]string)
+	return promotions
+}
+
+func SetFieldPromotions(s *types.APISchema, promotions map[string]string) {
+	setVal(s, "fieldPromotions", promotions)
+}
+
+// QueryHandler produces a custom-shaped response for a named collection query, GET /v1/{type}?query={name}.
+// It runs after the user is checked for list access and can use the schema store, which applies RBAC, to list.
+type QueryHandler func(apiOp *types.APIRequest, schema *types.APISchema) (interface{}, error)
+
+func QueryHandlers(s *types.APISchema) map[string]QueryHandler {
+	handlers, _ := s.Attributes["queryHandlers"].(map[string]QueryHandler)
+	return handlers
+}
+
+// AddQueryHandler registers handler for ?query=name on the collection of the schema.
+func AddQueryHandler(s *types.APISchema, name string, handler QueryHandler) {
+	handlers := QueryHandlers(s)
+	if handlers == nil {
+		handlers = map[string]QueryHandler{}
+		setVal(s, "queryHandlers", handlers)
+	}
+	handlers[name] = handler
+}
+
+// RedactionRule replaces the value of the field at Path, in dot notation such as "spec.password", with Replacement
+// in objects returned to users that are not granted RequiredVerb on the object.
+type RedactionRule struct {
+	Path         string
+	RequiredVerb string
+	Replacement  interface{}
+}
+
+func FieldRedactionPolicy(s *types.APISchema) []RedactionRule {
+	rules, _ := s.Attributes["fieldRedactionPolicy"].([]RedactionRule)
+	return rules
+}
+
+func SetFieldRedactionPolicy(s *types.APISchema, rules []RedactionRule) {
+	setVal(s, "fieldRedactionPolicy", rules)
+}
+
+// CreateFinalizers are added to the metadata.finalizers of every object of the schema created through steve.
+func CreateFinalizers(s *types.APISchema) []string {
+	finalizers, _ := s.Attributes["createFinalizers"].([]string)
+	return finalizers
+}
+
+func SetCreateFinalizers(s *types.APISchema, finalizers []string) {
+	setVal(s, "createFinalizers", finalizers)
+}
+
+// RevisionHistory is the number of writes through steve recorded in the revisions annotation of the objects of the
+// schema, zero keeps no history.
+func RevisionHistory(s *types.APISchema) int {
+	limit, _ := s.Attributes["revisionHistory"].(int)
+	return limit
+}
+
+func SetRevisionHistory(s *types.APISchema, limit int) {
+	setVal(s, "revisionHistory", limit)
+}
+
+// OwnerPolicy grants AllowedVerbs on an object to the users named in its OwnerAnnotation, a comma separated list of
+// user names, beyond what RBAC grants them.
+type OwnerPolicy struct {
+	OwnerAnnotation string
+	AllowedVerbs    []string
+}
+
+func SharedOwnership(s *types.APISchema) []OwnerPolicy {
+	policies, _ := s.Attributes["sharedOwnership"].([]OwnerPolicy)
+	return policies
+}
+
+func SetSharedOwnership(s *types.APISchema, policies []OwnerPolicy) {
+	setVal(s, "sharedOwnership", policies)
+}
+
+// DefaultQuery holds query parameters, such as labelSelector or limit, that lists and watches of the schema use
+// when the client does not send them.
+func DefaultQuery(s *types.APISchema) url.Values {
+	query, _ := s.Attributes["defaultQuery"].(url.Values)
+	return query
+}
+
+func SetDefaultQuery(s *types.APISchema, query url.Values) {
+	setVal(s, "defaultQuery", query)
+}
+
+// AuditEvents is whether creates, updates and deletes of the schema through steve are recorded as Kubernetes Events
+// of the changed object.
+func AuditEvents(s *types.APISchema) bool {
+	audit, _ := s.Attributes["auditEvents"].(bool)
+	return audit
+}
+
+func SetAuditEvents(s *types.APISchema, audit bool) {
+	setVal(s, "auditEvents", audit)
+}
+
+// NamespaceLabelFilter holds the labels a namespace needs for the objects of the schema in it to be listed and
+// watched.
+func NamespaceLabelFilter(s *types.APISchema) map[string]string {
+	filter, _ := s.Attributes["namespaceLabelFilter"].(map[string]string)
+	return filter
+}
+
+func SetNamespaceLabelFilter(s *types.APISchema, filter map[string]string) {
+	setVal(s, "namespaceLabelFilter", filter)
+}
+
+// Aliases are additional schema IDs the schema is served under, for clients that expect the resource by another name.
+func Aliases(s *types.APISchema) []string {
+	aliases, _ := s.Attributes["aliases"].([]string)
+	return aliases
+}
+
+func SetAliases(s *types.APISchema, aliases []string) {
+	setVal(s, "aliases", aliases)
+}
+
+// AliasOf is the ID of the schema an alias schema serves, empty for a schema that is not an alias.
+func AliasOf(s *types.APISchema) string {
+	return str(s, "aliasOf")
+}
+
+func SetAliasOf(s *types.APISchema, id string) {
+	setVal(s, "aliasOf", id)
+}
+
+// FieldRenames maps the names of fields used by clients to the names in the Kubernetes object, both in dot
+// notation, for example "displayName": "spec.displayName".
+func FieldRenames(s *types.APISchema) map[string]string {
+	renames, _ := s.Attributes["fieldRenames"].(map[string]string)
+	return renames
+}
+
+func SetFieldRenames(s *types.APISchema, renames map[string]string) {
+	setVal(s, "fieldRenames", renames)
+}
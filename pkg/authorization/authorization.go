@@ -0,0 +1,64 @@
+// Package authorization lets installations add policy checks on top of RBAC, evaluated by the API handler after
+// the schema of a request is resolved and before the request reaches the store.
+package authorization
+
+import (
+	"context"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+type Decision int
+
+const (
+	NoOpinion Decision = iota
+	Allow
+	Deny
+)
+
+type Attributes struct {
+	User   user.Info
+	Schema *types.APISchema
+	// Verb is get, list, create, update or delete, or the name of the action or link such as exec.
+	Verb      string
+	Namespace string
+	Name      string
+	// Object is the parsed body of a write, nil otherwise.
+	Object map[string]interface{}
+}
+
+type Authorizer interface {
+	Authorize(ctx context.Context, attrs Attributes) (Decision, string, error)
+}
+
+type AuthorizerFunc func(ctx context.Context, attrs Attributes) (Decision, string, error)
+
+func (a AuthorizerFunc) Authorize(ctx context.Context, attrs Attributes) (Decision, string, error) {
+	return a(ctx, attrs)
+}
+
+// Chain asks every authorizer and denies if any of them denies, so one authorizer can not override another's
+// denial. An authorizer that fails is treated as a denial. If no authorizer denies the result is Allow if any
+// allowed, NoOpinion otherwise.
+type Chain []Authorizer
+
+func (c Chain) Authorize(ctx context.Context, attrs Attributes) (Decision, string, error) {
+	result := NoOpinion
+	reason := ""
+	for _, authorizer := range c {
+		decision, why, err := authorizer.Authorize(ctx, attrs)
+		if err != nil {
+			return Deny, "authorization check failed: " + err.Error(), nil
+		}
+		switch decision {
+		case Deny:
+			return Deny, why, nil
+		case Allow:
+			if result == NoOpinion {
+				result, reason = Allow, why
+			}
+		}
+	}
+	return result, reason, nil
+}
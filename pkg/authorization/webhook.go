@@ -0,0 +1,138 @@
+package authorization
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+type webhookRequest struct {
+	User      string                 `json:"user"`
+	Groups    []string               `json:"groups,omitempty"`
+	SchemaID  string                 `json:"schemaId"`
+	Verb      string                 `json:"verb"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Object    map[string]interface{} `json:"object,omitempty"`
+}
+
+type webhookResponse struct {
+	// Decision is allow, deny or empty for no opinion.
+	Decision string `json:"decision"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type cachedDecision struct {
+	decision Decision
+	reason   string
+}
+
+// Webhook is an Authorizer that POSTs the request attributes as JSON to URL and expects a JSON response of the
+// form {"decision": "allow" | "deny" | "", "reason": "..."}. Decisions for requests without a body are cached
+// for TTL, writes are always sent since the decision can depend on the object.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+	TTL    time.Duration
+
+	cache *cache.LRUExpireCache
+}
+
+func NewWebhook(url string, client *http.Client, ttl time.Duration) *Webhook {
+	if client == nil {
+		client = &http.Client{
+			Timeout: 10 * time.Second,
+		}
+	}
+	return &Webhook{
+		URL:    url,
+		Client: client,
+		TTL:    ttl,
+		cache:  cache.NewLRUExpireCache(1000),
+	}
+}
+
+func (w *Webhook) Authorize(ctx context.Context, attrs Attributes) (Decision, string, error) {
+	req := webhookRequest{
+		Verb:      attrs.Verb,
+		Namespace: attrs.Namespace,
+		Name:      attrs.Name,
+		Object:    attrs.Object,
+	}
+	if attrs.User != nil {
+		req.User = attrs.User.GetName()
+		req.Groups = attrs.User.GetGroups()
+	}
+	if attrs.Schema != nil {
+		req.SchemaID = attrs.Schema.ID
+	}
+
+	key := ""
+	if req.Object == nil && w.TTL > 0 {
+		key = cacheKey(req)
+		if val, ok := w.cache.Get(key); ok {
+			cached := val.(cachedDecision)
+			return cached.decision, cached.reason, nil
+		}
+	}
+
+	decision, reason, err := w.call(ctx, req)
+	if err != nil {
+		return NoOpinion, "", err
+	}
+	if key != "" {
+		w.cache.Add(key, cachedDecision{decision: decision, reason: reason}, w.TTL)
+	}
+	return decision, reason, nil
+}
+
+func (w *Webhook) call(ctx context.Context, req webhookRequest) (Decision, string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return NoOpinion, "", err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return NoOpinion, "", err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(httpReq)
+	if err != nil {
+		return NoOpinion, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return NoOpinion, "", fmt.Errorf("authorization webhook returned %d", resp.StatusCode)
+	}
+
+	var result webhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return NoOpinion, "", err
+	}
+
+	switch strings.ToLower(result.Decision) {
+	case "allow":
+		return Allow, result.Reason, nil
+	case "deny":
+		return Deny, result.Reason, nil
+	default:
+		return NoOpinion, result.Reason, nil
+	}
+}
+
+func cacheKey(req webhookRequest) string {
+	groups := append([]string(nil), req.Groups...)
+	sort.Strings(groups)
+	return strings.Join([]string{req.User, strings.Join(groups, ","), req.SchemaID, req.Verb, req.Namespace, req.Name}, "\x00")
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	defaultClientCacheSize = 1000
+	defaultClientCacheTTL  = 10 * time.Minute
+)
+
+// clientKey identifies a cached dynamic client: the config it was built from and, for impersonating clients, every
+// part of the impersonated identity so users that differ in a group or extra never share a client. The access set
+// ID of the user is kept so the clients of an identity can be forgotten once it goes idle.
+type clientKey struct {
+	cfg    *rest.Config
+	user   string
+	groups string
+	extra  string
+	access string
+}
+
+// clientCache keeps the dynamic clients of the factory so a request doesn't pay for building a client, and its
+// transport, every time. Clients are created per identity, not per resource, as the resource clients of a dynamic
+// client are cheap.
+type clientCache struct {
+	clients *cache.LRUExpireCache
+	ttl     time.Duration
+}
+
+func newClientCache(size int, ttl time.Duration) *clientCache {
+	return &clientCache{
+		clients: cache.NewLRUExpireCache(size),
+		ttl:     ttl,
+	}
+}
+
+func (c *clientCache) get(ctx *types.APIRequest, cfg *rest.Config, impersonate bool) (dynamic.Interface, error) {
+	key := clientKey{cfg: cfg}
+	if impersonate {
+		user, ok := request.UserFrom(ctx.Context())
+		if !ok {
+			// let setupConfig report the missing user
+			return newDynamicClient(ctx, cfg, impersonate)
+		}
+		key.user = user.GetName()
+		key.groups = strings.Join(user.GetGroups(), "\x00")
+
+		var extra []string
+		for k, values := range user.GetExtra() {
+			extra = append(extra, k+"="+strings.Join(values, "\x00"))
+		}
+		sort.Strings(extra)
+		key.extra = strings.Join(extra, "\x01")
+		if ctx.Schemas != nil {
+			if accessSet, ok := ctx.Schemas.Attributes["accessSet"].(*accesscontrol.AccessSet); ok {
+				key.access = accessSet.ID
+			}
+		}
+	}
+
+	if client, ok := c.clients.Get(key); ok {
+		return client.(dynamic.Interface), nil
+	}
+
+	client, err := newDynamicClient(ctx, cfg, impersonate)
+	if err != nil {
+		return nil, err
+	}
+	c.clients.Add(key, client, c.ttl)
+	return client, nil
+}
+
+// forget drops the clients of the identities with the access set ID accessID.
+func (c *clientCache) forget(accessID string) {
+	for _, key := range c.clients.Keys() {
+		if key.(clientKey).access == accessID {
+			c.clients.Remove(key)
+		}
+	}
+}
+
+func (c *clientCache) reset() {
+	for _, key := range c.clients.Keys() {
+		c.clients.Remove(key)
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/rest"
+)
+
+func userRequest(name, accessID string) *types.APIRequest {
+	ctx := request.WithUser(request.NewContext(), &user.DefaultInfo{Name: name})
+	return &types.APIRequest{
+		Request: httptest.NewRequest(http.MethodGet, "/v1/pods", nil).WithContext(ctx),
+		Schemas: &types.APISchemas{
+			Attributes: map[string]interface{}{
+				"accessSet": &accesscontrol.AccessSet{ID: accessID},
+			},
+		},
+	}
+}
+
+func TestClientCacheForget(t *testing.T) {
+	cfg := &rest.Config{Host: "https://127.0.0.1:6443"}
+	c := newClientCache(10, defaultClientCacheTTL)
+
+	for _, apiOp := range []*types.APIRequest{userRequest("alice", "a"), userRequest("bob", "b")} {
+		if _, err := c.get(apiOp, cfg, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.get(userRequest("admin", "a"), cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(c.clients.Keys()); got != 3 {
+		t.Fatalf("got %d cached clients, want 3", got)
+	}
+
+	c.forget("a")
+
+	keys := c.clients.Keys()
+	if len(keys) != 2 {
+		t.Fatalf("got %d cached clients, want the clients of bob and the admin client", len(keys))
+	}
+	for _, key := range keys {
+		if key.(clientKey).user == "alice" {
+			t.Error("the client of alice was not forgotten")
+		}
+	}
+}
+
+func TestClientCacheSharesClientOfIdentity(t *testing.T) {
+	cfg := &rest.Config{Host: "https://127.0.0.1:6443"}
+	c := newClientCache(10, defaultClientCacheTTL)
+
+	first, err := c.get(userRequest("alice", "a"), cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.get(userRequest("alice", "a"), cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("got a new client for the same identity")
+	}
+}
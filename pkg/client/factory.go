@@ -12,6 +12,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 type Factory struct {
@@ -22,6 +23,7 @@ type Factory struct {
 	watchClientCfg      *rest.Config
 	metadata            metadata.Interface
 	dynamic             dynamic.Interface
+	clients             *clientCache
 	Config              *rest.Config
 }
 
@@ -40,10 +42,70 @@ func (a *addQuery) RoundTrip(req *http.Request) (*http.Response, error) {
 	return a.next.RoundTrip(req)
 }
 
-func NewFactory(cfg *rest.Config, impersonate bool) (*Factory, error) {
+// Option configures the clients created by a Factory.
+type Option func(*factoryOptions)
+
+type factoryOptions struct {
+	cfg             *rest.Config
+	clientCacheSize int
+	clientCacheTTL  time.Duration
+	limiters        *userLimiters
+}
+
+// WithRateLimit limits the requests the factory's clients send for each user to qps per second with bursts of up
+// to burst requests. Every user has its own token bucket, keyed by the impersonated user name, shared by all the
+// clients of the user, so one busy user doesn't slow down the others; requests steve sends as itself share one
+// more bucket. It replaces the rate limiter of the rest config, including ratelimit.None set by
+// server.RestConfigDefaults. Requests over the limit wait in steve, before the apiserver sees them, so a limit set
+// too low shows as latency rather than errors, while the apiserver's own priority and fairness still answers with
+// 429 when it is overloaded. Without this option each client has its own limiter of 10000 qps and a burst of 100,
+// unless the rest config sets one.
+func WithRateLimit(qps float32, burst int) Option {
+	return func(o *factoryOptions) {
+		o.cfg.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+		o.limiters = newUserLimiters(qps, burst)
+	}
+}
+
+// WithClientCache keeps up to size dynamic clients, per impersonated identity, for ttl after they were created
+// instead of the default of 1000 clients for 10 minutes. A size of zero or less builds a client for every call.
+func WithClientCache(size int, ttl time.Duration) Option {
+	return func(o *factoryOptions) {
+		o.clientCacheSize = size
+		o.clientCacheTTL = ttl
+	}
+}
+
+func NewFactory(cfg *rest.Config, impersonate bool, opts ...Option) (*Factory, error) {
 	clientCfg := rest.CopyConfig(cfg)
 	clientCfg.QPS = 10000
 	clientCfg.Burst = 100
+	options := &factoryOptions{
+		cfg:             clientCfg,
+		clientCacheSize: defaultClientCacheSize,
+		clientCacheTTL:  defaultClientCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	clientCfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &fieldValidation{
+			next: rt,
+		}
+	})
+	if options.limiters != nil {
+		clientCfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &rateLimit{
+				limiters: options.limiters,
+				next:     rt,
+			}
+		})
+	}
+
+	var clients *clientCache
+	if options.clientCacheSize > 0 {
+		clients = newClientCache(options.clientCacheSize, options.clientCacheTTL)
+	}
 
 	watchClientCfg := rest.CopyConfig(clientCfg)
 	watchClientCfg.Timeout = 30 * time.Minute
@@ -77,6 +139,7 @@ func NewFactory(cfg *rest.Config, impersonate bool) (*Factory, error) {
 	return &Factory{
 		dynamic:             d,
 		metadata:            md,
+		clients:             clients,
 		impersonate:         impersonate,
 		tableClientCfg:      tableClientCfg,
 		tableWatchClientCfg: tableWatchClientCfg,
@@ -98,6 +161,22 @@ func (p *Factory) IsImpersonating() bool {
 	return p.impersonate
 }
 
+// ResetClients drops the cached dynamic clients, call it after changing the rest config the factory was made
+// with, for example when its credentials are rotated.
+func (p *Factory) ResetClients() {
+	if p.clients != nil {
+		p.clients.reset()
+	}
+}
+
+// Forget drops the cached clients of the identities with the access set ID accessID, it implements
+// idle.Forgetter.
+func (p *Factory) Forget(accessID string) {
+	if p.clients != nil {
+		p.clients.forget(accessID)
+	}
+}
+
 func (p *Factory) K8sInterface(ctx *types.APIRequest) (kubernetes.Interface, error) {
 	cfg, err := setupConfig(ctx, p.clientCfg, p.impersonate)
 	if err != nil {
@@ -112,49 +191,49 @@ func (p *Factory) AdminK8sInterface() (kubernetes.Interface, error) {
 }
 
 func (p *Factory) DynamicClient(ctx *types.APIRequest) (dynamic.Interface, error) {
-	return newDynamicClient(ctx, p.clientCfg, p.impersonate)
+	return p.dynamicClient(ctx, p.clientCfg, p.impersonate)
 }
 
 func (p *Factory) Client(ctx *types.APIRequest, s *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
-	return newClient(ctx, p.clientCfg, s, namespace, p.impersonate)
+	return p.newClient(ctx, p.clientCfg, s, namespace, p.impersonate)
 }
 
 func (p *Factory) AdminClient(ctx *types.APIRequest, s *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
-	return newClient(ctx, p.clientCfg, s, namespace, false)
+	return p.newClient(ctx, p.clientCfg, s, namespace, false)
 }
 
 func (p *Factory) ClientForWatch(ctx *types.APIRequest, s *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
-	return newClient(ctx, p.watchClientCfg, s, namespace, p.impersonate)
+	return p.newClient(ctx, p.watchClientCfg, s, namespace, p.impersonate)
 }
 
 func (p *Factory) AdminClientForWatch(ctx *types.APIRequest, s *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
-	return newClient(ctx, p.watchClientCfg, s, namespace, false)
+	return p.newClient(ctx, p.watchClientCfg, s, namespace, false)
 }
 
 func (p *Factory) TableClient(ctx *types.APIRequest, s *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
 	if attributes.Table(s) {
-		return newClient(ctx, p.tableClientCfg, s, namespace, p.impersonate)
+		return p.newClient(ctx, p.tableClientCfg, s, namespace, p.impersonate)
 	}
 	return p.Client(ctx, s, namespace)
 }
 
 func (p *Factory) TableAdminClient(ctx *types.APIRequest, s *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
 	if attributes.Table(s) {
-		return newClient(ctx, p.tableClientCfg, s, namespace, false)
+		return p.newClient(ctx, p.tableClientCfg, s, namespace, false)
 	}
 	return p.AdminClient(ctx, s, namespace)
 }
 
 func (p *Factory) TableClientForWatch(ctx *types.APIRequest, s *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
 	if attributes.Table(s) {
-		return newClient(ctx, p.tableWatchClientCfg, s, namespace, p.impersonate)
+		return p.newClient(ctx, p.tableWatchClientCfg, s, namespace, p.impersonate)
 	}
 	return p.ClientForWatch(ctx, s, namespace)
 }
 
 func (p *Factory) TableAdminClientForWatch(ctx *types.APIRequest, s *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
 	if attributes.Table(s) {
-		return newClient(ctx, p.tableWatchClientCfg, s, namespace, false)
+		return p.newClient(ctx, p.tableWatchClientCfg, s, namespace, false)
 	}
 	return p.AdminClientForWatch(ctx, s, namespace)
 }
@@ -182,8 +261,15 @@ func newDynamicClient(ctx *types.APIRequest, cfg *rest.Config, impersonate bool)
 	return dynamic.NewForConfig(cfg)
 }
 
-func newClient(ctx *types.APIRequest, cfg *rest.Config, s *types.APISchema, namespace string, impersonate bool) (dynamic.ResourceInterface, error) {
-	client, err := newDynamicClient(ctx, cfg, impersonate)
+func (p *Factory) dynamicClient(ctx *types.APIRequest, cfg *rest.Config, impersonate bool) (dynamic.Interface, error) {
+	if p.clients == nil {
+		return newDynamicClient(ctx, cfg, impersonate)
+	}
+	return p.clients.get(ctx, cfg, impersonate)
+}
+
+func (p *Factory) newClient(ctx *types.APIRequest, cfg *rest.Config, s *types.APISchema, namespace string, impersonate bool) (dynamic.ResourceInterface, error) {
+	client, err := p.dynamicClient(ctx, cfg, impersonate)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type fieldValidationKey struct{}
+
+type warningsKey struct{}
+
+// Warnings collects the Warning headers of the apiserver responses to requests made with its context.
+type Warnings struct {
+	lock     sync.Mutex
+	warnings []string
+}
+
+func (w *Warnings) add(warnings []string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.warnings = append(w.warnings, warnings...)
+}
+
+// List returns the collected Warning header values.
+func (w *Warnings) List() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]string(nil), w.warnings...)
+}
+
+// WithFieldValidation returns a context that makes the creates, updates and patches sent with it pass
+// fieldValidation=value, Strict, Warn or Ignore, to the apiserver, and collects the warnings of their responses.
+// Callers only set it for apiservers that support it, see clusterversion.FieldValidation.
+func WithFieldValidation(ctx context.Context, value string) (context.Context, *Warnings) {
+	warnings := &Warnings{}
+	ctx = context.WithValue(ctx, fieldValidationKey{}, value)
+	return context.WithValue(ctx, warningsKey{}, warnings), warnings
+}
+
+// fieldValidation adds the field validation of the request context to writes and collects the warnings from the
+// response.
+type fieldValidation struct {
+	next http.RoundTripper
+}
+
+func (f *fieldValidation) RoundTrip(req *http.Request) (*http.Response, error) {
+	value, _ := req.Context().Value(fieldValidationKey{}).(string)
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		value = ""
+	}
+
+	if value != "" {
+		req = req.Clone(req.Context())
+		q := req.URL.Query()
+		q.Set("fieldValidation", value)
+		req.URL.RawQuery = q.Encode()
+	}
+
+	resp, err := f.next.RoundTrip(req)
+	if resp != nil {
+		if warnings, ok := req.Context().Value(warningsKey{}).(*Warnings); ok && len(resp.Header["Warning"]) > 0 {
+			warnings.add(resp.Header["Warning"])
+		}
+	}
+	return resp, err
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestFieldValidationRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		value  string
+		want   string
+	}{
+		{name: "write", method: http.MethodPost, value: "Strict", want: "Strict"},
+		{name: "patch", method: http.MethodPatch, value: "Warn", want: "Warn"},
+		{name: "read", method: http.MethodGet, value: "Strict", want: ""},
+		{name: "not requested", method: http.MethodPut, value: "", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sent string
+			f := &fieldValidation{next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				sent = req.URL.Query().Get("fieldValidation")
+				resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+				resp.Header.Add("Warning", `299 - "unknown field \"spec.foo\""`)
+				return resp, nil
+			})}
+
+			ctx, warnings := WithFieldValidation(context.Background(), test.value)
+			req := httptest.NewRequest(test.method, "/api/v1/namespaces/default/configmaps", nil).WithContext(ctx)
+			if _, err := f.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+			if sent != test.want {
+				t.Errorf("sent fieldValidation %q, want %q", sent, test.want)
+			}
+			if len(warnings.List()) != 1 {
+				t.Errorf("got warnings %v, want the warning of the response", warnings.List())
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/transport"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// idleLimiterExpiry is how long the token bucket of a user is kept after its last request.
+const idleLimiterExpiry = 10 * time.Minute
+
+type userLimiter struct {
+	limiter  flowcontrol.RateLimiter
+	lastUsed time.Time
+}
+
+// userLimiters are the token buckets of the users, by the user name a request impersonates. Requests that
+// impersonate no one, those steve sends as itself, share a bucket. Buckets of users that sent nothing for
+// idleLimiterExpiry are dropped, a user that comes back starts with a full burst.
+type userLimiters struct {
+	qps   float32
+	burst int
+
+	lock      sync.Mutex
+	limiters  map[string]*userLimiter
+	lastSweep time.Time
+}
+
+func newUserLimiters(qps float32, burst int) *userLimiters {
+	return &userLimiters{
+		qps:      qps,
+		burst:    burst,
+		limiters: map[string]*userLimiter{},
+	}
+}
+
+func (u *userLimiters) get(user string) flowcontrol.RateLimiter {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(u.lastSweep) > idleLimiterExpiry {
+		for name, l := range u.limiters {
+			if now.Sub(l.lastUsed) > idleLimiterExpiry {
+				delete(u.limiters, name)
+			}
+		}
+		u.lastSweep = now
+	}
+
+	l, ok := u.limiters[user]
+	if !ok {
+		l = &userLimiter{limiter: flowcontrol.NewTokenBucketRateLimiter(u.qps, u.burst)}
+		u.limiters[user] = l
+	}
+	l.lastUsed = now
+	return l.limiter
+}
+
+// rateLimit holds each request until the bucket of the user it impersonates has a token. It runs after the
+// impersonation headers are set, client-go adds them outside of the wrappers of the rest config.
+type rateLimit struct {
+	limiters *userLimiters
+	next     http.RoundTripper
+}
+
+func (r *rateLimit) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := r.limiters.get(req.Header.Get(transport.ImpersonateUserHeader)).Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return r.next.RoundTrip(req)
+}
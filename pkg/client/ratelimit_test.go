@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/transport"
+)
+
+func TestRateLimitIsPerUser(t *testing.T) {
+	r := &rateLimit{
+		limiters: newUserLimiters(0.001, 1),
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}),
+	}
+	send := func(user string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/pods", nil).WithContext(ctx)
+		if user != "" {
+			req.Header.Set(transport.ImpersonateUserHeader, user)
+		}
+		_, err := r.RoundTrip(req)
+		return err
+	}
+
+	if err := send("alice"); err != nil {
+		t.Fatalf("got %v for the first request of alice", err)
+	}
+	if err := send("alice"); err == nil {
+		t.Error("got no error for a request of alice over the limit")
+	}
+	if err := send("bob"); err != nil {
+		t.Errorf("got %v for bob, want a bucket apart from alice", err)
+	}
+	if err := send(""); err != nil {
+		t.Errorf("got %v for a request without impersonation, want it limited apart from the users", err)
+	}
+}
+
+func TestIdleLimitersAreDropped(t *testing.T) {
+	l := newUserLimiters(1, 1)
+	alice := l.get("alice")
+	if l.get("alice") != alice {
+		t.Fatal("got a new bucket for the same user")
+	}
+
+	l.limiters["alice"].lastUsed = time.Now().Add(-2 * idleLimiterExpiry)
+	l.lastSweep = time.Now().Add(-2 * idleLimiterExpiry)
+	l.get("bob")
+	if _, ok := l.limiters["alice"]; ok {
+		t.Error("the bucket of the idle user was kept")
+	}
+	if _, ok := l.limiters["bob"]; !ok {
+		t.Error("the bucket of bob is missing")
+	}
+}
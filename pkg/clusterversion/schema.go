@@ -0,0 +1,69 @@
+package clusterversion
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// ClusterVersion is the version of the cluster and which of the Features steve uses with it, served by the
+// clusterVersion schema as the object with ID local.
+type ClusterVersion struct {
+	GitVersion string          `json:"gitVersion,omitempty"`
+	Major      string          `json:"major,omitempty"`
+	Minor      string          `json:"minor,omitempty"`
+	Platform   string          `json:"platform,omitempty"`
+	Features   map[string]bool `json:"features,omitempty"`
+}
+
+func Register(schemas *types.APISchemas, tracker *Tracker) {
+	schemas.MustImportAndCustomize(ClusterVersion{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{http.MethodGet}
+		schema.Store = &Store{
+			tracker: tracker,
+		}
+	})
+}
+
+// Store serves the version of the tracker as the single object "local".
+type Store struct {
+	empty.Store
+	tracker *Tracker
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	if id != "local" {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, "cluster version "+id+" not found")
+	}
+	return s.local(), nil
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	return types.APIObjectList{
+		Objects: []types.APIObject{s.local()},
+	}, nil
+}
+
+func (s *Store) local() types.APIObject {
+	result := ClusterVersion{
+		Features: map[string]bool{},
+	}
+	if info := s.tracker.Info(); info != nil {
+		result.GitVersion = info.GitVersion
+		result.Major = info.Major
+		result.Minor = info.Minor
+		result.Platform = info.Platform
+	}
+	for _, feature := range Features {
+		result.Features[feature.Name] = s.tracker.Supports(feature)
+	}
+	return types.APIObject{
+		Type:   "clusterVersion",
+		ID:     "local",
+		Object: result,
+	}
+}
@@ -0,0 +1,134 @@
+// Package clusterversion keeps the Kubernetes version of the cluster steve serves, so features the apiserver only
+// has from some version on are used against clusters that have them and left out against older ones.
+package clusterversion
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+)
+
+// DefaultRefreshInterval is how often Start reads the version again, the version changes when the cluster is
+// upgraded.
+const DefaultRefreshInterval = 10 * time.Minute
+
+// Feature is an apiserver behavior available from MinVersion on.
+type Feature struct {
+	Name       string
+	MinVersion *utilversion.Version
+}
+
+var (
+	// ServerSideApply is PATCH with Content-Type application/apply-patch+yaml, on by default since 1.16.
+	ServerSideApply = Feature{Name: "serverSideApply", MinVersion: utilversion.MustParseGeneric("v1.16.0")}
+	// WatchBookmarks is allowWatchBookmarks on watches, on by default since 1.16.
+	WatchBookmarks = Feature{Name: "watchBookmarks", MinVersion: utilversion.MustParseGeneric("v1.16.0")}
+	// FieldValidation is ?fieldValidation= on writes, honored without a feature gate since 1.25.
+	FieldValidation = Feature{Name: "fieldValidation", MinVersion: utilversion.MustParseGeneric("v1.25.0")}
+
+	// Features are the features reported by the clusterversion schema.
+	Features = []Feature{ServerSideApply, WatchBookmarks, FieldValidation}
+)
+
+// Tracker caches the version of the apiserver. A nil Tracker supports every feature, nothing is gated.
+type Tracker struct {
+	discovery discovery.ServerVersionInterface
+
+	lock   sync.RWMutex
+	info   *version.Info
+	parsed *utilversion.Version
+
+	// decisions holds the last logged support of each feature by name
+	decisions sync.Map
+}
+
+func New(discovery discovery.ServerVersionInterface) *Tracker {
+	return &Tracker{
+		discovery: discovery,
+	}
+}
+
+// Start reads the version and then reads it again every interval until ctx is done. An interval of zero or less
+// uses DefaultRefreshInterval. It returns once the first read is done, failed reads are logged and retried at the
+// next interval.
+func (t *Tracker) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	if err := t.Refresh(); err != nil {
+		logrus.Warnf("failed to read the cluster version: %v", err)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.Refresh(); err != nil {
+					logrus.Debugf("failed to refresh the cluster version: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Refresh reads the version from the apiserver.
+func (t *Tracker) Refresh() error {
+	info, err := t.discovery.ServerVersion()
+	if err != nil {
+		return err
+	}
+	parsed, err := utilversion.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.info == nil || t.info.GitVersion != info.GitVersion {
+		logrus.Debugf("cluster version is %s", info.GitVersion)
+	}
+	t.info = info
+	t.parsed = parsed
+	return nil
+}
+
+// Info returns the last version read, nil before the first successful read.
+func (t *Tracker) Info() *version.Info {
+	if t == nil {
+		return nil
+	}
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.info
+}
+
+// Supports reports whether the cluster has feature. It is assumed not to while the version is unknown. Every
+// change of the decision for a feature is logged at debug level, so operators can see which mode is active.
+func (t *Tracker) Supports(feature Feature) bool {
+	if t == nil {
+		return true
+	}
+
+	t.lock.RLock()
+	parsed := t.parsed
+	t.lock.RUnlock()
+
+	supported := parsed != nil && parsed.AtLeast(feature.MinVersion)
+	if last, ok := t.decisions.Load(feature.Name); !ok || last.(bool) != supported {
+		t.decisions.Store(feature.Name, supported)
+		if supported {
+			logrus.Debugf("cluster version %v supports %s, using it", parsed, feature.Name)
+		} else {
+			logrus.Debugf("cluster version %v is older than %v or unknown, not using %s", parsed, feature.MinVersion, feature.Name)
+		}
+	}
+	return supported
+}
@@ -0,0 +1,151 @@
+package idle
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Forgetter drops the state kept for an identity. It is called by the Tracker once the identity has been idle
+// for longer than the expiry, the identity is rebuilt on its next request.
+type Forgetter interface {
+	Forget(id string)
+}
+
+// ForgetterFunc is a func that implements Forgetter.
+type ForgetterFunc func(id string)
+
+func (f ForgetterFunc) Forget(id string) {
+	f(id)
+}
+
+// Stats are the current values of the tracker metrics. Tracked is a gauge, Sweeps and Forgotten are counters.
+type Stats struct {
+	Tracked   int
+	Sweeps    int64
+	Forgotten int64
+}
+
+// Tracker records when every identity, usually an access set ID, was last seen and periodically sweeps the
+// per identity state of the registered forgetters for identities idle beyond the expiry. Identities with an
+// active watch are never swept.
+type Tracker struct {
+	expiry time.Duration
+
+	lock       sync.Mutex
+	lastSeen   map[string]time.Time
+	active     map[string]int
+	forgetters []Forgetter
+
+	sweeps    int64
+	forgotten int64
+}
+
+func NewTracker(expiry time.Duration) *Tracker {
+	return &Tracker{
+		expiry:   expiry,
+		lastSeen: map[string]time.Time{},
+		active:   map[string]int{},
+	}
+}
+
+// Register adds forgetters whose state is dropped when an identity is swept.
+func (t *Tracker) Register(forgetters ...Forgetter) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.forgetters = append(t.forgetters, forgetters...)
+}
+
+// Seen marks id as used now.
+func (t *Tracker) Seen(id string) {
+	if id == "" {
+		return
+	}
+	t.lock.Lock()
+	t.lastSeen[id] = time.Now()
+	t.lock.Unlock()
+}
+
+// Active marks id as having a long running request, such as a watch, until the returned func is called.
+func (t *Tracker) Active(id string) func() {
+	if id == "" {
+		return func() {}
+	}
+
+	t.lock.Lock()
+	t.lastSeen[id] = time.Now()
+	t.active[id]++
+	t.lock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.lastSeen[id] = time.Now()
+			if t.active[id]--; t.active[id] <= 0 {
+				delete(t.active, id)
+			}
+		})
+	}
+}
+
+// Start sweeps every interval until ctx is done.
+func (t *Tracker) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if swept := t.Sweep(); len(swept) > 0 {
+					logrus.Debugf("dropped state of %d idle identities", len(swept))
+				}
+			}
+		}
+	}()
+}
+
+// Sweep forgets every identity idle beyond the expiry without an active watch and returns them.
+func (t *Tracker) Sweep() []string {
+	cutoff := time.Now().Add(-t.expiry)
+
+	t.lock.Lock()
+	var swept []string
+	for id, seen := range t.lastSeen {
+		if t.active[id] > 0 || seen.After(cutoff) {
+			continue
+		}
+		delete(t.lastSeen, id)
+		swept = append(swept, id)
+	}
+	forgetters := t.forgetters
+	t.lock.Unlock()
+
+	for _, id := range swept {
+		for _, f := range forgetters {
+			f.Forget(id)
+		}
+	}
+
+	atomic.AddInt64(&t.sweeps, 1)
+	atomic.AddInt64(&t.forgotten, int64(len(swept)))
+	return swept
+}
+
+func (t *Tracker) Stats() Stats {
+	t.lock.Lock()
+	tracked := len(t.lastSeen)
+	t.lock.Unlock()
+
+	return Stats{
+		Tracked:   tracked,
+		Sweeps:    atomic.LoadInt64(&t.sweeps),
+		Forgotten: atomic.LoadInt64(&t.forgotten),
+	}
+}
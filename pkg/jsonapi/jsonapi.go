@@ -0,0 +1,161 @@
+// Package jsonapi converts Kubernetes objects to and from the JSON:API (https://jsonapi.org) document format.
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/jsonnumber"
+)
+
+// MediaType is the media type of JSON:API documents.
+const MediaType = "application/vnd.api+json"
+
+// Resource is a JSON:API resource object.
+type Resource struct {
+	Type          string                  `json:"type"`
+	ID            string                  `json:"id,omitempty"`
+	Attributes    map[string]interface{}  `json:"attributes,omitempty"`
+	Relationships map[string]Relationship `json:"relationships,omitempty"`
+	Meta          map[string]interface{}  `json:"meta,omitempty"`
+}
+
+// Relationship is a to-many relationship of resource identifiers.
+type Relationship struct {
+	Data []Identifier `json:"data"`
+}
+
+// Identifier identifies a related resource, Meta holds the apiVersion and uid of an owner reference.
+type Identifier struct {
+	Type string                 `json:"type"`
+	ID   string                 `json:"id"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+// Document is a JSON:API top level document, Data is a Resource or a list of them.
+type Document struct {
+	Data interface{}            `json:"data"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+// JSONAPITransformer maps an object to a JSON:API resource: the type and id of the object become data.type and
+// data.id, spec becomes data.attributes, owner references become the owners relationship and every other top
+// level field, metadata and status included, goes to data.meta so the conversion round trips.
+type JSONAPITransformer struct{}
+
+func (JSONAPITransformer) ToJSONAPI(obj types.APIObject) Resource {
+	data := copyMap(obj.Data())
+	resource := Resource{
+		Type: obj.Type,
+		ID:   obj.ID,
+		Meta: map[string]interface{}{},
+	}
+
+	if spec, ok := data["spec"].(map[string]interface{}); ok {
+		resource.Attributes = spec
+		delete(data, "spec")
+	}
+
+	if metadata, ok := data["metadata"].(map[string]interface{}); ok {
+		if owners, ok := metadata["ownerReferences"].([]interface{}); ok {
+			relationship := Relationship{Data: []Identifier{}}
+			for _, owner := range owners {
+				ref, _ := owner.(map[string]interface{})
+				kind, _ := ref["kind"].(string)
+				name, _ := ref["name"].(string)
+				meta := map[string]interface{}{}
+				for k, v := range ref {
+					if k != "kind" && k != "name" {
+						meta[k] = v
+					}
+				}
+				relationship.Data = append(relationship.Data, Identifier{
+					Type: kind,
+					ID:   name,
+					Meta: meta,
+				})
+			}
+			resource.Relationships = map[string]Relationship{"owners": relationship}
+			delete(metadata, "ownerReferences")
+		}
+	}
+
+	for k, v := range data {
+		resource.Meta[k] = v
+	}
+	return resource
+}
+
+// FromJSONAPI reverses ToJSONAPI, it returns the object for a resource.
+func (JSONAPITransformer) FromJSONAPI(resource Resource) map[string]interface{} {
+	obj := map[string]interface{}{}
+	for k, v := range copyMap(resource.Meta) {
+		obj[k] = v
+	}
+	if resource.Attributes != nil {
+		obj["spec"] = copyMap(resource.Attributes)
+	}
+
+	owners, ok := resource.Relationships["owners"]
+	if !ok || len(owners.Data) == 0 {
+		return obj
+	}
+
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		obj["metadata"] = metadata
+	}
+	var refs []interface{}
+	for _, owner := range owners.Data {
+		ref := copyMap(owner.Meta)
+		ref["kind"] = owner.Type
+		ref["name"] = owner.ID
+		refs = append(refs, ref)
+	}
+	metadata["ownerReferences"] = refs
+	return obj
+}
+
+// DecodeDocument returns the object of a JSON:API document holding a single resource.
+func DecodeDocument(body []byte) (map[string]interface{}, error) {
+	doc := struct {
+		Data *Resource `json:"data"`
+	}{}
+	// numbers are kept as json.Number so large integers in the attributes are not rounded to a float64
+	if err := jsonnumber.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Data == nil {
+		return nil, fmt.Errorf("JSON:API document has no data")
+	}
+	return JSONAPITransformer{}.FromJSONAPI(*doc.Data), nil
+}
+
+// Accepts returns true if the client asked for JSON:API responses.
+func Accepts(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), MediaType)
+}
+
+// IsDocument returns true if the request body is a JSON:API document.
+func IsDocument(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), MediaType)
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	if m == nil {
+		return result
+	}
+	bytes, err := json.Marshal(m)
+	if err != nil {
+		return m
+	}
+	if err := jsonnumber.Unmarshal(bytes, &result); err != nil {
+		return m
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// deployment is the object of the tests as it is decoded from the apiserver, with numbers as json.Number.
+func deployment(t *testing.T) map[string]interface{} {
+	t.Helper()
+	obj := map[string]interface{}{}
+	err := jsonnumber.Unmarshal([]byte(`{
+		"apiVersion": "apps/v1",
+		"kind": "Deployment",
+		"metadata": {
+			"name": "web",
+			"namespace": "default",
+			"labels": {"app": "web"},
+			"ownerReferences": [
+				{"apiVersion": "example.com/v1", "kind": "App", "name": "shop", "uid": "1234", "controller": true}
+			]
+		},
+		"spec": {"replicas": 3, "revisionHistoryLimit": 9007199254740993, "template": {"spec": {"containers": [{"name": "web"}]}}},
+		"status": {"readyReplicas": 2}
+	}`), &obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func apiObject(obj map[string]interface{}) types.APIObject {
+	return types.APIObject{
+		Type:   "apps.deployment",
+		ID:     "default/web",
+		Object: &unstructured.Unstructured{Object: obj},
+	}
+}
+
+func TestToJSONAPI(t *testing.T) {
+	resource := JSONAPITransformer{}.ToJSONAPI(apiObject(deployment(t)))
+
+	if resource.Type != "apps.deployment" || resource.ID != "default/web" {
+		t.Errorf("got type %q and id %q, want the type and id of the object", resource.Type, resource.ID)
+	}
+	if resource.Attributes["revisionHistoryLimit"] != json.Number("9007199254740993") {
+		t.Errorf("got attributes %v, want the spec", resource.Attributes)
+	}
+	owners := resource.Relationships["owners"].Data
+	if len(owners) != 1 || owners[0].Type != "App" || owners[0].ID != "shop" || owners[0].Meta["uid"] != "1234" {
+		t.Errorf("got owners %+v, want the owner reference", owners)
+	}
+	metadata, _ := resource.Meta["metadata"].(map[string]interface{})
+	if _, ok := metadata["ownerReferences"]; ok || metadata["name"] != "web" {
+		t.Errorf("got metadata %v, want it without the owner references", metadata)
+	}
+	if _, ok := resource.Meta["spec"]; ok || resource.Meta["status"] == nil {
+		t.Errorf("got meta %v, want every field but spec", resource.Meta)
+	}
+}
+
+func TestJSONAPIRoundTrip(t *testing.T) {
+	transformer := JSONAPITransformer{}
+	original := deployment(t)
+	resource := transformer.ToJSONAPI(apiObject(deployment(t)))
+
+	// through the wire like a client would send it back
+	body, err := json.Marshal(Document{Data: resource})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeDocument(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("got\n%v\nwant\n%v", decoded, original)
+	}
+
+	// and without it
+	if converted := transformer.FromJSONAPI(resource); !reflect.DeepEqual(converted, original) {
+		t.Errorf("got\n%v\nwant\n%v", converted, original)
+	}
+}
+
+func TestDecodeDocumentWithoutData(t *testing.T) {
+	for _, body := range []string{`{}`, `{"data": null}`, `{"data":`} {
+		if obj, err := DecodeDocument([]byte(body)); err == nil {
+			t.Errorf("%s: got %v, want an error", body, obj)
+		}
+	}
+}
+
+// recordingWriter is the writer of the regular clients.
+type recordingWriter struct {
+	types.ResponseWriter
+	written bool
+}
+
+func (r *recordingWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	r.written = true
+}
+
+func TestResponseWriter(t *testing.T) {
+	for _, test := range []struct {
+		accept  string
+		jsonAPI bool
+	}{
+		{accept: MediaType, jsonAPI: true},
+		{accept: "application/json"},
+		{},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/apps.deployments/default/web", nil)
+		req.Header.Set("Accept", test.accept)
+		rw := httptest.NewRecorder()
+		next := &recordingWriter{}
+		(&ResponseWriter{Next: next}).Write(&types.APIRequest{Request: req, Response: rw}, http.StatusOK, apiObject(deployment(t)))
+
+		if next.written == test.jsonAPI {
+			t.Errorf("Accept %q: got the regular writer used %v", test.accept, next.written)
+		}
+		if !test.jsonAPI {
+			continue
+		}
+		if contentType := rw.Header().Get("Content-Type"); contentType != MediaType {
+			t.Errorf("got content type %q, want %s", contentType, MediaType)
+		}
+		doc := struct {
+			Data Resource `json:"data"`
+		}{}
+		if err := json.Unmarshal(rw.Body.Bytes(), &doc); err != nil || doc.Data.ID != "default/web" {
+			t.Errorf("got %s %v, want a JSON:API document of the deployment", rw.Body, err)
+		}
+	}
+}
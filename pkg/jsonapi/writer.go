@@ -0,0 +1,60 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// ResponseWriter writes JSON:API documents to clients that send Accept: application/vnd.api+json and uses Next
+// for every other client.
+type ResponseWriter struct {
+	Next        types.ResponseWriter
+	Transformer JSONAPITransformer
+}
+
+func (r *ResponseWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	// errors keep the regular format
+	if !Accepts(apiOp.Request) || obj.Object == nil || obj.Type == "error" {
+		r.Next.Write(apiOp, code, obj)
+		return
+	}
+	r.write(apiOp, code, Document{
+		Data: r.Transformer.ToJSONAPI(obj),
+	})
+}
+
+func (r *ResponseWriter) WriteList(apiOp *types.APIRequest, code int, list types.APIObjectList) {
+	if !Accepts(apiOp.Request) {
+		r.Next.WriteList(apiOp, code, list)
+		return
+	}
+
+	resources := make([]Resource, 0, len(list.Objects))
+	for _, obj := range list.Objects {
+		resources = append(resources, r.Transformer.ToJSONAPI(obj))
+	}
+
+	doc := Document{
+		Data: resources,
+	}
+	if list.Revision != "" || list.Continue != "" {
+		doc.Meta = map[string]interface{}{
+			"revision": list.Revision,
+			"continue": list.Continue,
+		}
+	}
+	r.write(apiOp, code, doc)
+}
+
+func (r *ResponseWriter) write(apiOp *types.APIRequest, code int, doc Document) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		apiOp.Response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	apiOp.Response.Header().Set("Content-Type", MediaType)
+	apiOp.Response.WriteHeader(code)
+	_, _ = apiOp.Response.Write(body)
+}
@@ -0,0 +1,15 @@
+// Package jsonnumber decodes JSON keeping numbers as json.Number, so integers too large for a float64 survive
+// being decoded and encoded again.
+package jsonnumber
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Unmarshal is json.Unmarshal with the numbers of data decoded as json.Number instead of float64.
+func Unmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/slice"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/proxy"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// AddStreamingActions registers the subresources, such as exec or portforward, that stream over an upgraded
+// connection as links of the schema served by the SubResourceProxyHandler.
+func AddStreamingActions(schema *types.APISchema, cfg *rest.Config, actions ...string) {
+	attributes.SetStreamingActions(schema, actions)
+	if schema.LinkHandlers == nil {
+		schema.LinkHandlers = map[string]http.Handler{}
+	}
+	handler := SubResourceProxyHandler(cfg)
+	for _, action := range actions {
+		schema.LinkHandlers[action] = handler
+	}
+}
+
+// SubResourceProxyHandler proxies the raw connection of a streaming subresource to the apiserver as the
+// requesting user. These can't go through the dynamic client because the response is a SPDY or
+// websocket stream and not an object.
+func SubResourceProxyHandler(cfg *rest.Config) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		apiOp := types.GetAPIContext(req.Context())
+		if apiOp == nil || apiOp.Schema == nil || apiOp.Name == "" ||
+			!slice.ContainsString(attributes.StreamingActions(apiOp.Schema), apiOp.Link) {
+			http.Error(rw, "not found", http.StatusNotFound)
+			return
+		}
+
+		user, ok := request.UserFrom(req.Context())
+		if !ok {
+			http.Error(rw, "not authorized", http.StatusUnauthorized)
+			return
+		}
+
+		cfg, authed := setupUserAuth(req, user, cfg)
+		if !authed {
+			http.Error(rw, "not authorized", http.StatusUnauthorized)
+			return
+		}
+
+		handler, err := subResourceHandler(cfg)
+		if err != nil {
+			logrus.Errorf("failed to proxy %s subresource for %v: %v", apiOp.Link, user, err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		req = req.Clone(req.Context())
+		req.URL.Path = subResourcePath(apiOp)
+		req.URL.RawPath = ""
+		for k := range req.Header {
+			if strings.HasPrefix(k, "Impersonate-") {
+				delete(req.Header, k)
+			}
+		}
+		req.Header.Del("Authorization")
+		handler.ServeHTTP(rw, req)
+	})
+}
+
+func subResourcePath(apiOp *types.APIRequest) string {
+	gvr := attributes.GVR(apiOp.Schema)
+	buf := &strings.Builder{}
+	if gvr.Group == "" {
+		buf.WriteString("/api/")
+	} else {
+		buf.WriteString("/apis/")
+		buf.WriteString(gvr.Group)
+		buf.WriteString("/")
+	}
+	buf.WriteString(gvr.Version)
+	if apiOp.Namespace != "" {
+		buf.WriteString("/namespaces/")
+		buf.WriteString(apiOp.Namespace)
+	}
+	buf.WriteString("/")
+	buf.WriteString(gvr.Resource)
+	buf.WriteString("/")
+	buf.WriteString(apiOp.Name)
+	buf.WriteString("/")
+	buf.WriteString(apiOp.Link)
+	return buf.String()
+}
+
+func subResourceHandler(cfg *rest.Config) (http.Handler, error) {
+	host := cfg.Host
+	if !strings.HasSuffix(host, "/") {
+		host = host + "/"
+	}
+	target, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+
+	rt, err := rest.TransportFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	upgradeTransport, err := makeSPDYUpgradeTransport(cfg, rt)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := proxy.NewUpgradeAwareHandler(target, rt, false, false, er)
+	handler.UpgradeTransport = upgradeTransport
+	handler.UseRequestLocation = true
+	handler.InterceptRedirects = false
+
+	if len(target.Path) > 1 {
+		return prependPath(target.Path[:len(target.Path)-1], handler), nil
+	}
+	return handler, nil
+}
+
+// makeSPDYUpgradeTransport dials upgraded connections with the SPDY round tripper from client-go. The
+// connection is only used as a raw stream, so websocket upgrades work over it too.
+func makeSPDYUpgradeTransport(cfg *rest.Config, rt http.RoundTripper) (proxy.UpgradeRequestRoundTripper, error) {
+	transportConfig, err := cfg.TransportConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	upgrader, err := transport.HTTPWrappersForConfig(transportConfig, proxy.MirrorRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	_, spdyUpgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	connection, ok := spdyUpgrader.(http.RoundTripper)
+	if !ok {
+		connection = rt
+	}
+
+	return proxy.NewUpgradeRequestRoundTripper(connection, upgrader), nil
+}
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	kubespdy "k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// spdyServer is an apiserver that upgrades every request to SPDY and echoes the streams opened on it.
+type spdyServer struct {
+	lock        sync.Mutex
+	path        string
+	impersonate string
+}
+
+func (s *spdyServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	s.path, s.impersonate = req.URL.Path, req.Header.Get("Impersonate-User")
+	s.lock.Unlock()
+
+	conn := kubespdy.NewResponseUpgrader().UpgradeResponse(rw, req, func(stream httpstream.Stream, replySent <-chan struct{}) error {
+		go func() {
+			_, _ = io.Copy(stream, stream)
+			_ = stream.Close()
+		}()
+		return nil
+	})
+	if conn == nil {
+		return
+	}
+	<-conn.CloseChan()
+}
+
+func podSchema(cfg *rest.Config) *types.APISchema {
+	s := &types.APISchema{Schema: &schemas.Schema{ID: "pod"}}
+	attributes.SetGVK(s, schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	attributes.SetResource(s, "pods")
+	attributes.SetNamespaced(s, true)
+	AddStreamingActions(s, cfg, "exec", "portforward")
+	return s
+}
+
+// linkServer serves the link handler of the schema for the pod default/web as the user alice, like the link
+// handling of the apiserver does.
+func linkServer(s *types.APISchema, link string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "alice"}))
+		apiOp := types.StoreAPIContext(&types.APIRequest{
+			Schema:    s,
+			Namespace: "default",
+			Name:      "web",
+			Link:      link,
+			Request:   req,
+			Response:  rw,
+		})
+		s.LinkHandlers[link].ServeHTTP(rw, apiOp.Request)
+	}))
+}
+
+func TestStreamingSubresourceIsProxied(t *testing.T) {
+	kube := &spdyServer{}
+	kubeServer := httptest.NewServer(kube)
+	defer kubeServer.Close()
+
+	steve := linkServer(podSchema(&rest.Config{Host: kubeServer.URL}), "exec")
+	defer steve.Close()
+
+	rt, upgrader, err := spdy.RoundTripperFor(&rest.Config{Host: steve.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := url.Parse(steve.URL + "/v1/pods/default/web?link=exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _, err := spdy.NewDialer(upgrader, &http.Client{Transport: rt}, http.MethodPost, target).Dial()
+	if err != nil {
+		t.Fatalf("upgrading the exec request: %v", err)
+	}
+	defer conn.Close()
+
+	stream, err := conn.CreateStream(http.Header{"streamType": []string{"stdin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	echo := make([]byte, len("hello"))
+	if _, err := io.ReadFull(stream, echo); err != nil {
+		t.Fatal(err)
+	}
+	if string(echo) != "hello" {
+		t.Errorf("got %q back from the stream, want hello", echo)
+	}
+
+	kube.lock.Lock()
+	defer kube.lock.Unlock()
+	if kube.path != "/api/v1/namespaces/default/pods/web/exec" {
+		t.Errorf("got the subresource proxied to %s", kube.path)
+	}
+	if kube.impersonate != "alice" {
+		t.Errorf("got the subresource proxied as %q, want alice", kube.impersonate)
+	}
+}
+
+func TestUnregisteredSubresourceIsNotProxied(t *testing.T) {
+	kube := &spdyServer{}
+	kubeServer := httptest.NewServer(kube)
+	defer kubeServer.Close()
+
+	s := podSchema(&rest.Config{Host: kubeServer.URL})
+	s.LinkHandlers["log"] = s.LinkHandlers["exec"]
+	steve := linkServer(s, "log")
+	defer steve.Close()
+
+	resp, err := http.Get(steve.URL + "/v1/pods/default/web?link=log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got status %d, want 404 for a subresource that doesn't stream", resp.StatusCode)
+	}
+	if kube.path != "" {
+		t.Errorf("got the request proxied to %s", kube.path)
+	}
+}
@@ -0,0 +1,55 @@
+package bulk
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/slice"
+)
+
+const (
+	labelsAction      = "labels"
+	annotationsAction = "annotations"
+)
+
+func Register(apiSchemas *types.APISchemas) {
+	apiSchemas.MustImportAndCustomize(&MetadataInput{}, nil)
+	apiSchemas.MustImportAndCustomize(&MetadataOutput{}, nil)
+	apiSchemas.MustImportAndCustomize(&MetadataResult{}, nil)
+}
+
+// AddMetadataActions adds the labels and annotations collection actions to a kubernetes schema that can be
+// listed and patched. Both take a MetadataInput and patch every object matching the selector.
+func AddMetadataActions(cg proxy.ClientGetter, schema *types.APISchema) {
+	if attributes.GVK(schema).Kind == "" {
+		return
+	}
+	verbs := attributes.Verbs(schema)
+	if !slice.ContainsString(verbs, "list") || !slice.ContainsString(verbs, "patch") {
+		return
+	}
+
+	if schema.ActionHandlers == nil {
+		schema.ActionHandlers = map[string]http.Handler{}
+	}
+	if schema.CollectionActions == nil {
+		schema.CollectionActions = map[string]schemas.Action{}
+	}
+
+	for _, action := range []string{labelsAction, annotationsAction} {
+		if _, ok := schema.ActionHandlers[action]; ok {
+			continue
+		}
+		schema.ActionHandlers[action] = &MetadataPatch{
+			cg:    cg,
+			field: action,
+		}
+		schema.CollectionActions[action] = schemas.Action{
+			Input:  "metadataInput",
+			Output: "metadataOutput",
+		}
+	}
+}
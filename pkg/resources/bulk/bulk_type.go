@@ -0,0 +1,23 @@
+package bulk
+
+// MetadataInput selects the objects to patch with Selector, an empty selector is only accepted with All set so
+// that a missing selector can't patch every object by accident.
+type MetadataInput struct {
+	Selector map[string]string `json:"selector,omitempty"`
+	All      bool              `json:"all,omitempty"`
+	Set      map[string]string `json:"set,omitempty"`
+	Remove   []string          `json:"remove,omitempty"`
+}
+
+type MetadataOutput struct {
+	Matched   int              `json:"matched"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []MetadataResult `json:"results"`
+}
+
+type MetadataResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
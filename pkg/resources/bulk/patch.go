@@ -0,0 +1,160 @@
+package bulk
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	apitypes "k8s.io/apimachinery/pkg/types"
+)
+
+const patchWorkers = 10
+
+// MetadataPatch adds and removes labels or annotations, depending on field, on every object of a schema that
+// matches a label selector. Each object is patched on its own so the response reports a result per object and
+// one failure does not stop the others.
+type MetadataPatch struct {
+	cg    proxy.ClientGetter
+	field string
+}
+
+func (m *MetadataPatch) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	var (
+		apiContext = types.GetAPIContext(req.Context())
+		input      MetadataInput
+	)
+
+	if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+		apiContext.WriteError(apierror.NewAPIError(validation.InvalidBodyContent, err.Error()))
+		return
+	}
+
+	if len(input.Set) == 0 && len(input.Remove) == 0 {
+		apiContext.WriteError(apierror.NewAPIError(validation.MissingRequired, "at least one of set or remove is required"))
+		return
+	}
+
+	if err := checkScope(apiContext, input); err != nil {
+		apiContext.WriteError(err)
+		return
+	}
+
+	output, err := m.patch(apiContext, input)
+	if err != nil {
+		apiContext.WriteError(err)
+		return
+	}
+
+	apiContext.WriteResponse(http.StatusOK, types.APIObject{
+		Type:   "metadataOutput",
+		Object: output,
+	})
+}
+
+// checkScope refuses patches that would reach more objects than the user is likely to mean: a selector is
+// required unless all is set and namespaced schemas are only patched within one namespace.
+func checkScope(apiContext *types.APIRequest, input MetadataInput) error {
+	if len(input.Selector) == 0 && !input.All {
+		return apierror.NewAPIError(validation.MissingRequired, "selector is required, set all to patch every object")
+	}
+	if len(input.Selector) > 0 && input.All {
+		return apierror.NewAPIError(validation.InvalidOption, "selector and all can not be combined")
+	}
+	if attributes.Namespaced(apiContext.Schema) && apiContext.Namespace == "" {
+		return apierror.NewAPIError(validation.MissingRequired, "namespace is required to patch "+apiContext.Schema.ID)
+	}
+	return nil
+}
+
+func (m *MetadataPatch) patch(apiContext *types.APIRequest, input MetadataInput) (*MetadataOutput, error) {
+	client, err := m.cg.Client(apiContext, apiContext.Schema, apiContext.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.List(apiContext.Context(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(input.Selector).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := m.patchBytes(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, patchWorkers)
+		results = make([]MetadataResult, len(list.Items))
+	)
+
+	for i := range list.Items {
+		wg.Add(1)
+		go func(i int, obj unstructured.Unstructured) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = MetadataResult{
+				ID:      objectID(&obj),
+				Success: true,
+			}
+
+			client, err := m.cg.Client(apiContext, apiContext.Schema, obj.GetNamespace())
+			if err == nil {
+				_, err = client.Patch(apiContext.Context(), obj.GetName(), apitypes.MergePatchType, patch, metav1.PatchOptions{})
+			}
+			if err != nil {
+				results[i].Success = false
+				results[i].Error = err.Error()
+			}
+		}(i, list.Items[i])
+	}
+	wg.Wait()
+
+	output := &MetadataOutput{
+		Matched: len(results),
+		Results: results,
+	}
+	for _, result := range results {
+		if result.Success {
+			output.Succeeded++
+		} else {
+			output.Failed++
+		}
+	}
+	return output, nil
+}
+
+func (m *MetadataPatch) patchBytes(input MetadataInput) ([]byte, error) {
+	values := map[string]interface{}{}
+	for _, key := range input.Remove {
+		values[key] = nil
+	}
+	for key, value := range input.Set {
+		values[key] = value
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			m.field: values,
+		},
+	})
+}
+
+func objectID(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
@@ -0,0 +1,178 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// fakeClient lists objects and fails the patches of the objects in failures.
+type fakeClient struct {
+	dynamic.ResourceInterface
+
+	lock     sync.Mutex
+	items    []unstructured.Unstructured
+	selector string
+	failures map[string]bool
+	patches  map[string]string
+}
+
+func (f *fakeClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.selector = opts.LabelSelector
+	return &unstructured.UnstructuredList{Items: f.items}, nil
+}
+
+func (f *fakeClient) Patch(ctx context.Context, name string, pt apitypes.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.failures[name] {
+		return nil, errors.New("patch of " + name + " failed")
+	}
+	f.patches[name] = string(data)
+	return &unstructured.Unstructured{}, nil
+}
+
+type fakeClientGetter struct {
+	proxy.ClientGetter
+	client *fakeClient
+}
+
+func (f *fakeClientGetter) Client(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return f.client, nil
+}
+
+func pods(names ...string) []unstructured.Unstructured {
+	var result []unstructured.Unstructured
+	for _, name := range names {
+		obj := unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetNamespace("default")
+		obj.SetName(name)
+		result = append(result, obj)
+	}
+	return result
+}
+
+func newRequest(namespaced bool, namespace string) *types.APIRequest {
+	schema := &types.APISchema{Schema: &schemas.Schema{ID: "pod"}}
+	attributes.SetNamespaced(schema, namespaced)
+	return &types.APIRequest{
+		Schema:    schema,
+		Namespace: namespace,
+		Request:   httptest.NewRequest(http.MethodPost, "/v1/pods?action=labels", nil),
+	}
+}
+
+func TestPatchReportsPartialFailures(t *testing.T) {
+	client := &fakeClient{
+		items:    pods("p1", "p2", "p3"),
+		failures: map[string]bool{"p2": true},
+		patches:  map[string]string{},
+	}
+	m := &MetadataPatch{cg: &fakeClientGetter{client: client}, field: labelsAction}
+
+	output, err := m.patch(newRequest(true, "default"), MetadataInput{
+		Selector: map[string]string{"app": "web"},
+		Set:      map[string]string{"app.kubernetes.io/managed-by": "helm"},
+		Remove:   []string{"old"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.Matched != 3 || output.Succeeded != 2 || output.Failed != 1 {
+		t.Errorf("got matched %d succeeded %d failed %d, want 3, 2 and 1", output.Matched, output.Succeeded, output.Failed)
+	}
+	sort.Slice(output.Results, func(i, j int) bool { return output.Results[i].ID < output.Results[j].ID })
+	for _, result := range output.Results {
+		want := result.ID != "default/p2"
+		if result.Success != want {
+			t.Errorf("%s: got success %v, want %v", result.ID, result.Success, want)
+		}
+		if !result.Success && result.Error == "" {
+			t.Errorf("%s: the error is not reported", result.ID)
+		}
+	}
+
+	if client.selector != "app=web" {
+		t.Errorf("listed with selector %q, want app=web", client.selector)
+	}
+	var patch map[string]map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(client.patches["p1"]), &patch); err != nil {
+		t.Fatal(err)
+	}
+	values := patch["metadata"]["labels"]
+	if values["app.kubernetes.io/managed-by"] != "helm" || values["old"] != nil {
+		t.Errorf("got patch %s, want the label set and old removed", client.patches["p1"])
+	}
+	if _, ok := values["old"]; !ok {
+		t.Errorf("got patch %s, want old set to null", client.patches["p1"])
+	}
+}
+
+func TestCheckScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		apiOp   *types.APIRequest
+		input   MetadataInput
+		wantErr bool
+	}{
+		{
+			name:    "empty selector",
+			apiOp:   newRequest(true, "default"),
+			input:   MetadataInput{},
+			wantErr: true,
+		},
+		{
+			name:  "empty selector with all",
+			apiOp: newRequest(true, "default"),
+			input: MetadataInput{All: true},
+		},
+		{
+			name:    "selector with all",
+			apiOp:   newRequest(true, "default"),
+			input:   MetadataInput{Selector: map[string]string{"app": "web"}, All: true},
+			wantErr: true,
+		},
+		{
+			name:    "namespaced without namespace",
+			apiOp:   newRequest(true, ""),
+			input:   MetadataInput{Selector: map[string]string{"app": "web"}},
+			wantErr: true,
+		},
+		{
+			name:  "cluster scoped without namespace",
+			apiOp: newRequest(false, ""),
+			input: MetadataInput{Selector: map[string]string{"app": "web"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkScope(test.apiOp, test.input)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(*apierror.APIError); !ok {
+					t.Errorf("got %T, want an API error", err)
+				}
+			}
+		})
+	}
+}
@@ -47,7 +47,10 @@ func (a *Apply) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := apply.WithDefaultNamespace(input.DefaultNamespace).ApplyObjects(objs...); err != nil {
+	err = proxy.DefaultWebhookRetry.Do(apiContext.Context(), func() error {
+		return apply.WithDefaultNamespace(input.DefaultNamespace).ApplyObjects(objs...)
+	})
+	if err != nil {
 		apiContext.WriteError(err)
 		return
 	}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"strings"
+	"time"
 
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
@@ -20,9 +21,10 @@ import (
 
 func DefaultTemplate(clientGetter proxy.ClientGetter,
 	summaryCache *summarycache.SummaryCache,
-	asl accesscontrol.AccessSetLookup) schema.Template {
+	asl accesscontrol.AccessSetLookup,
+	opts ...proxy.Option) schema.Template {
 	return schema.Template{
-		Store:     proxy.NewProxyStore(clientGetter, summaryCache, asl),
+		Store:     proxy.NewProxyStore(clientGetter, summaryCache, asl, opts...),
 		Formatter: formatter(summaryCache),
 	}
 }
@@ -90,6 +92,14 @@ func formatter(summarycache *summarycache.SummaryCache) types.Formatter {
 				"message":       strings.Join(s.Message, ":"),
 			}, "metadata", "state")
 			data.PutValue(unstr.Object, rel, "metadata", "relationships")
+			if deleted := unstr.GetDeletionTimestamp(); deleted != nil {
+				// the object is gone once its finalizers are done, until then it can't be changed
+				data.PutValue(unstr.Object, "removing", "metadata", "state", "name")
+				data.PutValue(unstr.Object, map[string]interface{}{
+					"deletionTimestamp": deleted.UTC().Format(time.RFC3339),
+					"finalizers":        unstr.GetFinalizers(),
+				}, "metadata", "removing")
+			}
 
 			summary.NormalizeConditions(unstr)
 		}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/summarycache"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// format runs the formatter on a config map of default with the given finalizers and deletion time, if any.
+func format(t *testing.T, deleted *metav1.Time, finalizers ...string) map[string]interface{} {
+	t.Helper()
+	configMaps := &types.APISchema{Schema: &schemas.Schema{ID: "configmap"}}
+	attributes.SetGVK(configMaps, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	attributes.SetResource(configMaps, "configmaps")
+	attributes.SetNamespaced(configMaps, true)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "a", "namespace": "default"},
+	}}
+	obj.SetFinalizers(finalizers)
+	obj.SetDeletionTimestamp(deleted)
+
+	apiSchemas := types.EmptyAPISchemas()
+	if err := apiSchemas.AddSchema(*configMaps); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v1/configmaps/default/a", nil)
+	urlBuilder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiOp := &types.APIRequest{Request: req, Schemas: apiSchemas, URLBuilder: urlBuilder}
+	resource := &types.RawResource{
+		Schema:    configMaps,
+		APIObject: types.APIObject{Type: "configmap", ID: "default/a", Object: obj},
+		Links:     map[string]string{},
+	}
+	formatter(summarycache.New(nil, nil))(apiOp, resource)
+	return obj.Object
+}
+
+func TestObjectBeingDeletedIsRemoving(t *testing.T) {
+	deleted := metav1.NewTime(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC))
+	obj := format(t, &deleted, "example.com/cleanup")
+
+	if state := data.Object(obj).String("metadata", "state", "name"); state != "removing" {
+		t.Errorf("got state %q, want removing", state)
+	}
+	want := map[string]interface{}{
+		"deletionTimestamp": "2021-03-04T05:06:07Z",
+		"finalizers":        []string{"example.com/cleanup"},
+	}
+	if removing := data.GetValueN(obj, "metadata", "removing"); !reflect.DeepEqual(removing, want) {
+		t.Errorf("got removing %v, want %v", removing, want)
+	}
+}
+
+func TestLiveObjectIsNotRemoving(t *testing.T) {
+	obj := format(t, nil, "example.com/cleanup")
+	if state := data.Object(obj).String("metadata", "state", "name"); state == "removing" {
+		t.Error("got a live object in the removing state")
+	}
+	if removing := data.GetValueN(obj, "metadata", "removing"); removing != nil {
+		t.Errorf("got removing %v, want none for a live object", removing)
+	}
+}
@@ -0,0 +1,173 @@
+// Package namespaces lets users without permission to list all namespaces still see the namespaces they can
+// work in.
+package namespaces
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/data"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// SourceAnnotation is set on namespaces returned from the user's access set instead of a cluster wide list.
+	SourceAnnotation = "steve.cattle.io/source"
+	sourceAccessSet  = "access-set"
+	// DegradedHeader is set on responses built from the user's access set.
+	DegradedHeader = "X-Steve-Degraded-Source"
+
+	pollInterval = 30 * time.Second
+)
+
+func Template(cg proxy.ClientGetter) schema.Template {
+	return schema.Template{
+		ID: "namespace",
+		StoreFactory: func(next types.Store) types.Store {
+			return &Store{
+				Store: next,
+				cg:    cg,
+			}
+		},
+	}
+}
+
+// Store falls back to the namespaces in the caller's access set when the caller can not list all namespaces.
+// Namespaces the caller can get are read from the apiserver, the others are synthesized with only a name. In this
+// mode watches poll the access set and apiserver instead of watching.
+type Store struct {
+	types.Store
+	cg proxy.ClientGetter
+}
+
+func canListAll(apiSchema *types.APISchema) bool {
+	return accesscontrol.GetAccessListMap(apiSchema).All("list")
+}
+
+func (s *Store) List(apiOp *types.APIRequest, apiSchema *types.APISchema) (types.APIObjectList, error) {
+	if canListAll(apiSchema) {
+		list, err := s.Store.List(apiOp, apiSchema)
+		if !apierrors.IsForbidden(err) {
+			return list, err
+		}
+	}
+	if apiOp.Response != nil {
+		apiOp.Response.Header().Set(DegradedHeader, sourceAccessSet)
+	}
+	return s.fromAccessSet(apiOp, apiSchema)
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, apiSchema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	if canListAll(apiSchema) {
+		c, err := s.Store.Watch(apiOp, apiSchema, w)
+		if !apierrors.IsForbidden(err) {
+			return c, err
+		}
+	}
+	return s.poll(apiOp, apiSchema)
+}
+
+func (s *Store) fromAccessSet(apiOp *types.APIRequest, apiSchema *types.APISchema) (types.APIObjectList, error) {
+	accessSet, _ := apiOp.Schemas.Attributes["accessSet"].(*accesscontrol.AccessSet)
+	if accessSet == nil {
+		return types.APIObjectList{}, nil
+	}
+
+	client, err := s.cg.Client(apiOp, apiSchema, "")
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+
+	names := accessSet.Namespaces()
+	sort.Strings(names)
+
+	var result types.APIObjectList
+	for _, name := range names {
+		if name == accesscontrol.All {
+			continue
+		}
+		obj, err := client.Get(apiOp.Context(), name, metav1.GetOptions{})
+		if err != nil {
+			obj = &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Namespace",
+					"metadata": map[string]interface{}{
+						"name": name,
+					},
+				},
+			}
+		}
+		data.PutValue(obj.Object, sourceAccessSet, "metadata", "annotations", SourceAnnotation)
+		result.Objects = append(result.Objects, types.APIObject{
+			Type:   apiSchema.ID,
+			ID:     name,
+			Object: obj,
+		})
+	}
+	return result, nil
+}
+
+func (s *Store) poll(apiOp *types.APIRequest, apiSchema *types.APISchema) (chan types.APIEvent, error) {
+	last, err := s.fromAccessSet(apiOp, apiSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(chan types.APIEvent)
+	go func() {
+		defer close(result)
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		seen := byName(last)
+
+		for {
+			select {
+			case <-apiOp.Context().Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := s.fromAccessSet(apiOp, apiSchema)
+			if err != nil {
+				continue
+			}
+			current := byName(next)
+			for name, obj := range current {
+				old, ok := seen[name]
+				switch {
+				case !ok:
+					result <- types.APIEvent{Name: types.CreateAPIEvent, ResourceType: apiSchema.ID, Object: obj}
+				case resourceVersion(old) != resourceVersion(obj):
+					result <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: apiSchema.ID, Object: obj}
+				}
+			}
+			for name, obj := range seen {
+				if _, ok := current[name]; !ok {
+					result <- types.APIEvent{Name: types.RemoveAPIEvent, ResourceType: apiSchema.ID, Object: obj}
+				}
+			}
+			seen = current
+		}
+	}()
+	return result, nil
+}
+
+func byName(list types.APIObjectList) map[string]types.APIObject {
+	result := map[string]types.APIObject{}
+	for _, obj := range list.Objects {
+		result[obj.ID] = obj
+	}
+	return result
+}
+
+func resourceVersion(obj types.APIObject) string {
+	return data.Object(obj.Data()).String("metadata", "resourceVersion")
+}
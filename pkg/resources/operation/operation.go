@@ -0,0 +1,221 @@
+// Package operation runs creates, updates and deletes in the background for clients that can not wait for a slow
+// apiserver. Every queued write is an operation object the client polls for the result.
+package operation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	SchemaID = "operation"
+
+	StatePending   = "pending"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+)
+
+var (
+	ErrQueueFull = validation.ErrorCode{
+		Code:   "QueueFull",
+		Status: http.StatusTooManyRequests,
+	}
+)
+
+type Operation struct {
+	ID        string      `json:"id"`
+	Owner     string      `json:"-"`
+	Verb      string      `json:"verb"`
+	Target    string      `json:"target"`
+	State     string      `json:"state"`
+	Created   time.Time   `json:"created"`
+	Completed *time.Time  `json:"completed,omitempty"`
+	Result    interface{} `json:"result,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// Func performs the write and returns the status code and body of its response.
+type Func func() (int, []byte)
+
+// Queue is a bounded pool of workers running queued writes. Finished operations are kept for ttl.
+type Queue struct {
+	sync.RWMutex
+
+	jobs       chan job
+	operations map[string]*Operation
+	ttl        time.Duration
+}
+
+type job struct {
+	op *Operation
+	f  Func
+}
+
+func NewQueue(ctx context.Context, workers, size int, ttl time.Duration) *Queue {
+	q := &Queue{
+		jobs:       make(chan job, size),
+		operations: map[string]*Operation{},
+		ttl:        ttl,
+	}
+	for i := 0; i < workers; i++ {
+		go q.work(ctx)
+	}
+	go q.expire(ctx)
+	return q
+}
+
+// Enqueue queues f as a new operation owned by the user of the request, failing with 429 if the queue is full.
+func (q *Queue) Enqueue(apiOp *types.APIRequest, verb, target string, f Func) (Operation, error) {
+	op := &Operation{
+		ID:      uuid.New(),
+		Owner:   owner(apiOp),
+		Verb:    verb,
+		Target:  target,
+		State:   StatePending,
+		Created: time.Now(),
+	}
+
+	q.Lock()
+	defer q.Unlock()
+	select {
+	case q.jobs <- job{op: op, f: f}:
+	default:
+		return Operation{}, apierror.NewAPIError(ErrQueueFull, "too many operations are queued, retry later")
+	}
+	q.operations[op.ID] = op
+	return *op, nil
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-q.jobs:
+			status, body := j.f()
+			q.complete(j.op, status, body)
+		}
+	}
+}
+
+func (q *Queue) complete(op *Operation, status int, body []byte) {
+	var result map[string]interface{}
+	_ = json.Unmarshal(body, &result)
+
+	q.Lock()
+	defer q.Unlock()
+
+	now := time.Now()
+	op.Completed = &now
+	if status >= http.StatusBadRequest {
+		op.State = StateFailed
+		op.Error, _ = result["message"].(string)
+		if op.Error == "" {
+			op.Error = http.StatusText(status)
+		}
+		return
+	}
+	op.State = StateSucceeded
+	if result != nil {
+		op.Result = result
+	}
+}
+
+func (q *Queue) expire(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			q.Lock()
+			for id, op := range q.operations {
+				if op.Completed != nil && now.Sub(*op.Completed) > q.ttl {
+					delete(q.operations, id)
+				}
+			}
+			q.Unlock()
+		}
+	}
+}
+
+func (q *Queue) get(apiOp *types.APIRequest, id string) (Operation, bool) {
+	q.RLock()
+	defer q.RUnlock()
+	op, ok := q.operations[id]
+	if !ok || op.Owner != owner(apiOp) {
+		return Operation{}, false
+	}
+	return *op, true
+}
+
+func (q *Queue) list(apiOp *types.APIRequest) (result []Operation) {
+	user := owner(apiOp)
+	q.RLock()
+	defer q.RUnlock()
+	for _, op := range q.operations {
+		if op.Owner == user {
+			result = append(result, *op)
+		}
+	}
+	return
+}
+
+func owner(apiOp *types.APIRequest) string {
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return ""
+	}
+	return user.GetName()
+}
+
+func Register(schemas *types.APISchemas, queue *Queue) {
+	schemas.MustImportAndCustomize(Operation{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{http.MethodGet}
+		schema.Store = &Store{
+			queue: queue,
+		}
+	})
+}
+
+// Store serves the operations of the requesting user.
+type Store struct {
+	empty.Store
+	queue *Queue
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	op, ok := s.queue.get(apiOp, id)
+	if !ok {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, "operation "+id+" not found")
+	}
+	return toAPI(op), nil
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	var result types.APIObjectList
+	for _, op := range s.queue.list(apiOp) {
+		result.Objects = append(result.Objects, toAPI(op))
+	}
+	return result, nil
+}
+
+func toAPI(op Operation) types.APIObject {
+	return types.APIObject{
+		Type:   SchemaID,
+		ID:     op.ID,
+		Object: op,
+	}
+}
@@ -7,17 +7,23 @@ import (
 	"github.com/rancher/apiserver/pkg/subscribe"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
 	"github.com/rancher/steve/pkg/client"
 	"github.com/rancher/steve/pkg/clustercache"
+	k8sproxy "github.com/rancher/steve/pkg/proxy"
 	"github.com/rancher/steve/pkg/resources/apigroups"
+	"github.com/rancher/steve/pkg/resources/bulk"
 	"github.com/rancher/steve/pkg/resources/cluster"
 	"github.com/rancher/steve/pkg/resources/common"
 	"github.com/rancher/steve/pkg/resources/counts"
 	"github.com/rancher/steve/pkg/resources/formatters"
+	"github.com/rancher/steve/pkg/resources/namespaces"
+	"github.com/rancher/steve/pkg/resources/snapshot"
 	"github.com/rancher/steve/pkg/resources/userpreferences"
 	"github.com/rancher/steve/pkg/schema"
 	steveschema "github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/steve/pkg/stores/readiness"
 	"github.com/rancher/steve/pkg/summarycache"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/client-go/discovery"
@@ -39,6 +45,9 @@ func DefaultSchemas(ctx context.Context, baseSchema *types.APISchemas, ccache cl
 	apiroot.Register(baseSchema, []string{"v1"}, "proxy:/apis")
 	cluster.Register(ctx, baseSchema, cg, schemaFactory)
 	userpreferences.Register(baseSchema)
+	bulk.Register(baseSchema)
+	snapshot.Register(baseSchema)
+	baseSchema.MustImportAndCustomize(proxy.DependentsPreview{}, nil)
 	return nil
 }
 
@@ -46,10 +55,17 @@ func DefaultSchemaTemplates(cf *client.Factory,
 	baseSchemas *types.APISchemas,
 	summaryCache *summarycache.SummaryCache,
 	lookup accesscontrol.AccessSetLookup,
-	discovery discovery.DiscoveryInterface) []schema.Template {
+	discovery discovery.DiscoveryInterface,
+	storeOpts ...proxy.Option) []schema.Template {
 	return []schema.Template{
-		common.DefaultTemplate(cf, summaryCache, lookup),
+		common.DefaultTemplate(cf, summaryCache, lookup, storeOpts...),
+		{
+			Customize: func(apiSchema *types.APISchema) {
+				bulk.AddMetadataActions(cf, apiSchema)
+			},
+		},
 		apigroups.Template(discovery),
+		namespaces.Template(cf),
 		{
 			ID:        "configmap",
 			Formatter: formatters.DropHelmData,
@@ -57,10 +73,25 @@ func DefaultSchemaTemplates(cf *client.Factory,
 		{
 			ID:        "secret",
 			Formatter: formatters.DropHelmData,
+			Customize: func(apiSchema *types.APISchema) {
+				attributes.SetSensitive(apiSchema, true)
+			},
+		},
+		{
+			ID:                 "pod",
+			Formatter:          formatters.Pod,
+			ReadinessExtractor: readiness.Pod,
+			Customize: func(apiSchema *types.APISchema) {
+				k8sproxy.AddStreamingActions(apiSchema, cf.Config, "exec", "attach", "portforward")
+			},
+		},
+		{
+			ID:                 "apps.deployment",
+			ReadinessExtractor: readiness.Deployment,
 		},
 		{
-			ID:        "pod",
-			Formatter: formatters.Pod,
+			ID:                 "batch.job",
+			ReadinessExtractor: readiness.Job,
 		},
 		{
 			ID: "management.cattle.io.cluster",
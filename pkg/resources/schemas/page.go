@@ -0,0 +1,79 @@
+package schemas
+
+import (
+	"encoding/base64"
+	"sort"
+	"strconv"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	schemastore "github.com/rancher/apiserver/pkg/store/schema"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// NextPageTokenHeader carries the token of the next page of schemas, it is also returned as the continue
+// value of the collection.
+const NextPageTokenHeader = "X-Next-Page-Token"
+
+// List returns the schemas the user can see. With ?limit=N only N schemas are returned and the response
+// carries a token that is passed back as ?pageToken= to get the next page. Schemas are in memory so the
+// token is just an offset into the list sorted by ID.
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	q := apiOp.Request.URL.Query()
+	limitParam, token := q.Get("limit"), q.Get("pageToken")
+	if limitParam == "" && token == "" {
+		return s.Store.List(apiOp, schema)
+	}
+
+	limit := 0
+	if limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 0 {
+			return types.APIObjectList{}, apierror.NewAPIError(validation.InvalidOption, "invalid limit "+limitParam)
+		}
+	}
+
+	offset, err := decodePageToken(token)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+
+	list := schemastore.FilterSchemas(apiOp, apiOp.Schemas.Schemas)
+	sort.Slice(list.Objects, func(i, j int) bool {
+		return list.Objects[i].ID < list.Objects[j].ID
+	})
+
+	if offset > len(list.Objects) {
+		return types.APIObjectList{}, apierror.NewAPIError(validation.InvalidOption, "invalid pageToken "+token)
+	}
+	list.Objects = list.Objects[offset:]
+
+	if limit > 0 && len(list.Objects) > limit {
+		list.Objects = list.Objects[:limit]
+		list.Continue = encodePageToken(offset + limit)
+		apiOp.Response.Header().Set(NextPageTokenHeader, list.Continue)
+	}
+
+	return list, nil
+}
+
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	invalid := apierror.NewAPIError(validation.InvalidOption, "invalid pageToken "+token)
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, invalid
+	}
+	offset, err := strconv.Atoi(string(data))
+	if err != nil || offset < 0 {
+		return 0, invalid
+	}
+	return offset, nil
+}
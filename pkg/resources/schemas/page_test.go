@@ -0,0 +1,98 @@
+package schemas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas"
+)
+
+func pageSchemas() *types.APISchemas {
+	apiSchemas := types.EmptyAPISchemas()
+	for _, id := range []string{"e", "c", "a", "d", "b"} {
+		apiSchemas.MustAddSchema(types.APISchema{Schema: &schemas.Schema{ID: id, CollectionMethods: []string{http.MethodGet}}})
+	}
+	return apiSchemas
+}
+
+func listPage(t *testing.T, limit, token string) ([]string, string, string, error) {
+	t.Helper()
+	q := url.Values{}
+	if limit != "" {
+		q.Set("limit", limit)
+	}
+	if token != "" {
+		q.Set("pageToken", token)
+	}
+	rw := httptest.NewRecorder()
+	apiOp := &types.APIRequest{
+		Schemas:  pageSchemas(),
+		Request:  httptest.NewRequest(http.MethodGet, "/v1/schemas?"+q.Encode(), nil),
+		Response: rw,
+	}
+	list, err := (&Store{}).List(apiOp, nil)
+	var ids []string
+	for _, obj := range list.Objects {
+		ids = append(ids, obj.ID)
+	}
+	return ids, list.Continue, rw.Header().Get(NextPageTokenHeader), err
+}
+
+func TestSchemaPages(t *testing.T) {
+	var pages [][]string
+	token := ""
+	for {
+		ids, next, header, err := listPage(t, "2", token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next != header {
+			t.Errorf("got continue %q and header %q, want the same token", next, header)
+		}
+		pages = append(pages, ids)
+		if next == "" {
+			break
+		}
+		if len(pages) > 3 {
+			t.Fatal("the pages don't end")
+		}
+		token = next
+	}
+
+	if want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("got pages %v, want %v", pages, want)
+	}
+}
+
+func TestSchemaPageWithoutLimitReturnsTheRest(t *testing.T) {
+	ids, next, _, err := listPage(t, "", encodePageToken(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"d", "e"}) || next != "" {
+		t.Errorf("got %v and token %q, want the last two schemas and no token", ids, next)
+	}
+}
+
+func TestInvalidSchemaPage(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		limit string
+		token string
+	}{
+		{name: "not base64", token: "!!"},
+		{name: "not an offset", token: "bm90LWEtbnVtYmVy"},
+		{name: "negative offset", token: encodePageToken(-1)},
+		{name: "past the end", token: encodePageToken(6)},
+		{name: "invalid limit", limit: "ten"},
+		{name: "negative limit", limit: "-1"},
+	} {
+		if ids, _, _, err := listPage(t, test.limit, test.token); err == nil {
+			t.Errorf("%s: got %v, want an error", test.name, ids)
+		}
+	}
+}
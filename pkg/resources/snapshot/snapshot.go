@@ -0,0 +1,148 @@
+package snapshot
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// Snapshot is the result of listing one schema as part of GET /v1/snapshot?schemas=a,b,c. Revision is the
+// resourceVersion of the list, a watch started at it continues exactly where the snapshot stopped.
+type Snapshot struct {
+	ID       string        `json:"id,omitempty"`
+	Revision string        `json:"revision,omitempty"`
+	Data     []interface{} `json:"data"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func Register(schemas *types.APISchemas) {
+	schemas.MustImportAndCustomize(Snapshot{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{}
+		schema.Store = &Store{}
+	})
+}
+
+// Store lists every schema of the schemas query parameter concurrently. The snapshot token, returned as the
+// revision of the collection, holds the revision of every schema and can be decoded with Revisions. A schema
+// that can't be listed is reported in the Error of its entry and doesn't fail the other schemas.
+type Store struct {
+	empty.Store
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	ids := schemaIDs(apiOp.Request.URL.Query().Get("schemas"))
+	if len(ids) == 0 {
+		return types.APIObjectList{}, apierror.NewAPIError(validation.MissingRequired, "schemas query parameter is required")
+	}
+
+	snapshots := make([]Snapshot, len(ids))
+	wg := sync.WaitGroup{}
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			snapshots[i] = list(apiOp, id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	revisions := map[string]string{}
+	result := types.APIObjectList{}
+	for _, snapshot := range snapshots {
+		if snapshot.Error == "" {
+			revisions[snapshot.ID] = snapshot.Revision
+		}
+		result.Objects = append(result.Objects, types.APIObject{
+			Type:   "snapshot",
+			ID:     snapshot.ID,
+			Object: snapshot,
+		})
+	}
+
+	token, err := json.Marshal(revisions)
+	if err != nil {
+		return result, err
+	}
+	result.Revision = base64.RawURLEncoding.EncodeToString(token)
+	return result, nil
+}
+
+// Revisions decodes a snapshot token into the list revision of every schema in the snapshot, keyed by schema ID.
+func Revisions(token string) (map[string]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, apierror.NewAPIError(validation.InvalidFormat, "invalid snapshot token")
+	}
+	result := map[string]string{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, apierror.NewAPIError(validation.InvalidFormat, "invalid snapshot token")
+	}
+	return result, nil
+}
+
+func schemaIDs(param string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, id := range strings.Split(param, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func list(apiOp *types.APIRequest, id string) Snapshot {
+	snapshot := Snapshot{
+		ID:   id,
+		Data: []interface{}{},
+	}
+
+	schema := apiOp.Schemas.LookupSchema(id)
+	if schema == nil || schema.Store == nil {
+		snapshot.Error = "schema not found"
+		return snapshot
+	}
+	if err := apiOp.AccessControl.CanList(apiOp, schema); err != nil {
+		snapshot.Error = err.Error()
+		return snapshot
+	}
+
+	list, err := schema.Store.List(listRequest(apiOp, schema), schema)
+	if err != nil {
+		snapshot.Error = err.Error()
+		return snapshot
+	}
+
+	snapshot.Revision = list.Revision
+	for _, obj := range list.Objects {
+		snapshot.Data = append(snapshot.Data, obj.Object)
+	}
+	return snapshot
+}
+
+// listRequest is a copy of the snapshot request for a single schema, without the snapshot query parameters.
+func listRequest(apiOp *types.APIRequest, schema *types.APISchema) *types.APIRequest {
+	req := apiOp.Request.Clone(apiOp.Context())
+	req.URL.RawQuery = ""
+
+	result := apiOp.Clone()
+	result.Request = req
+	result.Type = schema.ID
+	result.Schema = schema
+	result.Name = ""
+	result.Namespace = ""
+	return result
+}
@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/sirupsen/logrus"
+)
+
+// aliasUses counts the requests per alias schema ID, the values are *int64.
+var aliasUses sync.Map
+
+// AliasUse is the number of requests made through an alias of a schema.
+type AliasUse struct {
+	Alias    string `json:"alias"`
+	SchemaID string `json:"schemaId"`
+	Requests int64  `json:"requests"`
+}
+
+// AliasUsage returns the requests made through each alias since the process started, sorted by alias, to find the
+// clients still on an alias before it is removed.
+func AliasUsage() []AliasUse {
+	var result []AliasUse
+	aliasUses.Range(func(key, value interface{}) bool {
+		use := key.(AliasUse)
+		use.Requests = atomic.LoadInt64(value.(*int64))
+		result = append(result, use)
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Alias < result[j].Alias
+	})
+	return result
+}
+
+// AliasUsageHandler writes the AliasUsage as a JSON array.
+var AliasUsageHandler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(AliasUsage())
+})
+
+// aliasSchemas returns a copy of s for each of its aliases that isn't the ID of another schema. The copies keep the
+// GVR of s so the requests to them are sent for the canonical resource.
+func aliasSchemas(s *types.APISchema, taken map[string]bool) []*types.APISchema {
+	var result []*types.APISchema
+	for _, alias := range attributes.Aliases(s) {
+		if alias == s.ID || taken[alias] {
+			logrus.Debugf("ignoring alias %s of schema %s, the ID is taken", alias, s.ID)
+			continue
+		}
+		aliasSchema := s.DeepCopy()
+		aliasSchema.ID = alias
+		aliasSchema.PluralName = alias
+		attributes.SetAliases(aliasSchema, nil)
+		attributes.SetAliasOf(aliasSchema, s.ID)
+		if aliasSchema.Store != nil {
+			aliasSchema.Store = &aliasStore{
+				Store: aliasSchema.Store,
+				use: AliasUse{
+					Alias:    alias,
+					SchemaID: s.ID,
+				},
+			}
+		}
+		result = append(result, aliasSchema)
+	}
+	return result
+}
+
+// aliasStore counts the requests to an alias schema.
+type aliasStore struct {
+	types.Store
+	use AliasUse
+}
+
+func (a *aliasStore) count() {
+	counter, _ := aliasUses.LoadOrStore(a.use, new(int64))
+	atomic.AddInt64(counter.(*int64), 1)
+}
+
+func (a *aliasStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	a.count()
+	return a.Store.ByID(apiOp, schema, id)
+}
+
+func (a *aliasStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	a.count()
+	return a.Store.List(apiOp, schema)
+}
+
+func (a *aliasStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	a.count()
+	return a.Store.Create(apiOp, schema, data)
+}
+
+func (a *aliasStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	a.count()
+	return a.Store.Update(apiOp, schema, data, id)
+}
+
+func (a *aliasStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	a.count()
+	return a.Store.Delete(apiOp, schema, id)
+}
+
+func (a *aliasStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	a.count()
+	return a.Store.Watch(apiOp, schema, w)
+}
@@ -3,13 +3,20 @@ package schema
 import (
 	"context"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/rancher/apiserver/pkg/server"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/compat"
+	"github.com/rancher/steve/pkg/stores/ids"
+	"github.com/rancher/steve/pkg/stores/mapperguard"
+	"github.com/rancher/steve/pkg/stores/nslabel"
+	"github.com/rancher/steve/pkg/stores/readiness"
 	"github.com/rancher/wrangler/pkg/name"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -27,6 +34,12 @@ type Factory interface {
 }
 
 type Collection struct {
+	// revision and fullRevision are first so they are 64 bit aligned for atomic access
+	revision         int64
+	fullRevision     int64
+	schemaRevisions  map[string]int64
+	removedRevisions map[string]int64
+
 	toSync     int32
 	baseSchema *types.APISchemas
 	schemas    map[string]*types.APISchema
@@ -37,10 +50,23 @@ type Collection struct {
 	byGVK      map[schema.GroupVersionKind]string
 	cache      *cache.LRUExpireCache
 	lock       sync.RWMutex
+	// sources are the schemas as added, before templates were applied, so templates can be applied again
+	sources map[string]*types.APISchema
+	// mutation serializes the changes of schemas and templates, c.lock is only held to swap them in
+	mutation sync.Mutex
 
 	ctx     context.Context
 	running map[string]func()
 	as      accesscontrol.AccessSetLookup
+
+	// methodCache memoizes accessMethods, it is replaced on every Reset
+	methodCache *sync.Map
+
+	// featureFlags holds the FeatureFlags by schema ID, read on every store operation without locking
+	featureFlags sync.Map
+
+	// namespaces is used for the schemas with a NamespaceLabelFilter
+	namespaces nslabel.NamespaceLister
 }
 
 type Template struct {
@@ -52,6 +78,48 @@ type Template struct {
 	Store        types.Store
 	Start        func(ctx context.Context) error
 	StoreFactory func(types.Store) types.Store
+	// Converters translate objects for clients pinned to an older shape, keyed by the compatibility
+	// version the client requests.
+	Converters map[string]compat.Converter
+	// ConversionPipeline migrates objects still stored with an older apiVersion to the version of the schema.
+	ConversionPipeline []compat.VersionConverter
+	// IDResolver replaces namespace/name as the id of the objects of the schema.
+	IDResolver ids.IDResolver
+	// ReadinessExtractor adds status.ready and status.readyMessage to the objects of the schema.
+	ReadinessExtractor readiness.Extractor
+	// SharedOwnership grants the users named in an annotation of an object verbs on it beyond RBAC.
+	SharedOwnership []OwnerPolicy
+	// DefaultQuery holds query parameters, such as labelSelector or limit, used by lists and watches of the
+	// schema that don't send them. Earlier templates and attributes.SetDefaultQuery in a Customize win per parameter.
+	DefaultQuery url.Values
+	// AuditToKubernetesEvents records creates, updates and deletes of the schema through steve as Kubernetes Events
+	// of the changed object, see attributes.SetAuditEvents.
+	AuditToKubernetesEvents bool
+	// NamespaceLabelFilter limits lists and watches of the schema to the namespaces with all of these labels, see
+	// attributes.SetNamespaceLabelFilter. It needs the namespace lister of SetNamespaceLister.
+	NamespaceLabelFilter map[string]string
+	// MapperFailurePolicy is what happens to objects the mapper of the schema fails on, see mapperguard.Policy.
+	// Defaults to mapperguard.Raw.
+	MapperFailurePolicy mapperguard.Policy
+	// Aliases are additional IDs the schema is served under, see attributes.SetAliases. Requests to an alias go to
+	// the store of the schema and are counted in AliasUsage.
+	Aliases []string
+	// FieldRenames renames fields between the names clients use and the names of the Kubernetes object, keyed by
+	// the external name, see RenameMapper. Earlier templates and attributes.SetFieldRenames in a Customize win per
+	// field, renames whose paths overlap are ignored. Merge patches are renamed too, JSON patches and server-side
+	// applies use the internal names.
+	FieldRenames map[string]string
+}
+
+// OwnerPolicy grants AllowedVerbs on an object to the users named in its OwnerAnnotation.
+type OwnerPolicy = attributes.OwnerPolicy
+
+// WithSharedOwnership returns a Customize func that adds policies to the shared ownership of the schema, for
+// templates that already customize the schema use the SharedOwnership field instead.
+func WithSharedOwnership(policies ...OwnerPolicy) func(*types.APISchema) {
+	return func(schema *types.APISchema) {
+		attributes.SetSharedOwnership(schema, append(attributes.SharedOwnership(schema), policies...))
+	}
 }
 
 func WrapServer(factory Factory, server *server.Server) http.Handler {
@@ -78,19 +146,30 @@ func WrapServer(factory Factory, server *server.Server) http.Handler {
 
 func NewCollection(ctx context.Context, baseSchema *types.APISchemas, access accesscontrol.AccessSetLookup) *Collection {
 	return &Collection{
-		baseSchema: baseSchema,
-		schemas:    map[string]*types.APISchema{},
-		templates:  map[string][]*Template{},
-		byGVR:      map[schema.GroupVersionResource]string{},
-		byGVK:      map[schema.GroupVersionKind]string{},
-		cache:      cache.NewLRUExpireCache(1000),
-		notifiers:  map[int]func(){},
-		ctx:        ctx,
-		as:         access,
-		running:    map[string]func(){},
+		baseSchema:  baseSchema,
+		schemas:     map[string]*types.APISchema{},
+		sources:     map[string]*types.APISchema{},
+		templates:   map[string][]*Template{},
+		byGVR:       map[schema.GroupVersionResource]string{},
+		byGVK:       map[schema.GroupVersionKind]string{},
+		cache:       cache.NewLRUExpireCache(1000),
+		notifiers:   map[int]func(){},
+		ctx:         ctx,
+		as:          access,
+		running:     map[string]func(){},
+		methodCache: &sync.Map{},
+
+		schemaRevisions:  map[string]int64{},
+		removedRevisions: map[string]int64{},
 	}
 }
 
+// SetNamespaceLister sets the lister used to find the namespaces matching the NamespaceLabelFilter of schemas.
+// Without one the filter is not applied. It must be set before the templates are added.
+func (c *Collection) SetNamespaceLister(lister nslabel.NamespaceLister) {
+	c.namespaces = lister
+}
+
 func (c *Collection) OnChange(ctx context.Context, cb func()) {
 	c.lock.Lock()
 	id := c.notifierID
@@ -107,6 +186,60 @@ func (c *Collection) OnChange(ctx context.Context, cb func()) {
 }
 
 func (c *Collection) Reset(schemas map[string]*types.APISchema) {
+	c.mutation.Lock()
+	defer c.mutation.Unlock()
+
+	sources := make(map[string]*types.APISchema, len(schemas))
+	for id, s := range schemas {
+		sources[id] = s.DeepCopy()
+		c.applyTemplates(s)
+	}
+	c.sources = sources
+	c.replace(schemas)
+}
+
+// AddSchema adds schema, or replaces the schema with the same ID, with the templates applied. It is safe to call
+// at any time, the cached schemas of users are dropped and the change listeners are notified.
+func (c *Collection) AddSchema(schema *types.APISchema) {
+	c.mutation.Lock()
+	defer c.mutation.Unlock()
+
+	c.sources[schema.ID] = schema.DeepCopy()
+	c.applyTemplates(schema)
+
+	schemas := c.current()
+	schemas[schema.ID] = schema
+	c.replace(schemas)
+}
+
+// RemoveSchema removes the schema with the given ID, if there is one, and notifies the change listeners.
+func (c *Collection) RemoveSchema(id string) {
+	c.mutation.Lock()
+	defer c.mutation.Unlock()
+
+	schemas := c.current()
+	if _, ok := schemas[id]; !ok {
+		return
+	}
+	delete(c.sources, id)
+	delete(schemas, id)
+	c.replace(schemas)
+}
+
+// current returns a copy of the map of schemas, for a mutation to modify and replace.
+func (c *Collection) current() map[string]*types.APISchema {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	schemas := make(map[string]*types.APISchema, len(c.schemas))
+	for id, s := range c.schemas {
+		schemas[id] = s
+	}
+	return schemas
+}
+
+// replace swaps in schemas, which must already have the templates applied, and notifies the listeners.
+func (c *Collection) replace(schemas map[string]*types.APISchema) {
 	byGVK := map[schema.GroupVersionKind]string{}
 	byGVR := map[schema.GroupVersionResource]string{}
 
@@ -119,13 +252,13 @@ func (c *Collection) Reset(schemas map[string]*types.APISchema) {
 		if gvk.Kind != "" {
 			byGVK[gvk] = s.ID
 		}
-
-		c.applyTemplates(s)
 	}
 
 	c.lock.Lock()
 	c.startStopTemplate(schemas)
+	c.trackRevisions(schemas)
 	c.schemas = schemas
+	c.methodCache = &sync.Map{}
 	c.byGVR = byGVR
 	c.byGVK = byGVK
 	for _, k := range c.cache.Keys() {
@@ -223,9 +356,54 @@ func (c *Collection) ByGVK(gvk schema.GroupVersionKind) string {
 	return c.byGVK[gvk]
 }
 
+// AddTemplate registers templates and applies them, again with the templates registered before, to the schemas
+// they match. It is safe to call at any time.
 func (c *Collection) AddTemplate(templates ...Template) {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	c.mutation.Lock()
+	defer c.mutation.Unlock()
+
+	c.lock.Lock()
+	c.addTemplates(templates)
+	c.lock.Unlock()
+
+	schemas := c.current()
+	changed := false
+	for id, source := range c.sources {
+		if !matchesAny(source, templates) {
+			continue
+		}
+		schema := source.DeepCopy()
+		c.applyTemplates(schema)
+		schemas[id] = schema
+		changed = true
+	}
+	if changed {
+		c.replace(schemas)
+	}
+}
+
+func matchesAny(schema *types.APISchema, templates []Template) bool {
+	for _, t := range templates {
+		switch {
+		case t.Kind != "":
+			if t.Group == attributes.Group(schema) && t.Kind == attributes.Kind(schema) {
+				return true
+			}
+		case t.ID != "":
+			if t.ID == schema.ID {
+				return true
+			}
+		case t.Group == "":
+			return true
+		}
+	}
+	return false
+}
+
+// addTemplates must be called with the write lock held.
+func (c *Collection) addTemplates(templates []Template) {
+	// a template can change any schema, clients syncing from an older revision must refetch everything
+	atomic.StoreInt64(&c.fullRevision, atomic.AddInt64(&c.revision, 1))
 
 	for i, template := range templates {
 		if template.Kind != "" {
@@ -0,0 +1,210 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+// allAccess grants every verb on every resource.
+type allAccess struct{}
+
+func (allAccess) AccessFor(user user.Info) *accesscontrol.AccessSet {
+	set := &accesscontrol.AccessSet{ID: "all"}
+	set.Add(accesscontrol.All, schema.GroupResource{Group: accesscontrol.All, Resource: accesscontrol.All}, accesscontrol.Access{
+		Namespace:    accesscontrol.All,
+		ResourceName: accesscontrol.All,
+	})
+	return set
+}
+
+var admin = &user.DefaultInfo{Name: "admin"}
+
+func widgetSchema(id string) *types.APISchema {
+	s := &types.APISchema{Schema: &schemas.Schema{ID: id}}
+	attributes.SetGVR(s, schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: id + "s"})
+	attributes.SetGVK(s, schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: id})
+	attributes.SetVerbs(s, []string{"get", "list", "update"})
+	return s
+}
+
+func customize(description string) func(*types.APISchema) {
+	return func(s *types.APISchema) {
+		s.Description = description
+	}
+}
+
+// TestConcurrentRegistration adds and removes schemas and templates while users read their schemas, run it with
+// -race.
+func TestConcurrentRegistration(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCollection(ctx, types.EmptyAPISchemas(), allAccess{})
+
+	var notified int32
+	c.OnChange(ctx, func() {
+		atomic.AddInt32(&notified, 1)
+	})
+
+	var writers, readers sync.WaitGroup
+	done := make(chan struct{})
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprintf("widget%d%d", w, i%5)
+				c.AddSchema(widgetSchema(id))
+				switch {
+				case i%10 == 0:
+					c.AddTemplate(Template{ID: id, Customize: customize(id)})
+				case i%7 == 0:
+					c.RemoveSchema(id)
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				userSchemas, err := c.Schemas(admin)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for _, s := range userSchemas.Schemas {
+					_ = attributes.GVR(s)
+					_ = s.Description
+				}
+				_ = c.Schema("widget00")
+				_ = c.ByGVR(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widget00s"})
+				_ = c.IDs()
+			}
+		}()
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	if atomic.LoadInt32(&notified) == 0 {
+		t.Error("the change listeners were not notified")
+	}
+	for w := 0; w < 4; w++ {
+		// the last change of widget<w>4 is the add of i=99
+		if c.Schema(fmt.Sprintf("widget%d4", w)) == nil {
+			t.Errorf("widget%d4 is missing", w)
+		}
+	}
+}
+
+func TestTemplatesAddedAtRuntimeApplyToExistingSchemas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCollection(ctx, types.EmptyAPISchemas(), allAccess{})
+
+	c.AddSchema(widgetSchema("gizmo"))
+	before, err := c.Schemas(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := before.LookupSchema("gizmo"); s == nil || s.Description != "" {
+		t.Fatalf("got %v, want gizmo without a description", s)
+	}
+
+	c.AddTemplate(Template{ID: "gizmo", Customize: customize("customized")})
+	if got := c.Schema("gizmo").Description; got != "customized" {
+		t.Errorf("got description %q, want the template applied to the schema added before it", got)
+	}
+	after, err := c.Schemas(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := after.LookupSchema("gizmo"); s == nil || s.Description != "customized" {
+		t.Errorf("got %v, want the cached schemas of the user dropped", s)
+	}
+
+	// the schema is added again as discovered, the template is applied to it and the source isn't changed
+	c.AddSchema(widgetSchema("gizmo"))
+	if got := c.Schema("gizmo").Description; got != "customized" {
+		t.Errorf("got description %q after the schema was added again, want the template applied", got)
+	}
+
+	c.RemoveSchema("gizmo")
+	removed, err := c.Schemas(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed.LookupSchema("gizmo") != nil {
+		t.Error("the removed schema is still served to the user")
+	}
+}
+
+// BenchmarkConcurrentSchemasForSubject builds the schemas of distinct users in parallel, with and without schemas
+// being added and removed at the same time. The readers only share the lock with the writer while they take a
+// snapshot, what the writes still cost is mostly the method cache every write starts over.
+func BenchmarkConcurrentSchemasForSubject(b *testing.B) {
+	for _, writes := range []bool{false, true} {
+		name := "reads"
+		if writes {
+			name = "reads with writes"
+		}
+		b.Run(name, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c := NewCollection(ctx, types.EmptyAPISchemas(), allAccess{})
+			for i := 0; i < 200; i++ {
+				c.AddSchema(widgetSchema(fmt.Sprintf("widget%d", i)))
+			}
+			access := allAccess{}.AccessFor(admin)
+
+			done := make(chan struct{})
+			var writer sync.WaitGroup
+			if writes {
+				writer.Add(1)
+				go func() {
+					defer writer.Done()
+					for i := 0; ; i++ {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						c.AddSchema(widgetSchema(fmt.Sprintf("runtime%d", i%10)))
+						c.RemoveSchema(fmt.Sprintf("runtime%d", (i+5)%10))
+					}
+				}()
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					// schemasForSubject skips the cache of Schemas, as distinct users would
+					if _, err := c.schemasForSubject(access); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			close(done)
+			writer.Wait()
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package converter
 
 import (
+	"encoding/json"
+
 	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/data/convert"
 	"github.com/rancher/wrangler/pkg/schemas"
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
@@ -16,8 +20,12 @@ func modelV3ToSchema(name string, k *v1.JSONSchemaProps, schemasMap map[string]*
 		},
 	}
 
+	constraints := map[string]attributes.FieldConstraint{}
 	for fieldName, schemaField := range k.Properties {
 		s.ResourceFields[fieldName] = toResourceField(name+"."+fieldName, schemaField, schemasMap)
+		if constraint, ok := toFieldConstraint(schemaField); ok {
+			constraints[fieldName] = constraint
+		}
 	}
 
 	for _, fieldName := range k.Required {
@@ -30,6 +38,9 @@ func modelV3ToSchema(name string, k *v1.JSONSchemaProps, schemasMap map[string]*
 	if existing, ok := schemasMap[s.ID]; ok && len(existing.Attributes) > 0 {
 		s.Attributes = existing.Attributes
 	}
+	if len(constraints) > 0 {
+		attributes.SetFieldConstraints(&s, constraints)
+	}
 	schemasMap[s.ID] = &s
 
 	for k, v := range s.ResourceFields {
@@ -90,3 +101,41 @@ func toResourceField(name string, schema v1.JSONSchemaProps, schemasMap map[stri
 
 	return f
 }
+
+func toFieldConstraint(schema v1.JSONSchemaProps) (attributes.FieldConstraint, bool) {
+	if len(schema.Enum) > 0 || schema.Pattern != "" {
+		return attributes.FieldConstraint{
+			Enum:    enumValues(schema.Enum),
+			Pattern: schema.Pattern,
+		}, true
+	}
+
+	var valueSchema *v1.JSONSchemaProps
+	switch {
+	case schema.Type == "array" && schema.Items != nil && schema.Items.Schema != nil:
+		valueSchema = schema.Items.Schema
+	case schema.Type == "object" && schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+		valueSchema = schema.AdditionalProperties.Schema
+	}
+
+	if valueSchema == nil || (len(valueSchema.Enum) == 0 && valueSchema.Pattern == "") {
+		return attributes.FieldConstraint{}, false
+	}
+
+	return attributes.FieldConstraint{
+		Enum:    enumValues(valueSchema.Enum),
+		Pattern: valueSchema.Pattern,
+		Items:   true,
+	}, true
+}
+
+func enumValues(enum []v1.JSON) (result []string) {
+	for _, value := range enum {
+		var v interface{}
+		if err := json.Unmarshal(value.Raw, &v); err != nil {
+			continue
+		}
+		result = append(result, convert.ToString(v))
+	}
+	return
+}
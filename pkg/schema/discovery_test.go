@@ -0,0 +1,160 @@
+package schema
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/schema/converter"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/slice"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// readOnlyDiscovery is the discovery of a cluster with the read-only componentstatuses, bindings that can only be
+// created, events that can be updated but not patched and the config maps supporting every verb.
+var readOnlyDiscovery = []*metav1.APIResourceList{
+	{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "componentstatuses", Kind: "ComponentStatus", Verbs: []string{"get", "list"}},
+			{Name: "bindings", Kind: "Binding", Namespaced: true, Verbs: []string{"create"}},
+			{Name: "events", Kind: "Event", Namespaced: true, Verbs: []string{"get", "list", "update"}},
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true,
+				Verbs: []string{"create", "delete", "deletecollection", "get", "list", "patch", "update", "watch"}},
+		},
+	},
+}
+
+// discoveredSchemas are the schemas of admin for the resources of readOnlyDiscovery, served by the proxy store.
+func discoveredSchemas(t *testing.T) *types.APISchemas {
+	t.Helper()
+	discovery := &discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{Resources: readOnlyDiscovery}}
+	schemasMap := map[string]*types.APISchema{}
+	if err := converter.AddDiscovery(discovery, schemasMap); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCollection(ctx, types.EmptyAPISchemas(), allAccess{})
+	store := proxy.NewProxyStore(nil, nil, allAccess{})
+	for _, s := range schemasMap {
+		s.Store = store
+		c.AddSchema(s)
+	}
+	userSchemas, err := c.Schemas(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userSchemas
+}
+
+func TestMethodsAreLimitedToTheDiscoveredVerbs(t *testing.T) {
+	userSchemas := discoveredSchemas(t)
+
+	for _, test := range []struct {
+		id                string
+		resourceMethods   []string
+		collectionMethods []string
+	}{
+		{id: "componentstatus", resourceMethods: []string{http.MethodGet}, collectionMethods: []string{http.MethodGet}},
+		{id: "binding", collectionMethods: []string{http.MethodPost}},
+		{id: "event", resourceMethods: []string{http.MethodGet, http.MethodPut}, collectionMethods: []string{http.MethodGet}},
+		{
+			id:                "configmap",
+			resourceMethods:   []string{http.MethodGet, http.MethodDelete, http.MethodPut, http.MethodPatch},
+			collectionMethods: []string{http.MethodGet, http.MethodPost},
+		},
+	} {
+		// the schemas are named by the versioned IDs of discovery
+		s := userSchemas.LookupSchema("core.v1." + test.id)
+		if s == nil {
+			t.Errorf("%s: the discovered resource has no schema", test.id)
+			continue
+		}
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if got, want := slice.ContainsString(s.ResourceMethods, method), slice.ContainsString(test.resourceMethods, method); got != want {
+				t.Errorf("%s: got resource method %s %v, want %v", test.id, method, got, want)
+			}
+			if got, want := slice.ContainsString(s.CollectionMethods, method), slice.ContainsString(test.collectionMethods, method); got != want {
+				t.Errorf("%s: got collection method %s %v, want %v", test.id, method, got, want)
+			}
+		}
+	}
+}
+
+func TestUnsupportedVerbsAreRejected(t *testing.T) {
+	userSchemas := discoveredSchemas(t)
+
+	for _, test := range []struct {
+		id     string
+		method string
+		allow  string
+		call   func(apiOp *types.APIRequest, s *types.APISchema) error
+	}{
+		{
+			id:     "componentstatus",
+			method: http.MethodPost,
+			allow:  "GET",
+			call: func(apiOp *types.APIRequest, s *types.APISchema) error {
+				_, err := s.Store.Create(apiOp, s, types.APIObject{Object: map[string]interface{}{}})
+				return err
+			},
+		},
+		{
+			id:     "componentstatus",
+			method: http.MethodPut,
+			allow:  "GET",
+			call: func(apiOp *types.APIRequest, s *types.APISchema) error {
+				_, err := s.Store.Update(apiOp, s, types.APIObject{Object: map[string]interface{}{}}, "scheduler")
+				return err
+			},
+		},
+		{
+			id:     "binding",
+			method: http.MethodGet,
+			allow:  "POST",
+			call: func(apiOp *types.APIRequest, s *types.APISchema) error {
+				_, err := s.Store.List(apiOp, s)
+				return err
+			},
+		},
+		{
+			id:     "event",
+			method: http.MethodPatch,
+			allow:  "GET, PUT",
+			call: func(apiOp *types.APIRequest, s *types.APISchema) error {
+				_, err := s.Store.Update(apiOp, s, types.APIObject{Object: map[string]interface{}{}}, "a")
+				return err
+			},
+		},
+	} {
+		s := userSchemas.LookupSchema("core.v1." + test.id)
+		if s == nil {
+			t.Fatalf("%s: the discovered resource has no schema", test.id)
+		}
+		rw := httptest.NewRecorder()
+		apiOp := &types.APIRequest{
+			Method:   test.method,
+			Schema:   s,
+			Request:  httptest.NewRequest(test.method, "/v1/"+test.id, nil),
+			Response: rw,
+		}
+
+		err := test.call(apiOp, s)
+		apiErr, ok := err.(*apierror.APIError)
+		if !ok || apiErr.Code.Status != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: got %v, want a 405", test.method, test.id, err)
+			continue
+		}
+		if allow := rw.Header().Get("Allow"); allow != test.allow {
+			t.Errorf("%s %s: got Allow %q, want %q", test.method, test.id, allow, test.allow)
+		}
+	}
+}
@@ -3,12 +3,25 @@ package schema
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rancher/apiserver/pkg/builtin"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/compat"
+	"github.com/rancher/steve/pkg/stores/ids"
+	"github.com/rancher/steve/pkg/stores/mapperguard"
+	"github.com/rancher/steve/pkg/stores/nslabel"
+	"github.com/rancher/steve/pkg/stores/querydefaults"
+	"github.com/rancher/steve/pkg/stores/readiness"
+	"github.com/rancher/steve/pkg/stores/redact"
+	"github.com/rancher/steve/pkg/stores/sharedowner"
+	"github.com/rancher/wrangler/pkg/slice"
 	"k8s.io/apiserver/pkg/authentication/user"
 )
 
@@ -38,10 +51,29 @@ func (c *Collection) Schemas(user user.Info) (*types.APISchemas, error) {
 	return schemas, nil
 }
 
-func (c *Collection) schemasForSubject(access *accesscontrol.AccessSet) (*types.APISchemas, error) {
+// Forget drops the cached schemas of the access set with the given ID.
+func (c *Collection) Forget(accessID string) {
+	c.cache.Remove(accessID)
+}
+
+// snapshot returns the current schemas and method cache. Schemas are never modified once they are in the
+// collection, Reset swaps in new ones, so they can be read after the lock is released.
+func (c *Collection) snapshot() ([]*types.APISchema, *sync.Map) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
+	schemas := make([]*types.APISchema, 0, len(c.schemas))
+	for _, s := range c.schemas {
+		schemas = append(schemas, s)
+	}
+	return schemas, c.methodCache
+}
+
+// schemasForSubject builds the schemas of access from a snapshot of the collection taken under a short lock, so
+// concurrent users don't hold up a Reset while their schemas are assembled.
+func (c *Collection) schemasForSubject(access *accesscontrol.AccessSet) (*types.APISchemas, error) {
+	schemas, methodCache := c.snapshot()
+
 	result, err := newSchemas()
 	if err != nil {
 		return nil, err
@@ -51,7 +83,12 @@ func (c *Collection) schemasForSubject(access *accesscontrol.AccessSet) (*types.
 		return nil, err
 	}
 
-	for _, s := range c.schemas {
+	schemaIDs := make(map[string]bool, len(schemas))
+	for _, s := range schemas {
+		schemaIDs[s.ID] = true
+	}
+
+	for _, s := range schemas {
 		gr := attributes.GR(s)
 
 		if gr.Resource == "" {
@@ -81,6 +118,7 @@ func (c *Collection) schemasForSubject(access *accesscontrol.AccessSet) (*types.
 			}
 		}
 
+		s = s.DeepCopy()
 		if len(verbAccess) == 0 {
 			if gr.Group == "" && gr.Resource == "namespaces" {
 				var accessList accesscontrol.AccessList
@@ -99,29 +137,13 @@ func (c *Collection) schemasForSubject(access *accesscontrol.AccessSet) (*types.
 			}
 		}
 
-		allowed := func(method string) string {
-			if attributes.DisallowMethods(s)[method] {
-				return "blocked-" + method
-			}
-			return method
-		}
-
-		s = s.DeepCopy()
 		attributes.SetAccess(s, verbAccess)
-		if verbAccess.AnyVerb("list", "get") {
-			s.ResourceMethods = append(s.ResourceMethods, allowed(http.MethodGet))
-			s.CollectionMethods = append(s.CollectionMethods, allowed(http.MethodGet))
-		}
-		if verbAccess.AnyVerb("delete") {
-			s.ResourceMethods = append(s.ResourceMethods, allowed(http.MethodDelete))
-		}
-		if verbAccess.AnyVerb("update") {
-			s.ResourceMethods = append(s.ResourceMethods, allowed(http.MethodPut))
-			s.ResourceMethods = append(s.ResourceMethods, allowed(http.MethodPatch))
-		}
-		if verbAccess.AnyVerb("create") {
-			s.CollectionMethods = append(s.CollectionMethods, allowed(http.MethodPost))
+		methods := accessMethodsFor(methodCache, s, verbAccess)
+		s.ResourceMethods = append(s.ResourceMethods, methods.resource...)
+		if c.listsMatchingNamespace(s, verbAccess) {
+			s.CollectionMethods = append(s.CollectionMethods, methods.collection...)
 		}
+		s.ResourceMethods = append(s.ResourceMethods, sharedOwnershipMethods(s, verbAccess)...)
 
 		if len(s.CollectionMethods) == 0 && len(s.ResourceMethods) == 0 {
 			continue
@@ -130,6 +152,11 @@ func (c *Collection) schemasForSubject(access *accesscontrol.AccessSet) (*types.
 		if err := result.AddSchema(*s); err != nil {
 			return nil, err
 		}
+		for _, alias := range aliasSchemas(s, schemaIDs) {
+			if err := result.AddSchema(*alias); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	result.Attributes = map[string]interface{}{
@@ -138,6 +165,115 @@ func (c *Collection) schemasForSubject(access *accesscontrol.AccessSet) (*types.
 	return result, nil
 }
 
+type accessMethods struct {
+	resource   []string
+	collection []string
+}
+
+// accessMethodsFor returns the HTTP methods granted by verbAccess. Many users share the same verbs for a schema so
+// the result is cached by the schema ID and the granted verbs. The result also depends on the disallowed methods
+// of the schema, those can't change under the cache, a schema is only changed by replacing the schemas of the
+// collection and every replace starts a new cache.
+func accessMethodsFor(methodCache *sync.Map, s *types.APISchema, verbAccess accesscontrol.AccessListByVerb) accessMethods {
+	var verbs []string
+	for verb, access := range verbAccess {
+		if len(access) > 0 {
+			verbs = append(verbs, verb)
+		}
+	}
+	sort.Strings(verbs)
+	key := s.ID + "\x00" + strings.Join(verbs, ",")
+
+	if cached, ok := methodCache.Load(key); ok {
+		return cached.(accessMethods)
+	}
+
+	result := toAccessMethods(s, verbAccess)
+	methodCache.Store(key, result)
+	return result
+}
+
+func toAccessMethods(s *types.APISchema, verbAccess accesscontrol.AccessListByVerb) accessMethods {
+	var result accessMethods
+
+	allowed := func(method string) string {
+		if attributes.DisallowMethods(s)[method] {
+			return "blocked-" + method
+		}
+		return method
+	}
+
+	if verbAccess.AnyVerb("list", "get") {
+		result.resource = append(result.resource, allowed(http.MethodGet))
+		result.collection = append(result.collection, allowed(http.MethodGet))
+	}
+	if verbAccess.AnyVerb("delete") {
+		result.resource = append(result.resource, allowed(http.MethodDelete))
+	}
+	// verbAccess only holds verbs discovery reports for the resource, so a resource that supports update but not
+	// patch is not offered PATCH
+	if verbAccess.AnyVerb("update") {
+		result.resource = append(result.resource, allowed(http.MethodPut))
+	}
+	if verbAccess.AnyVerb("patch") {
+		result.resource = append(result.resource, allowed(http.MethodPatch))
+	}
+	if verbAccess.AnyVerb("create") {
+		result.collection = append(result.collection, allowed(http.MethodPost))
+	}
+
+	return result
+}
+
+// listsMatchingNamespace is false for a schema with a NamespaceLabelFilter when the user can't list it in any of
+// the namespaces matching the filter, the collection would always be empty. The result is cached with the schemas
+// of the user, so a namespace that starts or stops matching is seen once they are built again.
+func (c *Collection) listsMatchingNamespace(s *types.APISchema, verbAccess accesscontrol.AccessListByVerb) bool {
+	filter := attributes.NamespaceLabelFilter(s)
+	if len(filter) == 0 || c.namespaces == nil || !attributes.Namespaced(s) {
+		return true
+	}
+	matching, err := nslabel.Matching(c.namespaces, filter)
+	if err != nil {
+		return true
+	}
+	for namespace := range verbAccess.Granted("list") {
+		if (namespace == accesscontrol.All && matching.Len() > 0) || matching.Has(namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedOwnershipMethods returns the resource methods of the verbs the shared ownership of the schema can grant the
+// user on top of verbAccess. Whether the user owns a given object is checked by the store when it is accessed.
+func sharedOwnershipMethods(s *types.APISchema, verbAccess accesscontrol.AccessListByVerb) []string {
+	verbs := attributes.Verbs(s)
+	var result []string
+	for _, policy := range attributes.SharedOwnership(s) {
+		for _, verb := range policy.AllowedVerbs {
+			if verbAccess.AnyVerb(verb) || (len(verbs) > 0 && !slice.ContainsString(verbs, verb)) {
+				continue
+			}
+			var method string
+			switch verb {
+			case "get":
+				method = http.MethodGet
+			case "update":
+				method = http.MethodPut
+			case "patch":
+				method = http.MethodPatch
+			case "delete":
+				method = http.MethodDelete
+			}
+			if method != "" && !attributes.DisallowMethods(s)[method] && !slice.ContainsString(s.ResourceMethods, method) && !slice.ContainsString(result, method) {
+				result = append(result, method)
+			}
+		}
+	}
+	return result
+}
+
 func (c *Collection) defaultStore() types.Store {
 	templates := c.templates[""]
 	if len(templates) > 0 {
@@ -156,11 +292,53 @@ func (c *Collection) applyTemplates(schema *types.APISchema) {
 		c.templates[""],
 	}
 
+	converters := map[string]compat.Converter{}
+	var pipeline []compat.VersionConverter
+	var idResolver ids.IDResolver
+	var readinessExtractor readiness.Extractor
+	var ownerPolicies []OwnerPolicy
+	var mapperPolicy mapperguard.Policy
+	var aliases []string
+	renames := map[string]string{}
+	defaultQuery := url.Values{}
 	for _, templates := range templates {
 		for _, t := range templates {
 			if t == nil {
 				continue
 			}
+			pipeline = append(pipeline, t.ConversionPipeline...)
+			ownerPolicies = append(ownerPolicies, t.SharedOwnership...)
+			if t.AuditToKubernetesEvents {
+				attributes.SetAuditEvents(schema, true)
+			}
+			if len(t.NamespaceLabelFilter) > 0 && len(attributes.NamespaceLabelFilter(schema)) == 0 {
+				attributes.SetNamespaceLabelFilter(schema, t.NamespaceLabelFilter)
+			}
+			for key, values := range t.DefaultQuery {
+				if _, ok := defaultQuery[key]; !ok {
+					defaultQuery[key] = values
+				}
+			}
+			if idResolver == nil {
+				idResolver = t.IDResolver
+			}
+			if readinessExtractor == nil {
+				readinessExtractor = t.ReadinessExtractor
+			}
+			aliases = append(aliases, t.Aliases...)
+			for external, internal := range t.FieldRenames {
+				if _, ok := renames[external]; !ok {
+					renames[external] = internal
+				}
+			}
+			if mapperPolicy == "" {
+				mapperPolicy = t.MapperFailurePolicy
+			}
+			for version, converter := range t.Converters {
+				if _, ok := converters[version]; !ok {
+					converters[version] = converter
+				}
+			}
 			if schema.Formatter == nil {
 				schema.Formatter = t.Formatter
 			} else if t.Formatter != nil {
@@ -178,4 +356,81 @@ func (c *Collection) applyTemplates(schema *types.APISchema) {
 			}
 		}
 	}
+
+	if len(aliases) > 0 {
+		attributes.SetAliases(schema, append(attributes.Aliases(schema), aliases...))
+	}
+
+	if idResolver != nil && schema.Store != nil {
+		schema.Store = ids.NewStore(schema.Store, idResolver)
+	}
+
+	if len(pipeline) > 0 && schema.Store != nil && attributes.Version(schema) != "" {
+		schema.Store = compat.NewPipelineStore(schema.Store, pipeline, attributes.Version(schema))
+	}
+
+	if len(ownerPolicies) > 0 {
+		attributes.SetSharedOwnership(schema, append(attributes.SharedOwnership(schema), ownerPolicies...))
+	}
+	if policies := attributes.SharedOwnership(schema); len(policies) > 0 && schema.Store != nil {
+		schema.Store = sharedowner.NewStore(schema.Store, policies)
+	}
+
+	for key, values := range attributes.DefaultQuery(schema) {
+		defaultQuery[key] = values
+	}
+	if len(defaultQuery) > 0 && schema.Store != nil {
+		attributes.SetDefaultQuery(schema, defaultQuery)
+		schema.Store = querydefaults.NewStore(schema.Store, defaultQuery)
+	}
+
+	if len(attributes.NamespaceLabelFilter(schema)) > 0 && c.namespaces != nil && schema.Store != nil {
+		schema.Store = nslabel.NewStore(schema.Store, c.namespaces)
+	}
+
+	if readinessExtractor != nil && schema.Store != nil {
+		schema.Store = readiness.NewStore(schema.Store, readinessExtractor)
+	}
+
+	if len(converters) > 0 && schema.Store != nil {
+		schema.Store = compat.NewStore(schema.Store, converters)
+		attributes.SetCompatibilityVersions(schema, compat.Versions(converters))
+	}
+
+	if len(attributes.FieldRedactionPolicy(schema)) > 0 && schema.Store != nil {
+		schema.Store = redact.NewStore(schema.Store)
+	}
+
+	for external, internal := range attributes.FieldRenames(schema) {
+		renames[external] = internal
+	}
+	renames = checkRenames(schema.ID, renames)
+	if len(renames) > 0 {
+		attributes.SetFieldRenames(schema, renames)
+		renameResourceFields(schema.Schema, renames)
+		schema.Mapper = &RenameMapper{
+			Next:    schema.Mapper,
+			Renames: renames,
+		}
+		if schema.Store != nil {
+			schema.Store = &renameStore{
+				Store:   schema.Store,
+				renames: renames,
+			}
+		}
+	}
+
+	if schema.Mapper != nil {
+		schema.Mapper = mapperguard.NewMapper(schema.ID, schema.Mapper)
+		if mapperPolicy != "" && mapperPolicy != mapperguard.Raw && schema.Store != nil {
+			schema.Store = mapperguard.NewStore(schema.Store, mapperPolicy)
+		}
+	}
+
+	if schema.Store != nil {
+		schema.Store = &featureFlagStore{
+			Store:      schema.Store,
+			collection: c,
+		}
+	}
 }
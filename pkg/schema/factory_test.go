@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func TestAuditToKubernetesEventsTemplate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCollection(ctx, types.EmptyAPISchemas(), allAccess{})
+	c.AddSchema(widgetSchema("gizmo"))
+	c.AddSchema(widgetSchema("gadget"))
+	c.AddTemplate(Template{ID: "gizmo", AuditToKubernetesEvents: true})
+
+	userSchemas, err := c.Schemas(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := userSchemas.LookupSchema("gizmo"); s == nil || !attributes.AuditEvents(s) {
+		t.Errorf("got %v, want the audit events of gizmo enabled by its template", s)
+	}
+	if s := userSchemas.LookupSchema("gadget"); s == nil || attributes.AuditEvents(s) {
+		t.Errorf("got %v, want gadget without audit events", s)
+	}
+}
+
+type namespaceList []*v1.Namespace
+
+func (n namespaceList) List(selector labels.Selector) ([]*v1.Namespace, error) {
+	var result []*v1.Namespace
+	for _, namespace := range n {
+		if selector.Matches(labels.Set(namespace.Labels)) {
+			result = append(result, namespace)
+		}
+	}
+	return result, nil
+}
+
+// namespaceAccess grants every verb on every resource of namespace.
+type namespaceAccess string
+
+func (n namespaceAccess) AccessFor(user user.Info) *accesscontrol.AccessSet {
+	set := &accesscontrol.AccessSet{ID: string(n)}
+	set.Add(accesscontrol.All, k8sschema.GroupResource{Group: accesscontrol.All, Resource: accesscontrol.All}, accesscontrol.Access{
+		Namespace:    string(n),
+		ResourceName: accesscontrol.All,
+	})
+	return set
+}
+
+func TestNamespaceLabelFilterHidesCollectionsWithoutMatchingNamespaces(t *testing.T) {
+	namespaces := namespaceList{
+		{ObjectMeta: metav1.ObjectMeta{Name: "mesh", Labels: map[string]string{"istio-injection": "enabled"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "plain"}},
+	}
+	for _, test := range []struct {
+		name        string
+		access      accesscontrol.AccessSetLookup
+		collections bool
+	}{
+		{name: "labeled namespace", access: namespaceAccess("mesh"), collections: true},
+		{name: "unlabeled namespace", access: namespaceAccess("plain")},
+		{name: "every namespace", access: allAccess{}, collections: true},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := NewCollection(ctx, types.EmptyAPISchemas(), test.access)
+		c.SetNamespaceLister(namespaces)
+		for _, id := range []string{"sidecar", "gizmo"} {
+			s := widgetSchema(id)
+			attributes.SetNamespaced(s, true)
+			c.AddSchema(s)
+		}
+		c.AddTemplate(Template{ID: "sidecar", NamespaceLabelFilter: map[string]string{"istio-injection": "enabled"}})
+
+		userSchemas, err := c.Schemas(admin)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sidecar := userSchemas.LookupSchema("sidecar")
+		if sidecar == nil {
+			t.Fatalf("%s: the schema with a namespace label filter is missing", test.name)
+		}
+		if got := len(sidecar.CollectionMethods) > 0; got != test.collections {
+			t.Errorf("%s: got collection methods %v, want collection methods %v", test.name, sidecar.CollectionMethods, test.collections)
+		}
+		if len(sidecar.ResourceMethods) == 0 {
+			t.Errorf("%s: got no resource methods, want the objects still reachable", test.name)
+		}
+		if gizmo := userSchemas.LookupSchema("gizmo"); gizmo == nil || len(gizmo.CollectionMethods) == 0 {
+			t.Errorf("%s: got %v, want the schema without a filter listed", test.name, gizmo)
+		}
+	}
+
+	// no namespace matches the filter
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCollection(ctx, types.EmptyAPISchemas(), allAccess{})
+	c.SetNamespaceLister(namespaceList{{ObjectMeta: metav1.ObjectMeta{Name: "plain"}}})
+	s := widgetSchema("sidecar")
+	attributes.SetNamespaced(s, true)
+	c.AddSchema(s)
+	c.AddTemplate(Template{ID: "sidecar", NamespaceLabelFilter: map[string]string{"istio-injection": "enabled"}})
+	userSchemas, err := c.Schemas(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sidecar := userSchemas.LookupSchema("sidecar"); sidecar == nil || len(sidecar.CollectionMethods) != 0 {
+		t.Errorf("got %v, want no collection methods when no namespace matches", sidecar)
+	}
+}
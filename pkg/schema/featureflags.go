@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+var (
+	ErrOperationDisabled = validation.ErrorCode{
+		Code:   "OperationDisabled",
+		Status: http.StatusServiceUnavailable,
+	}
+)
+
+// FeatureFlags disables operations on a schema at runtime, for example to stop creates during maintenance.
+type FeatureFlags struct {
+	DisableCreate bool
+	DisableUpdate bool
+	DisableDelete bool
+	DisableList   bool
+	DisableWatch  bool
+}
+
+// SetFeatureFlags replaces the feature flags of the schema. The flags take effect immediately for every user,
+// setting the zero FeatureFlags enables all operations again.
+func (c *Collection) SetFeatureFlags(schemaID string, flags FeatureFlags) error {
+	c.lock.RLock()
+	_, ok := c.schemas[schemaID]
+	c.lock.RUnlock()
+	if !ok {
+		return fmt.Errorf("schema %s not found", schemaID)
+	}
+
+	if flags == (FeatureFlags{}) {
+		c.featureFlags.Delete(schemaID)
+	} else {
+		c.featureFlags.Store(schemaID, flags)
+	}
+	return nil
+}
+
+func (c *Collection) getFeatureFlags(schemaID string) FeatureFlags {
+	flags, _ := c.featureFlags.Load(schemaID)
+	result, _ := flags.(FeatureFlags)
+	return result
+}
+
+type featureFlagStore struct {
+	types.Store
+	collection *Collection
+}
+
+func disabled(schema *types.APISchema, operation string) error {
+	return apierror.NewAPIError(ErrOperationDisabled, operation+" is temporarily disabled for "+schema.ID)
+}
+
+func (f *featureFlagStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	if f.collection.getFeatureFlags(schema.ID).DisableList {
+		return types.APIObjectList{}, disabled(schema, "list")
+	}
+	return f.Store.List(apiOp, schema)
+}
+
+func (f *featureFlagStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	if f.collection.getFeatureFlags(schema.ID).DisableCreate {
+		return types.APIObject{}, disabled(schema, "create")
+	}
+	return f.Store.Create(apiOp, schema, data)
+}
+
+func (f *featureFlagStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	if f.collection.getFeatureFlags(schema.ID).DisableUpdate {
+		return types.APIObject{}, disabled(schema, "update")
+	}
+	return f.Store.Update(apiOp, schema, data, id)
+}
+
+func (f *featureFlagStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	if f.collection.getFeatureFlags(schema.ID).DisableDelete {
+		return types.APIObject{}, disabled(schema, "delete")
+	}
+	return f.Store.Delete(apiOp, schema, id)
+}
+
+func (f *featureFlagStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	if f.collection.getFeatureFlags(schema.ID).DisableWatch {
+		return nil, disabled(schema, "watch")
+	}
+	return f.Store.Watch(apiOp, schema, w)
+}
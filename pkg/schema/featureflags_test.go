@@ -0,0 +1,110 @@
+package schema
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// okStore succeeds every operation.
+type okStore struct {
+	types.Store
+}
+
+func (okStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	return types.APIObjectList{}, nil
+}
+
+func (okStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	return data, nil
+}
+
+func (okStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	return data, nil
+}
+
+func (okStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	return types.APIObject{}, nil
+}
+
+func (okStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	return nil, nil
+}
+
+// operations runs every operation on the store of the gizmo schema of admin and returns the error of each.
+func operations(t *testing.T, c *Collection) map[string]error {
+	t.Helper()
+	userSchemas, err := c.Schemas(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := userSchemas.LookupSchema("gizmo")
+	if s == nil || s.Store == nil {
+		t.Fatal("gizmo has no store")
+	}
+	apiOp := &types.APIRequest{}
+	result := map[string]error{}
+	_, result["list"] = s.Store.List(apiOp, s)
+	_, result["create"] = s.Store.Create(apiOp, s, types.APIObject{})
+	_, result["update"] = s.Store.Update(apiOp, s, types.APIObject{}, "a")
+	_, result["delete"] = s.Store.Delete(apiOp, s, "a")
+	_, result["watch"] = s.Store.Watch(apiOp, s, types.WatchRequest{})
+	return result
+}
+
+func TestFeatureFlagsDisableOperationsAtRuntime(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCollection(ctx, types.EmptyAPISchemas(), allAccess{})
+	c.AddTemplate(Template{ID: "gizmo", Store: okStore{}})
+	c.AddSchema(widgetSchema("gizmo"))
+
+	for _, test := range []struct {
+		flags    FeatureFlags
+		disabled string
+	}{
+		{flags: FeatureFlags{DisableList: true}, disabled: "list"},
+		{flags: FeatureFlags{DisableCreate: true}, disabled: "create"},
+		{flags: FeatureFlags{DisableUpdate: true}, disabled: "update"},
+		{flags: FeatureFlags{DisableDelete: true}, disabled: "delete"},
+		{flags: FeatureFlags{DisableWatch: true}, disabled: "watch"},
+	} {
+		if err := c.SetFeatureFlags("gizmo", test.flags); err != nil {
+			t.Fatal(err)
+		}
+		for operation, err := range operations(t, c) {
+			if operation != test.disabled {
+				if err != nil {
+					t.Errorf("%s disabled: got %v for %s, want it allowed", test.disabled, err, operation)
+				}
+				continue
+			}
+			apiErr, ok := err.(*apierror.APIError)
+			if !ok || apiErr.Code != ErrOperationDisabled || apiErr.Code.Status != http.StatusServiceUnavailable {
+				t.Errorf("got %v for %s, want a 503 while it is disabled", err, operation)
+			}
+		}
+	}
+
+	// the zero flags enable everything again without the schema being added again
+	if err := c.SetFeatureFlags("gizmo", FeatureFlags{}); err != nil {
+		t.Fatal(err)
+	}
+	for operation, err := range operations(t, c) {
+		if err != nil {
+			t.Errorf("got %v for %s, want it enabled again", err, operation)
+		}
+	}
+}
+
+func TestFeatureFlagsOfUnknownSchema(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCollection(ctx, types.EmptyAPISchemas(), allAccess{})
+	if err := c.SetFeatureFlags("gizmo", FeatureFlags{DisableCreate: true}); err == nil {
+		t.Error("got no error for a schema that doesn't exist")
+	}
+}
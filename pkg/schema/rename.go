@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/sirupsen/logrus"
+	apitypes "k8s.io/apimachinery/pkg/types"
+)
+
+// RenameMapper renames fields between the names clients use and the names of the Kubernetes object, keyed by the
+// external name with the internal name as value. Names are paths in dot notation, so nested fields can be renamed
+// and moved, for example "displayName": "spec.displayName". Renamed values replace any value already at the target
+// path. It runs after the mapper it wraps when converting from the internal object and before it when converting
+// to it, so that mapper always sees the internal names.
+//
+// Every renamed value is taken out of the object before any of them is put back, so renames that chain or swap
+// fields, like "a": "b" with "b": "c", don't depend on the order they are applied in. Renames whose paths overlap,
+// like "spec" with "spec.size", can't be applied independently and are dropped by the schema factory, see
+// checkRenames.
+type RenameMapper struct {
+	Next    schemas.Mapper
+	Renames map[string]string
+}
+
+func (r *RenameMapper) FromInternal(obj data.Object) {
+	if r.Next != nil {
+		r.Next.FromInternal(obj)
+	}
+	moveFields(obj, inverse(r.Renames))
+}
+
+func (r *RenameMapper) ToInternal(obj data.Object) error {
+	moveFields(obj, r.Renames)
+	if r.Next != nil {
+		return r.Next.ToInternal(obj)
+	}
+	return nil
+}
+
+func (r *RenameMapper) ModifySchema(schema *schemas.Schema, s *schemas.Schemas) error {
+	if r.Next != nil {
+		if err := r.Next.ModifySchema(schema, s); err != nil {
+			return err
+		}
+	}
+	renameResourceFields(schema, r.Renames)
+	return nil
+}
+
+// checkRenames returns renames without the renames whose external path overlaps the external path of another
+// rename, or whose internal path overlaps another internal path, where paths overlap if one is the other or is
+// inside of it.
+func checkRenames(schemaID string, renames map[string]string) map[string]string {
+	result := make(map[string]string, len(renames))
+	for external, internal := range renames {
+		valid := true
+		for otherExternal, otherInternal := range renames {
+			if otherExternal != external && (overlaps(external, otherExternal) || overlaps(internal, otherInternal)) {
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			logrus.Warnf("ignoring the rename of %s field %s to %s, it overlaps another rename", schemaID, internal, external)
+			continue
+		}
+		result[external] = internal
+	}
+	return result
+}
+
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
+func inverse(renames map[string]string) map[string]string {
+	result := make(map[string]string, len(renames))
+	for external, internal := range renames {
+		result[internal] = external
+	}
+	return result
+}
+
+// renameResourceFields renames the top level fields of schema, nested fields are not described by it. The fields are
+// copied, they can be shared with the source of the schema.
+func renameResourceFields(schema *schemas.Schema, renames map[string]string) {
+	fields := make(map[string]schemas.Field, len(schema.ResourceFields))
+	for name, field := range schema.ResourceFields {
+		fields[name] = field
+	}
+	renamed := map[string]schemas.Field{}
+	for external, internal := range renames {
+		if strings.Contains(external, ".") || strings.Contains(internal, ".") {
+			continue
+		}
+		if field, ok := schema.ResourceFields[internal]; ok {
+			delete(fields, internal)
+			renamed[external] = field
+		}
+	}
+	for name, field := range renamed {
+		fields[name] = field
+	}
+	schema.ResourceFields = fields
+}
+
+// renameStore applies the renames of a RenameMapper to merge patches, the body of a PATCH goes to the store without
+// passing through the mappers of the schema. JSON patches and server-side applies are sent as they are, they
+// address the fields by their internal names.
+type renameStore struct {
+	types.Store
+	renames map[string]string
+}
+
+func (r *renameStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	if apiOp.Method == http.MethodPatch {
+		if err := renamePatch(apiOp, r.renames); err != nil {
+			return types.APIObject{}, err
+		}
+	}
+	return r.Store.Update(apiOp, schema, data, id)
+}
+
+// renamePatch replaces the body of a merge or strategic merge patch with one that uses the internal names. A body
+// that is not a JSON object is left for the store to reject.
+func renamePatch(apiOp *types.APIRequest, renames map[string]string) error {
+	switch apiOp.Request.Header.Get("content-type") {
+	case string(apitypes.JSONPatchType), string(apitypes.ApplyPatchType):
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(apiOp.Request.Body, 3<<20))
+	if err != nil {
+		return err
+	}
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(body, &patch); err == nil {
+		moveFields(patch, renames)
+		if renamed, err := json.Marshal(patch); err == nil {
+			body = renamed
+		}
+	}
+	apiOp.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	apiOp.Request.ContentLength = int64(len(body))
+	return nil
+}
+
+func splitPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// moveFields moves the value at each path of moves in obj, if there is one, to the path it maps to. All values are
+// removed before any is put back, and maps emptied by the removal are removed, so a round trip leaves the object as
+// it was.
+func moveFields(obj map[string]interface{}, moves map[string]string) {
+	froms := make([]string, 0, len(moves))
+	for from := range moves {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+
+	values := make([]interface{}, len(froms))
+	found := make([]bool, len(froms))
+	for i, from := range froms {
+		values[i], found[i] = removeField(obj, splitPath(from))
+	}
+	for i, from := range froms {
+		if found[i] {
+			data.PutValue(obj, values[i], splitPath(moves[from])...)
+		}
+	}
+}
+
+// removeField removes the value at path from obj and returns it, with the maps on the path it leaves empty.
+func removeField(obj map[string]interface{}, path []string) (interface{}, bool) {
+	if len(path) == 1 {
+		value, ok := obj[path[0]]
+		delete(obj, path[0])
+		return value, ok
+	}
+	child, ok := obj[path[0]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := removeField(child, path[1:])
+	if ok && len(child) == 0 {
+		delete(obj, path[0])
+	}
+	return value, ok
+}
@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas"
+	apitypes "k8s.io/apimachinery/pkg/types"
+)
+
+func internalWidget() data.Object {
+	return data.Object{
+		"metadata": map[string]interface{}{"name": "a"},
+		"spec": map[string]interface{}{
+			"displayName": "A",
+			"resources": map[string]interface{}{
+				"size":  "large",
+				"count": float64(3),
+			},
+		},
+	}
+}
+
+func TestNestedRenameRoundTrip(t *testing.T) {
+	m := &RenameMapper{Renames: map[string]string{
+		"displayName": "spec.displayName",
+		"sizing.size": "spec.resources.size",
+	}}
+
+	obj := internalWidget()
+	m.FromInternal(obj)
+	want := data.Object{
+		"metadata":    map[string]interface{}{"name": "a"},
+		"displayName": "A",
+		"sizing":      map[string]interface{}{"size": "large"},
+		"spec": map[string]interface{}{
+			"resources": map[string]interface{}{"count": float64(3)},
+		},
+	}
+	if !reflect.DeepEqual(obj, want) {
+		t.Errorf("got %v, want %v", obj, want)
+	}
+
+	if err := m.ToInternal(obj); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(obj, internalWidget()) {
+		t.Errorf("got %v after the round trip, want %v", obj, internalWidget())
+	}
+}
+
+func TestRenamesThatEmptyAMapRemoveIt(t *testing.T) {
+	m := &RenameMapper{Renames: map[string]string{"displayName": "spec.displayName"}}
+	obj := data.Object{"spec": map[string]interface{}{"displayName": "A"}}
+	m.FromInternal(obj)
+	if !reflect.DeepEqual(obj, data.Object{"displayName": "A"}) {
+		t.Errorf("got %v, want the emptied spec removed", obj)
+	}
+}
+
+func TestChainedAndSwappedRenames(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		renames  map[string]string
+		internal data.Object
+		external data.Object
+	}{
+		{
+			name:     "chain",
+			renames:  map[string]string{"a": "b", "b": "c"},
+			internal: data.Object{"b": "1", "c": "2"},
+			external: data.Object{"a": "1", "b": "2"},
+		},
+		{
+			name:     "swap",
+			renames:  map[string]string{"a": "b", "b": "a"},
+			internal: data.Object{"a": "1", "b": "2"},
+			external: data.Object{"a": "2", "b": "1"},
+		},
+	} {
+		// the renames are a map, every run has to give the same result whatever order it is ranged in
+		for i := 0; i < 20; i++ {
+			m := &RenameMapper{Renames: test.renames}
+			obj := data.Object{}
+			for k, v := range test.internal {
+				obj[k] = v
+			}
+
+			m.FromInternal(obj)
+			if !reflect.DeepEqual(obj, test.external) {
+				t.Fatalf("%s: got %v, want %v", test.name, obj, test.external)
+			}
+			if err := m.ToInternal(obj); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(obj, test.internal) {
+				t.Fatalf("%s: got %v after the round trip, want %v", test.name, obj, test.internal)
+			}
+		}
+	}
+}
+
+func TestCheckRenamesDropsOverlaps(t *testing.T) {
+	got := checkRenames("widget", map[string]string{
+		"displayName": "spec.displayName",
+		"spec":        "internalSpec",
+		"spec.size":   "size",
+		"a":           "status.ready",
+		"b":           "status",
+	})
+	want := map[string]string{"displayName": "spec.displayName"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want only the rename that overlaps no other", got)
+	}
+
+	chain := map[string]string{"a": "b", "b": "c"}
+	if got := checkRenames("widget", chain); !reflect.DeepEqual(got, chain) {
+		t.Errorf("got %v, want the chained renames kept", got)
+	}
+}
+
+func TestRenameResourceFields(t *testing.T) {
+	schema := &schemas.Schema{ResourceFields: map[string]schemas.Field{
+		"b": {Type: "string"},
+		"c": {Type: "int"},
+	}}
+	renameResourceFields(schema, map[string]string{"a": "b", "b": "c"})
+	want := map[string]schemas.Field{
+		"a": {Type: "string"},
+		"b": {Type: "int"},
+	}
+	if !reflect.DeepEqual(schema.ResourceFields, want) {
+		t.Errorf("got %v, want %v", schema.ResourceFields, want)
+	}
+}
+
+func patchRequest(contentType, body string) *types.APIRequest {
+	req := httptest.NewRequest(http.MethodPatch, "/v1/widget/a", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return &types.APIRequest{Method: http.MethodPatch, Request: req}
+}
+
+func TestRenamePatch(t *testing.T) {
+	renames := map[string]string{"displayName": "spec.displayName"}
+	for _, test := range []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "merge",
+			contentType: string(apitypes.MergePatchType),
+			body:        `{"displayName":"B","spec":{"size":"small"}}`,
+			want:        `{"spec":{"displayName":"B","size":"small"}}`,
+		},
+		{
+			name:        "strategic merge",
+			contentType: string(apitypes.StrategicMergePatchType),
+			body:        `{"displayName":null}`,
+			want:        `{"spec":{"displayName":null}}`,
+		},
+		{
+			name:        "json patch",
+			contentType: string(apitypes.JSONPatchType),
+			body:        `[{"op":"replace","path":"/spec/displayName","value":"B"}]`,
+			want:        `[{"op":"replace","path":"/spec/displayName","value":"B"}]`,
+		},
+		{
+			name:        "invalid",
+			contentType: string(apitypes.MergePatchType),
+			body:        `{"displayName":`,
+			want:        `{"displayName":`,
+		},
+	} {
+		apiOp := patchRequest(test.contentType, test.body)
+		if err := renamePatch(apiOp, renames); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		body, err := ioutil.ReadAll(apiOp.Request.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != test.want {
+			t.Errorf("%s: got body %s, want %s", test.name, body, test.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package schema
+
+import (
+	"encoding/json"
+	"sort"
+	"sync/atomic"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// SchemaChanges are the schemas that changed after a revision, see Collection.ChangedSince.
+type SchemaChanges struct {
+	Revision int64
+	Changed  []*types.APISchema
+	Removed  []string
+	// Full is set when the changes can not be computed from the requested revision, for example because a
+	// template was added since. Changed then holds every schema and the client should replace its cache.
+	Full bool
+}
+
+// Revision returns the current schema revision, it increases on every schema or template change.
+func (c *Collection) Revision() int64 {
+	return atomic.LoadInt64(&c.revision)
+}
+
+// ChangedSince returns the schemas added, changed or removed after revision along with the current revision.
+func (c *Collection) ChangedSince(revision int64) SchemaChanges {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	result := SchemaChanges{
+		Revision: atomic.LoadInt64(&c.revision),
+	}
+
+	full := revision <= 0 || revision < atomic.LoadInt64(&c.fullRevision) || revision > result.Revision
+	for id, s := range c.schemas {
+		if full || c.schemaRevisions[id] > revision {
+			result.Changed = append(result.Changed, s)
+		}
+	}
+	if !full {
+		for id, removed := range c.removedRevisions {
+			if removed > revision {
+				result.Removed = append(result.Removed, id)
+			}
+		}
+	}
+
+	sort.Slice(result.Changed, func(i, j int) bool {
+		return result.Changed[i].ID < result.Changed[j].ID
+	})
+	sort.Strings(result.Removed)
+	result.Full = full
+	return result
+}
+
+// trackRevisions records the revision of every schema that was added, changed or removed. It must be called with
+// the write lock held, before c.schemas is replaced.
+func (c *Collection) trackRevisions(schemas map[string]*types.APISchema) {
+	var changed, removed []string
+	for id, s := range schemas {
+		old, ok := c.schemas[id]
+		if !ok || !sameSchema(old, s) {
+			changed = append(changed, id)
+		}
+	}
+	for id := range c.schemas {
+		if _, ok := schemas[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return
+	}
+
+	revision := atomic.AddInt64(&c.revision, 1)
+	for _, id := range changed {
+		c.schemaRevisions[id] = revision
+		delete(c.removedRevisions, id)
+	}
+	for _, id := range removed {
+		delete(c.schemaRevisions, id)
+		c.removedRevisions[id] = revision
+	}
+}
+
+func sameSchema(a, b *types.APISchema) bool {
+	left, err := json.Marshal(a.Schema)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b.Schema)
+	if err != nil {
+		return false
+	}
+	return string(left) == string(right)
+}
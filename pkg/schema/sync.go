@@ -0,0 +1,74 @@
+package schema
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas"
+)
+
+// syncedSchema is a schema in the blob of Export, the ID of a schema is not part of its JSON otherwise.
+type syncedSchema struct {
+	ID string `json:"id"`
+	*schemas.Schema
+}
+
+// Export returns the schema registry as JSON, sorted by ID so two instances with the same schemas produce the
+// same blob. The blob can be applied to another instance with SyncFrom.
+func (c *Collection) Export() ([]byte, error) {
+	c.lock.RLock()
+	exported := make([]syncedSchema, 0, len(c.schemas))
+	for _, s := range c.schemas {
+		exported = append(exported, syncedSchema{ID: s.ID, Schema: s.Schema})
+	}
+	c.lock.RUnlock()
+
+	sort.Slice(exported, func(i, j int) bool {
+		return exported[i].ID < exported[j].ID
+	})
+	return json.Marshal(exported)
+}
+
+// SyncFrom makes the registry match a blob produced by Export. Schemas missing from the registry are added
+// with the templates applied and schemas not in the blob are removed, schemas in both are left untouched.
+// The next discovery refresh of this instance replaces the synced registry again.
+func (c *Collection) SyncFrom(blob []byte) error {
+	var incoming []syncedSchema
+	if err := json.Unmarshal(blob, &incoming); err != nil {
+		return err
+	}
+
+	c.mutation.Lock()
+	defer c.mutation.Unlock()
+
+	c.lock.RLock()
+	schemas := make(map[string]*types.APISchema, len(incoming))
+	var missing []*types.APISchema
+	for _, s := range incoming {
+		if s.Schema == nil || s.ID == "" {
+			continue
+		}
+		if existing, ok := c.schemas[s.ID]; ok {
+			schemas[s.ID] = existing
+		} else {
+			s.Schema.ID = s.ID
+			missing = append(missing, &types.APISchema{Schema: s.Schema})
+		}
+	}
+	c.lock.RUnlock()
+
+	for _, s := range missing {
+		c.sources[s.ID] = s.DeepCopy()
+		c.applyTemplates(s)
+		schemas[s.ID] = s
+	}
+	for id := range c.sources {
+		if _, ok := schemas[id]; !ok {
+			delete(c.sources, id)
+		}
+	}
+
+	c.replace(schemas)
+	return nil
+}
@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func collectionOf(ctx context.Context, ids ...string) *Collection {
+	c := NewCollection(ctx, types.EmptyAPISchemas(), allAccess{})
+	for _, id := range ids {
+		c.AddSchema(widgetSchema(id))
+	}
+	return c
+}
+
+func TestSyncAddsAndRemovesSchemas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := collectionOf(ctx, "gizmo", "gadget")
+	target := collectionOf(ctx, "gadget", "doohickey")
+	target.AddTemplate(Template{ID: "gizmo", Customize: customize("customized")})
+
+	blob, err := source.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := target.SyncFrom(blob); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := target.IDs()
+	sort.Strings(ids)
+	if !reflect.DeepEqual(ids, []string{"gadget", "gizmo"}) {
+		t.Errorf("got schemas %v, want the schemas of the source", ids)
+	}
+	gizmo := target.Schema("gizmo")
+	if gizmo == nil {
+		t.Fatal("gizmo was not added")
+	}
+	if gvr := attributes.GVR(gizmo); gvr != (schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gizmos"}) {
+		t.Errorf("got the resource %v of the synced gizmo", gvr)
+	}
+	if gizmo.Description != "customized" {
+		t.Errorf("got description %q, want the templates applied to the synced schema", gizmo.Description)
+	}
+	if target.ByGVR(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "doohickeys"}) != "" {
+		t.Error("the schema missing from the source is still registered")
+	}
+
+	userSchemas, err := target.Schemas(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userSchemas.LookupSchema("gizmo") == nil || userSchemas.LookupSchema("doohickey") != nil {
+		t.Errorf("got the users schemas %v, want the synced registry", userSchemas.Schemas)
+	}
+}
+
+func TestExportIsDeterministic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first, err := collectionOf(ctx, "gizmo", "gadget", "doohickey").Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := collectionOf(ctx, "doohickey", "gizmo", "gadget").Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("got different blobs for the same schemas:\n%s\n%s", first, second)
+	}
+}
+
+func TestSyncFromInvalidBlob(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := collectionOf(ctx, "gizmo")
+	if err := c.SyncFrom([]byte(`{"gizmo":`)); err == nil {
+		t.Error("got no error for an invalid blob")
+	}
+	if c.Schema("gizmo") == nil {
+		t.Error("an invalid blob removed the schemas")
+	}
+}
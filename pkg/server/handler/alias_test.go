@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/builtin"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	steveschema "github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+type allAccessSetLookup struct{}
+
+func (allAccessSetLookup) AccessFor(user user.Info) *accesscontrol.AccessSet {
+	set := &accesscontrol.AccessSet{ID: "all"}
+	set.Add(accesscontrol.All, schema.GroupResource{Group: accesscontrol.All, Resource: accesscontrol.All}, accesscontrol.Access{
+		Namespace:    accesscontrol.All,
+		ResourceName: accesscontrol.All,
+	})
+	return set
+}
+
+var widgetAdmin = &user.DefaultInfo{
+	Name:   "admin",
+	Groups: []string{user.SystemPrivilegedGroup, user.AllAuthenticated},
+}
+
+// aliasedWidgetSchemas are the schemas of widgetAdmin from a collection with the widgets of url, also served as the
+// alias example.com.gadget.
+func aliasedWidgetSchemas(t *testing.T, url string) *types.APISchemas {
+	t.Helper()
+	client, err := dynamic.NewForConfig(&rest.Config{Host: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	widget := &types.APISchema{
+		Schema: &schemas.Schema{ID: "example.com.widget"},
+		Store:  proxy.NewProxyStore(&dynamicClientGetter{client: client}, nil, allAccessSetLookup{}),
+	}
+	attributes.SetGVK(widget, schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"})
+	attributes.SetResource(widget, "widgets")
+	attributes.SetNamespaced(widget, true)
+	attributes.SetVerbs(widget, []string{"get", "list"})
+
+	baseSchemas := types.EmptyAPISchemas()
+	if err := baseSchemas.AddSchemas(builtin.Schemas); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := steveschema.NewCollection(ctx, baseSchemas, allAccessSetLookup{})
+	c.AddSchema(widget)
+	c.AddTemplate(steveschema.Template{ID: "example.com.widget", Aliases: []string{"example.com.gadget"}})
+
+	userSchemas, err := c.Schemas(widgetAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userSchemas
+}
+
+// getWidget sends a get of the widget default/name as typ and returns the response.
+func getWidget(t *testing.T, apiSchemas *types.APISchemas, typ, name string) map[string]interface{} {
+	t.Helper()
+	path := "/v1/" + typ + "/default/" + name
+	vars := map[string]string{"type": typ, "namespace": "default", "name": name}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req = req.WithContext(request.WithUser(req.Context(), widgetAdmin))
+	req = mux.SetURLVars(req, vars)
+	urlBuilder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := httptest.NewRecorder()
+	apiOp := &types.APIRequest{
+		Schemas:    apiSchemas,
+		Request:    req,
+		Response:   rw,
+		URLBuilder: urlBuilder,
+	}
+	k8sAPI(nil, apiOp)
+	newAPIServer(nil).server.Handle(apiOp)
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got status %d for %s: %s", rw.Code, path, rw.Body)
+	}
+	result := map[string]interface{}{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func aliasRequests(alias string) int64 {
+	for _, use := range steveschema.AliasUsage() {
+		if use.Alias == alias {
+			return use.Requests
+		}
+	}
+	return 0
+}
+
+func TestAliasIsServedLikeTheCanonicalSchema(t *testing.T) {
+	cluster := &widgetAPIServer{objects: map[string]map[string]interface{}{
+		"a": {
+			"apiVersion": "example.com/v1",
+			"kind":       "Widget",
+			"metadata":   map[string]interface{}{"name": "a", "namespace": "default", "resourceVersion": "1"},
+			"spec":       map[string]interface{}{"size": "large"},
+		},
+	}}
+	srv := httptest.NewServer(cluster)
+	defer srv.Close()
+	apiSchemas := aliasedWidgetSchemas(t, srv.URL)
+
+	gadgets := apiSchemas.LookupSchema("example.com.gadget")
+	if gadgets == nil {
+		t.Fatal("the alias is not a schema of the user")
+	}
+	if aliasOf := attributes.AliasOf(gadgets); aliasOf != "example.com.widget" {
+		t.Errorf("got the alias of %q, want example.com.widget", aliasOf)
+	}
+	if gvr := attributes.GVR(gadgets); gvr.Resource != "widgets" {
+		t.Errorf("got the GVR %s for the alias, want the widgets", gvr)
+	}
+
+	before := aliasRequests("example.com.gadget")
+	widget := getWidget(t, apiSchemas, "example.com.widget", "a")
+	gadget := getWidget(t, apiSchemas, "example.com.gadget", "a")
+	if gadget["type"] != "example.com.gadget" || gadget["id"] != "default/a" {
+		t.Errorf("got type %v and id %v, want the widget as a gadget", gadget["type"], gadget["id"])
+	}
+	for _, key := range []string{"metadata", "spec", "apiVersion", "kind"} {
+		if !reflect.DeepEqual(widget[key], gadget[key]) {
+			t.Errorf("got %s %v through the alias, want %v", key, gadget[key], widget[key])
+		}
+	}
+
+	if got := aliasRequests("example.com.gadget") - before; got != 1 {
+		t.Errorf("got %d requests of the alias counted, want the get", got)
+	}
+
+	rw := httptest.NewRecorder()
+	steveschema.AliasUsageHandler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/alias-usage", nil))
+	var uses []steveschema.AliasUse
+	if err := json.Unmarshal(rw.Body.Bytes(), &uses); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, use := range uses {
+		if use.Alias == "example.com.gadget" && use.SchemaID == "example.com.widget" && use.Requests > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("got alias usage %v, want the requests of example.com.gadget", uses)
+	}
+}
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/rancher/apiserver/pkg/server"
 	apiserver "github.com/rancher/apiserver/pkg/server"
@@ -9,43 +13,139 @@ import (
 	"github.com/rancher/apiserver/pkg/urlbuilder"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/auth"
+	"github.com/rancher/steve/pkg/authorization"
+	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/steve/pkg/idle"
+	"github.com/rancher/steve/pkg/jsonapi"
 	k8sproxy "github.com/rancher/steve/pkg/proxy"
+	"github.com/rancher/steve/pkg/resources/operation"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/server/router"
+	"github.com/rancher/steve/pkg/stores/proxy"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/client-go/rest"
 )
 
+// Option configures optional behavior of the API handler.
+type Option func(*apiServer)
+
+// WithResponseCache caches successful GET responses for ttl, keyed by schema, query and the user's access set.
+// If ccache is not nil entries for a schema are also dropped as soon as one of its objects changes.
+func WithResponseCache(ctx context.Context, ttl time.Duration, ccache clustercache.ClusterCache) Option {
+	return func(a *apiServer) {
+		a.responses = newResponseCache(ttl)
+		if ccache != nil {
+			a.responses.invalidateOnChange(ctx, ccache, a.sf)
+		}
+	}
+}
+
+// WithFallbackProxy forwards requests for unregistered schemas to the Kubernetes apiserver at kubeAPIServerURL
+// instead of returning a 404. The type of the request names the resource as group.version.resource, or as
+// core.version.resource or version.resource for the core API. The transport must be allowed to impersonate the
+// requesting user.
+func WithFallbackProxy(kubeAPIServerURL string, transport http.RoundTripper) Option {
+	return func(a *apiServer) {
+		target, err := url.Parse(kubeAPIServerURL)
+		if err != nil {
+			logrus.Errorf("invalid fallback proxy URL %s: %v", kubeAPIServerURL, err)
+			return
+		}
+		a.fallback = newFallbackHandler(target, transport)
+	}
+}
+
+// WithSchemaChangeStream serves GET /api/schemas?watch=true, a server-sent event stream that reports the
+// schemas added to and removed from the requesting user's view.
+func WithSchemaChangeStream(enabled bool) Option {
+	return func(a *apiServer) {
+		a.schemaStream = enabled
+	}
+}
+
+// WithRequestBodyLimit rejects creates and updates whose body is larger than limit bytes with a 413. A limit of
+// zero or less disables the check. Defaults to DefaultRequestBodyLimit.
+func WithRequestBodyLimit(limit int64) Option {
+	return func(a *apiServer) {
+		a.bodyLimit = limit
+	}
+}
+
+// WithAsyncWrites queues creates, updates and deletes on queue when the client sends Prefer: respond-async or
+// the schema has attributes.AsyncWrites set. The client gets a 202 with the URL of an operation to poll.
+func WithAsyncWrites(queue *operation.Queue) Option {
+	return func(a *apiServer) {
+		a.async = &asyncWrites{
+			queue: queue,
+		}
+	}
+}
+
+// WithAuthorizers adds policy checks that run after the schema of a request is resolved and before it is
+// handled. The authorizers are chained with authorization.Chain, any denial rejects the request with a 403.
+func WithAuthorizers(authorizers ...authorization.Authorizer) Option {
+	return func(a *apiServer) {
+		a.authorizers = append(a.authorizers, authorizers...)
+	}
+}
+
+// WithSchemaSync serves /admin/schema-sync so steve instances can copy their schema registries from each other.
+// Bodies are signed with HMACSignature using secret. The schema factory must be a *schema.Collection.
+func WithSchemaSync(secret []byte) Option {
+	return func(a *apiServer) {
+		a.schemaSyncSecret = secret
+	}
+}
+
+// WithIdleTracker records every request on tracker by access set ID and keeps the identity active while it has
+// a watch open. Per user state held by the handler is registered to be swept once the identity goes idle.
+func WithIdleTracker(tracker *idle.Tracker) Option {
+	return func(a *apiServer) {
+		a.idle = tracker
+	}
+}
+
 func New(cfg *rest.Config, sf schema.Factory, authMiddleware auth.Middleware, next http.Handler,
-	routerFunc router.RouterFunc) (*apiserver.Server, http.Handler, error) {
+	routerFunc router.RouterFunc, opts ...Option) (*apiserver.Server, http.Handler, error) {
 	var (
-		proxy http.Handler
-		err   error
+		k8sProxy http.Handler
+		err      error
 	)
 
-	a := &apiServer{
-		sf:     sf,
-		server: server.DefaultAPIServer(),
+	a := newAPIServer(sf, opts...)
+	if a.idle != nil && a.responses != nil {
+		a.idle.Register(a.responses)
 	}
-	a.server.AccessControl = accesscontrol.NewAccessControl()
 
 	if authMiddleware == nil {
-		proxy, err = k8sproxy.Handler("/", cfg)
+		k8sProxy, err = k8sproxy.Handler("/", cfg)
 		if err != nil {
 			return a.server, nil, err
 		}
 		authMiddleware = auth.ToMiddleware(auth.AuthenticatorFunc(auth.AlwaysAdmin))
 	} else {
-		proxy = k8sproxy.ImpersonatingHandler("/", cfg)
+		k8sProxy = k8sproxy.ImpersonatingHandler("/", cfg)
 	}
 
 	w := authMiddleware
 	handlers := router.Handlers{
 		Next:        next,
 		K8sResource: w(a.apiHandler(k8sAPI)),
-		K8sProxy:    w(proxy),
+		K8sProxy:    w(k8sProxy),
 		APIRoot:     w(a.apiHandler(apiRoot)),
+		// the slow operations name schemas and namespaces, so only authenticated users can read them
+		SlowOperations: w(proxy.SlowOperations),
+		AliasUsage:     w(schema.AliasUsageHandler),
+	}
+	if a.schemaStream {
+		handlers.SchemaStream = w(&schemaStream{sf: sf})
+	}
+	if syncer, ok := sf.(schemaSyncer); ok && len(a.schemaSyncSecret) > 0 {
+		handlers.SchemaSync = w(&schemaSync{
+			syncer: syncer,
+			secret: a.schemaSyncSecret,
+		})
 	}
 	if routerFunc == nil {
 		return a.server, router.Routes(handlers), nil
@@ -53,9 +153,50 @@ func New(cfg *rest.Config, sf schema.Factory, authMiddleware auth.Middleware, ne
 	return a.server, routerFunc(handlers), nil
 }
 
+// newAPIServer returns the API server with the options applied and its JSON response writers set up, the envelope
+// wraps the output of every other writer.
+func newAPIServer(sf schema.Factory, opts ...Option) *apiServer {
+	a := &apiServer{
+		sf:        sf,
+		server:    server.DefaultAPIServer(),
+		bodyLimit: DefaultRequestBodyLimit,
+	}
+	a.server.AccessControl = accesscontrol.NewAccessControl()
+	if next, ok := a.server.ResponseWriters["json"]; ok {
+		a.server.ResponseWriters["json"] = &jsonapi.ResponseWriter{
+			Next: &fieldErrorWriter{
+				ResponseWriter: &paginationWriter{
+					ResponseWriter: next,
+				},
+			},
+		}
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if next, ok := a.server.ResponseWriters["json"]; ok && a.envelope != nil {
+		a.server.ResponseWriters["json"] = &envelopeWriter{
+			ResponseWriter: next,
+			envelope:       a.envelope,
+		}
+	}
+	return a
+}
+
 type apiServer struct {
-	sf     schema.Factory
-	server *server.Server
+	sf        schema.Factory
+	server    *server.Server
+	responses *responseCache
+	fallback  *fallbackHandler
+
+	schemaStream bool
+	bodyLimit    int64
+	async        *asyncWrites
+	authorizers  authorization.Chain
+
+	schemaSyncSecret []byte
+	idle             *idle.Tracker
+	envelope         Envelope
 }
 
 func (a *apiServer) common(rw http.ResponseWriter, req *http.Request) (*types.APIRequest, bool) {
@@ -80,21 +221,95 @@ func (a *apiServer) common(rw http.ResponseWriter, req *http.Request) (*types.AP
 
 	return &types.APIRequest{
 		Schemas:    schemas,
-		Request:    req,
+		Request:    req.WithContext(proxy.WithFieldErrors(req.Context())),
 		Response:   rw,
 		URLBuilder: urlBuilder,
 	}, true
 }
 
+// track marks the access set of the request as seen, for watches it stays active until the returned func is
+// called when the watch ends.
+func (a *apiServer) track(apiOp *types.APIRequest) func() {
+	if a.idle == nil {
+		return func() {}
+	}
+	accessSet, _ := apiOp.Schemas.Attributes["accessSet"].(*accesscontrol.AccessSet)
+	if accessSet == nil {
+		return func() {}
+	}
+	req := apiOp.Request
+	if apiOp.Type == "subscribe" || req.URL.Query().Get("watch") != "" ||
+		strings.EqualFold(req.Header.Get("Connection"), "upgrade") {
+		return a.idle.Active(accessSet.ID)
+	}
+	a.idle.Seen(accessSet.ID)
+	return func() {}
+}
+
 type APIFunc func(schema.Factory, *types.APIRequest)
 
 func (a *apiServer) apiHandler(apiFunc APIFunc) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		apiOp, ok := a.common(rw, req)
 		if ok {
+			defer a.track(apiOp)()
+			if err := limitBody(apiOp, a.bodyLimit); err != nil {
+				apiOp.WriteError(err)
+				return
+			}
+			if err := checkBody(apiOp); err != nil {
+				apiOp.WriteError(err)
+				return
+			}
+			if err := decodeJSONAPI(apiOp); err != nil {
+				apiOp.WriteError(err)
+				return
+			}
+			if err := overrideStores(apiOp); err != nil {
+				apiOp.WriteError(err)
+				return
+			}
+			if err := normalizeNamespaces(apiOp); err != nil {
+				apiOp.WriteError(err)
+				return
+			}
 			if apiFunc != nil {
 				apiFunc(a.sf, apiOp)
 			}
+			if err := defaultNamespace(apiOp); err != nil {
+				apiOp.WriteError(err)
+				return
+			}
+			if err := checkNamespace(apiOp); err != nil {
+				apiOp.WriteError(err)
+				return
+			}
+			if err := a.authorize(apiOp); err != nil {
+				apiOp.WriteError(err)
+				return
+			}
+			if err := injectOwner(apiOp); err != nil {
+				apiOp.WriteError(err)
+				return
+			}
+			if a.serveTable(apiOp) || a.serveQuery(apiOp) || a.serveCount(apiOp) || a.serveStatusPatch(apiOp) ||
+				a.serveRaw(apiOp) {
+				return
+			}
+			if a.fallback != nil && a.fallback.serve(apiOp, a.sf) {
+				return
+			}
+			if a.async != nil && a.async.serve(apiOp, func(queued *types.APIRequest) {
+				if apiFunc != nil {
+					apiFunc(a.sf, queued)
+				}
+			}, a.server.Handle) {
+				return
+			}
+			if a.responses != nil {
+				a.responses.serve(apiOp, a.server.Handle)
+				return
+			}
 			a.server.Handle(apiOp)
 		}
 	})
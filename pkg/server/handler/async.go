@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/resources/operation"
+)
+
+// asyncWrites queues writes as operations and answers 202 with the URL of the operation.
+type asyncWrites struct {
+	queue *operation.Queue
+}
+
+func preferAsync(req *http.Request) bool {
+	for _, prefer := range req.Header["Prefer"] {
+		for _, value := range strings.Split(prefer, ",") {
+			if strings.TrimSpace(value) == "respond-async" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// asyncVerb returns the verb of a write that can be queued. It runs before the request is parsed by the
+// apiserver so it looks at the route variables and query directly.
+func asyncVerb(apiOp *types.APIRequest) string {
+	req := apiOp.Request
+	vars := mux.Vars(req)
+	if apiOp.Type == "" || apiOp.Type == operation.SchemaID || vars["link"] != "" ||
+		req.URL.Query().Get("action") != "" || req.URL.Query().Get("link") != "" {
+		return ""
+	}
+
+	name := vars["name"]
+	switch req.Method {
+	case http.MethodPost:
+		if name == "" {
+			return "create"
+		}
+	case http.MethodPut, http.MethodPatch:
+		if name != "" {
+			return "update"
+		}
+	case http.MethodDelete:
+		if name != "" {
+			return "delete"
+		}
+	}
+	return ""
+}
+
+func (a *asyncWrites) serve(apiOp *types.APIRequest, prepare, handle func(*types.APIRequest)) bool {
+	verb := asyncVerb(apiOp)
+	if verb == "" {
+		return false
+	}
+	schema := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if schema == nil || (!preferAsync(apiOp.Request) && !attributes.AsyncWrites(schema)) {
+		return false
+	}
+
+	body, err := ioutil.ReadAll(apiOp.Request.Body)
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+
+	// the write runs after the client request is done, it keeps the request values, such as the user, but not
+	// its cancellation
+	req := apiOp.Request.Clone(detached{apiOp.Request.Context()})
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	target := schema.ID
+	if name := mux.Vars(apiOp.Request)["name"]; name != "" {
+		if apiOp.Namespace != "" {
+			name = apiOp.Namespace + "/" + name
+		}
+		target += "/" + name
+	}
+
+	op, err := a.queue.Enqueue(apiOp, verb, target, func() (int, []byte) {
+		recorder := &responseRecorder{
+			header: http.Header{},
+		}
+		queued := &types.APIRequest{
+			Schemas:    apiOp.Schemas,
+			Request:    req,
+			Response:   recorder,
+			URLBuilder: apiOp.URLBuilder,
+		}
+		prepare(queued)
+		handle(queued)
+		resp := recorder.toCachedResponse()
+		return resp.status, resp.body
+	})
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+
+	if opSchema := apiOp.Schemas.LookupSchema(operation.SchemaID); opSchema != nil {
+		apiOp.Response.Header().Set("Location", apiOp.URLBuilder.ResourceLink(opSchema, op.ID))
+	}
+	apiOp.Response.Header().Set("Preference-Applied", "respond-async")
+	apiOp.WriteResponse(http.StatusAccepted, types.APIObject{
+		Type:   operation.SchemaID,
+		ID:     op.ID,
+		Object: op,
+	})
+	return true
+}
+
+// detached keeps the values of the parent context but is never cancelled with it.
+type detached struct {
+	parent context.Context
+}
+
+func (d detached) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detached) Done() <-chan struct{}             { return nil }
+func (d detached) Err() error                        { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/authorization"
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// requestVerb returns the verb of a request from its method and route, before the apiserver parses it.
+func requestVerb(req *http.Request) string {
+	vars := mux.Vars(req)
+	q := req.URL.Query()
+	if link := vars["link"]; link != "" {
+		return link
+	}
+	if link := q.Get("link"); link != "" {
+		return link
+	}
+	if action := q.Get("action"); action != "" {
+		return action
+	}
+
+	name := vars["name"]
+	switch req.Method {
+	case http.MethodGet:
+		if name == "" {
+			return "list"
+		}
+		return "get"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut, http.MethodPatch:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return ""
+}
+
+func (a *apiServer) authorize(apiOp *types.APIRequest) error {
+	if len(a.authorizers) == 0 || apiOp.Type == "" {
+		return nil
+	}
+	schema := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if schema == nil {
+		return nil
+	}
+
+	user, _ := request.UserFrom(apiOp.Context())
+	attrs := authorization.Attributes{
+		User:      user,
+		Schema:    schema,
+		Verb:      requestVerb(apiOp.Request),
+		Namespace: apiOp.Namespace,
+		Name:      mux.Vars(apiOp.Request)["name"],
+	}
+
+	switch attrs.Verb {
+	case "create", "update":
+		obj, err := peekBody(apiOp.Request)
+		if err != nil {
+			return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+		}
+		attrs.Object = obj
+	}
+
+	decision, reason, err := a.authorizers.Authorize(apiOp.Context(), attrs)
+	if err != nil {
+		return err
+	}
+	if decision == authorization.Deny {
+		if reason == "" {
+			reason = attrs.Verb + " on " + schema.ID + " denied by policy"
+		}
+		return apierror.NewAPIError(validation.PermissionDenied, reason)
+	}
+	return nil
+}
+
+// peekBody parses a JSON body and puts it back so the request can still be read.
+func peekBody(req *http.Request) (map[string]interface{}, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil || len(body) == 0 {
+		return nil, err
+	}
+
+	obj := map[string]interface{}{}
+	if err := jsonnumber.Unmarshal(body, &obj); err != nil {
+		// JSON patches are arrays, policies see them without an object
+		return nil, nil
+	}
+	return obj, nil
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// DefaultRequestBodyLimit matches the largest request body the Kubernetes apiserver accepts.
+const DefaultRequestBodyLimit = 3 * 1024 * 1024
+
+var (
+	ErrRequestEntityTooLarge = validation.ErrorCode{
+		Code:   "RequestEntityTooLarge",
+		Status: http.StatusRequestEntityTooLarge,
+	}
+)
+
+// limitBody rejects a write whose body is larger than limit before the body is parsed. Bodies without a content
+// length are read into memory up to the limit so the rest of the request sees a complete body.
+func limitBody(apiOp *types.APIRequest, limit int64) error {
+	req := apiOp.Request
+	if limit <= 0 || req.Body == nil {
+		return nil
+	}
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return nil
+	}
+
+	if req.ContentLength > limit {
+		return tooLarge(limit)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, limit+1))
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > limit {
+		return tooLarge(limit)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+func tooLarge(limit int64) error {
+	return apierror.NewAPIError(ErrRequestEntityTooLarge, fmt.Sprintf("request body is larger than the limit of %d bytes", limit))
+}
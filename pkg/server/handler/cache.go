@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/steve/pkg/schema"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+const (
+	responseCacheSize   = 1000
+	maxCachedBodyLength = 4 << 20
+)
+
+// responseCache caches successful GET responses per schema, query and access set. Entries expire after the
+// TTL or, when a cluster cache is available, as soon as an object of the schema changes.
+type responseCache struct {
+	ttl       time.Duration
+	responses *cache.LRUExpireCache
+}
+
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+	etag   string
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{
+		ttl:       ttl,
+		responses: cache.NewLRUExpireCache(responseCacheSize),
+	}
+}
+
+func (c *responseCache) invalidateOnChange(ctx context.Context, ccache clustercache.ClusterCache, sf schema.Factory) {
+	invalidate := func(gvk schema2.GroupVersionKind) {
+		c.invalidate(sf.ByGVK(gvk))
+		// counts are derived from every other schema
+		c.invalidate("count")
+	}
+	ccache.OnAdd(ctx, func(gvk schema2.GroupVersionKind, key string, obj runtime.Object) error {
+		invalidate(gvk)
+		return nil
+	})
+	ccache.OnRemove(ctx, func(gvk schema2.GroupVersionKind, key string, obj runtime.Object) error {
+		invalidate(gvk)
+		return nil
+	})
+	ccache.OnChange(ctx, func(gvk schema2.GroupVersionKind, key string, obj, oldObj runtime.Object) error {
+		invalidate(gvk)
+		return nil
+	})
+}
+
+func (c *responseCache) invalidate(schemaID string) {
+	if schemaID == "" {
+		return
+	}
+	prefix := schemaID + "|"
+	for _, k := range c.responses.Keys() {
+		if key, ok := k.(string); ok && strings.HasPrefix(key, prefix) {
+			c.responses.Remove(k)
+		}
+	}
+}
+
+// Forget drops the cached responses of the access set with the given ID.
+func (c *responseCache) Forget(accessID string) {
+	suffix := "|" + accessID
+	for _, k := range c.responses.Keys() {
+		if key, ok := k.(string); ok && strings.HasSuffix(key, suffix) {
+			c.responses.Remove(k)
+		}
+	}
+}
+
+func (c *responseCache) key(apiOp *types.APIRequest) (string, bool) {
+	req := apiOp.Request
+	if req.Method != http.MethodGet || apiOp.Type == "" || apiOp.Type == "subscribe" ||
+		strings.EqualFold(req.Header.Get("Connection"), "upgrade") {
+		return "", false
+	}
+
+	query := req.URL.Query()
+	if query.Get("link") != "" || query.Get("action") != "" || query.Get("watch") != "" {
+		return "", false
+	}
+
+	apiSchema := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if apiSchema == nil || attributes.Sensitive(apiSchema) {
+		return "", false
+	}
+
+	accessSet, _ := apiOp.Schemas.Attributes["accessSet"].(*accesscontrol.AccessSet)
+	if accessSet == nil || accessSet.ID == "" {
+		return "", false
+	}
+
+	// Encode sorts by key so equivalent queries share an entry
+	return strings.Join([]string{
+		apiSchema.ID,
+		apiOp.Namespace,
+		apiOp.Name,
+		req.Header.Get("Accept"),
+		req.Header.Get(NamespaceHeader),
+		query.Encode(),
+		accessSet.ID,
+	}, "|"), true
+}
+
+func (c *responseCache) serve(apiOp *types.APIRequest, handle func(*types.APIRequest)) {
+	key, ok := c.key(apiOp)
+	if !ok {
+		handle(apiOp)
+		return
+	}
+
+	rw := apiOp.Response
+	if val, ok := c.responses.Get(key); ok {
+		val.(*cachedResponse).write(rw, apiOp.Request)
+		return
+	}
+
+	recorder := &responseRecorder{
+		header: http.Header{},
+	}
+	apiOp.Response = recorder
+	handle(apiOp)
+	apiOp.Response = rw
+
+	resp := recorder.toCachedResponse()
+	if resp.status == http.StatusOK && len(resp.body) <= maxCachedBodyLength {
+		c.responses.Add(key, resp, c.ttl)
+	}
+	resp.write(rw, apiOp.Request)
+}
+
+func (r *cachedResponse) write(rw http.ResponseWriter, req *http.Request) {
+	for k, v := range r.header {
+		rw.Header()[k] = v
+	}
+	if r.status == http.StatusOK {
+		rw.Header().Set("ETag", r.etag)
+		if etagMatches(req.Header.Get("If-None-Match"), r.etag) {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	rw.WriteHeader(r.status)
+	_, _ = rw.Write(r.body)
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) toCachedResponse() *cachedResponse {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	sum := sha256.Sum256(r.body.Bytes())
+	return &cachedResponse{
+		status: status,
+		header: r.header,
+		body:   r.body.Bytes(),
+		etag:   `"` + hex.EncodeToString(sum[:]) + `"`,
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// countPageSize is the number of objects listed at a time while counting, only one page is held in memory.
+const countPageSize = 1000
+
+// CountResult is the response to GET /v1/{type}?countBy={path}. Counts holds the number of objects by the value
+// at path, Missing the number of objects without a value there or with a null value.
+type CountResult struct {
+	Type    string         `json:"type"`
+	Field   string         `json:"field"`
+	Counts  map[string]int `json:"counts"`
+	Missing int            `json:"missing"`
+	Total   int            `json:"total"`
+}
+
+// serveCount answers collection GETs with ?countBy={path} with the number of objects by the value at path
+// instead of the objects. Every page of the list, including every namespace of a fan-out, is counted; the list
+// goes through the schema store so the counts only cover objects the user can list. Paths use the JSONPath subset
+// of field promotions, for example status.phase or status.conditions[?type==Ready].status.
+func (a *apiServer) serveCount(apiOp *types.APIRequest) bool {
+	req := apiOp.Request
+	path := req.URL.Query().Get("countBy")
+	if path == "" || req.Method != http.MethodGet || apiOp.Type == "" || apiOp.Name != "" {
+		return false
+	}
+
+	schema := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if schema == nil {
+		return false
+	}
+	if err := a.server.AccessControl.CanList(apiOp, schema); err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+	if schema.Store == nil {
+		apiOp.WriteError(apierror.NewAPIError(validation.MethodNotAllowed, schema.ID+" can not be listed"))
+		return true
+	}
+	if _, _, err := proxy.FieldValue(nil, path); err != nil {
+		apiOp.WriteError(apierror.NewAPIError(validation.InvalidOption, "invalid countBy path: "+err.Error()))
+		return true
+	}
+
+	result, err := countBy(apiOp, schema, path)
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+	apiOp.Response.Header().Set("Content-Type", "application/json")
+	apiOp.Response.WriteHeader(http.StatusOK)
+	_, _ = apiOp.Response.Write(body)
+	return true
+}
+
+func countBy(apiOp *types.APIRequest, schema *types.APISchema, path string) (*CountResult, error) {
+	result := &CountResult{
+		Type:   schema.ID,
+		Field:  path,
+		Counts: map[string]int{},
+	}
+
+	cont := ""
+	for {
+		pageOp := apiOp.Clone()
+		pageOp.Schema = schema
+		pageOp.Request = apiOp.Request.Clone(apiOp.Context())
+		query := pageOp.Request.URL.Query()
+		query.Del("countBy")
+		query.Set("limit", strconv.Itoa(countPageSize))
+		if cont == "" {
+			query.Del("continue")
+		} else {
+			query.Set("continue", cont)
+		}
+		pageOp.Request.URL.RawQuery = query.Encode()
+
+		list, err := schema.Store.List(pageOp, schema)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range list.Objects {
+			result.Total++
+			value, ok, _ := proxy.FieldValue(obj.Data(), path)
+			if !ok || value == nil {
+				result.Missing++
+				continue
+			}
+			result.Counts[fmt.Sprint(value)]++
+		}
+
+		if list.Continue == "" || list.Continue == cont {
+			return result, nil
+		}
+		cont = list.Continue
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// maxBodyDepth is the deepest nesting of objects and arrays accepted in a write body, well beyond the
+	// nesting of real objects including CRDs with their OpenAPI schemas.
+	maxBodyDepth = 100
+	// maxBodyNodes is the most values, keys included, accepted in a write body.
+	maxBodyNodes = 500000
+)
+
+// checkBody rejects a JSON write body with duplicate keys in an object, which would otherwise silently resolve to
+// the last one, or that is nested deeper than maxBodyDepth or holds more than maxBodyNodes values, which could only
+// be meant to exhaust the server. The YAML body of a create or update is converted to JSON first, the apiserver
+// decodes JSON numbers as json.Number but YAML numbers as float64, which rounds integers above 2^53. Other bodies
+// are left to the decoder of the request.
+func checkBody(apiOp *types.APIRequest) error {
+	req := apiOp.Request
+	if req.Body == nil {
+		return nil
+	}
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return nil
+	}
+	yamlBody := req.Method != http.MethodPatch && isYAMLBody(req)
+	if !yamlBody && !isJSONBody(req) {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if yamlBody {
+		if body, err = yaml.ToJSON(body); err != nil {
+			return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+	if err := validateJSON(body); err != nil {
+		return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	return nil
+}
+
+func isJSONBody(req *http.Request) bool {
+	contentType := req.Header.Get("Content-Type")
+	return contentType == "" || strings.Contains(contentType, "json")
+}
+
+// isYAMLBody matches the content type the apiserver decodes as YAML.
+func isYAMLBody(req *http.Request) bool {
+	return req.Header.Get("Content-Type") == "application/yaml"
+}
+
+// validateJSON walks the tokens of body, keeping a set of the keys seen for every open object.
+func validateJSON(body []byte) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	// keys holds the keys of every open object and nil for every open array, expectKey is whether the next
+	// token of the innermost object is a key
+	var keys []map[string]bool
+	expectKey := false
+	nodes := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		nodes++
+		if nodes > maxBodyNodes {
+			return fmt.Errorf("body has more than %d values", maxBodyNodes)
+		}
+
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				if len(keys) >= maxBodyDepth {
+					return fmt.Errorf("body is nested deeper than %d levels", maxBodyDepth)
+				}
+				if delim == '{' {
+					keys = append(keys, map[string]bool{})
+				} else {
+					keys = append(keys, nil)
+				}
+				expectKey = delim == '{'
+			default:
+				keys = keys[:len(keys)-1]
+				expectKey = inObject(keys)
+			}
+			continue
+		}
+
+		if expectKey {
+			key, _ := token.(string)
+			if keys[len(keys)-1][key] {
+				return fmt.Errorf("duplicate key %q", key)
+			}
+			keys[len(keys)-1][key] = true
+			expectKey = false
+			continue
+		}
+		expectKey = inObject(keys)
+	}
+}
+
+func inObject(keys []map[string]bool) bool {
+	return len(keys) > 0 && keys[len(keys)-1] != nil
+}
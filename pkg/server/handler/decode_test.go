@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/builtin"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// above 2^53, a float64 rounds them to an even neighbour
+const (
+	createdSize = "9007199254740993"
+	updatedSize = "9007199254740995"
+	patchedSize = "9007199254740997"
+)
+
+// widgetAPIServer serves the widgets of example.com/v1 like the apiserver does, keeping the numbers of the objects
+// it is sent exactly. Merge and strategic merge patches are both applied as merge patches, a patch of the status
+// subresource only changes the status.
+type widgetAPIServer struct {
+	lock    sync.Mutex
+	objects map[string]map[string]interface{}
+}
+
+func (w *widgetAPIServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	name := strings.TrimPrefix(req.URL.Path, "/apis/example.com/v1/namespaces/default/widgets")
+	name = strings.TrimPrefix(name, "/")
+	status := strings.HasSuffix(name, "/status")
+	name = strings.TrimSuffix(name, "/status")
+	body, _ := ioutil.ReadAll(req.Body)
+	input := map[string]interface{}{}
+	if len(body) > 0 {
+		if err := jsonnumber.Unmarshal(body, &input); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		name = data.Object(input).String("metadata", "name")
+	case http.MethodPatch:
+		live, ok := w.objects[name]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if status {
+			input = map[string]interface{}{"status": input["status"]}
+		}
+		input = mergePatch(live, input)
+	}
+	if req.Method != http.MethodGet {
+		data.PutValue(input, "default", "metadata", "namespace")
+		data.PutValue(input, "1", "metadata", "resourceVersion")
+		w.objects[name] = input
+	}
+
+	obj, ok := w.objects[name]
+	if !ok {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	if req.Method == http.MethodPost {
+		rw.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(rw).Encode(obj)
+}
+
+func mergePatch(live, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		patchMap, isMap := value.(map[string]interface{})
+		liveMap, liveIsMap := live[key].(map[string]interface{})
+		switch {
+		case value == nil:
+			delete(live, key)
+		case isMap && liveIsMap:
+			live[key] = mergePatch(liveMap, patchMap)
+		default:
+			live[key] = value
+		}
+	}
+	return live
+}
+
+// dynamicClientGetter gives the proxy store a dynamic client of the widget API server.
+type dynamicClientGetter struct {
+	proxy.ClientGetter
+	client dynamic.Interface
+}
+
+func (d *dynamicClientGetter) Client(apiOp *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return d.client.Resource(attributes.GVR(schema)).Namespace(namespace), nil
+}
+
+func (d *dynamicClientGetter) TableClient(apiOp *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return d.Client(apiOp, schema, namespace)
+}
+
+type noAccessSetLookup struct{}
+
+func (noAccessSetLookup) AccessFor(user user.Info) *accesscontrol.AccessSet {
+	return &accesscontrol.AccessSet{}
+}
+
+func widgetSchemas(t *testing.T, url string) *types.APISchemas {
+	t.Helper()
+	client, err := dynamic.NewForConfig(&rest.Config{Host: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	widget := types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "example.com.widget",
+			CollectionMethods: []string{http.MethodGet, http.MethodPost},
+			ResourceMethods:   []string{http.MethodGet, http.MethodPut, http.MethodPatch},
+		},
+		Store: proxy.NewProxyStore(&dynamicClientGetter{client: client}, nil, noAccessSetLookup{}),
+	}
+	attributes.SetGVK(&widget, schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"})
+	attributes.SetResource(&widget, "widgets")
+	attributes.SetNamespaced(&widget, true)
+	access := accesscontrol.AccessListByVerb{}
+	for _, verb := range []string{"get", "list", "create", "update", "patch"} {
+		access[verb] = accesscontrol.AccessList{{Namespace: accesscontrol.All, ResourceName: accesscontrol.All}}
+	}
+	attributes.SetAccess(&widget, access)
+
+	apiSchemas := types.EmptyAPISchemas()
+	if err := apiSchemas.AddSchemas(builtin.Schemas); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiSchemas.AddSchema(widget); err != nil {
+		t.Fatal(err)
+	}
+	return apiSchemas
+}
+
+// writeWidget sends a write through the body checks of apiHandler to the proxy store.
+func writeWidget(t *testing.T, apiSchemas *types.APISchemas, method, name, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	path := "/v1/example.com.widget"
+	vars := map[string]string{"type": "example.com.widget"}
+	if name != "" {
+		path += "/default/" + name
+		vars["namespace"], vars["name"] = "default", name
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	// the field errors are collected like apiServer.common does
+	req = req.WithContext(request.WithUser(proxy.WithFieldErrors(req.Context()), &user.DefaultInfo{
+		Name:   "admin",
+		Groups: []string{user.SystemPrivilegedGroup, user.AllAuthenticated},
+	}))
+	req = mux.SetURLVars(req, vars)
+	urlBuilder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := httptest.NewRecorder()
+	apiOp := &types.APIRequest{
+		Schemas:    apiSchemas,
+		Request:    req,
+		Response:   rw,
+		URLBuilder: urlBuilder,
+	}
+	if err := checkBody(apiOp); err != nil {
+		apiOp.WriteError(err)
+		return rw
+	}
+	k8sAPI(nil, apiOp)
+	newAPIServer(nil).server.Handle(apiOp)
+	return rw
+}
+
+func storedNumber(t *testing.T, cluster *widgetAPIServer, name string, path ...string) string {
+	t.Helper()
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	value, ok := data.GetValueN(cluster.objects[name], path...).(json.Number)
+	if !ok {
+		t.Fatalf("got %#v at %v of %s, want a number", data.GetValueN(cluster.objects[name], path...), path, name)
+	}
+	return value.String()
+}
+
+func TestLargeIntegersSurviveWrites(t *testing.T) {
+	cluster := &widgetAPIServer{objects: map[string]map[string]interface{}{}}
+	srv := httptest.NewServer(cluster)
+	defer srv.Close()
+	apiSchemas := widgetSchemas(t, srv.URL)
+
+	for _, test := range []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"metadata":{"name":"json","namespace":"default"},"spec":{"size":` + createdSize + `}}`,
+		},
+		{
+			name:        "yaml",
+			contentType: "application/yaml",
+			body:        "metadata:\n  name: yaml\n  namespace: default\nspec:\n  size: " + createdSize + "\n",
+		},
+	} {
+		if rw := writeWidget(t, apiSchemas, http.MethodPost, "", test.contentType, test.body); rw.Code != http.StatusCreated {
+			t.Fatalf("%s: got status %d for the create: %s", test.name, rw.Code, rw.Body)
+		}
+		if got := storedNumber(t, cluster, test.name, "spec", "size"); got != createdSize {
+			t.Errorf("%s: got size %s after the create, want %s", test.name, got, createdSize)
+		}
+	}
+
+	update := `{"metadata":{"name":"json","namespace":"default","resourceVersion":"1"},"spec":{"size":` + updatedSize + `}}`
+	if rw := writeWidget(t, apiSchemas, http.MethodPut, "json", "application/json", update); rw.Code != http.StatusOK {
+		t.Fatalf("got status %d for the update: %s", rw.Code, rw.Body)
+	}
+	if got := storedNumber(t, cluster, "json", "spec", "size"); got != updatedSize {
+		t.Errorf("got size %s after the update, want %s", got, updatedSize)
+	}
+
+	patch := `{"spec":{"limit":` + patchedSize + `}}`
+	if rw := writeWidget(t, apiSchemas, http.MethodPatch, "json", "application/json", patch); rw.Code != http.StatusOK {
+		t.Fatalf("got status %d for the patch: %s", rw.Code, rw.Body)
+	}
+	if got := storedNumber(t, cluster, "json", "spec", "limit"); got != patchedSize {
+		t.Errorf("got limit %s after the patch, want %s", got, patchedSize)
+	}
+	if got := storedNumber(t, cluster, "json", "spec", "size"); got != updatedSize {
+		t.Errorf("got size %s after the patch, want the updated %s", got, updatedSize)
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// ResponseKind is what a response body holds.
+type ResponseKind string
+
+const (
+	ObjectResponse     ResponseKind = "object"
+	CollectionResponse ResponseKind = "collection"
+	ErrorResponse      ResponseKind = "error"
+)
+
+// Envelope reshapes the JSON responses of the API for embedders whose gateways expect their own conventions, for
+// example {"data": ..., "metadata": ...}. Wrap gets the body steve would send and returns the value to encode
+// instead. It is called for objects, collections and errors of every request that is answered through the
+// response writers of the API server, reads, writes and actions alike.
+type Envelope interface {
+	Wrap(apiOp *types.APIRequest, kind ResponseKind, code int, body json.RawMessage) (interface{}, error)
+}
+
+// EnvelopeFunc adapts a func to an Envelope.
+type EnvelopeFunc func(apiOp *types.APIRequest, kind ResponseKind, code int, body json.RawMessage) (interface{}, error)
+
+func (e EnvelopeFunc) Wrap(apiOp *types.APIRequest, kind ResponseKind, code int, body json.RawMessage) (interface{}, error) {
+	return e(apiOp, kind, code, body)
+}
+
+// WithResponseEnvelope wraps the JSON responses of the API in envelope. Without the option responses keep the
+// native shape, unchanged. Subscriptions are written by the API server and keep their frames, streams written with
+// watchevent.StreamWith can use a watchevent.FrameEncoder of the same shape.
+func WithResponseEnvelope(envelope Envelope) Option {
+	return func(a *apiServer) {
+		a.envelope = envelope
+	}
+}
+
+// envelopeWriter passes the output of the native writer through the envelope.
+type envelopeWriter struct {
+	types.ResponseWriter
+	envelope Envelope
+}
+
+func (e *envelopeWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	kind := ObjectResponse
+	if obj.Type == "error" {
+		kind = ErrorResponse
+	}
+	e.wrap(apiOp, kind, func(apiOp *types.APIRequest) {
+		e.ResponseWriter.Write(apiOp, code, obj)
+	})
+}
+
+func (e *envelopeWriter) WriteList(apiOp *types.APIRequest, code int, list types.APIObjectList) {
+	e.wrap(apiOp, CollectionResponse, func(apiOp *types.APIRequest) {
+		e.ResponseWriter.WriteList(apiOp, code, list)
+	})
+}
+
+func (e *envelopeWriter) wrap(apiOp *types.APIRequest, kind ResponseKind, write func(*types.APIRequest)) {
+	rw := apiOp.Response
+	recorder := &responseRecorder{
+		header: http.Header{},
+	}
+	apiOp.Response = recorder
+	write(apiOp)
+	apiOp.Response = rw
+
+	status := recorder.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	for k, v := range recorder.header {
+		rw.Header()[k] = v
+	}
+
+	body := recorder.body.Bytes()
+	if len(body) > 0 && json.Valid(body) {
+		wrapped, err := e.envelope.Wrap(apiOp, kind, status, body)
+		if err == nil {
+			body, err = json.Marshal(wrapped)
+		}
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Del("Content-Length")
+	}
+	rw.WriteHeader(status)
+	_, _ = rw.Write(body)
+}
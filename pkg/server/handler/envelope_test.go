@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/builtin"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/steve/pkg/stores/memory"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type envelopeResponse struct {
+	name string
+	code int
+	body []byte
+}
+
+// envelopeSuite sends reads, writes and failing requests through the response writers of an API server built with
+// opts, against a new memory store, and returns the responses.
+func envelopeSuite(t *testing.T, opts ...Option) []envelopeResponse {
+	t.Helper()
+	apiSchemas := types.EmptyAPISchemas()
+	if err := apiSchemas.AddSchemas(builtin.Schemas); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiSchemas.AddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "widget",
+			CollectionMethods: []string{http.MethodGet, http.MethodPost},
+			ResourceMethods:   []string{http.MethodGet, http.MethodPut, http.MethodDelete},
+		},
+		Store: memory.NewMemoryStore(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a := newAPIServer(nil, opts...)
+
+	send := func(name, method, path, body string) envelopeResponse {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Accept", "application/json")
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{
+			Name:   "admin",
+			Groups: []string{user.SystemPrivilegedGroup, user.AllAuthenticated},
+		}))
+		urlBuilder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the route variables of /v1/{type}/{namespace}/{name}
+		vars := map[string]string{}
+		for i, part := range strings.Split(strings.Trim(req.URL.Path, "/"), "/")[1:] {
+			vars[[]string{"type", "namespace", "name"}[i]] = part
+		}
+		req = mux.SetURLVars(req, vars)
+		rw := httptest.NewRecorder()
+		a.server.Handle(&types.APIRequest{
+			Type:       vars["type"],
+			Namespace:  vars["namespace"],
+			Name:       vars["name"],
+			Schemas:    apiSchemas,
+			Request:    req,
+			Response:   rw,
+			URLBuilder: urlBuilder,
+		})
+		return envelopeResponse{name: name, code: rw.Code, body: rw.Body.Bytes()}
+	}
+
+	widget := `{"metadata":{"name":"a","namespace":"default"},"spec":{"size":"large"}}`
+	return []envelopeResponse{
+		send("create", http.MethodPost, "/v1/widget", widget),
+		send("get", http.MethodGet, "/v1/widget/default/a", ""),
+		send("list", http.MethodGet, "/v1/widget", ""),
+		send("update", http.MethodPut, "/v1/widget/default/a", `{"metadata":{"name":"a","namespace":"default"},"spec":{"size":"small"}}`),
+		send("conflict", http.MethodPost, "/v1/widget", widget),
+		send("invalid body", http.MethodPost, "/v1/widget", `{"metadata":`),
+		send("delete", http.MethodDelete, "/v1/widget/default/a", ""),
+		send("not found", http.MethodGet, "/v1/widget/default/a", ""),
+		send("unknown schema", http.MethodGet, "/v1/gadget", ""),
+	}
+}
+
+func decodeBody(t *testing.T, name string, body []byte) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("%s: invalid JSON %q: %v", name, body, err)
+	}
+	return value
+}
+
+func TestDefaultEnvelopeKeepsNativeResponses(t *testing.T) {
+	without := envelopeSuite(t)
+	with := envelopeSuite(t, WithResponseEnvelope(nil))
+	for i, resp := range without {
+		if resp.code != with[i].code || !bytes.Equal(resp.body, with[i].body) {
+			t.Errorf("%s: got %d %s, want %d %s", resp.name, with[i].code, with[i].body, resp.code, resp.body)
+		}
+		if len(resp.body) == 0 {
+			continue
+		}
+		obj, _ := decodeBody(t, resp.name, resp.body).(map[string]interface{})
+		if _, ok := obj["type"]; !ok {
+			t.Errorf("%s: got %s, want the native shape with a type", resp.name, resp.body)
+		}
+	}
+}
+
+func TestCustomEnvelopeWrapsEveryResponse(t *testing.T) {
+	kinds := map[string]ResponseKind{}
+	envelope := EnvelopeFunc(func(apiOp *types.APIRequest, kind ResponseKind, code int, body json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"data":     body,
+			"metadata": map[string]interface{}{"kind": kind, "code": code},
+		}, nil
+	})
+
+	native := envelopeSuite(t)
+	wrapped := envelopeSuite(t, WithResponseEnvelope(envelope))
+	for i, resp := range wrapped {
+		want := native[i]
+		if resp.code != want.code {
+			t.Errorf("%s: got status %d, want %d", resp.name, resp.code, want.code)
+		}
+		if len(want.body) == 0 {
+			if len(resp.body) != 0 {
+				t.Errorf("%s: got %s, want an empty body", resp.name, resp.body)
+			}
+			continue
+		}
+
+		obj, _ := decodeBody(t, resp.name, resp.body).(map[string]interface{})
+		if len(obj) != 2 || obj["data"] == nil || obj["metadata"] == nil {
+			t.Errorf("%s: got %s, want the body in the envelope", resp.name, resp.body)
+			continue
+		}
+		if !reflect.DeepEqual(obj["data"], decodeBody(t, want.name, want.body)) {
+			t.Errorf("%s: got data %v, want the native body %s", resp.name, obj["data"], want.body)
+		}
+		kinds[resp.name] = ResponseKind(obj["metadata"].(map[string]interface{})["kind"].(string))
+	}
+
+	for name, want := range map[string]ResponseKind{
+		"get":       ObjectResponse,
+		"list":      CollectionResponse,
+		"not found": ErrorResponse,
+		"conflict":  ErrorResponse,
+	} {
+		if kinds[name] != want {
+			t.Errorf("%s: got kind %q, want %q", name, kinds[name], want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	steveschema "github.com/rancher/steve/pkg/schema"
+	"github.com/sirupsen/logrus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// fallbackHandler forwards requests for schemas steve doesn't know about to the Kubernetes apiserver. The
+// transport must be authorized to impersonate users, the request is sent as the user that made it.
+type fallbackHandler struct {
+	proxy *httputil.ReverseProxy
+}
+
+func newFallbackHandler(target *url.URL, transport http.RoundTripper) *fallbackHandler {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = transport
+	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
+		logrus.Errorf("fallback proxy request to %s failed: %v", req.URL.Path, err)
+		http.Error(rw, err.Error(), http.StatusBadGateway)
+	}
+	return &fallbackHandler{
+		proxy: proxy,
+	}
+}
+
+// versionPattern matches Kubernetes API versions, such as v1 and v1beta2.
+var versionPattern = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// typeToGVR parses the type of a request in the group.version.resource form used by versioned schema IDs, with
+// core as the group of the core API, or in the version.resource form of the core API.
+func typeToGVR(typ string) (schema.GroupVersionResource, bool) {
+	parts := strings.Split(typ, ".")
+	switch {
+	case len(parts) == 2 && versionPattern.MatchString(parts[0]):
+		return schema.GroupVersionResource{Version: parts[0], Resource: parts[1]}, true
+	case len(parts) >= 3 && versionPattern.MatchString(parts[len(parts)-2]):
+		group := strings.Join(parts[:len(parts)-2], ".")
+		if group == "core" {
+			group = ""
+		}
+		return schema.GroupVersionResource{Group: group, Version: parts[len(parts)-2], Resource: parts[len(parts)-1]}, true
+	}
+	return schema.GroupVersionResource{}, false
+}
+
+// kubernetesPath returns the path of the Kubernetes API of gvr, in the namespace and with the name of apiOp.
+func kubernetesPath(apiOp *types.APIRequest, gvr schema.GroupVersionResource) string {
+	buf := &strings.Builder{}
+	if gvr.Group == "" {
+		buf.WriteString("/api/")
+	} else {
+		buf.WriteString("/apis/")
+		buf.WriteString(gvr.Group)
+		buf.WriteString("/")
+	}
+	buf.WriteString(gvr.Version)
+	if apiOp.Namespace != "" {
+		buf.WriteString("/namespaces/")
+		buf.WriteString(apiOp.Namespace)
+	}
+	buf.WriteString("/")
+	buf.WriteString(gvr.Resource)
+	if apiOp.Name != "" {
+		buf.WriteString("/")
+		buf.WriteString(apiOp.Name)
+	}
+	return buf.String()
+}
+
+// serve proxies the request and returns false if its type is not the ID of a Kubernetes resource or the resource
+// has a schema in sf. A schema that isn't in the schemas of the user is hidden from them, it must not be reached
+// through the apiserver instead.
+func (f *fallbackHandler) serve(apiOp *types.APIRequest, sf steveschema.Factory) bool {
+	if apiOp.Type == "" || apiOp.Schemas.LookupSchema(apiOp.Type) != nil {
+		return false
+	}
+	gvr, ok := typeToGVR(apiOp.Type)
+	if !ok || (sf != nil && sf.ByGVR(gvr) != "") {
+		return false
+	}
+
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return false
+	}
+
+	req := apiOp.Request.Clone(apiOp.Context())
+	req.URL.Path = kubernetesPath(apiOp, gvr)
+	req.URL.RawPath = ""
+	req.Header.Del("Authorization")
+	for k := range req.Header {
+		if strings.HasPrefix(k, "Impersonate-") {
+			delete(req.Header, k)
+		}
+	}
+	req.Header.Set(authenticationv1.ImpersonateUserHeader, user.GetName())
+	for _, group := range user.GetGroups() {
+		req.Header.Add(authenticationv1.ImpersonateGroupHeader, group)
+	}
+	for k, values := range user.GetExtra() {
+		for _, v := range values {
+			req.Header.Add(authenticationv1.ImpersonateUserExtraHeaderPrefix+k, v)
+		}
+	}
+
+	f.proxy.ServeHTTP(apiOp.Response, req)
+	return true
+}
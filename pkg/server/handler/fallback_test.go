@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/builtin"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	steveschema "github.com/rancher/steve/pkg/schema"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// registeredFactory is a schema factory with a schema for every resource of registered, whatever the user.
+type registeredFactory struct {
+	steveschema.Factory
+	registered map[schema.GroupVersionResource]string
+}
+
+func (r *registeredFactory) ByGVR(gvr schema.GroupVersionResource) string {
+	return r.registered[gvr]
+}
+
+type upstreamRequest struct {
+	path        string
+	impersonate string
+}
+
+// fallbackRequest sends a get of typ, in namespace and of name when they are set, as the user alice to an API server
+// falling back to a fake apiserver, and returns the response and the requests the fake apiserver got.
+func fallbackRequest(t *testing.T, sf steveschema.Factory, typ, namespace, name string) (*httptest.ResponseRecorder, []upstreamRequest) {
+	t.Helper()
+	var upstream []upstreamRequest
+	kube := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		upstream = append(upstream, upstreamRequest{path: req.URL.Path, impersonate: req.Header.Get("Impersonate-User")})
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{"kind":"GadgetList","items":[]}`))
+	}))
+	defer kube.Close()
+
+	apiSchemas := types.EmptyAPISchemas()
+	if err := apiSchemas.AddSchemas(builtin.Schemas); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v1/"+typ, nil)
+	req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "alice"}))
+	req = mux.SetURLVars(req, map[string]string{"type": typ, "namespace": namespace, "name": name})
+	urlBuilder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := httptest.NewRecorder()
+	apiOp := &types.APIRequest{
+		Type:       typ,
+		Namespace:  namespace,
+		Name:       name,
+		Schemas:    apiSchemas,
+		Request:    req,
+		Response:   rw,
+		URLBuilder: urlBuilder,
+	}
+
+	a := newAPIServer(sf, WithFallbackProxy(kube.URL, http.DefaultTransport))
+	if a.fallback == nil {
+		t.Fatal("the fallback proxy is not set")
+	}
+	if !a.fallback.serve(apiOp, a.sf) {
+		a.server.Handle(apiOp)
+	}
+	return rw, upstream
+}
+
+func TestUnregisteredResourceIsForwarded(t *testing.T) {
+	for _, test := range []struct {
+		typ       string
+		namespace string
+		name      string
+		path      string
+	}{
+		{typ: "example.com.v1.gadgets", path: "/apis/example.com/v1/gadgets"},
+		{typ: "example.com.v1beta1.gadgets", namespace: "default", name: "a", path: "/apis/example.com/v1beta1/namespaces/default/gadgets/a"},
+		{typ: "core.v1.podtemplates", namespace: "default", path: "/api/v1/namespaces/default/podtemplates"},
+		{typ: "v1.podtemplates", namespace: "default", name: "a", path: "/api/v1/namespaces/default/podtemplates/a"},
+	} {
+		rw, upstream := fallbackRequest(t, &registeredFactory{}, test.typ, test.namespace, test.name)
+		if len(upstream) != 1 || upstream[0].path != test.path {
+			t.Errorf("%s: got upstream requests %v, want one to %s", test.typ, upstream, test.path)
+			continue
+		}
+		if upstream[0].impersonate != "alice" {
+			t.Errorf("%s: got the request impersonating %q, want alice", test.typ, upstream[0].impersonate)
+		}
+		if body, _ := ioutil.ReadAll(rw.Body); rw.Code != http.StatusOK || string(body) != `{"kind":"GadgetList","items":[]}` {
+			t.Errorf("%s: got %d %s, want the response of the apiserver", test.typ, rw.Code, body)
+		}
+	}
+}
+
+func TestRegisteredResourceIsNotForwarded(t *testing.T) {
+	// alice can't see the gadgets or the pods, they are still not reached through the apiserver
+	sf := &registeredFactory{registered: map[schema.GroupVersionResource]string{
+		{Group: "example.com", Version: "v1", Resource: "gadgets"}: "example.com.gadget",
+		{Version: "v1", Resource: "pods"}:                          "pod",
+	}}
+	for _, typ := range []string{"example.com.v1.gadgets", "core.v1.pods", "v1.pods"} {
+		rw, upstream := fallbackRequest(t, sf, typ, "", "")
+		if len(upstream) != 0 {
+			t.Errorf("%s: got upstream requests %v, want none for a registered resource", typ, upstream)
+		}
+		if rw.Code != http.StatusNotFound {
+			t.Errorf("%s: got status %d, want 404", typ, rw.Code)
+		}
+	}
+}
+
+func TestTypeToGVR(t *testing.T) {
+	for typ, want := range map[string]schema.GroupVersionResource{
+		"apps.v1.deployments":          {Group: "apps", Version: "v1", Resource: "deployments"},
+		"example.com.v2.gadgets":       {Group: "example.com", Version: "v2", Resource: "gadgets"},
+		"core.v1.pods":                 {Version: "v1", Resource: "pods"},
+		"v1.pods":                      {Version: "v1", Resource: "pods"},
+		"v2alpha1.things":              {Version: "v2alpha1", Resource: "things"},
+		"batch.v1beta1.cronjobs":       {Group: "batch", Version: "v1beta1", Resource: "cronjobs"},
+		"metrics.k8s.io.v1beta1.nodes": {Group: "metrics.k8s.io", Version: "v1beta1", Resource: "nodes"},
+	} {
+		if gvr, ok := typeToGVR(typ); !ok || gvr != want {
+			t.Errorf("%s: got %v %v, want %v", typ, gvr, ok, want)
+		}
+	}
+	// schema IDs without a version are not Kubernetes resources
+	for _, typ := range []string{"pod", "apps.deployment", "management.cattle.io.setting", "v1"} {
+		if gvr, ok := typeToGVR(typ); ok {
+			t.Errorf("%s: got %v, want no resource", typ, gvr)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+)
+
+// fieldErrorWriter adds the field errors of a create or update rejected by the apiserver, see
+// proxy.ValidationError, to the error response as "fields" so a UI can show them next to the inputs.
+type fieldErrorWriter struct {
+	types.ResponseWriter
+}
+
+func (f *fieldErrorWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	if obj.Type == "error" {
+		if fields := proxy.FieldErrors(apiOp.Context()); len(fields) > 0 {
+			data := map[string]interface{}{}
+			for k, v := range obj.Data() {
+				data[k] = v
+			}
+			data["fields"] = fields
+			obj.Object = data
+		}
+	}
+	f.ResponseWriter.Write(apiOp, code, obj)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rancher/steve/pkg/stores/proxy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// invalidWidgetServer rejects every widget like the apiserver rejects an object that fails validation.
+func invalidWidgetServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(rw).Encode(metav1.Status{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+			Status:   metav1.StatusFailure,
+			Message:  `Widget.example.com "a" is invalid`,
+			Reason:   metav1.StatusReasonInvalid,
+			Code:     http.StatusUnprocessableEntity,
+			Details: &metav1.StatusDetails{
+				Name:  "a",
+				Group: "example.com",
+				Kind:  "Widget",
+				Causes: []metav1.StatusCause{
+					{Type: metav1.CauseTypeFieldValueInvalid, Field: "spec.size", Message: "Invalid value: -1: must be positive"},
+					{Type: metav1.CauseTypeFieldValueRequired, Field: "spec.color", Message: "Required value"},
+				},
+			},
+		})
+	}))
+}
+
+func TestRejectedCreateRespondsWithFieldErrors(t *testing.T) {
+	srv := invalidWidgetServer()
+	defer srv.Close()
+
+	rw := writeWidget(t, widgetSchemas(t, srv.URL), http.MethodPost, "", "application/json",
+		`{"metadata":{"name":"a","namespace":"default"},"spec":{"size":-1}}`)
+	if rw.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d: %s", rw.Code, rw.Body)
+	}
+
+	body := struct {
+		Code      string             `json:"code"`
+		FieldName string             `json:"fieldName"`
+		Fields    []proxy.FieldError `json:"fields"`
+	}{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != string(metav1.StatusReasonInvalid) || body.FieldName != "spec.size" {
+		t.Errorf("got code %q and field %q, want Invalid for spec.size", body.Code, body.FieldName)
+	}
+	want := []proxy.FieldError{
+		{Field: "spec.size", Message: "Invalid value: -1: must be positive", Reason: string(metav1.CauseTypeFieldValueInvalid)},
+		{Field: "spec.color", Message: "Required value", Reason: string(metav1.CauseTypeFieldValueRequired)},
+	}
+	if !reflect.DeepEqual(body.Fields, want) {
+		t.Errorf("got fields %+v, want %+v", body.Fields, want)
+	}
+}
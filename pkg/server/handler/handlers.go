@@ -17,6 +17,13 @@ func k8sAPI(sf schema.Factory, apiOp *types.APIRequest) {
 	apiOp.Name = vars["name"]
 	apiOp.Type = vars["type"]
 
+	// /v1/{type}/{name}/status of a cluster scoped schema is matched as /v1/{type}/{namespace}/{name}
+	if s := apiOp.Schemas.LookupSchema(apiOp.Type); s != nil && !attributes.Namespaced(s) && vars["name"] == "status" && vars["link"] == "" {
+		vars["name"], vars["link"] = vars["namespace"], "status"
+		delete(vars, "namespace")
+		apiOp.Name = vars["name"]
+	}
+
 	nOrN := vars["nameorns"]
 	if nOrN != "" {
 		schema := apiOp.Schemas.LookupSchema(apiOp.Type)
@@ -29,6 +36,9 @@ func k8sAPI(sf schema.Factory, apiOp *types.APIRequest) {
 
 	if namespace := vars["namespace"]; namespace != "" {
 		apiOp.Namespace = namespace
+	} else if namespaces := apiOp.Request.URL.Query()["namespace"]; len(namespaces) == 1 {
+		// several namespaces are a namespace constraint, see normalizeNamespaces
+		apiOp.Namespace = namespaces[0]
 	}
 }
 
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/jsonapi"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// decodeJSONAPI replaces a JSON:API request body with the object it holds so the rest of the request is handled
+// like any other JSON body.
+func decodeJSONAPI(apiOp *types.APIRequest) error {
+	req := apiOp.Request
+	if req.Body == nil || !jsonapi.IsDocument(req) {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	obj, err := jsonapi.DecodeDocument(body)
+	if err != nil {
+		return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	converted, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(converted))
+	req.ContentLength = int64(len(converted))
+	req.Header.Set("Content-Type", "application/json")
+	return nil
+}
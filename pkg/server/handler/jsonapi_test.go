@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/builtin"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/jsonapi"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// createRecorder keeps the object it is asked to create and returns it.
+type createRecorder struct {
+	types.Store
+	created data.Object
+}
+
+func (c *createRecorder) Create(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject) (types.APIObject, error) {
+	c.created = obj.Data()
+	return types.APIObject{
+		Type:   schema.ID,
+		ID:     c.created.String("metadata", "namespace") + "/" + c.created.String("metadata", "name"),
+		Object: &unstructured.Unstructured{Object: c.created},
+	}, nil
+}
+
+func TestJSONAPICreateIsConverted(t *testing.T) {
+	store := &createRecorder{}
+	gadget := types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "example.com.gadget",
+			CollectionMethods: []string{http.MethodGet, http.MethodPost},
+		},
+		Store: store,
+	}
+	attributes.SetGVK(&gadget, schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Gadget"})
+	attributes.SetResource(&gadget, "gadgets")
+	attributes.SetNamespaced(&gadget, true)
+	attributes.SetAccess(&gadget, accesscontrol.AccessListByVerb{
+		"create": accesscontrol.AccessList{{Namespace: accesscontrol.All, ResourceName: accesscontrol.All}},
+	})
+	apiSchemas := types.EmptyAPISchemas()
+	if err := apiSchemas.AddSchemas(builtin.Schemas); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiSchemas.AddSchema(gadget); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"data": {
+		"type": "example.com.gadget",
+		"attributes": {"size": 9007199254740993},
+		"relationships": {"owners": {"data": [{"type": "App", "id": "shop", "meta": {"apiVersion": "example.com/v1", "uid": "1234"}}]}},
+		"meta": {"apiVersion": "example.com/v1", "kind": "Gadget", "metadata": {"name": "a", "namespace": "default"}}
+	}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/example.com.gadget", strings.NewReader(body))
+	req.Header.Set("Content-Type", jsonapi.MediaType)
+	req.Header.Set("Accept", jsonapi.MediaType)
+	req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{
+		Name:   "admin",
+		Groups: []string{user.SystemPrivilegedGroup, user.AllAuthenticated},
+	}))
+	req = mux.SetURLVars(req, map[string]string{"type": "example.com.gadget"})
+	urlBuilder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := httptest.NewRecorder()
+	apiOp := &types.APIRequest{
+		Schemas:    apiSchemas,
+		Request:    req,
+		Response:   rw,
+		URLBuilder: urlBuilder,
+	}
+	if err := checkBody(apiOp); err != nil {
+		t.Fatal(err)
+	}
+	if err := decodeJSONAPI(apiOp); err != nil {
+		t.Fatal(err)
+	}
+	k8sAPI(nil, apiOp)
+	newAPIServer(nil).server.Handle(apiOp)
+
+	if rw.Code != http.StatusCreated {
+		t.Fatalf("got status %d: %s", rw.Code, rw.Body)
+	}
+	if store.created.String("metadata", "name") != "a" || store.created.String("kind") != "Gadget" {
+		t.Errorf("got %v created, want the object of the document", store.created)
+	}
+	if size := data.GetValueN(store.created, "spec", "size"); size != json.Number("9007199254740993") {
+		t.Errorf("got size %#v, want the attributes as the spec", size)
+	}
+	owners, _ := data.GetValueN(store.created, "metadata", "ownerReferences").([]interface{})
+	if len(owners) != 1 || data.Object(owners[0].(map[string]interface{})).String("name") != "shop" {
+		t.Errorf("got owners %v, want the owners relationship", owners)
+	}
+
+	if contentType := rw.Header().Get("Content-Type"); contentType != jsonapi.MediaType {
+		t.Errorf("got the response as %q, want %s", contentType, jsonapi.MediaType)
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+)
+
+// The namespace of a request for a resource can be given in the path, /v1/{type}/{namespace} for a collection and
+// /v1/{type}/{namespace}/{name} for an object, or with ?namespace= on paths without one. checkNamespace rejects a
+// namespace for a cluster scoped schema and a query namespace that contradicts the path. Requests that pass have
+// the namespace in apiOp.Namespace, and collection links of namespaced schemas point to the same namespace.
+func checkNamespace(apiOp *types.APIRequest) error {
+	if apiOp.Type == "" || apiOp.Namespace == "" {
+		return nil
+	}
+
+	schema := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if schema == nil || attributes.Resource(schema) == "" {
+		return nil
+	}
+	if !attributes.Namespaced(schema) {
+		return apierror.NewAPIError(validation.InvalidOption, schema.ID+" is cluster scoped and can not be requested in namespace "+apiOp.Namespace)
+	}
+	for _, query := range apiOp.Request.URL.Query()["namespace"] {
+		if query != apiOp.Namespace {
+			return apierror.NewAPIError(validation.InvalidOption, "namespace "+query+" does not match namespace "+apiOp.Namespace+" of the path")
+		}
+	}
+
+	if apiOp.URLBuilder != nil {
+		apiOp.URLBuilder = &namespacedURLBuilder{
+			URLBuilder: apiOp.URLBuilder,
+			namespace:  apiOp.Namespace,
+		}
+	}
+	return nil
+}
+
+// normalizeNamespaces cleans up the ?namespace= values of a request before anything reads them: empty values and
+// repeats are dropped and names that are not valid namespaces are dropped with a warning. The query is rewritten
+// to the remaining names, so a single one scopes the request as before. Several scope lists and watches to those
+// namespaces with a namespace constraint, which the fan-out over namespaces partitions by, so no namespace is
+// listed or watched twice. A request whose names were all invalid fails instead of listing every namespace.
+func normalizeNamespaces(apiOp *types.APIRequest) error {
+	query := apiOp.Request.URL.Query()
+	values, ok := query["namespace"]
+	if !ok {
+		return nil
+	}
+
+	var (
+		namespaces []string
+		invalid    []string
+		seen       = map[string]bool{}
+	)
+	for _, namespace := range values {
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		if errs := k8svalidation.IsDNS1123Label(namespace); len(errs) > 0 {
+			invalid = append(invalid, namespace)
+			continue
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	if len(invalid) > 0 {
+		if len(namespaces) == 0 {
+			return apierror.NewAPIError(validation.InvalidOption, "no valid namespace in namespace "+strings.Join(invalid, ", "))
+		}
+		apiOp.Response.Header().Add("Warning", fmt.Sprintf("299 - %q", "ignoring invalid namespace "+strings.Join(invalid, ", ")))
+	}
+	if len(namespaces) != len(values) {
+		if dropped := len(values) - len(namespaces) - len(invalid); dropped > 0 {
+			logrus.Debugf("dropped %d empty or repeated namespace query values from %s", dropped, apiOp.Request.URL.Path)
+		}
+		if len(namespaces) == 0 {
+			query.Del("namespace")
+		} else {
+			query["namespace"] = namespaces
+		}
+		u := *apiOp.Request.URL
+		u.RawQuery = query.Encode()
+		apiOp.Request.URL = &u
+	}
+	if len(namespaces) > 1 {
+		apiOp.Request = proxy.IntersectNamespaceConstraint(apiOp.Request, namespaces...)
+	}
+	return nil
+}
+
+const (
+	// NamespaceHeader sets the namespace of a request to a namespaced schema that names none in its path or query.
+	NamespaceHeader = "X-API-Namespace"
+	// NamespaceAppliedHeader is set on the response, to the namespace, when the request was scoped by the
+	// NamespaceHeader instead of the path or query.
+	NamespaceAppliedHeader = "X-API-Namespace-Applied"
+)
+
+// defaultNamespace scopes requests to namespaced schemas that don't name a namespace to the one in the
+// NamespaceHeader. An explicit namespace always wins and cluster scoped schemas ignore the header, while a header
+// that is not a valid namespace name fails the request instead of quietly querying all namespaces.
+func defaultNamespace(apiOp *types.APIRequest) error {
+	namespace := apiOp.Request.Header.Get(NamespaceHeader)
+	if namespace == "" || apiOp.Type == "" || apiOp.Namespace != "" {
+		return nil
+	}
+
+	schema := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if schema == nil || attributes.Resource(schema) == "" || !attributes.Namespaced(schema) {
+		return nil
+	}
+	if errs := k8svalidation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return apierror.NewAPIError(validation.InvalidOption, NamespaceHeader+" "+namespace+" is not a valid namespace: "+strings.Join(errs, ", "))
+	}
+
+	apiOp.Namespace = namespace
+	apiOp.Response.Header().Set(NamespaceAppliedHeader, namespace)
+	return nil
+}
+
+// namespacedURLBuilder builds the collection links of namespaced schemas in namespace.
+type namespacedURLBuilder struct {
+	types.URLBuilder
+	namespace string
+}
+
+func (n *namespacedURLBuilder) Collection(schema *types.APISchema) string {
+	if attributes.Namespaced(schema) {
+		return n.URLBuilder.Collection(schema) + "/" + n.namespace
+	}
+	return n.URLBuilder.Collection(schema)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/builtin"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/fake"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// namespaceStore records the namespace and the ?namespace= values of every list.
+type namespaceStore struct {
+	*fake.FakeStore
+	namespaces []string
+	queries    [][]string
+}
+
+func (n *namespaceStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	n.namespaces = append(n.namespaces, apiOp.Namespace)
+	n.queries = append(n.queries, apiOp.Request.URL.Query()["namespace"])
+	return n.FakeStore.List(apiOp, schema)
+}
+
+// listWidgets sends a list of widgets with rawQuery through the namespace handling of apiHandler and returns the
+// store the list reached and the response.
+func listWidgets(t *testing.T, rawQuery string) (*namespaceStore, *httptest.ResponseRecorder) {
+	t.Helper()
+	store := &namespaceStore{FakeStore: fake.NewFakeStore()}
+	apiSchemas := types.EmptyAPISchemas()
+	if err := apiSchemas.AddSchemas(builtin.Schemas); err != nil {
+		t.Fatal(err)
+	}
+	widget := types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "widget",
+			CollectionMethods: []string{http.MethodGet},
+		},
+		Store: store,
+	}
+	attributes.SetNamespaced(&widget, true)
+	if err := apiSchemas.AddSchema(widget); err != nil {
+		t.Fatal(err)
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v1/widget?"+rawQuery, nil), map[string]string{"type": "widget"})
+	urlBuilder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := httptest.NewRecorder()
+	apiOp := &types.APIRequest{
+		Schemas:    apiSchemas,
+		Request:    req,
+		Response:   rw,
+		URLBuilder: urlBuilder,
+	}
+	if err := normalizeNamespaces(apiOp); err != nil {
+		apiOp.WriteError(err)
+		return store, rw
+	}
+	k8sAPI(nil, apiOp)
+	if err := checkNamespace(apiOp); err != nil {
+		apiOp.WriteError(err)
+		return store, rw
+	}
+	newAPIServer(nil).server.Handle(apiOp)
+	return store, rw
+}
+
+func listCalls(store *namespaceStore) int {
+	count := 0
+	for _, call := range store.Calls() {
+		if call.Method == fake.List {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRepeatedNamespacesAreListedOnce(t *testing.T) {
+	store, rw := listWidgets(t, "namespace=a&namespace=a&namespace=")
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rw.Code, rw.Body)
+	}
+	if calls := listCalls(store); calls != 1 {
+		t.Fatalf("got %d list calls, want 1", calls)
+	}
+	if store.namespaces[0] != "a" || !reflect.DeepEqual(store.queries[0], []string{"a"}) {
+		t.Errorf("got namespace %q with query %v, want the list scoped to a", store.namespaces[0], store.queries[0])
+	}
+	if warning := rw.Header().Get("Warning"); warning != "" {
+		t.Errorf("got warning %q, want none for empty and repeated values", warning)
+	}
+}
+
+func TestSeveralNamespacesAreAConstraint(t *testing.T) {
+	store, rw := listWidgets(t, "namespace=b&namespace=a&namespace=b&namespace=Not_Valid")
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rw.Code, rw.Body)
+	}
+	if calls := listCalls(store); calls != 1 {
+		t.Fatalf("got %d list calls, want 1", calls)
+	}
+	// the namespaces are a namespace constraint instead of a single namespace
+	if store.namespaces[0] != "" || !reflect.DeepEqual(store.queries[0], []string{"b", "a"}) {
+		t.Errorf("got namespace %q with query %v, want no namespace and the query b, a", store.namespaces[0], store.queries[0])
+	}
+	if warning := rw.Header().Get("Warning"); warning == "" {
+		t.Error("got no warning for the invalid namespace")
+	}
+}
+
+func TestOnlyInvalidNamespacesFail(t *testing.T) {
+	apiOp := &types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodGet, "/v1/widget?namespace=Not_Valid", nil),
+		Response: httptest.NewRecorder(),
+	}
+	err := normalizeNamespaces(apiOp)
+	if apiErr, ok := err.(*apierror.APIError); !ok || apiErr.Code != validation.InvalidOption {
+		t.Errorf("got %v, want an invalid option error instead of a list of every namespace", err)
+	}
+}
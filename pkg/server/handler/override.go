@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+type storeOverridesKey struct{}
+
+// WithStoreOverride returns a context that makes requests carrying it use store for the schema with the given ID
+// instead of the store the schema was built with, for example to send the requests of an integration test or
+// of shadow traffic to a memory.Store. Overrides can only be set by middleware wrapping the handler, never by
+// the client, since they are read from the request context.
+func WithStoreOverride(ctx context.Context, schemaID string, store types.Store) context.Context {
+	overrides := map[string]types.Store{}
+	for id, store := range storeOverrides(ctx) {
+		overrides[id] = store
+	}
+	overrides[schemaID] = store
+	return context.WithValue(ctx, storeOverridesKey{}, overrides)
+}
+
+func storeOverrides(ctx context.Context) map[string]types.Store {
+	overrides, _ := ctx.Value(storeOverridesKey{}).(map[string]types.Store)
+	return overrides
+}
+
+// overrideStores replaces the schemas of apiOp with copies using the overridden stores. The schemas are shared by
+// every request of the user so they are never modified in place.
+func overrideStores(apiOp *types.APIRequest) error {
+	overrides := storeOverrides(apiOp.Context())
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	schemas := types.EmptyAPISchemas()
+	if err := schemas.AddSchemas(apiOp.Schemas); err != nil {
+		return err
+	}
+	schemas.Attributes = apiOp.Schemas.Attributes
+
+	for id, store := range overrides {
+		if schema := schemas.LookupSchema(id); schema != nil {
+			schema.Store = store
+		}
+	}
+	apiOp.Schemas = schemas
+	return nil
+}
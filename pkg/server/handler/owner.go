@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The owner headers make a create set an owner reference to the object they name, so a UI can link the children it
+// creates without building the reference itself. All four must be set.
+const (
+	OwnerAPIVersionHeader = "X-Owner-APIVersion"
+	OwnerKindHeader       = "X-Owner-Kind"
+	OwnerNameHeader       = "X-Owner-Name"
+	OwnerUIDHeader        = "X-Owner-UID"
+)
+
+// injectOwner adds the owner named by the owner headers to the ownerReferences in the body of a create, unless it
+// is already referenced. The owner must be readable by the user and have the given UID, a namespaced owner is
+// looked up in the namespace of the created object.
+func injectOwner(apiOp *types.APIRequest) error {
+	req := apiOp.Request
+	if req.Method != http.MethodPost || apiOp.Type == "" || apiOp.Name != "" || req.Body == nil {
+		return nil
+	}
+
+	apiVersion := req.Header.Get(OwnerAPIVersionHeader)
+	kind := req.Header.Get(OwnerKindHeader)
+	name := req.Header.Get(OwnerNameHeader)
+	uid := req.Header.Get(OwnerUIDHeader)
+	if apiVersion == "" && kind == "" && name == "" && uid == "" {
+		return nil
+	}
+	if apiVersion == "" || kind == "" || name == "" || uid == "" {
+		return apierror.NewAPIError(validation.MissingRequired, "the "+OwnerAPIVersionHeader+", "+OwnerKindHeader+", "+
+			OwnerNameHeader+" and "+OwnerUIDHeader+" headers must be set together")
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	input := data.Object{}
+	if err := jsonnumber.Unmarshal(body, &input); err != nil {
+		return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return apierror.NewAPIError(validation.InvalidFormat, "invalid "+OwnerAPIVersionHeader+": "+err.Error())
+	}
+	ownerSchema := schemaForGVK(apiOp.Schemas, gv.WithKind(kind))
+	if ownerSchema == nil || ownerSchema.Store == nil {
+		return apierror.NewAPIError(validation.InvalidReference, "owner kind "+kind+" of "+apiVersion+" not found")
+	}
+
+	ownerOp := apiOp.Clone()
+	ownerOp.Method = http.MethodGet
+	ownerOp.Type = ownerSchema.ID
+	ownerOp.Schema = ownerSchema
+	ownerOp.Name = name
+	ownerOp.Namespace = ""
+	id := name
+	if attributes.Namespaced(ownerSchema) {
+		ownerOp.Namespace = apiOp.Namespace
+		if ownerOp.Namespace == "" {
+			ownerOp.Namespace = input.String("metadata", "namespace")
+		}
+		id = ownerOp.Namespace + "/" + name
+	}
+
+	owner, err := ownerSchema.Store.ByID(ownerOp, ownerSchema, name)
+	if err != nil {
+		if apiErr, ok := err.(*apierror.APIError); ok && apiErr.Code.Status == http.StatusNotFound {
+			return apierror.NewAPIError(validation.InvalidReference, "owner "+kind+" "+id+" not found")
+		}
+		return err
+	}
+	if owner.Data().String("metadata", "uid") != uid {
+		return apierror.NewAPIError(validation.InvalidReference, "owner "+kind+" "+id+" does not have uid "+uid)
+	}
+
+	metadata := input.Map("metadata")
+	if metadata == nil {
+		metadata = data.Object{}
+		input["metadata"] = metadata
+	}
+	refs, _ := metadata["ownerReferences"].([]interface{})
+	for _, ref := range refs {
+		if m, ok := ref.(map[string]interface{}); ok && m["uid"] == uid {
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			return nil
+		}
+	}
+	metadata["ownerReferences"] = append(refs, map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"name":       name,
+		"uid":        uid,
+	})
+
+	body, err = json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return nil
+}
+
+func schemaForGVK(schemas *types.APISchemas, gvk schema.GroupVersionKind) *types.APISchema {
+	for _, s := range schemas.Schemas {
+		if attributes.GVK(s) == gvk {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ownerStore holds the deployments that can be owners, by namespace/name. Like the proxy store, ByID gets the
+// name and the namespace of the request.
+type ownerStore struct {
+	types.Store
+	objects map[string]map[string]interface{}
+}
+
+func (o *ownerStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	if apiOp.Method != http.MethodGet || apiOp.Schema != schema {
+		return types.APIObject{}, apierror.NewAPIError(validation.ServerError, "the owner is not read with a get of its schema")
+	}
+	key := apiOp.Namespace + "/" + id
+	obj, ok := o.objects[key]
+	if !ok {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, key+" not found")
+	}
+	return types.APIObject{Type: schema.ID, ID: key, Object: obj}, nil
+}
+
+const ownerUID = "0d5f7e3a-uid"
+
+func ownerSchemas(t *testing.T) *types.APISchemas {
+	t.Helper()
+	deployment := types.APISchema{
+		Schema: &schemas.Schema{ID: "apps.deployment"},
+		Store: &ownerStore{objects: map[string]map[string]interface{}{
+			"default/web": {"metadata": map[string]interface{}{"name": "web", "namespace": "default", "uid": ownerUID}},
+		}},
+	}
+	attributes.SetGVK(&deployment, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	attributes.SetNamespaced(&deployment, true)
+
+	apiSchemas := types.EmptyAPISchemas()
+	if err := apiSchemas.AddSchema(deployment); err != nil {
+		t.Fatal(err)
+	}
+	return apiSchemas
+}
+
+// createWithOwner runs injectOwner for the create of a config map in default with body and the owner headers, and
+// returns the body the store would get.
+func createWithOwner(t *testing.T, headers map[string]string, body string) (map[string]interface{}, error) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/configmaps/default", strings.NewReader(body))
+	for header, value := range headers {
+		req.Header.Set(header, value)
+	}
+	apiOp := &types.APIRequest{
+		Type:      "configmap",
+		Namespace: "default",
+		Schemas:   ownerSchemas(t),
+		Request:   req,
+	}
+	if err := injectOwner(apiOp); err != nil {
+		return nil, err
+	}
+	sent, err := ioutil.ReadAll(apiOp.Request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := map[string]interface{}{}
+	if err := jsonnumber.Unmarshal(sent, &result); err != nil {
+		t.Fatal(err)
+	}
+	return result, nil
+}
+
+func ownerHeaders(name, uid string) map[string]string {
+	return map[string]string{
+		OwnerAPIVersionHeader: "apps/v1",
+		OwnerKindHeader:       "Deployment",
+		OwnerNameHeader:       name,
+		OwnerUIDHeader:        uid,
+	}
+}
+
+func ownerReferences(obj map[string]interface{}) []interface{} {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	refs, _ := metadata["ownerReferences"].([]interface{})
+	return refs
+}
+
+func TestOwnerIsInjected(t *testing.T) {
+	obj, err := createWithOwner(t, ownerHeaders("web", ownerUID), `{"metadata":{"name":"config"},"data":{"key":"value"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"name":       "web",
+		"uid":        ownerUID,
+	}}
+	if refs := ownerReferences(obj); !reflect.DeepEqual(refs, want) {
+		t.Errorf("got owner references %v, want %v", refs, want)
+	}
+	if data, _ := obj["data"].(map[string]interface{}); data["key"] != "value" {
+		t.Errorf("got data %v, want the body kept", obj["data"])
+	}
+}
+
+func TestOwnerInjectionKeepsLargeIntegers(t *testing.T) {
+	obj, err := createWithOwner(t, ownerHeaders("web", ownerUID), `{"metadata":{"name":"config"},"spec":{"size":`+createdSize+`}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, _ := obj["spec"].(map[string]interface{})["size"].(json.Number); size.String() != createdSize {
+		t.Errorf("got size %v, want %s", size, createdSize)
+	}
+}
+
+func TestExistingOwnerReferenceIsKept(t *testing.T) {
+	body := `{"metadata":{"name":"config","ownerReferences":[{"apiVersion":"apps/v1","kind":"Deployment","name":"web","uid":"` +
+		ownerUID + `","controller":true}]}}`
+	obj, err := createWithOwner(t, ownerHeaders("web", ownerUID), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := ownerReferences(obj)
+	if len(refs) != 1 || refs[0].(map[string]interface{})["controller"] != true {
+		t.Errorf("got owner references %v, want the existing reference only", refs)
+	}
+}
+
+func TestInvalidOwnerIsRejected(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		headers map[string]string
+		code    validation.ErrorCode
+	}{
+		{name: "missing owner", headers: ownerHeaders("api", ownerUID), code: validation.InvalidReference},
+		{name: "other uid", headers: ownerHeaders("web", "other-uid"), code: validation.InvalidReference},
+		{name: "unknown kind", headers: map[string]string{
+			OwnerAPIVersionHeader: "example.com/v1",
+			OwnerKindHeader:       "Gadget",
+			OwnerNameHeader:       "web",
+			OwnerUIDHeader:        ownerUID,
+		}, code: validation.InvalidReference},
+		{name: "invalid api version", headers: map[string]string{
+			OwnerAPIVersionHeader: "a/b/c",
+			OwnerKindHeader:       "Deployment",
+			OwnerNameHeader:       "web",
+			OwnerUIDHeader:        ownerUID,
+		}, code: validation.InvalidFormat},
+		{name: "incomplete headers", headers: map[string]string{OwnerNameHeader: "web"}, code: validation.MissingRequired},
+	} {
+		_, err := createWithOwner(t, test.headers, `{"metadata":{"name":"config"}}`)
+		if apiErr, ok := err.(*apierror.APIError); !ok || apiErr.Code != test.code {
+			t.Errorf("%s: got %v, want %s", test.name, err, test.code.Code)
+		}
+	}
+}
+
+func TestCreateWithoutOwnerHeadersIsUnchanged(t *testing.T) {
+	body := `{"metadata":{"name":"config"}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/configmaps/default", strings.NewReader(body))
+	apiOp := &types.APIRequest{Type: "configmap", Namespace: "default", Schemas: ownerSchemas(t), Request: req}
+	if err := injectOwner(apiOp); err != nil {
+		t.Fatal(err)
+	}
+	if sent, _ := ioutil.ReadAll(apiOp.Request.Body); string(sent) != body {
+		t.Errorf("got body %s, want %s", sent, body)
+	}
+}
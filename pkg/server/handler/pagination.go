@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// paginationWriter adds links to the pages of a list paged with ?limit= and ?continue=: links.next while the store
+// returned a continue token, and links.first once the client is past the first page. The links are built from
+// the URL of the request as seen by the client, so they keep the URL prefix, forwarded host, namespace and every
+// filter and sort of the query. Kubernetes continue tokens only go forward so there is no links.previous.
+type paginationWriter struct {
+	types.ResponseWriter
+}
+
+func (p *paginationWriter) WriteList(apiOp *types.APIRequest, code int, list types.APIObjectList) {
+	if apiOp.URLBuilder == nil || (list.Continue == "" && apiOp.Request.URL.Query().Get("continue") == "") {
+		p.ResponseWriter.WriteList(apiOp, code, list)
+		return
+	}
+
+	rw := apiOp.Response
+	recorder := &responseRecorder{
+		header: http.Header{},
+	}
+	apiOp.Response = recorder
+	p.ResponseWriter.WriteList(apiOp, code, list)
+	apiOp.Response = rw
+
+	body := recorder.body.Bytes()
+	if withLinks, ok := p.addLinks(apiOp, list, body); ok {
+		body = withLinks
+		recorder.header.Del("Content-Length")
+	}
+	for k, v := range recorder.header {
+		rw.Header()[k] = v
+	}
+	if recorder.status != 0 {
+		rw.WriteHeader(recorder.status)
+	}
+	_, _ = rw.Write(body)
+}
+
+func (p *paginationWriter) addLinks(apiOp *types.APIRequest, list types.APIObjectList, body []byte) ([]byte, bool) {
+	var collection map[string]json.RawMessage
+	if err := json.Unmarshal(body, &collection); err != nil {
+		return nil, false
+	}
+	links := map[string]string{}
+	if raw, ok := collection["links"]; ok {
+		if err := json.Unmarshal(raw, &links); err != nil {
+			return nil, false
+		}
+	}
+
+	if list.Continue != "" {
+		links["next"] = pageURL(apiOp, list.Continue)
+	}
+	if apiOp.Request.URL.Query().Get("continue") != "" {
+		links["first"] = pageURL(apiOp, "")
+	}
+
+	raw, err := json.Marshal(links)
+	if err != nil {
+		return nil, false
+	}
+	collection["links"] = raw
+	body, err = json.Marshal(collection)
+	return body, err == nil
+}
+
+// pageURL is the URL of the request with its continue token replaced by cont, or removed when cont is empty.
+func pageURL(apiOp *types.APIRequest, cont string) string {
+	query := apiOp.Request.URL.Query()
+	if cont == "" {
+		query.Del("continue")
+	} else {
+		query.Set("continue", cont)
+	}
+
+	current, err := url.Parse(apiOp.URLBuilder.Current())
+	if err != nil {
+		return ""
+	}
+	current.RawQuery = query.Encode()
+	return current.String()
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// serveQuery dispatches GET /v1/{type}?query={name} to the query handler registered for the schema with
+// attributes.AddQueryHandler. The user must be allowed to list the schema.
+func (a *apiServer) serveQuery(apiOp *types.APIRequest) bool {
+	req := apiOp.Request
+	name := req.URL.Query().Get("query")
+	if name == "" || req.Method != http.MethodGet || apiOp.Type == "" || apiOp.Name != "" {
+		return false
+	}
+
+	schema := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if schema == nil {
+		return false
+	}
+
+	handlers := attributes.QueryHandlers(schema)
+	handler, ok := handlers[name]
+	if !ok {
+		var names []string
+		for name := range handlers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		apiOp.WriteError(apierror.NewAPIError(validation.InvalidOption,
+			"unknown query "+name+" for "+schema.ID+", must be one of: "+strings.Join(names, ", ")))
+		return true
+	}
+
+	if err := a.server.AccessControl.CanList(apiOp, schema); err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+
+	apiOp.Schema = schema
+	result, err := handler(apiOp, schema)
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+	apiOp.Response.Header().Set("Content-Type", "application/json")
+	apiOp.Response.WriteHeader(http.StatusOK)
+	_, _ = apiOp.Response.Write(body)
+	return true
+}
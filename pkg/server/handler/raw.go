@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// RawHeader is set to "true" on responses to ?_raw=true.
+const RawHeader = "X-Steve-Raw"
+
+// rawList is the response to a collection GET with ?_raw=true, shaped like a Kubernetes list.
+type rawList struct {
+	APIVersion string                   `json:"apiVersion"`
+	Kind       string                   `json:"kind"`
+	Metadata   rawListMeta              `json:"metadata"`
+	Items      []map[string]interface{} `json:"items"`
+}
+
+type rawListMeta struct {
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	Continue        string `json:"continue,omitempty"`
+}
+
+// serveRaw answers GETs with ?_raw=true with the objects as the apiserver stores them, for debugging the changes
+// steve makes to objects. The response is plain Kubernetes JSON, not a steve resource or collection: there are no
+// links, the formatters don't run and fields such as metadata.state are missing, so it may not match the schema.
+// Objects still come from the schema store, which applies RBAC and field redaction.
+func (a *apiServer) serveRaw(apiOp *types.APIRequest) bool {
+	req := apiOp.Request
+	if req.Method != http.MethodGet || req.URL.Query().Get("_raw") != "true" || apiOp.Type == "" || apiOp.Link != "" {
+		return false
+	}
+
+	schema := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if schema == nil {
+		return false
+	}
+	if schema.Store == nil {
+		apiOp.WriteError(apierror.NewAPIError(validation.MethodNotAllowed, schema.ID+" can not be read"))
+		return true
+	}
+
+	rawOp := apiOp.Clone()
+	rawOp.Request = proxy.WithRawObjects(req)
+	rawOp.Schema = schema
+
+	var result interface{}
+	if apiOp.Name != "" {
+		if err := a.server.AccessControl.CanGet(apiOp, schema); err != nil {
+			apiOp.WriteError(err)
+			return true
+		}
+		obj, err := schema.Store.ByID(rawOp, schema, apiOp.Name)
+		if err != nil {
+			apiOp.WriteError(err)
+			return true
+		}
+		result = obj.Data()
+	} else {
+		if err := a.server.AccessControl.CanList(apiOp, schema); err != nil {
+			apiOp.WriteError(err)
+			return true
+		}
+		list, err := schema.Store.List(rawOp, schema)
+		if err != nil {
+			apiOp.WriteError(err)
+			return true
+		}
+		items := make([]map[string]interface{}, 0, len(list.Objects))
+		for _, obj := range list.Objects {
+			items = append(items, obj.Data())
+		}
+		result = rawList{
+			APIVersion: "v1",
+			Kind:       "List",
+			Metadata: rawListMeta{
+				ResourceVersion: list.Revision,
+				Continue:        list.Continue,
+			},
+			Items: items,
+		}
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+	apiOp.Response.Header().Set("Content-Type", "application/json")
+	apiOp.Response.Header().Set(RawHeader, "true")
+	apiOp.Response.WriteHeader(http.StatusOK)
+	_, _ = apiOp.Response.Write(body)
+	return true
+}
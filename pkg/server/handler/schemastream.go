@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type schemaChangeEvent struct {
+	Type     string      `json:"type"`
+	SchemaID string      `json:"schemaId,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// schemaStream sends a server-sent event every time a schema is added to or removed from the view of the
+// requesting user.
+type schemaStream struct {
+	sf schema.Factory
+}
+
+func (s *schemaStream) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	user, ok := request.UserFrom(req.Context())
+	if !ok {
+		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	current, err := s.sf.Schemas(user)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	changed := make(chan struct{}, 1)
+	s.sf.OnChange(req.Context(), func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("Connection", "keep-alive")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-changed:
+		}
+
+		next, err := s.sf.Schemas(user)
+		if err != nil {
+			logrus.Errorf("failed to lookup schemas for user %v: %v", user, err)
+			continue
+		}
+
+		for _, event := range diffSchemas(current, next) {
+			if err := writeSSE(rw, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+		current = next
+	}
+}
+
+func diffSchemas(old, new *types.APISchemas) (result []schemaChangeEvent) {
+	for _, id := range sortedIDs(new) {
+		if _, ok := old.Schemas[id]; !ok {
+			// the ID of a schema isn't part of its JSON
+			result = append(result, schemaChangeEvent{
+				Type:     "ADDED",
+				SchemaID: id,
+				Data:     new.Schemas[id].Schema,
+			})
+		}
+	}
+	for _, id := range sortedIDs(old) {
+		if _, ok := new.Schemas[id]; !ok {
+			result = append(result, schemaChangeEvent{
+				Type:     "REMOVED",
+				SchemaID: id,
+			})
+		}
+	}
+	return
+}
+
+func sortedIDs(schemas *types.APISchemas) []string {
+	ids := make([]string, 0, len(schemas.Schemas))
+	for id := range schemas.Schemas {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func writeSSE(rw http.ResponseWriter, event schemaChangeEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(rw, "data: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	steveschema "github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// changingFactory is a schema factory whose schemas are changed by the test, notifying the change callbacks like
+// the collection does when a CRD is installed or removed.
+type changingFactory struct {
+	steveschema.Factory
+
+	lock      sync.Mutex
+	ids       []string
+	callbacks []func()
+}
+
+func (c *changingFactory) Schemas(user user.Info) (*types.APISchemas, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	result := types.EmptyAPISchemas()
+	for _, id := range c.ids {
+		result.MustAddSchema(types.APISchema{Schema: &schemas.Schema{ID: id}})
+	}
+	return result, nil
+}
+
+func (c *changingFactory) OnChange(ctx context.Context, cb func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.callbacks = append(c.callbacks, cb)
+}
+
+func (c *changingFactory) set(ids ...string) {
+	c.lock.Lock()
+	c.ids = ids
+	callbacks := c.callbacks
+	c.lock.Unlock()
+	for _, cb := range callbacks {
+		cb()
+	}
+}
+
+func readSchemaEvent(t *testing.T, events *bufio.Reader) schemaChangeEvent {
+	t.Helper()
+	line, err := events.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := events.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	var event schemaChangeEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+		t.Fatalf("got the event %q: %v", line, err)
+	}
+	return event
+}
+
+func TestSchemaStreamReportsChanges(t *testing.T) {
+	sf := &changingFactory{ids: []string{"pod"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "alice"}))
+		(&schemaStream{sf: sf}).ServeHTTP(rw, req)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/schemas?watch=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("got content type %q, want an event stream", contentType)
+	}
+	events := bufio.NewReader(resp.Body)
+
+	sf.set("pod", "example.com.gadget")
+	event := readSchemaEvent(t, events)
+	added, _ := event.Data.(map[string]interface{})
+	if event.Type != "ADDED" || event.SchemaID != "example.com.gadget" || added["pluralName"] != "example.com.gadgets" {
+		t.Errorf("got %+v, want the gadget schema added", event)
+	}
+
+	sf.set("example.com.gadget")
+	if event := readSchemaEvent(t, events); event.Type != "REMOVED" || event.SchemaID != "pod" {
+		t.Errorf("got %+v, want the pod schema removed", event)
+	}
+}
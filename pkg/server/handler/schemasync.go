@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SchemaSyncSignatureHeader carries the hex encoded HMACSignature of a schema sync body.
+const SchemaSyncSignatureHeader = "X-Steve-Signature"
+
+// schemaSyncer is implemented by schema.Collection.
+type schemaSyncer interface {
+	Export() ([]byte, error)
+	SyncFrom(blob []byte) error
+}
+
+// HMACSignature signs a schema sync body with the shared secret of the steve instances.
+func HMACSignature(secret, body []byte) string {
+	return hex.EncodeToString(hmacSum(secret, body))
+}
+
+func hmacSum(secret, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// schemaSync serves /admin/schema-sync. GET returns the schema registry of this instance, signed in the
+// response header, and POST applies the registry of another instance if the request is correctly signed.
+type schemaSync struct {
+	syncer schemaSyncer
+	secret []byte
+}
+
+func (s *schemaSync) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		blob, err := s.syncer.Export()
+		if err != nil {
+			logrus.Errorf("failed to export schemas: %v", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set(SchemaSyncSignatureHeader, HMACSignature(s.secret, blob))
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(blob)
+	case http.MethodPost:
+		blob, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		signature, err := hex.DecodeString(req.Header.Get(SchemaSyncSignatureHeader))
+		if err != nil || !hmac.Equal(signature, hmacSum(s.secret, blob)) {
+			http.Error(rw, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if err := s.syncer.SyncFrom(blob); err != nil {
+			http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
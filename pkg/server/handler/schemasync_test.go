@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordingSyncer exports blob and records the blobs it is synced from.
+type recordingSyncer struct {
+	blob   []byte
+	synced []string
+}
+
+func (r *recordingSyncer) Export() ([]byte, error) {
+	return r.blob, nil
+}
+
+func (r *recordingSyncer) SyncFrom(blob []byte) error {
+	r.synced = append(r.synced, string(blob))
+	return nil
+}
+
+var syncSecret = []byte("shared")
+
+func TestSchemaSyncExportIsSigned(t *testing.T) {
+	syncer := &recordingSyncer{blob: []byte(`[{"id":"pod"}]`)}
+	rw := httptest.NewRecorder()
+	(&schemaSync{syncer: syncer, secret: syncSecret}).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/admin/schema-sync", nil))
+
+	if rw.Code != http.StatusOK || rw.Body.String() != `[{"id":"pod"}]` {
+		t.Fatalf("got %d %s, want the exported registry", rw.Code, rw.Body)
+	}
+	if got := rw.Header().Get(SchemaSyncSignatureHeader); got != HMACSignature(syncSecret, syncer.blob) {
+		t.Errorf("got signature %q, want the registry signed with the secret", got)
+	}
+}
+
+func TestSchemaSyncVerifiesTheSignature(t *testing.T) {
+	blob := `[{"id":"pod"}]`
+	for _, test := range []struct {
+		name      string
+		signature string
+		status    int
+	}{
+		{name: "signed", signature: HMACSignature(syncSecret, []byte(blob)), status: http.StatusNoContent},
+		{name: "unsigned", status: http.StatusUnauthorized},
+		{name: "other secret", signature: HMACSignature([]byte("other"), []byte(blob)), status: http.StatusUnauthorized},
+		{name: "other body", signature: HMACSignature(syncSecret, []byte(`[]`)), status: http.StatusUnauthorized},
+		{name: "not hex", signature: "signed", status: http.StatusUnauthorized},
+	} {
+		syncer := &recordingSyncer{}
+		req := httptest.NewRequest(http.MethodPost, "/admin/schema-sync", strings.NewReader(blob))
+		req.Header.Set(SchemaSyncSignatureHeader, test.signature)
+		rw := httptest.NewRecorder()
+		(&schemaSync{syncer: syncer, secret: syncSecret}).ServeHTTP(rw, req)
+
+		if rw.Code != test.status {
+			t.Errorf("%s: got status %d, want %d", test.name, rw.Code, test.status)
+		}
+		if applied := len(syncer.synced) == 1 && syncer.synced[0] == blob; applied != (test.status == http.StatusNoContent) {
+			t.Errorf("%s: got the blobs %v synced", test.name, syncer.synced)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// serveStatusPatch answers PATCH /v1/{type}/{namespace}/{name}/status, and /v1/{type}/{name}/status for cluster
+// scoped schemas, by patching the status subresource so controllers can change a few status fields without
+// sending, or racing on, the whole object. A body with Content-Type application/apply-patch+yaml is server-side
+// applied so controllers can own their status fields. The user needs the update verb on the status subresource, for example
+// pods/status, the verbs on the resource itself don't matter.
+func (a *apiServer) serveStatusPatch(apiOp *types.APIRequest) bool {
+	if apiOp.Request.Method != http.MethodPatch || apiOp.Type == "" {
+		return false
+	}
+
+	s := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if s == nil || s.Store == nil || attributes.Resource(s) == "" {
+		return false
+	}
+
+	if mux.Vars(apiOp.Request)["link"] != "status" || apiOp.Name == "" {
+		return false
+	}
+	namespace, name := apiOp.Namespace, apiOp.Name
+
+	accessSet, _ := apiOp.Schemas.Attributes["accessSet"].(*accesscontrol.AccessSet)
+	gr := schema.GroupResource{
+		Group:    attributes.Group(s),
+		Resource: attributes.Resource(s) + "/status",
+	}
+	if accessSet == nil || !accessSet.Grants("update", gr, namespace, name) {
+		apiOp.WriteError(apierror.NewAPIError(validation.PermissionDenied, "can not update the status of "+s.ID+" "+name))
+		return true
+	}
+
+	statusOp := apiOp.Clone()
+	statusOp.Request = proxy.WithStatusSubresource(apiOp.Request)
+	statusOp.Method = http.MethodPatch
+	statusOp.Schema = s
+	statusOp.Namespace = namespace
+	statusOp.Name = name
+
+	input := map[string]interface{}{}
+	if namespace != "" {
+		input["metadata"] = map[string]interface{}{"namespace": namespace}
+	}
+	obj, err := s.Store.Update(statusOp, s, types.APIObject{Type: s.ID, Object: input}, name)
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+	apiOp.WriteResponse(http.StatusOK, obj)
+	return true
+}
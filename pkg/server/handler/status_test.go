@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/handlers"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/wrangler/pkg/data"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// patchWidgetStatus sends PATCH /v1/example.com.widget/default/{name}/status as a user granted access.
+func patchWidgetStatus(t *testing.T, apiSchemas *types.APISchemas, access *accesscontrol.AccessSet, name, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPatch, "/v1/example.com.widget/default/"+name+"/status", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "controller"}))
+	req = mux.SetURLVars(req, map[string]string{"type": "example.com.widget", "namespace": "default", "name": name, "link": "status"})
+	urlBuilder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := httptest.NewRecorder()
+	userSchemas := apiSchemas.ShallowCopy()
+	userSchemas.Attributes = map[string]interface{}{"accessSet": access}
+	apiOp := &types.APIRequest{
+		Schemas:    userSchemas,
+		Request:    req,
+		Response:   rw,
+		URLBuilder: urlBuilder,
+	}
+	k8sAPI(nil, apiOp)
+	a := newAPIServer(nil)
+	apiOp.AccessControl = a.server.AccessControl
+	apiOp.ResponseWriter = a.server.ResponseWriters["json"]
+	apiOp.ErrorHandler = handlers.ErrorHandler
+	if !a.serveStatusPatch(apiOp) {
+		t.Fatal("the status patch was not served")
+	}
+	return rw
+}
+
+func accessTo(verb, resource string) *accesscontrol.AccessSet {
+	set := &accesscontrol.AccessSet{}
+	set.Add(verb, schema.GroupResource{Group: "example.com", Resource: resource}, accesscontrol.Access{
+		Namespace:    accesscontrol.All,
+		ResourceName: accesscontrol.All,
+	})
+	return set
+}
+
+func TestStatusPatchLeavesTheSpec(t *testing.T) {
+	cluster := &widgetAPIServer{objects: map[string]map[string]interface{}{}}
+	srv := httptest.NewServer(cluster)
+	defer srv.Close()
+	apiSchemas := widgetSchemas(t, srv.URL)
+	if rw := writeWidget(t, apiSchemas, http.MethodPost, "", "application/json",
+		`{"metadata":{"name":"a","namespace":"default"},"spec":{"size":1},"status":{"phase":"Pending"}}`); rw.Code != http.StatusCreated {
+		t.Fatalf("got status %d for the create: %s", rw.Code, rw.Body)
+	}
+
+	rw := patchWidgetStatus(t, apiSchemas, accessTo("update", "widgets/status"), "a", `{"spec":{"size":5},"status":{"phase":"Ready"}}`)
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got status %d for the status patch: %s", rw.Code, rw.Body)
+	}
+	cluster.lock.Lock()
+	phase := data.Object(cluster.objects["a"]).String("status", "phase")
+	cluster.lock.Unlock()
+	if phase != "Ready" {
+		t.Errorf("got phase %q, want the status patched", phase)
+	}
+	if size := storedNumber(t, cluster, "a", "spec", "size"); size != "1" {
+		t.Errorf("got size %s, want the spec untouched", size)
+	}
+}
+
+func TestStatusPatchNeedsUpdateOfTheStatus(t *testing.T) {
+	cluster := &widgetAPIServer{objects: map[string]map[string]interface{}{}}
+	srv := httptest.NewServer(cluster)
+	defer srv.Close()
+	apiSchemas := widgetSchemas(t, srv.URL)
+
+	// updating the widgets themselves doesn't allow updating their status
+	for _, access := range []*accesscontrol.AccessSet{accessTo("update", "widgets"), accessTo("get", "widgets/status")} {
+		rw := patchWidgetStatus(t, apiSchemas, access, "a", `{"status":{"phase":"Ready"}}`)
+		if rw.Code != http.StatusForbidden {
+			t.Errorf("got status %d, want 403 without update of widgets/status", rw.Code)
+		}
+	}
+}
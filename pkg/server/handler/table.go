@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+)
+
+// serveTable answers collection GETs that ask for a meta.k8s.io/v1 Table with the Table from the apiserver
+// instead of a steve collection. The list goes through the schema store so access control and namespace scoping
+// are the same as for normal lists, the Tables of every partition are merged into one. Schemas whose store
+// doesn't return Tables get a 406.
+func (a *apiServer) serveTable(apiOp *types.APIRequest) bool {
+	req := apiOp.Request
+	if req.Method != http.MethodGet || apiOp.Type == "" || apiOp.Name != "" || !proxy.IsTableRequest(req) {
+		return false
+	}
+	if req.URL.Query().Get("watch") != "" || req.URL.Query().Get("link") != "" {
+		return false
+	}
+
+	schema := apiOp.Schemas.LookupSchema(apiOp.Type)
+	if schema == nil {
+		return false
+	}
+	if err := a.server.AccessControl.CanList(apiOp, schema); err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+	if schema.Store == nil {
+		apiOp.WriteError(apierror.NewAPIError(proxy.ErrNotAcceptable, schema.ID+" can not be listed as a Table"))
+		return true
+	}
+
+	apiOp.Schema = schema
+	list, err := schema.Store.List(apiOp, schema)
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+
+	table, ok := mergeTables(list)
+	if !ok {
+		apiOp.WriteError(apierror.NewAPIError(proxy.ErrNotAcceptable, schema.ID+" can not be listed as a Table"))
+		return true
+	}
+
+	body, err := json.Marshal(table)
+	if err != nil {
+		apiOp.WriteError(err)
+		return true
+	}
+	apiOp.Response.Header().Set("Content-Type", "application/json")
+	apiOp.Response.WriteHeader(http.StatusOK)
+	_, _ = apiOp.Response.Write(body)
+	return true
+}
+
+// mergeTables combines the Tables returned by each partition of a list, the column definitions are the same
+// for all of them so the rows are appended to the first Table.
+func mergeTables(list types.APIObjectList) (map[string]interface{}, bool) {
+	if len(list.Objects) == 0 {
+		return nil, false
+	}
+
+	var result map[string]interface{}
+	var rows []interface{}
+	for _, obj := range list.Objects {
+		table := obj.Data()
+		if !proxy.IsTable(table) {
+			return nil, false
+		}
+		if result == nil {
+			result = table
+		}
+		tableRows, _ := table["rows"].([]interface{})
+		rows = append(rows, tableRows...)
+	}
+
+	if rows == nil {
+		rows = []interface{}{}
+	}
+	result["rows"] = rows
+	if metadata, ok := result["metadata"].(map[string]interface{}); ok && len(list.Objects) > 1 {
+		metadata["resourceVersion"] = list.Revision
+		metadata["continue"] = list.Continue
+	}
+	return result, true
+}
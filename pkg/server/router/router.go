@@ -14,6 +14,14 @@ type Handlers struct {
 	APIRoot     http.Handler
 	K8sProxy    http.Handler
 	Next        http.Handler
+	// SchemaStream is optional, when set it serves the schema change event stream.
+	SchemaStream http.Handler
+	// SchemaSync is optional, when set it serves /admin/schema-sync.
+	SchemaSync http.Handler
+	// SlowOperations is optional, when set it serves /debug/slow-ops.
+	SlowOperations http.Handler
+	// AliasUsage is optional, when set it serves /debug/alias-usage.
+	AliasUsage http.Handler
 }
 
 func Routes(h Handlers) http.Handler {
@@ -33,6 +41,18 @@ func Routes(h Handlers) http.Handler {
 	m.Path("/v1/{type}/{namespace}/{name}").Queries("link", "{link}").Handler(h.K8sResource)
 	m.Path("/v1/{type}/{namespace}/{name}").Handler(h.K8sResource)
 	m.Path("/v1/{type}/{namespace}/{name}/{link}").Handler(h.K8sResource)
+	if h.SchemaStream != nil {
+		m.Path("/api/schemas").Methods(http.MethodGet).Queries("watch", "true").Handler(h.SchemaStream)
+	}
+	if h.SchemaSync != nil {
+		m.Path("/admin/schema-sync").Handler(h.SchemaSync)
+	}
+	if h.SlowOperations != nil {
+		m.Path("/debug/slow-ops").Methods(http.MethodGet).Handler(h.SlowOperations)
+	}
+	if h.AliasUsage != nil {
+		m.Path("/debug/alias-usage").Methods(http.MethodGet).Handler(h.AliasUsage)
+	}
 	m.Path("/api").Handler(h.K8sProxy) // Can't just prefix this as UI needs /apikeys path
 	m.PathPrefix("/api/").Handler(h.K8sProxy)
 	m.PathPrefix("/apis").Handler(h.K8sProxy)
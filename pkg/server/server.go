@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	apiserver "github.com/rancher/apiserver/pkg/server"
 	"github.com/rancher/apiserver/pkg/types"
@@ -11,15 +12,20 @@ import (
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/aggregation"
 	"github.com/rancher/steve/pkg/auth"
+	"github.com/rancher/steve/pkg/authorization"
 	"github.com/rancher/steve/pkg/client"
 	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/steve/pkg/clusterversion"
 	schemacontroller "github.com/rancher/steve/pkg/controllers/schema"
+	"github.com/rancher/steve/pkg/idle"
 	"github.com/rancher/steve/pkg/resources"
 	"github.com/rancher/steve/pkg/resources/common"
+	"github.com/rancher/steve/pkg/resources/operation"
 	"github.com/rancher/steve/pkg/resources/schemas"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/server/handler"
 	"github.com/rancher/steve/pkg/server/router"
+	"github.com/rancher/steve/pkg/stores/proxy"
 	"github.com/rancher/steve/pkg/summarycache"
 	"k8s.io/client-go/rest"
 )
@@ -38,6 +44,8 @@ type Server struct {
 	APIServer       *apiserver.Server
 	ClusterRegistry string
 	Version         string
+	// IdleTracker is set when Options.IdleUserExpiry is, its Stats report the tracked identities and sweeps
+	IdleTracker *idle.Tracker
 
 	authMiddleware      auth.Middleware
 	controllers         *Controllers
@@ -47,6 +55,22 @@ type Server struct {
 
 	aggregationSecretNamespace string
 	aggregationSecretName      string
+	proxyStoreOptions          []proxy.Option
+	responseCacheTTL           time.Duration
+	fallbackProxy              bool
+	fallbackProxyURL           string
+	fallbackProxyTransport     http.RoundTripper
+	schemaChangeStream         bool
+	requestBodyLimit           int64
+	asyncWriteWorkers          int
+	asyncWriteQueueSize        int
+	asyncOperationTTL          time.Duration
+	authorizers                []authorization.Authorizer
+	schemaSyncSecret           []byte
+	idleUserExpiry             time.Duration
+	clientQPS                  float32
+	clientBurst                int
+	responseEnvelope           handler.Envelope
 }
 
 type Options struct {
@@ -61,6 +85,40 @@ type Options struct {
 	AggregationSecretName      string
 	ClusterRegistry            string
 	ServerVersion              string
+	// ProxyStoreOptions are passed to the proxy store backing the default schema template
+	ProxyStoreOptions []proxy.Option
+	// ResponseCacheTTL enables caching of GET responses for the given duration, zero disables the cache
+	ResponseCacheTTL time.Duration
+	// FallbackProxy forwards requests for unregistered schemas to the Kubernetes apiserver instead of returning a 404
+	FallbackProxy bool
+	// FallbackProxyURL and FallbackProxyTransport default to the host and transport of the rest config
+	FallbackProxyURL       string
+	FallbackProxyTransport http.RoundTripper
+	// SchemaChangeStream serves GET /api/schemas?watch=true, a server-sent event stream of schema changes
+	SchemaChangeStream bool
+	// RequestBodyLimit is the largest create or update body accepted, zero uses the 3MB default and a negative
+	// value disables the limit
+	RequestBodyLimit int64
+	// AsyncWriteWorkers enables queueing writes as operations for clients sending Prefer: respond-async, see
+	// handler.WithAsyncWrites. AsyncWriteQueueSize defaults to 100 and AsyncOperationTTL to 15 minutes
+	AsyncWriteWorkers   int
+	AsyncWriteQueueSize int
+	AsyncOperationTTL   time.Duration
+	// Authorizers are policy checks run on every request before it reaches the store, see handler.WithAuthorizers
+	Authorizers []authorization.Authorizer
+	// SchemaSyncSecret enables /admin/schema-sync, requests are signed with the secret, see handler.WithSchemaSync
+	SchemaSyncSecret []byte
+	// IdleUserExpiry drops cached per user state, such as schemas, access sets, clients and cached responses, of
+	// identities that made no request for this long and have no watch or subscription open. Zero keeps the state
+	// until it is evicted by the cache limits
+	IdleUserExpiry time.Duration
+	// ClientQPS and ClientBurst limit the requests sent to the apiserver on behalf of each user, see
+	// client.WithRateLimit. They are ignored when ClientFactory is set or ClientQPS is zero
+	ClientQPS   float32
+	ClientBurst int
+	// ResponseEnvelope reshapes the JSON responses of the API, see handler.WithResponseEnvelope. Nil keeps the
+	// native shape
+	ResponseEnvelope handler.Envelope
 }
 
 func New(ctx context.Context, restConfig *rest.Config, opts *Options) (*Server, error) {
@@ -80,6 +138,22 @@ func New(ctx context.Context, restConfig *rest.Config, opts *Options) (*Server,
 		aggregationSecretName:      opts.AggregationSecretName,
 		ClusterRegistry:            opts.ClusterRegistry,
 		Version:                    opts.ServerVersion,
+		proxyStoreOptions:          opts.ProxyStoreOptions,
+		responseCacheTTL:           opts.ResponseCacheTTL,
+		fallbackProxy:              opts.FallbackProxy,
+		fallbackProxyURL:           opts.FallbackProxyURL,
+		fallbackProxyTransport:     opts.FallbackProxyTransport,
+		schemaChangeStream:         opts.SchemaChangeStream,
+		requestBodyLimit:           opts.RequestBodyLimit,
+		asyncWriteWorkers:          opts.AsyncWriteWorkers,
+		asyncWriteQueueSize:        opts.AsyncWriteQueueSize,
+		asyncOperationTTL:          opts.AsyncOperationTTL,
+		authorizers:                opts.Authorizers,
+		schemaSyncSecret:           opts.SchemaSyncSecret,
+		idleUserExpiry:             opts.IdleUserExpiry,
+		clientQPS:                  opts.ClientQPS,
+		clientBurst:                opts.ClientBurst,
+		responseEnvelope:           opts.ResponseEnvelope,
 	}
 
 	if err := setup(ctx, server); err != nil {
@@ -122,7 +196,11 @@ func setup(ctx context.Context, server *Server) error {
 
 	cf := server.ClientFactory
 	if cf == nil {
-		cf, err = client.NewFactory(server.RESTConfig, server.authMiddleware != nil)
+		var clientOpts []client.Option
+		if server.clientQPS > 0 {
+			clientOpts = append(clientOpts, client.WithRateLimit(server.clientQPS, server.clientBurst))
+		}
+		cf, err = client.NewFactory(server.RESTConfig, server.authMiddleware != nil, clientOpts...)
 		if err != nil {
 			return err
 		}
@@ -137,15 +215,33 @@ func setup(ctx context.Context, server *Server) error {
 	ccache := clustercache.NewClusterCache(ctx, cf.AdminDynamicClient())
 	server.ClusterCache = ccache
 	sf := schema.NewCollection(ctx, server.BaseSchemas, asl)
+	sf.SetNamespaceLister(server.controllers.Core.Namespace().Cache())
 
 	if err = resources.DefaultSchemas(ctx, server.BaseSchemas, ccache, server.ClientFactory, sf, server.Version); err != nil {
 		return err
 	}
 
+	// the features of the proxy store that depend on the version of the cluster are gated on it
+	versionTracker := clusterversion.New(server.controllers.K8s.Discovery())
+	versionTracker.Start(ctx, clusterversion.DefaultRefreshInterval)
+	clusterversion.Register(server.BaseSchemas, versionTracker)
+
+	var operations *operation.Queue
+	if server.asyncWriteWorkers > 0 {
+		operations = newOperationQueue(ctx, server)
+		operation.Register(server.BaseSchemas, operations)
+	}
+
 	summaryCache := summarycache.New(sf, ccache)
 	summaryCache.Start(ctx)
 
-	for _, template := range resources.DefaultSchemaTemplates(cf, server.BaseSchemas, summaryCache, asl, server.controllers.K8s.Discovery()) {
+	// lists forbidden because of a stale access set drop the schemas cached for it before they are retried
+	proxyStoreOptions := append([]proxy.Option{
+		proxy.WithAccessForgetters(sf),
+		proxy.WithNamespaceLister(server.controllers.Core.Namespace().Cache()),
+		proxy.WithClusterVersion(versionTracker),
+	}, server.proxyStoreOptions...)
+	for _, template := range resources.DefaultSchemaTemplates(cf, server.BaseSchemas, summaryCache, asl, server.controllers.K8s.Discovery(), proxyStoreOptions...) {
 		sf.AddTemplate(template)
 	}
 
@@ -165,7 +261,47 @@ func setup(ctx context.Context, server *Server) error {
 		ccache,
 		sf)
 
-	apiServer, handler, err := handler.New(server.RESTConfig, sf, server.authMiddleware, server.next, server.router)
+	var handlerOpts []handler.Option
+	if server.responseCacheTTL > 0 {
+		handlerOpts = append(handlerOpts, handler.WithResponseCache(ctx, server.responseCacheTTL, ccache))
+	}
+	if server.fallbackProxy {
+		opt, err := fallbackProxyOption(server)
+		if err != nil {
+			return err
+		}
+		handlerOpts = append(handlerOpts, opt)
+	}
+	if server.schemaChangeStream {
+		handlerOpts = append(handlerOpts, handler.WithSchemaChangeStream(true))
+	}
+	if server.requestBodyLimit != 0 {
+		handlerOpts = append(handlerOpts, handler.WithRequestBodyLimit(server.requestBodyLimit))
+	}
+	if operations != nil {
+		handlerOpts = append(handlerOpts, handler.WithAsyncWrites(operations))
+	}
+	if len(server.authorizers) > 0 {
+		handlerOpts = append(handlerOpts, handler.WithAuthorizers(server.authorizers...))
+	}
+
+	if server.idleUserExpiry > 0 {
+		server.IdleTracker = idle.NewTracker(server.idleUserExpiry)
+		server.IdleTracker.Register(sf, cf)
+		if forgetter, ok := asl.(idle.Forgetter); ok {
+			server.IdleTracker.Register(forgetter)
+		}
+		server.IdleTracker.Start(ctx, server.idleUserExpiry/2)
+		handlerOpts = append(handlerOpts, handler.WithIdleTracker(server.IdleTracker))
+	}
+	if len(server.schemaSyncSecret) > 0 {
+		handlerOpts = append(handlerOpts, handler.WithSchemaSync(server.schemaSyncSecret))
+	}
+	if server.responseEnvelope != nil {
+		handlerOpts = append(handlerOpts, handler.WithResponseEnvelope(server.responseEnvelope))
+	}
+
+	apiServer, handler, err := handler.New(server.RESTConfig, sf, server.authMiddleware, server.next, server.router, handlerOpts...)
 	if err != nil {
 		return err
 	}
@@ -176,6 +312,36 @@ func setup(ctx context.Context, server *Server) error {
 	return nil
 }
 
+func newOperationQueue(ctx context.Context, server *Server) *operation.Queue {
+	size := server.asyncWriteQueueSize
+	if size <= 0 {
+		size = 100
+	}
+	ttl := server.asyncOperationTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	return operation.NewQueue(ctx, server.asyncWriteWorkers, size, ttl)
+}
+
+func fallbackProxyOption(server *Server) (handler.Option, error) {
+	target := server.fallbackProxyURL
+	if target == "" {
+		target = server.RESTConfig.Host
+	}
+
+	transport := server.fallbackProxyTransport
+	if transport == nil {
+		var err error
+		transport, err = rest.TransportFor(server.RESTConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return handler.WithFallbackProxy(target, transport), nil
+}
+
 func (c *Server) start(ctx context.Context) error {
 	if c.needControllerStart {
 		if err := c.controllers.Start(ctx); err != nil {
@@ -0,0 +1,328 @@
+// Package steve wires a complete steve API server, the schema collection, access control, client factory,
+// default templates and HTTP handler, from a rest config and functional options.
+//
+//	s, err := steve.New(ctx, restConfig,
+//		steve.WithResponseCacheTTL(time.Minute),
+//		steve.WithBasePath("/k8s"))
+//	...
+//	http.Handle("/k8s/", s)
+//	defer s.Stop()
+//
+// Options not covered here can be passed to the proxy store with WithProxyStoreOptions, or set on server.Options
+// with WithOptions.
+package steve
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/auth"
+	"github.com/rancher/steve/pkg/authorization"
+	"github.com/rancher/steve/pkg/client"
+	"github.com/rancher/steve/pkg/server"
+	"github.com/rancher/steve/pkg/server/handler"
+	"github.com/rancher/steve/pkg/server/router"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"k8s.io/client-go/rest"
+)
+
+// Option configures the server built by New.
+type Option func(*config)
+
+type config struct {
+	options      server.Options
+	basePath     string
+	replaySize   int
+	replayMaxAge time.Duration
+}
+
+// urlPrefixHeader is read by the URL builder of the apiserver, its value is put before the path of every link.
+const urlPrefixHeader = "X-API-URL-Prefix"
+
+// Steve is a running steve API server. It serves HTTP until Stop is called or the context given to New is done,
+// requests that arrive afterwards fail with 503 Service Unavailable.
+type Steve struct {
+	// Server exposes the wired components, such as the schema factory and cluster cache
+	Server *server.Server
+
+	handler http.Handler
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	lock     sync.Mutex
+	stopped  bool
+	requests sync.WaitGroup
+}
+
+// New builds the server and starts its controllers, which run until Stop is called or ctx is done.
+func New(ctx context.Context, restConfig *rest.Config, opts ...Option) (*Steve, error) {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	if cfg.replaySize > 0 {
+		cfg.options.ProxyStoreOptions = append(cfg.options.ProxyStoreOptions,
+			proxy.WithEventReplay(ctx, cfg.replaySize, cfg.replayMaxAge))
+	}
+	s, err := server.New(ctx, restConfig, &cfg.options)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	var h http.Handler = s
+	if cfg.basePath != "" {
+		h = http.StripPrefix(cfg.basePath, withURLPrefix(cfg.basePath, h))
+	}
+
+	result := &Steve{
+		Server:  s,
+		handler: h,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go result.shutdown()
+	return result, nil
+}
+
+// withURLPrefix makes the links of the responses start with prefix, which StripPrefix removed from the path.
+func withURLPrefix(prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		req = req.Clone(req.Context())
+		req.Header.Set(urlPrefixHeader, req.Header.Get(urlPrefixHeader)+prefix)
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// ServeHTTP serves req until it is done or the server stops, a stop cancels the context of the requests still
+// running, such as watches.
+func (s *Steve) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	if s.stopped {
+		s.lock.Unlock()
+		http.Error(rw, "server is stopped", http.StatusServiceUnavailable)
+		return
+	}
+	s.requests.Add(1)
+	s.lock.Unlock()
+	defer s.requests.Done()
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	s.handler.ServeHTTP(rw, req.WithContext(ctx))
+}
+
+// shutdown refuses new requests once the server is stopped and closes done when the running ones returned.
+func (s *Steve) shutdown() {
+	<-s.ctx.Done()
+	s.lock.Lock()
+	s.stopped = true
+	s.lock.Unlock()
+	s.requests.Wait()
+	close(s.done)
+}
+
+// Stop stops the controllers and caches started by New and returns once the requests in flight returned.
+func (s *Steve) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// Done is closed once the server is stopped and its requests in flight returned.
+func (s *Steve) Done() <-chan struct{} {
+	return s.done
+}
+
+// WithBasePath serves the API under path, requests must start with it and it is removed before routing. The
+// links of the responses keep it.
+func WithBasePath(path string) Option {
+	return func(c *config) {
+		c.basePath = strings.TrimSuffix(path, "/")
+	}
+}
+
+// WithControllers uses controllers the caller starts itself instead of creating and starting new ones.
+func WithControllers(controllers *server.Controllers) Option {
+	return func(c *config) {
+		c.options.Controllers = controllers
+	}
+}
+
+// WithClientFactory uses factory to create the Kubernetes clients of requests.
+func WithClientFactory(factory *client.Factory) Option {
+	return func(c *config) {
+		c.options.ClientFactory = factory
+	}
+}
+
+// WithClientRateLimit limits the requests sent to the apiserver for each user to qps with bursts of burst, see
+// client.WithRateLimit. It has no effect with WithClientFactory.
+func WithClientRateLimit(qps float32, burst int) Option {
+	return func(c *config) {
+		c.options.ClientQPS = qps
+		c.options.ClientBurst = burst
+	}
+}
+
+// WithAccessSetLookup replaces the RBAC based access control.
+func WithAccessSetLookup(lookup accesscontrol.AccessSetLookup) Option {
+	return func(c *config) {
+		c.options.AccessSetLookup = lookup
+	}
+}
+
+// WithAuthMiddleware authenticates requests, requests to the apiserver then impersonate the authenticated user.
+func WithAuthMiddleware(middleware auth.Middleware) Option {
+	return func(c *config) {
+		c.options.AuthMiddleware = middleware
+	}
+}
+
+// WithNext handles the requests that are not routed to the API.
+func WithNext(next http.Handler) Option {
+	return func(c *config) {
+		c.options.Next = next
+	}
+}
+
+// WithRouter replaces the default routes.
+func WithRouter(routerFunc router.RouterFunc) Option {
+	return func(c *config) {
+		c.options.Router = routerFunc
+	}
+}
+
+// WithAggregation sets the secret holding the aggregation configuration, see server.Server.StartAggregation.
+func WithAggregation(namespace, name string) Option {
+	return func(c *config) {
+		c.options.AggregationSecretNamespace = namespace
+		c.options.AggregationSecretName = name
+	}
+}
+
+// WithVersion sets the server version and cluster registry reported by the cluster schema.
+func WithVersion(version, clusterRegistry string) Option {
+	return func(c *config) {
+		c.options.ServerVersion = version
+		c.options.ClusterRegistry = clusterRegistry
+	}
+}
+
+// WithProxyStoreOptions configures the proxy store serving Kubernetes resources, for example the number of objects
+// a watch resync may list with proxy.WithWatchResync.
+func WithProxyStoreOptions(opts ...proxy.Option) Option {
+	return func(c *config) {
+		c.options.ProxyStoreOptions = append(c.options.ProxyStoreOptions, opts...)
+	}
+}
+
+// WithResponseCacheTTL caches GET responses for ttl.
+func WithResponseCacheTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.options.ResponseCacheTTL = ttl
+	}
+}
+
+// WithFallbackProxy forwards requests for unregistered schemas to url, or the host of the rest config when url is
+// empty, using transport, or the transport of the rest config when it is nil.
+func WithFallbackProxy(url string, transport http.RoundTripper) Option {
+	return func(c *config) {
+		c.options.FallbackProxy = true
+		c.options.FallbackProxyURL = url
+		c.options.FallbackProxyTransport = transport
+	}
+}
+
+// WithSchemaChangeStream serves GET /api/schemas?watch=true.
+func WithSchemaChangeStream() Option {
+	return func(c *config) {
+		c.options.SchemaChangeStream = true
+	}
+}
+
+// WithRequestBodyLimit sets the largest create or update body accepted, a negative limit disables it.
+func WithRequestBodyLimit(limit int64) Option {
+	return func(c *config) {
+		c.options.RequestBodyLimit = limit
+	}
+}
+
+// WithAsyncWrites queues writes of clients sending Prefer: respond-async on workers goroutines, zero queueSize and
+// ttl use the defaults of server.Options.
+func WithAsyncWrites(workers, queueSize int, ttl time.Duration) Option {
+	return func(c *config) {
+		c.options.AsyncWriteWorkers = workers
+		c.options.AsyncWriteQueueSize = queueSize
+		c.options.AsyncOperationTTL = ttl
+	}
+}
+
+// WithAuthorizers runs policy checks on every request before it reaches the store.
+func WithAuthorizers(authorizers ...authorization.Authorizer) Option {
+	return func(c *config) {
+		c.options.Authorizers = append(c.options.Authorizers, authorizers...)
+	}
+}
+
+// WithSchemaSync enables /admin/schema-sync with requests signed by secret.
+func WithSchemaSync(secret []byte) Option {
+	return func(c *config) {
+		c.options.SchemaSyncSecret = secret
+	}
+}
+
+// WithIdleUserExpiry drops the cached state of users that made no request for expiry.
+func WithIdleUserExpiry(expiry time.Duration) Option {
+	return func(c *config) {
+		c.options.IdleUserExpiry = expiry
+	}
+}
+
+// WithOperationTimeouts sets the deadlines of the operations of the proxy store, see proxy.WithOperationTimeouts.
+func WithOperationTimeouts(timeouts proxy.OperationTimeouts) Option {
+	return WithProxyStoreOptions(proxy.WithOperationTimeouts(timeouts))
+}
+
+// WithContinueRestart answers lists whose continue token expired with their first page, see
+// proxy.WithContinueRestart.
+func WithContinueRestart(enabled bool) Option {
+	return WithProxyStoreOptions(proxy.WithContinueRestart(enabled))
+}
+
+// WithEventReplay keeps the last size watch events of every schema, no older than maxAge, so resumed watches are
+// replayed from memory, see proxy.WithEventReplay. The buffers stop with the server.
+func WithEventReplay(size int, maxAge time.Duration) Option {
+	return func(c *config) {
+		c.replaySize = size
+		c.replayMaxAge = maxAge
+	}
+}
+
+// WithResponseEnvelope reshapes the JSON responses of the API, see handler.WithResponseEnvelope.
+func WithResponseEnvelope(envelope handler.Envelope) Option {
+	return func(c *config) {
+		c.options.ResponseEnvelope = envelope
+	}
+}
+
+// WithOptions starts from opts, options given after it override its fields.
+func WithOptions(opts server.Options) Option {
+	return func(c *config) {
+		c.options = opts
+	}
+}
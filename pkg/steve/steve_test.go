@@ -0,0 +1,283 @@
+package steve
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rancher/steve/pkg/accesscontrol"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/rest"
+)
+
+// listKinds are the kinds of the lists the fake apiserver serves by resource, the informers of the server list
+// and watch some of them.
+var listKinds = map[string]string{
+	"apiservices":               "APIServiceList",
+	"clusterrolebindings":       "ClusterRoleBindingList",
+	"clusterroles":              "ClusterRoleList",
+	"configmaps":                "ConfigMapList",
+	"customresourcedefinitions": "CustomResourceDefinitionList",
+	"events":                    "EventList",
+	"namespaces":                "NamespaceList",
+	"nodes":                     "NodeList",
+	"pods":                      "PodList",
+	"rolebindings":              "RoleBindingList",
+	"roles":                     "RoleList",
+	"secrets":                   "SecretList",
+	"serviceaccounts":           "ServiceAccountList",
+	"services":                  "ServiceList",
+}
+
+// fakeAPIServer answers discovery, lists and watches like an apiserver with one APIService, so the schemas are
+// loaded, and one config map. Watches stay open without events until the server is closed.
+type fakeAPIServer struct {
+	*httptest.Server
+	stop chan struct{}
+}
+
+func newFakeAPIServer(t *testing.T) *fakeAPIServer {
+	f := &fakeAPIServer{stop: make(chan struct{})}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(func() {
+		close(f.stop)
+		f.CloseClientConnections()
+		f.Close()
+	})
+	return f
+}
+
+func (f *fakeAPIServer) serve(rw http.ResponseWriter, req *http.Request) {
+	write := func(obj interface{}) {
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(obj)
+	}
+
+	verbs := metav1.Verbs{"get", "list", "watch", "create", "update", "patch", "delete"}
+	switch {
+	case req.URL.Path == "/version":
+		write(version.Info{Major: "1", Minor: "20", GitVersion: "v1.20.0"})
+	case req.URL.Path == "/openapi/v2":
+		// an empty body is an empty OpenAPI document
+		rw.WriteHeader(http.StatusOK)
+	case req.URL.Path == "/api":
+		write(metav1.APIVersions{TypeMeta: metav1.TypeMeta{Kind: "APIVersions"}, Versions: []string{"v1"}})
+	case req.URL.Path == "/apis":
+		write(metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}})
+	case req.URL.Path == "/api/v1":
+		write(metav1.APIResourceList{
+			TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: verbs},
+				{Name: "namespaces", Kind: "Namespace", Verbs: verbs},
+			},
+		})
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/selfsubjectaccessreviews"):
+		review := map[string]interface{}{}
+		_ = json.NewDecoder(req.Body).Decode(&review)
+		review["apiVersion"] = "authorization.k8s.io/v1"
+		review["kind"] = "SelfSubjectAccessReview"
+		review["status"] = map[string]interface{}{"allowed": true}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusCreated)
+		write(review)
+	case req.Method == http.MethodGet && req.URL.Query().Get("watch") == "true":
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusOK)
+		if flusher, ok := rw.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		select {
+		case <-req.Context().Done():
+		case <-f.stop:
+		}
+	case req.Method == http.MethodGet:
+		f.list(rw, req, write)
+	default:
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeAPIServer) list(rw http.ResponseWriter, req *http.Request, write func(interface{})) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	resource := parts[len(parts)-1]
+	kind, ok := listKinds[resource]
+	if !ok || len(parts) < 3 {
+		rw.WriteHeader(http.StatusNotFound)
+		write(metav1.Status{
+			TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+			Status:   metav1.StatusFailure,
+			Reason:   metav1.StatusReasonNotFound,
+			Code:     http.StatusNotFound,
+		})
+		return
+	}
+
+	apiVersion := parts[1]
+	if parts[0] == "apis" {
+		apiVersion = parts[1] + "/" + parts[2]
+	}
+
+	items := []interface{}{}
+	switch resource {
+	case "apiservices":
+		items = append(items, map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       "APIService",
+			"metadata":   map[string]interface{}{"name": "v1.", "resourceVersion": "1"},
+			"spec":       map[string]interface{}{"version": "v1"},
+		})
+	case "configmaps":
+		items = append(items, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "cm1", "namespace": "default", "resourceVersion": "1"},
+			"data":       map[string]interface{}{"key": "value"},
+		})
+	}
+	write(map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"resourceVersion": "1"},
+		"items":      items,
+	})
+}
+
+// allAccess grants every verb on every resource.
+type allAccess struct{}
+
+func (allAccess) AccessFor(user user.Info) *accesscontrol.AccessSet {
+	set := &accesscontrol.AccessSet{ID: "all"}
+	set.Add(accesscontrol.All, schema.GroupResource{Group: accesscontrol.All, Resource: accesscontrol.All}, accesscontrol.Access{
+		Namespace:    accesscontrol.All,
+		ResourceName: accesscontrol.All,
+	})
+	return set
+}
+
+func get(t *testing.T, url string) (int, map[string]interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := map[string]interface{}{}
+	_ = json.Unmarshal(data, &body)
+	return resp.StatusCode, body
+}
+
+func TestNewServesTheAPIUnderTheBasePath(t *testing.T) {
+	api := newFakeAPIServer(t)
+
+	s, err := New(context.Background(), &rest.Config{Host: api.URL},
+		WithBasePath("/k8s"),
+		WithAccessSetLookup(allAccess{}),
+		WithEventReplay(100, time.Minute),
+		WithContinueRestart(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	defer s.Stop()
+
+	// the schemas are loaded once the controllers saw the APIService
+	var (
+		code int
+		list map[string]interface{}
+	)
+	for deadline := time.Now().Add(30 * time.Second); ; {
+		if code, list = get(t, srv.URL+"/k8s/v1/configmaps"); code == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got status %d for the config maps, want 200: %v", code, list)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	data, _ := list["data"].([]interface{})
+	if len(data) != 1 {
+		t.Fatalf("got %v, want the config map of the apiserver", list)
+	}
+	if id := data[0].(map[string]interface{})["id"]; id != "default/cm1" {
+		t.Errorf("got id %v, want default/cm1", id)
+	}
+	links, _ := list["links"].(map[string]interface{})
+	if self := links["self"]; self != srv.URL+"/k8s/v1/configmaps" {
+		t.Errorf("got self link %v, want it under the base path", self)
+	}
+
+	s.Stop()
+	select {
+	case <-s.Done():
+	default:
+		t.Error("Done is not closed after Stop")
+	}
+	if code, _ := get(t, srv.URL+"/k8s/v1/configmaps"); code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d after Stop, want 503", code)
+	}
+}
+
+func TestStopWaitsForRequestsInFlight(t *testing.T) {
+	var finished int32
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Steve{
+		handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			close(started)
+			<-req.Context().Done()
+			time.Sleep(50 * time.Millisecond)
+			atomic.StoreInt32(&finished, 1)
+		}),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go s.shutdown()
+
+	go s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/configmaps?watch=true", nil))
+	<-started
+	s.Stop()
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("Stop returned before the request in flight")
+	}
+
+	rw := httptest.NewRecorder()
+	s.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/configmaps", nil))
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d after Stop, want 503", rw.Code)
+	}
+}
+
+func TestWithURLPrefix(t *testing.T) {
+	var prefix string
+	h := http.StripPrefix("/k8s", withURLPrefix("/k8s", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		prefix = req.Header.Get(urlPrefixHeader)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/k8s/v1/configmaps", nil)
+	req.Header.Set(urlPrefixHeader, "/rancher")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if prefix != "/rancher/k8s" {
+		t.Errorf("got prefix %q, want the base path after the prefix of the proxy in front", prefix)
+	}
+	if req.Header.Get(urlPrefixHeader) != "/rancher" {
+		t.Error("the header of the request of the caller was changed")
+	}
+}
@@ -0,0 +1,188 @@
+package compat
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	VersionHeader = "X-API-Compatibility-Version"
+	VersionQuery  = "compatVersion"
+)
+
+// Converter translates objects between an older shape a client is pinned to and the shape currently served by
+// the cluster. ToCurrent is applied to input before it is written; FromCurrent to every object returned.
+type Converter interface {
+	ToCurrent(obj map[string]interface{}) (map[string]interface{}, error)
+	FromCurrent(obj map[string]interface{}) (map[string]interface{}, error)
+}
+
+// Versions returns the sorted compatibility versions the converters support.
+func Versions(converters map[string]Converter) []string {
+	var versions []string
+	for version := range converters {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Store converts objects to and from the compatibility version the client requested with the
+// X-API-Compatibility-Version header or compatVersion query parameter. Requests that do not ask for a
+// version are passed through untouched.
+type Store struct {
+	types.Store
+	converters map[string]Converter
+}
+
+func NewStore(store types.Store, converters map[string]Converter) types.Store {
+	return &Store{
+		Store:      store,
+		converters: converters,
+	}
+}
+
+func (s *Store) converter(apiOp *types.APIRequest) (Converter, error) {
+	version := apiOp.Request.Header.Get(VersionHeader)
+	if version == "" {
+		version = apiOp.Request.URL.Query().Get(VersionQuery)
+	}
+	if version == "" {
+		return nil, nil
+	}
+
+	converter, ok := s.converters[version]
+	if !ok {
+		return nil, apierror.NewAPIError(validation.InvalidOption, "compatibility version "+version+
+			" is not supported, must be one of: "+strings.Join(Versions(s.converters), ", "))
+	}
+	return converter, nil
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	converter, err := s.converter(apiOp)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	if err != nil || converter == nil {
+		return obj, err
+	}
+	return fromCurrent(converter, obj)
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	converter, err := s.converter(apiOp)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+	list, err := s.Store.List(apiOp, schema)
+	if err != nil || converter == nil {
+		return list, err
+	}
+	for i, obj := range list.Objects {
+		if list.Objects[i], err = fromCurrent(converter, obj); err != nil {
+			return types.APIObjectList{}, err
+		}
+	}
+	return list, nil
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	converter, err := s.converter(apiOp)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	if converter == nil {
+		return s.Store.Create(apiOp, schema, data)
+	}
+	if data, err = toCurrent(converter, data); err != nil {
+		return types.APIObject{}, err
+	}
+	obj, err := s.Store.Create(apiOp, schema, data)
+	if err != nil {
+		return obj, err
+	}
+	return fromCurrent(converter, obj)
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	converter, err := s.converter(apiOp)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	if converter == nil {
+		return s.Store.Update(apiOp, schema, data, id)
+	}
+	if data, err = toCurrent(converter, data); err != nil {
+		return types.APIObject{}, err
+	}
+	obj, err := s.Store.Update(apiOp, schema, data, id)
+	if err != nil {
+		return obj, err
+	}
+	return fromCurrent(converter, obj)
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	converter, err := s.converter(apiOp)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	obj, err := s.Store.Delete(apiOp, schema, id)
+	if err != nil || converter == nil || obj.Object == nil {
+		return obj, err
+	}
+	return fromCurrent(converter, obj)
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	converter, err := s.converter(apiOp)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.Store.Watch(apiOp, schema, w)
+	if err != nil || converter == nil || c == nil {
+		return c, err
+	}
+
+	result := make(chan types.APIEvent)
+	go func() {
+		defer close(result)
+		for event := range c {
+			if event.Error == nil && event.Object.Object != nil {
+				if event.Object, err = fromCurrent(converter, event.Object); err != nil {
+					event = types.APIEvent{
+						Name:  "resource.error",
+						Error: err,
+					}
+				}
+			}
+			result <- event
+		}
+	}()
+	return result, nil
+}
+
+func toCurrent(converter Converter, obj types.APIObject) (types.APIObject, error) {
+	converted, err := converter.ToCurrent(obj.Data())
+	if err != nil {
+		return obj, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	obj.Object = converted
+	return obj, nil
+}
+
+func fromCurrent(converter Converter, obj types.APIObject) (types.APIObject, error) {
+	converted, err := converter.FromCurrent(obj.Data())
+	if err != nil {
+		return obj, apierror.NewAPIError(validation.ServerError, err.Error())
+	}
+	obj.Object = &unstructured.Unstructured{Object: converted}
+	return obj, nil
+}
@@ -0,0 +1,175 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// VersionConverter migrates an object stored with the FromVersion apiVersion to ToVersion, both are the version
+// without the group.
+type VersionConverter struct {
+	FromVersion string
+	ToVersion   string
+	Convert     func(map[string]interface{}) (map[string]interface{}, error)
+}
+
+// PipelineStore migrates every object returned by the wrapped store that still has an older apiVersion to the
+// version of the schema, chaining converters, for example v1alpha1 to v1beta1 to v1. An object with a version
+// the pipeline can't reach the schema version from fails with an error rather than being returned half migrated.
+type PipelineStore struct {
+	types.Store
+	pipeline []VersionConverter
+	version  string
+}
+
+// NewPipelineStore wraps store so objects are returned in version, the served version of the schema.
+func NewPipelineStore(store types.Store, pipeline []VersionConverter, version string) types.Store {
+	return &PipelineStore{
+		Store:    store,
+		pipeline: pipeline,
+		version:  version,
+	}
+}
+
+func (p *PipelineStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := p.Store.ByID(apiOp, schema, id)
+	if err != nil {
+		return obj, err
+	}
+	return p.migrate(obj)
+}
+
+func (p *PipelineStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := p.Store.List(apiOp, schema)
+	if err != nil {
+		return list, err
+	}
+	for i, obj := range list.Objects {
+		if list.Objects[i], err = p.migrate(obj); err != nil {
+			return types.APIObjectList{}, err
+		}
+	}
+	return list, nil
+}
+
+func (p *PipelineStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	obj, err := p.Store.Create(apiOp, schema, data)
+	if err != nil {
+		return obj, err
+	}
+	return p.migrate(obj)
+}
+
+func (p *PipelineStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	obj, err := p.Store.Update(apiOp, schema, data, id)
+	if err != nil {
+		return obj, err
+	}
+	return p.migrate(obj)
+}
+
+func (p *PipelineStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := p.Store.Delete(apiOp, schema, id)
+	if err != nil || obj.Object == nil {
+		return obj, err
+	}
+	return p.migrate(obj)
+}
+
+func (p *PipelineStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c, err := p.Store.Watch(apiOp, schema, w)
+	if err != nil || c == nil {
+		return c, err
+	}
+
+	result := make(chan types.APIEvent)
+	go func() {
+		defer close(result)
+		for event := range c {
+			if event.Error == nil && event.Object.Object != nil {
+				if event.Object, err = p.migrate(event.Object); err != nil {
+					event = types.APIEvent{
+						Name:  "resource.error",
+						Error: err,
+					}
+				}
+			}
+			result <- event
+		}
+	}()
+	return result, nil
+}
+
+func (p *PipelineStore) migrate(obj types.APIObject) (types.APIObject, error) {
+	data := obj.Data()
+	apiVersion, _ := data["apiVersion"].(string)
+	if apiVersion == "" {
+		return obj, nil
+	}
+
+	migrated, err := Migrate(p.pipeline, data, p.version)
+	if err != nil {
+		return obj, apierror.NewAPIError(validation.ServerError, err.Error())
+	}
+	if newVersion, _ := migrated["apiVersion"].(string); newVersion == apiVersion {
+		return obj, nil
+	}
+	obj.Object = &unstructured.Unstructured{Object: migrated}
+	return obj, nil
+}
+
+// Migrate applies the converters of pipeline to obj, one after the other, until its apiVersion is at version.
+// The group of the apiVersion is kept.
+func Migrate(pipeline []VersionConverter, obj map[string]interface{}, version string) (map[string]interface{}, error) {
+	apiVersion, _ := obj["apiVersion"].(string)
+	group, current := splitAPIVersion(apiVersion)
+
+	// every converter is used at most once, this also stops cycles in the pipeline
+	for steps := 0; current != version; steps++ {
+		if steps >= len(pipeline) {
+			return nil, fmt.Errorf("no conversion from %s to %s", apiVersion, version)
+		}
+
+		converter, ok := findConverter(pipeline, current)
+		if !ok {
+			return nil, fmt.Errorf("no conversion from %s to %s, missing converter from version %s", apiVersion, version, current)
+		}
+
+		converted, err := converter.Convert(obj)
+		if err != nil {
+			return nil, fmt.Errorf("converting %s from %s to %s: %w", apiVersion, converter.FromVersion, converter.ToVersion, err)
+		}
+
+		current = converter.ToVersion
+		obj = converted
+		if group == "" {
+			obj["apiVersion"] = current
+		} else {
+			obj["apiVersion"] = group + "/" + current
+		}
+	}
+
+	return obj, nil
+}
+
+func findConverter(pipeline []VersionConverter, from string) (VersionConverter, bool) {
+	for _, converter := range pipeline {
+		if converter.FromVersion == from && converter.Convert != nil {
+			return converter, true
+		}
+	}
+	return VersionConverter{}, false
+}
+
+func splitAPIVersion(apiVersion string) (string, string) {
+	i := strings.LastIndex(apiVersion, "/")
+	if i < 0 {
+		return "", apiVersion
+	}
+	return apiVersion[:i], apiVersion[i+1:]
+}
@@ -0,0 +1,124 @@
+package compat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// rename moves the value at from to to, the change the versions of the gadget made.
+func rename(from, to string) func(map[string]interface{}) (map[string]interface{}, error) {
+	return func(obj map[string]interface{}) (map[string]interface{}, error) {
+		spec := data.Object(obj).Map("spec")
+		spec[to] = spec[from]
+		delete(spec, from)
+		return obj, nil
+	}
+}
+
+var gadgetPipeline = []VersionConverter{
+	{FromVersion: "v1beta1", ToVersion: "v1", Convert: rename("size", "replicas")},
+	{FromVersion: "v1alpha1", ToVersion: "v1beta1", Convert: rename("count", "size")},
+}
+
+func gadget(apiVersion, field string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "Gadget",
+		"spec":       map[string]interface{}{field: int64(3)},
+	}
+}
+
+func TestMigrateTwoSteps(t *testing.T) {
+	migrated, err := Migrate(gadgetPipeline, gadget("example.com/v1alpha1", "count"), "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiVersion := migrated["apiVersion"]; apiVersion != "example.com/v1" {
+		t.Errorf("got apiVersion %v, want example.com/v1", apiVersion)
+	}
+	if spec := data.Object(migrated).Map("spec"); spec["replicas"] != int64(3) || len(spec) != 1 {
+		t.Errorf("got spec %v, want count converted to size and then to replicas", spec)
+	}
+}
+
+func TestMigrateWithoutGroup(t *testing.T) {
+	migrated, err := Migrate(gadgetPipeline, gadget("v1beta1", "size"), "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiVersion := migrated["apiVersion"]; apiVersion != "v1" {
+		t.Errorf("got apiVersion %v, want v1", apiVersion)
+	}
+}
+
+func TestMigrateMissingConverter(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		pipeline []VersionConverter
+		obj      map[string]interface{}
+	}{
+		{name: "unknown version", pipeline: gadgetPipeline, obj: gadget("example.com/v0", "count")},
+		{name: "missing step", pipeline: gadgetPipeline[1:], obj: gadget("example.com/v1alpha1", "count")},
+		{
+			name: "cycle",
+			pipeline: []VersionConverter{
+				{FromVersion: "v1alpha1", ToVersion: "v1beta1", Convert: rename("count", "size")},
+				{FromVersion: "v1beta1", ToVersion: "v1alpha1", Convert: rename("size", "count")},
+			},
+			obj: gadget("example.com/v1alpha1", "count"),
+		},
+	} {
+		if migrated, err := Migrate(test.pipeline, test.obj, "v1"); err == nil {
+			t.Errorf("%s: got %v, want an error", test.name, migrated)
+		}
+	}
+}
+
+func TestMigrateConverterError(t *testing.T) {
+	pipeline := []VersionConverter{{
+		FromVersion: "v1beta1",
+		ToVersion:   "v1",
+		Convert: func(map[string]interface{}) (map[string]interface{}, error) {
+			return nil, errors.New("size is not a number")
+		},
+	}}
+	if _, err := Migrate(pipeline, gadget("example.com/v1beta1", "size"), "v1"); err == nil {
+		t.Error("got no error from a failing converter")
+	}
+}
+
+// gadgetStore returns one gadget stored with apiVersion.
+type gadgetStore struct {
+	types.Store
+	obj map[string]interface{}
+}
+
+func (g *gadgetStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	return types.APIObject{ID: id, Object: &unstructured.Unstructured{Object: g.obj}}, nil
+}
+
+func TestPipelineStoreReturnsTheServedVersion(t *testing.T) {
+	store := NewPipelineStore(&gadgetStore{obj: gadget("example.com/v1alpha1", "count")}, gadgetPipeline, "v1")
+	obj, err := store.ByID(&types.APIRequest{}, nil, "default/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiVersion := obj.Data().String("apiVersion"); apiVersion != "example.com/v1" {
+		t.Errorf("got apiVersion %s, want the served version", apiVersion)
+	}
+	if obj.ID != "default/a" {
+		t.Errorf("got ID %q, want the ID kept", obj.ID)
+	}
+
+	store = NewPipelineStore(&gadgetStore{obj: gadget("example.com/v0", "count")}, gadgetPipeline, "v1")
+	_, err = store.ByID(&types.APIRequest{}, nil, "default/a")
+	if apiErr, ok := err.(*apierror.APIError); !ok || apiErr.Code != validation.ServerError {
+		t.Errorf("got %v, want a server error for an object that can't be migrated", err)
+	}
+}
@@ -0,0 +1,401 @@
+// Package conformance is a test suite for types.Store implementations. It checks the behavior clients of steve
+// rely on, whatever store serves a schema: ids are namespace/name, lists are scoped to the namespace of the
+// request and filtered by labelSelector, continue tokens page through every object exactly once, watches send
+// create and remove events and close when the request is done, and missing or conflicting objects fail with the
+// matching API errors.
+//
+//	func TestMemoryStore(t *testing.T) {
+//		conformance.Run(t, func(t *testing.T) conformance.Setup {
+//			return conformance.Setup{
+//				Store:  memory.NewMemoryStore(),
+//				Schema: schema,
+//			}
+//		})
+//	}
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// eventTimeout is how long a watch may take to send an event or close.
+const eventTimeout = 10 * time.Second
+
+// Setup is a store under test. Schema must be namespaced. The store must start without objects in the namespaces
+// of the suite, conformance-a and conformance-b.
+type Setup struct {
+	Store  types.Store
+	Schema *types.APISchema
+	// NewRequest builds the requests of the suite, nil uses DefaultRequest
+	NewRequest func(ctx context.Context, method, namespace string, query url.Values) *types.APIRequest
+}
+
+// Factory returns a new store for every test of the suite.
+type Factory func(t *testing.T) Setup
+
+// DefaultRequest returns a request for the schema made by an admin user.
+func DefaultRequest(schema *types.APISchema) func(ctx context.Context, method, namespace string, query url.Values) *types.APIRequest {
+	return func(ctx context.Context, method, namespace string, query url.Values) *types.APIRequest {
+		req := httptest.NewRequest(method, "/v1/"+schema.ID+"?"+query.Encode(), nil)
+		req = req.WithContext(request.WithUser(ctx, &user.DefaultInfo{
+			Name:   "admin",
+			Groups: []string{"system:masters", "system:authenticated"},
+		}))
+
+		schemas := types.EmptyAPISchemas()
+		_ = schemas.AddSchema(*schema)
+		return &types.APIRequest{
+			Type:      schema.ID,
+			Namespace: namespace,
+			Method:    method,
+			Schema:    schema,
+			Schemas:   schemas,
+			Request:   req,
+			Response:  httptest.NewRecorder(),
+		}
+	}
+}
+
+const (
+	namespaceA = "conformance-a"
+	namespaceB = "conformance-b"
+)
+
+// Run runs every test of the suite against stores from factory.
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s *suite)
+	}{
+		{"CreateGetRoundTrip", testCreateGet},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"NamespaceScoping", testNamespaceScoping},
+		{"SelectorFiltering", testSelectorFiltering},
+		{"Pagination", testPagination},
+		{"Watch", testWatch},
+		{"WatchClosesOnCancel", testWatchCancel},
+		{"Errors", testErrors},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup := factory(t)
+			if setup.NewRequest == nil {
+				setup.NewRequest = DefaultRequest(setup.Schema)
+			}
+			tt.test(t, &suite{
+				Setup: setup,
+				ctx:   context.Background(),
+			})
+		})
+	}
+}
+
+type suite struct {
+	Setup
+	ctx context.Context
+}
+
+func (s *suite) request(method, namespace string, query url.Values) *types.APIRequest {
+	return s.NewRequest(s.ctx, method, namespace, query)
+}
+
+func (s *suite) object(namespace, name string, labels map[string]string) types.APIObject {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return types.APIObject{
+		Type:   s.Schema.ID,
+		Object: obj.Object,
+	}
+}
+
+func (s *suite) create(t *testing.T, namespace, name string, labels map[string]string) types.APIObject {
+	t.Helper()
+	obj, err := s.Store.Create(s.request(http.MethodPost, namespace, nil), s.Schema, s.object(namespace, name, labels))
+	if err != nil {
+		t.Fatalf("create %s/%s: %v", namespace, name, err)
+	}
+	return obj
+}
+
+func (s *suite) list(t *testing.T, namespace string, query url.Values) types.APIObjectList {
+	t.Helper()
+	list, err := s.Store.List(s.request(http.MethodGet, namespace, query), s.Schema)
+	if err != nil {
+		t.Fatalf("list %s: %v", namespace, err)
+	}
+	return list
+}
+
+func ids(list types.APIObjectList) map[string]bool {
+	result := map[string]bool{}
+	for _, obj := range list.Objects {
+		result[obj.ID] = true
+	}
+	return result
+}
+
+func expectIDs(t *testing.T, got map[string]bool, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("expected ids %v, got %v", want, got)
+		return
+	}
+	for _, id := range want {
+		if !got[id] {
+			t.Errorf("expected ids %v, got %v", want, got)
+			return
+		}
+	}
+}
+
+func expectStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	if err == nil {
+		t.Errorf("expected an error with status %d, got none", status)
+		return
+	}
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok {
+		t.Errorf("expected an *apierror.APIError with status %d, got %T: %v", status, err, err)
+		return
+	}
+	if apiErr.Code.Status != status {
+		t.Errorf("expected status %d, got %d: %v", status, apiErr.Code.Status, err)
+	}
+}
+
+// expectDeleted fails unless err is the result of a successful delete. Stores answer the delete of an object that
+// is gone right away either without an error or with an error with status 204, which is sent as an empty response.
+func expectDeleted(t *testing.T, err error) {
+	t.Helper()
+	switch e := err.(type) {
+	case nil:
+		return
+	case validation.ErrorCode:
+		if e.Status == http.StatusNoContent {
+			return
+		}
+	case *apierror.APIError:
+		if e.Code.Status == http.StatusNoContent {
+			return
+		}
+	}
+	t.Fatalf("delete: %v", err)
+}
+
+func testCreateGet(t *testing.T, s *suite) {
+	created := s.create(t, namespaceA, "round-trip", map[string]string{"app": "conformance"})
+	if created.ID != namespaceA+"/round-trip" {
+		t.Errorf("expected id %s/round-trip from create, got %q", namespaceA, created.ID)
+	}
+
+	obj, err := s.Store.ByID(s.request(http.MethodGet, namespaceA, nil), s.Schema, "round-trip")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if obj.ID != namespaceA+"/round-trip" {
+		t.Errorf("expected id %s/round-trip from get, got %q", namespaceA, obj.ID)
+	}
+	if got := obj.Data().String("metadata", "labels", "app"); got != "conformance" {
+		t.Errorf("expected label app=conformance, got %q", got)
+	}
+	if obj.Data().String("metadata", "resourceVersion") == "" {
+		t.Error("expected a resourceVersion")
+	}
+}
+
+func testUpdate(t *testing.T, s *suite) {
+	created := s.create(t, namespaceA, "update", nil)
+
+	input := created.Data()
+	input.SetNested("updated", "metadata", "labels", "state")
+	updated, err := s.Store.Update(s.request(http.MethodPut, namespaceA, nil), s.Schema, types.APIObject{
+		Type:   s.Schema.ID,
+		Object: map[string]interface{}(input),
+	}, "update")
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Data().String("metadata", "resourceVersion") == created.Data().String("metadata", "resourceVersion") {
+		t.Error("expected update to change the resourceVersion")
+	}
+
+	obj, err := s.Store.ByID(s.request(http.MethodGet, namespaceA, nil), s.Schema, "update")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got := obj.Data().String("metadata", "labels", "state"); got != "updated" {
+		t.Errorf("expected label state=updated after update, got %q", got)
+	}
+}
+
+func testDelete(t *testing.T, s *suite) {
+	s.create(t, namespaceA, "delete", nil)
+
+	_, err := s.Store.Delete(s.request(http.MethodDelete, namespaceA, nil), s.Schema, "delete")
+	expectDeleted(t, err)
+
+	_, err = s.Store.ByID(s.request(http.MethodGet, namespaceA, nil), s.Schema, "delete")
+	expectStatus(t, err, http.StatusNotFound)
+}
+
+func testNamespaceScoping(t *testing.T, s *suite) {
+	s.create(t, namespaceA, "scoped", nil)
+	s.create(t, namespaceB, "scoped", nil)
+
+	expectIDs(t, ids(s.list(t, namespaceA, nil)), namespaceA+"/scoped")
+	expectIDs(t, ids(s.list(t, namespaceB, nil)), namespaceB+"/scoped")
+
+	all := ids(s.list(t, "", nil))
+	if !all[namespaceA+"/scoped"] || !all[namespaceB+"/scoped"] {
+		t.Errorf("expected a list without namespace to include both namespaces, got %v", all)
+	}
+
+	_, err := s.Store.ByID(s.request(http.MethodGet, namespaceB, nil), s.Schema, "missing")
+	expectStatus(t, err, http.StatusNotFound)
+}
+
+func testSelectorFiltering(t *testing.T, s *suite) {
+	s.create(t, namespaceA, "selected", map[string]string{"conformance": "selected"})
+	s.create(t, namespaceA, "other", map[string]string{"conformance": "other"})
+
+	list := s.list(t, namespaceA, url.Values{"labelSelector": []string{"conformance=selected"}})
+	expectIDs(t, ids(list), namespaceA+"/selected")
+
+	list = s.list(t, namespaceA, url.Values{"labelSelector": []string{"conformance in (selected,other)"}})
+	expectIDs(t, ids(list), namespaceA+"/selected", namespaceA+"/other")
+}
+
+// testPagination allows stores to ignore limit, a single page must then hold every object.
+func testPagination(t *testing.T, s *suite) {
+	var want []string
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("page-%d", i)
+		s.create(t, namespaceA, name, nil)
+		want = append(want, namespaceA+"/"+name)
+	}
+
+	seen := map[string]bool{}
+	cont := ""
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatalf("pagination did not end after %d pages", pages)
+		}
+		query := url.Values{"limit": []string{"2"}}
+		if cont != "" {
+			query.Set("continue", cont)
+		}
+		list := s.list(t, namespaceA, query)
+		for _, obj := range list.Objects {
+			if seen[obj.ID] {
+				t.Errorf("object %s returned on more than one page", obj.ID)
+			}
+			seen[obj.ID] = true
+		}
+		if list.Continue == "" {
+			break
+		}
+		cont = list.Continue
+	}
+	expectIDs(t, seen, want...)
+}
+
+func (s *suite) watch(t *testing.T, ctx context.Context, namespace string) chan types.APIEvent {
+	t.Helper()
+	apiOp := s.NewRequest(ctx, http.MethodGet, namespace, url.Values{"watch": []string{"true"}})
+	c, err := s.Store.Watch(apiOp, s.Schema, types.WatchRequest{})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if c == nil {
+		t.Fatal("watch returned no channel")
+	}
+	return c
+}
+
+// nextEvent returns the next event named name for the object with id, skipping others such as bookmarks.
+func nextEvent(t *testing.T, c chan types.APIEvent, name, id string) types.APIEvent {
+	t.Helper()
+	timeout := time.After(eventTimeout)
+	for {
+		select {
+		case event, ok := <-c:
+			if !ok {
+				t.Fatalf("watch closed before %s of %s", name, id)
+			}
+			if event.Error != nil {
+				t.Fatalf("watch error before %s of %s: %v", name, id, event.Error)
+			}
+			if event.Name == name && event.Object.ID == id {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no %s event for %s within %s", name, id, eventTimeout)
+		}
+	}
+}
+
+func testWatch(t *testing.T, s *suite) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := s.watch(t, ctx, namespaceA)
+
+	s.create(t, namespaceA, "watched", nil)
+	created := nextEvent(t, c, types.CreateAPIEvent, namespaceA+"/watched")
+	if created.Revision == "" {
+		t.Error("expected the create event to have a revision")
+	}
+
+	_, err := s.Store.Delete(s.request(http.MethodDelete, namespaceA, nil), s.Schema, "watched")
+	expectDeleted(t, err)
+	removed := nextEvent(t, c, types.RemoveAPIEvent, namespaceA+"/watched")
+	if removed.Object.Object == nil {
+		t.Error("expected the remove event to carry the deleted object")
+	}
+}
+
+func testWatchCancel(t *testing.T, s *suite) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := s.watch(t, ctx, namespaceA)
+	cancel()
+
+	timeout := time.After(eventTimeout)
+	for {
+		select {
+		case _, ok := <-c:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("watch did not close within %s of the request being canceled", eventTimeout)
+		}
+	}
+}
+
+func testErrors(t *testing.T, s *suite) {
+	_, err := s.Store.ByID(s.request(http.MethodGet, namespaceA, nil), s.Schema, "missing")
+	expectStatus(t, err, http.StatusNotFound)
+
+	_, err = s.Store.Delete(s.request(http.MethodDelete, namespaceA, nil), s.Schema, "missing")
+	expectStatus(t, err, http.StatusNotFound)
+
+	s.create(t, namespaceA, "duplicate", nil)
+	_, err = s.Store.Create(s.request(http.MethodPost, namespaceA, nil), s.Schema, s.object(namespaceA, "duplicate", nil))
+	expectStatus(t, err, validation.Conflict.Status)
+}
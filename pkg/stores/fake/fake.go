@@ -0,0 +1,218 @@
+// Package fake provides a types.Store for tests that records every call and returns canned responses.
+package fake
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+const (
+	ByID   = "ByID"
+	List   = "List"
+	Create = "Create"
+	Update = "Update"
+	Delete = "Delete"
+	Watch  = "Watch"
+)
+
+// Call is a recorded call to the store. Args are the arguments after the request and schema: the id for ByID
+// and Delete, the data for Create, the data and id for Update and the watch request for Watch.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+type objectResponse struct {
+	obj types.APIObject
+	err error
+}
+
+// FakeStore implements types.Store. Methods without a canned response return a zero value, ByID and Delete
+// without a response for the id return a not found error.
+type FakeStore struct {
+	lock     sync.Mutex
+	calls    []Call
+	byID     map[string]objectResponse
+	delete   map[string]objectResponse
+	list     *types.APIObjectList
+	listErr  error
+	create   *objectResponse
+	update   *objectResponse
+	watch    chan types.APIEvent
+	watchErr error
+}
+
+var _ types.Store = &FakeStore{}
+
+func NewFakeStore() *FakeStore {
+	return &FakeStore{
+		byID:   map[string]objectResponse{},
+		delete: map[string]objectResponse{},
+	}
+}
+
+// OnByID sets the response of ByID for id.
+func (f *FakeStore) OnByID(id string, obj types.APIObject, err error) *FakeStore {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.byID[id] = objectResponse{obj: obj, err: err}
+	return f
+}
+
+// OnList sets the response of List.
+func (f *FakeStore) OnList(list types.APIObjectList, err error) *FakeStore {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.list = &list
+	f.listErr = err
+	return f
+}
+
+// OnCreate sets the response of Create, without one Create returns its input.
+func (f *FakeStore) OnCreate(obj types.APIObject, err error) *FakeStore {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.create = &objectResponse{obj: obj, err: err}
+	return f
+}
+
+// OnUpdate sets the response of Update, without one Update returns its input.
+func (f *FakeStore) OnUpdate(obj types.APIObject, err error) *FakeStore {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.update = &objectResponse{obj: obj, err: err}
+	return f
+}
+
+// OnDelete sets the response of Delete for id.
+func (f *FakeStore) OnDelete(id string, obj types.APIObject, err error) *FakeStore {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.delete[id] = objectResponse{obj: obj, err: err}
+	return f
+}
+
+// OnWatch sets the channel returned by Watch, the test owns it and closes it to end the watch.
+func (f *FakeStore) OnWatch(c chan types.APIEvent, err error) *FakeStore {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.watch = c
+	f.watchErr = err
+	return f
+}
+
+// Calls returns the calls made so far, in order.
+func (f *FakeStore) Calls() []Call {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Called returns true if method was called with args. Only the given args are compared, so Called(ByID) is
+// true for any call to ByID and Called(Update, data) for an update of data with any id.
+func (f *FakeStore) Called(method string, args ...interface{}) bool {
+	for _, call := range f.Calls() {
+		if call.Method == method && argsMatch(args, call.Args) {
+			return true
+		}
+	}
+	return false
+}
+
+func argsMatch(args, callArgs []interface{}) bool {
+	if len(args) > len(callArgs) {
+		return false
+	}
+	for i, arg := range args {
+		if !reflect.DeepEqual(arg, callArgs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// AssertCalled fails the test if method was not called with args, see Called.
+func (f *FakeStore) AssertCalled(t testing.TB, method string, args ...interface{}) {
+	t.Helper()
+	if !f.Called(method, args...) {
+		t.Errorf("expected call %s%v, got calls %v", method, args, f.Calls())
+	}
+}
+
+// AssertNotCalled fails the test if method was called at all.
+func (f *FakeStore) AssertNotCalled(t testing.TB, method string) {
+	t.Helper()
+	if f.Called(method) {
+		t.Errorf("unexpected call to %s, got calls %v", method, f.Calls())
+	}
+}
+
+func (f *FakeStore) record(method string, args ...interface{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls = append(f.calls, Call{
+		Method: method,
+		Args:   args,
+	})
+}
+
+func (f *FakeStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	f.record(ByID, id)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if resp, ok := f.byID[id]; ok {
+		return resp.obj, resp.err
+	}
+	return types.APIObject{}, validation.NotFound
+}
+
+func (f *FakeStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	f.record(List)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.list == nil {
+		return types.APIObjectList{}, f.listErr
+	}
+	return *f.list, f.listErr
+}
+
+func (f *FakeStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	f.record(Create, data)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.create == nil {
+		return data, nil
+	}
+	return f.create.obj, f.create.err
+}
+
+func (f *FakeStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	f.record(Update, data, id)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.update == nil {
+		return data, nil
+	}
+	return f.update.obj, f.update.err
+}
+
+func (f *FakeStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	f.record(Delete, id)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if resp, ok := f.delete[id]; ok {
+		return resp.obj, resp.err
+	}
+	return types.APIObject{}, validation.NotFound
+}
+
+func (f *FakeStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	f.record(Watch, w)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.watch, f.watchErr
+}
@@ -0,0 +1,139 @@
+package fake
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// recordingT records the failures of the assertion helpers instead of failing the test.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func pod(id string) types.APIObject {
+	return types.APIObject{Type: "pod", ID: id}
+}
+
+func TestCannedResponses(t *testing.T) {
+	boom := errors.New("boom")
+	events := make(chan types.APIEvent)
+	f := NewFakeStore().
+		OnByID("pod-1", pod("pod-1"), nil).
+		OnList(types.APIObjectList{Objects: []types.APIObject{pod("pod-1")}}, nil).
+		OnCreate(pod("created"), nil).
+		OnUpdate(types.APIObject{}, boom).
+		OnDelete("pod-1", pod("pod-1"), nil).
+		OnWatch(events, nil)
+
+	if obj, err := f.ByID(nil, nil, "pod-1"); err != nil || obj.ID != "pod-1" {
+		t.Errorf("ByID: got %v %v", obj, err)
+	}
+	if list, err := f.List(nil, nil); err != nil || len(list.Objects) != 1 {
+		t.Errorf("List: got %v %v", list, err)
+	}
+	if obj, err := f.Create(nil, nil, pod("input")); err != nil || obj.ID != "created" {
+		t.Errorf("Create: got %v %v", obj, err)
+	}
+	if _, err := f.Update(nil, nil, pod("input"), "pod-1"); err != boom {
+		t.Errorf("Update: got %v, want the canned error", err)
+	}
+	if obj, err := f.Delete(nil, nil, "pod-1"); err != nil || obj.ID != "pod-1" {
+		t.Errorf("Delete: got %v %v", obj, err)
+	}
+	if c, err := f.Watch(nil, nil, types.WatchRequest{}); err != nil || c != events {
+		t.Errorf("Watch: got %v %v, want the canned channel", c, err)
+	}
+}
+
+func TestDefaultResponses(t *testing.T) {
+	f := NewFakeStore()
+
+	if _, err := f.ByID(nil, nil, "missing"); err != validation.NotFound {
+		t.Errorf("ByID: got %v, want not found", err)
+	}
+	if _, err := f.Delete(nil, nil, "missing"); err != validation.NotFound {
+		t.Errorf("Delete: got %v, want not found", err)
+	}
+	if list, err := f.List(nil, nil); err != nil || len(list.Objects) != 0 {
+		t.Errorf("List: got %v %v, want an empty list", list, err)
+	}
+	if obj, err := f.Create(nil, nil, pod("input")); err != nil || obj.ID != "input" {
+		t.Errorf("Create: got %v %v, want the input", obj, err)
+	}
+	if obj, err := f.Update(nil, nil, pod("input"), "input"); err != nil || obj.ID != "input" {
+		t.Errorf("Update: got %v %v, want the input", obj, err)
+	}
+}
+
+func TestCallsAreRecordedInOrder(t *testing.T) {
+	f := NewFakeStore()
+	_, _ = f.List(nil, nil)
+	_, _ = f.ByID(nil, nil, "pod-1")
+	_, _ = f.Update(nil, nil, pod("pod-2"), "pod-2")
+
+	calls := f.Calls()
+	if len(calls) != 3 || calls[0].Method != List || calls[1].Method != ByID || calls[2].Method != Update {
+		t.Fatalf("got %v, want List, ByID and Update", calls)
+	}
+	if len(calls[2].Args) != 2 || calls[2].Args[1] != "pod-2" {
+		t.Errorf("got args %v, want the data and the id", calls[2].Args)
+	}
+}
+
+func TestAssertCalled(t *testing.T) {
+	f := NewFakeStore()
+	_, _ = f.ByID(nil, nil, "pod-1")
+	_, _ = f.Update(nil, nil, pod("pod-2"), "pod-2")
+
+	passing := &recordingT{TB: t}
+	f.AssertCalled(passing, ByID)
+	f.AssertCalled(passing, ByID, "pod-1")
+	f.AssertCalled(passing, Update, pod("pod-2"))
+	f.AssertCalled(passing, Update, pod("pod-2"), "pod-2")
+	if len(passing.errors) != 0 {
+		t.Errorf("AssertCalled failed for calls that were made: %v", passing.errors)
+	}
+
+	for _, test := range []struct {
+		method string
+		args   []interface{}
+	}{
+		{method: List},
+		{method: ByID, args: []interface{}{"pod-2"}},
+		{method: Update, args: []interface{}{pod("pod-2"), "pod-3"}},
+		{method: Delete, args: []interface{}{"pod-1"}},
+	} {
+		failing := &recordingT{TB: t}
+		f.AssertCalled(failing, test.method, test.args...)
+		if len(failing.errors) != 1 {
+			t.Errorf("AssertCalled(%s, %v) did not fail", test.method, test.args)
+		}
+	}
+}
+
+func TestAssertNotCalled(t *testing.T) {
+	f := NewFakeStore()
+	_, _ = f.Delete(nil, nil, "pod-1")
+
+	passing := &recordingT{TB: t}
+	f.AssertNotCalled(passing, Create)
+	if len(passing.errors) != 0 {
+		t.Errorf("AssertNotCalled failed for a method that was not called: %v", passing.errors)
+	}
+
+	failing := &recordingT{TB: t}
+	f.AssertNotCalled(failing, Delete)
+	if len(failing.errors) != 1 {
+		t.Error("AssertNotCalled did not fail for a method that was called")
+	}
+}
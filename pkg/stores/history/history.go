@@ -0,0 +1,216 @@
+// Package history records the changes made to objects through steve, who changed what and when, to an external
+// sink such as a database backing a history view.
+package history
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// DefaultBufferSize is the number of change records held for the sink when NewStore is given no buffer size.
+const DefaultBufferSize = 1000
+
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// ignoredPaths change on every write or are added by steve, they are left out of the diff.
+var ignoredPaths = map[string]bool{
+	"metadata.resourceVersion": true,
+	"metadata.managedFields":   true,
+	"metadata.generation":      true,
+	"metadata.state":           true,
+	"metadata.relationships":   true,
+	"metadata.fields":          true,
+}
+
+// Change is a field that differs between the object before and after a write, Path is in dot notation. A field
+// that was added has no Old value and a field that was removed no New value.
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ChangeRecord is a successful create, update or delete. Changes lists every field of a create, the fields an
+// update changed and nothing for a delete.
+type ChangeRecord struct {
+	Operation       string    `json:"operation"`
+	SchemaID        string    `json:"schemaId"`
+	Namespace       string    `json:"namespace,omitempty"`
+	Name            string    `json:"name"`
+	ResourceVersion string    `json:"resourceVersion,omitempty"`
+	User            string    `json:"user,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+	Changes         []Change  `json:"changes,omitempty"`
+}
+
+// Sink saves change records. Record is called from a single goroutine in the order of the writes.
+type Sink interface {
+	Record(record ChangeRecord) error
+}
+
+// Store emits a ChangeRecord to a Sink for every write that succeeds. Records are buffered and handed to the sink
+// in the background so a slow sink never holds up a write, once the buffer is full records are dropped and
+// counted in Dropped. An update costs an extra get of the object before it is written, for the diff.
+type Store struct {
+	types.Store
+	records chan ChangeRecord
+	dropped int64
+}
+
+// NewStore wraps store, for use in Template.StoreFactory:
+//
+//	StoreFactory: func(store types.Store) types.Store { return history.NewStore(store, sink, 0) },
+func NewStore(store types.Store, sink Sink, bufferSize int) *Store {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	s := &Store{
+		Store:   store,
+		records: make(chan ChangeRecord, bufferSize),
+	}
+	go func() {
+		for record := range s.records {
+			if err := sink.Record(record); err != nil {
+				logrus.Errorf("failed to record %s of %s %s/%s: %v", record.Operation, record.SchemaID, record.Namespace, record.Name, err)
+			}
+		}
+	}()
+	return s
+}
+
+// Dropped is the number of records dropped because the sink did not keep up.
+func (s *Store) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	obj, err := s.Store.Create(apiOp, schema, data)
+	if err == nil {
+		s.emit(apiOp, schema, OperationCreate, obj, nil, obj.Data())
+	}
+	return obj, err
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	var before map[string]interface{}
+	if old, err := s.Store.ByID(apiOp, schema, id); err == nil {
+		before = old.Data()
+	}
+
+	obj, err := s.Store.Update(apiOp, schema, data, id)
+	if err == nil {
+		s.emit(apiOp, schema, OperationUpdate, obj, before, obj.Data())
+	}
+	return obj, err
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.Delete(apiOp, schema, id)
+	if err == nil || isNoContent(err) {
+		record := s.record(apiOp, schema, OperationDelete, obj)
+		if record.Name == "" {
+			record.Name = id
+			if i := strings.LastIndex(id, "/"); i >= 0 {
+				record.Namespace, record.Name = id[:i], id[i+1:]
+			}
+		}
+		s.send(record)
+	}
+	return obj, err
+}
+
+func (s *Store) emit(apiOp *types.APIRequest, schema *types.APISchema, operation string, obj types.APIObject, before, after map[string]interface{}) {
+	record := s.record(apiOp, schema, operation, obj)
+	record.Changes = Diff(before, after)
+	s.send(record)
+}
+
+func (s *Store) record(apiOp *types.APIRequest, schema *types.APISchema, operation string, obj types.APIObject) ChangeRecord {
+	record := ChangeRecord{
+		Operation: operation,
+		SchemaID:  schema.ID,
+		Namespace: apiOp.Namespace,
+		Timestamp: time.Now().UTC(),
+	}
+	if obj.Object != nil {
+		metadata := obj.Data().Map("metadata")
+		record.Namespace = metadata.String("namespace")
+		record.Name = metadata.String("name")
+		record.ResourceVersion = metadata.String("resourceVersion")
+	}
+	if user, ok := request.UserFrom(apiOp.Context()); ok {
+		record.User = user.GetName()
+	}
+	return record
+}
+
+func (s *Store) send(record ChangeRecord) {
+	select {
+	case s.records <- record:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+// Diff returns the fields that differ between before and after, sorted by path. Maps are compared field by field
+// and every other value, lists included, as a whole.
+func Diff(before, after map[string]interface{}) []Change {
+	var changes []Change
+	diff("", before, after, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diff(prefix string, before, after map[string]interface{}, changes *[]Change) {
+	for key, old := range before {
+		path := join(prefix, key)
+		if ignoredPaths[path] {
+			continue
+		}
+		if value, ok := after[key]; ok {
+			oldMap, oldIsMap := old.(map[string]interface{})
+			newMap, newIsMap := value.(map[string]interface{})
+			if oldIsMap && newIsMap {
+				diff(path, oldMap, newMap, changes)
+			} else if !reflect.DeepEqual(old, value) {
+				*changes = append(*changes, Change{Path: path, Old: old, New: value})
+			}
+			continue
+		}
+		*changes = append(*changes, Change{Path: path, Old: old})
+	}
+	for key, value := range after {
+		path := join(prefix, key)
+		if _, ok := before[key]; !ok && !ignoredPaths[path] {
+			*changes = append(*changes, Change{Path: path, New: value})
+		}
+	}
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// isNoContent is the error the proxy store returns for a delete that removed the object right away.
+func isNoContent(err error) bool {
+	code, ok := err.(validation.ErrorCode)
+	return ok && code.Status == http.StatusNoContent
+}
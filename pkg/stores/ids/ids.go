@@ -0,0 +1,148 @@
+// Package ids lets a schema use an identity other than namespace/name in its URLs and the id of its objects.
+package ids
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// IDResolver maps between the id of an object in the API and its Kubernetes namespace and name.
+type IDResolver interface {
+	// ToInternalName returns the namespace and name of the object with the given id.
+	ToInternalName(id string) (namespace string, name string, err error)
+	// FromObject returns the id of obj.
+	FromObject(obj types.APIObject) string
+}
+
+// DefaultResolver is namespace/name for namespaced objects and name otherwise.
+type DefaultResolver struct{}
+
+func (DefaultResolver) ToInternalName(id string) (string, string, error) {
+	if i := strings.Index(id, "/"); i >= 0 {
+		return id[:i], id[i+1:], nil
+	}
+	return "", id, nil
+}
+
+func (DefaultResolver) FromObject(obj types.APIObject) string {
+	m, err := meta.Accessor(obj.Object)
+	if err != nil {
+		return obj.ID
+	}
+	if m.GetNamespace() == "" {
+		return m.GetName()
+	}
+	return m.GetNamespace() + "/" + m.GetName()
+}
+
+// Store resolves the ids of ByID, Update and Delete to the namespace and name the wrapped store expects and sets
+// the id of every object it returns with the resolver.
+type Store struct {
+	types.Store
+	resolver IDResolver
+}
+
+func NewStore(store types.Store, resolver IDResolver) types.Store {
+	return &Store{
+		Store:    store,
+		resolver: resolver,
+	}
+}
+
+// internal returns a request for the namespace of id and the name the wrapped store expects.
+func (s *Store) internal(apiOp *types.APIRequest, id string) (*types.APIRequest, string, error) {
+	namespace, name, err := s.resolver.ToInternalName(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if namespace == "" {
+		return apiOp, name, nil
+	}
+	op := apiOp.Clone()
+	op.Namespace = namespace
+	return op, name, nil
+}
+
+func (s *Store) withID(obj types.APIObject) types.APIObject {
+	if obj.Object != nil {
+		obj.ID = s.resolver.FromObject(obj)
+	}
+	return obj
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	op, name, err := s.internal(apiOp, id)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	obj, err := s.Store.ByID(op, schema, name)
+	return s.withID(obj), err
+}
+
+// List sets the id of every object. Two objects with the same id mean the resolver is not unique, that is
+// logged and returned to the client as a Warning header.
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := s.Store.List(apiOp, schema)
+	if err != nil {
+		return list, err
+	}
+
+	seen := map[string]bool{}
+	for i := range list.Objects {
+		list.Objects[i] = s.withID(list.Objects[i])
+		id := list.Objects[i].ID
+		if seen[id] {
+			warning := fmt.Sprintf("more than one %s maps to the id %s", schema.ID, id)
+			logrus.Warn(warning)
+			if apiOp.Response != nil {
+				apiOp.Response.Header().Add("Warning", fmt.Sprintf("299 - %q", warning))
+			}
+		}
+		seen[id] = true
+	}
+	return list, nil
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	obj, err := s.Store.Create(apiOp, schema, data)
+	return s.withID(obj), err
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	op, name, err := s.internal(apiOp, id)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	obj, err := s.Store.Update(op, schema, data, name)
+	return s.withID(obj), err
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	op, name, err := s.internal(apiOp, id)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	obj, err := s.Store.Delete(op, schema, name)
+	return s.withID(obj), err
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c, err := s.Store.Watch(apiOp, schema, w)
+	if err != nil || c == nil {
+		return c, err
+	}
+
+	result := make(chan types.APIEvent)
+	go func() {
+		defer close(result)
+		for event := range c {
+			event.Object = s.withID(event.Object)
+			result <- event
+		}
+	}()
+	return result, nil
+}
@@ -0,0 +1,200 @@
+// Package mapperguard keeps a schema mapper that fails on a malformed object, for example a third-party CRD missing a
+// field the mapper expects, from breaking the whole response the object is part of.
+package mapperguard
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+)
+
+// Policy is what happens to an object the mapper of its schema fails on.
+type Policy string
+
+const (
+	// Raw returns the object as it was before the mapper ran and logs a warning. It is the default.
+	Raw Policy = "raw"
+	// Skip leaves the object out of lists and watches and logs a warning. A get returns the object raw.
+	Skip Policy = "skip"
+	// Fail fails the request that returns the object, a watch sends an error event in its place.
+	Fail Policy = "fail"
+)
+
+// ErrMapperFailed is returned for an object the mapper failed on under the Fail policy.
+var ErrMapperFailed = validation.ErrorCode{
+	Code:   "MapperFailed",
+	Status: http.StatusInternalServerError,
+}
+
+// Mapper wraps the mapper of a schema. A FromInternal that panics is recovered and the object is restored to what it
+// was before the mapper ran, so it is returned raw.
+type Mapper struct {
+	schemas.Mapper
+	schemaID string
+}
+
+// NewMapper returns mapper guarded, or nil for a nil mapper. A mapper that is already guarded is returned as is.
+func NewMapper(schemaID string, mapper schemas.Mapper) schemas.Mapper {
+	if mapper == nil {
+		return nil
+	}
+	if _, ok := mapper.(*Mapper); ok {
+		return mapper
+	}
+	return &Mapper{
+		Mapper:   mapper,
+		schemaID: schemaID,
+	}
+}
+
+func (m *Mapper) FromInternal(obj data.Object) {
+	if obj == nil {
+		return
+	}
+	original := copyValue(map[string]interface{}(obj)).(map[string]interface{})
+	if err := fromInternal(m.Mapper, obj); err != nil {
+		logrus.Warnf("returning %s %s unmapped: %v", m.schemaID, name(original), err)
+		for k := range obj {
+			delete(obj, k)
+		}
+		for k, v := range original {
+			obj[k] = v
+		}
+	}
+}
+
+// fromInternal runs the mapper on obj and returns the panic of the mapper as an error.
+func fromInternal(mapper schemas.Mapper, obj data.Object) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("mapper failed: %v", r)
+		}
+	}()
+	mapper.FromInternal(obj)
+	return nil
+}
+
+// Store applies the Skip and Fail policies to the objects it returns by trying the mapper of the schema on a copy of
+// each of them. The Raw policy needs no store, the guarded Mapper already returns the object raw.
+type Store struct {
+	types.Store
+	policy Policy
+}
+
+func NewStore(store types.Store, policy Policy) types.Store {
+	return &Store{
+		Store:  store,
+		policy: policy,
+	}
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	if err != nil || s.policy != Fail {
+		return obj, err
+	}
+	return obj, check(schema, obj)
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := s.Store.List(apiOp, schema)
+	if err != nil || schema.Mapper == nil {
+		return list, err
+	}
+
+	objects := list.Objects[:0]
+	for _, obj := range list.Objects {
+		if err := check(schema, obj); err != nil {
+			if s.policy == Fail {
+				return types.APIObjectList{}, err
+			}
+			logrus.Warnf("skipping %s %s in list: %v", schema.ID, obj.ID, err)
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	list.Objects = objects
+	return list, nil
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c, err := s.Store.Watch(apiOp, schema, w)
+	if err != nil || c == nil || schema.Mapper == nil {
+		return c, err
+	}
+
+	result := make(chan types.APIEvent)
+	go func() {
+		defer close(result)
+		for event := range c {
+			if event.Error == nil {
+				if err := check(schema, event.Object); err != nil {
+					if s.policy != Fail {
+						logrus.Warnf("skipping %s %s in watch: %v", schema.ID, event.Object.ID, err)
+						continue
+					}
+					event = types.APIEvent{
+						Name:  "resource.error",
+						Error: err,
+					}
+				}
+			}
+			result <- event
+		}
+	}()
+	return result, nil
+}
+
+// check runs the mapper of schema on a copy of obj.
+func check(schema *types.APISchema, obj types.APIObject) error {
+	mapper := schema.Mapper
+	if guarded, ok := mapper.(*Mapper); ok {
+		mapper = guarded.Mapper
+	}
+	if mapper == nil || obj.Object == nil {
+		return nil
+	}
+	original := obj.Data()
+	if original == nil {
+		return nil
+	}
+	if err := fromInternal(mapper, copyValue(map[string]interface{}(original)).(map[string]interface{})); err != nil {
+		return apierror.NewAPIError(ErrMapperFailed, fmt.Sprintf("%s %s: %v", schema.ID, obj.ID, err))
+	}
+	return nil
+}
+
+// copyValue copies the maps and slices of v, the mapper can change them in place.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			copied[k] = copyValue(val)
+		}
+		return copied
+	case data.Object:
+		return copyValue(map[string]interface{}(v))
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, val := range v {
+			copied[i] = copyValue(val)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+func name(obj data.Object) string {
+	if ns := obj.String("metadata", "namespace"); ns != "" {
+		return ns + "/" + obj.String("metadata", "name")
+	}
+	return obj.String("metadata", "name")
+}
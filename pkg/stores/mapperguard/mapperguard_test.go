@@ -0,0 +1,168 @@
+package mapperguard
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/fake"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas"
+)
+
+// sizeMapper copies spec.size to size, it marks the object and then panics when spec is missing, like a mapper
+// written for a CRD that doesn't expect a malformed object.
+type sizeMapper struct{}
+
+func (sizeMapper) FromInternal(obj data.Object) {
+	obj["mapped"] = true
+	obj["size"] = obj["spec"].(map[string]interface{})["size"]
+}
+
+func (sizeMapper) ToInternal(obj data.Object) error {
+	return nil
+}
+
+func (sizeMapper) ModifySchema(schema *schemas.Schema, all *schemas.Schemas) error {
+	return nil
+}
+
+func widgetData(id string, spec map[string]interface{}) data.Object {
+	obj := data.Object{
+		"metadata": map[string]interface{}{"name": id},
+	}
+	if spec != nil {
+		obj["spec"] = spec
+	}
+	return obj
+}
+
+func widget(id string, spec map[string]interface{}) types.APIObject {
+	return types.APIObject{Type: "widget", ID: id, Object: map[string]interface{}(widgetData(id, spec))}
+}
+
+func guardedSchema(store types.Store, policy Policy) *types.APISchema {
+	return &types.APISchema{
+		Schema: &schemas.Schema{
+			ID:     "widget",
+			Mapper: NewMapper("widget", sizeMapper{}),
+		},
+		Store: NewStore(store, policy),
+	}
+}
+
+func TestMapperRecoversAndReturnsTheObjectRaw(t *testing.T) {
+	m := NewMapper("widget", sizeMapper{})
+
+	good := widgetData("good", map[string]interface{}{"size": "large"})
+	m.FromInternal(good)
+	if good["size"] != "large" {
+		t.Errorf("got %v, want the mapped object", good)
+	}
+
+	bad := widgetData("bad", nil)
+	want := copyValue(map[string]interface{}(bad))
+	m.FromInternal(bad)
+	if !reflect.DeepEqual(map[string]interface{}(bad), want) {
+		t.Errorf("got %v, want the object as it was before the mapper ran %v", bad, want)
+	}
+}
+
+func TestNewMapper(t *testing.T) {
+	if NewMapper("widget", nil) != nil {
+		t.Error("got a mapper for a nil mapper")
+	}
+	m := NewMapper("widget", sizeMapper{})
+	if NewMapper("widget", m) != m {
+		t.Error("got a guarded mapper guarded again")
+	}
+}
+
+func objects() []types.APIObject {
+	return []types.APIObject{
+		widget("good", map[string]interface{}{"size": "large"}),
+		widget("bad", nil),
+	}
+}
+
+func TestSkipLeavesTheObjectOut(t *testing.T) {
+	store := fake.NewFakeStore().
+		OnList(types.APIObjectList{Objects: objects()}, nil).
+		OnByID("bad", widget("bad", nil), nil)
+	schema := guardedSchema(store, Skip)
+
+	list, err := schema.Store.List(nil, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Objects) != 1 || list.Objects[0].ID != "good" {
+		t.Errorf("got %v, want only the good object", list.Objects)
+	}
+	if _, ok := list.Objects[0].Data()["mapped"]; ok {
+		t.Error("the store ran the mapper on the object it returns, want it tried on a copy")
+	}
+
+	if obj, err := schema.Store.ByID(nil, schema, "bad"); err != nil || obj.ID != "bad" {
+		t.Errorf("got %v %v, want a get to return the object", obj, err)
+	}
+}
+
+func TestFailFailsTheRequest(t *testing.T) {
+	store := fake.NewFakeStore().
+		OnList(types.APIObjectList{Objects: objects()}, nil).
+		OnByID("bad", widget("bad", nil), nil).
+		OnByID("good", widget("good", map[string]interface{}{"size": "large"}), nil)
+	schema := guardedSchema(store, Fail)
+
+	_, err := schema.Store.List(nil, schema)
+	expectMapperFailed(t, err)
+	_, err = schema.Store.ByID(nil, schema, "bad")
+	expectMapperFailed(t, err)
+	if _, err := schema.Store.ByID(nil, schema, "good"); err != nil {
+		t.Errorf("got %v for the good object", err)
+	}
+}
+
+func expectMapperFailed(t *testing.T, err error) {
+	t.Helper()
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok || apiErr.Code != ErrMapperFailed {
+		t.Errorf("got %v, want a mapper failed error", err)
+	}
+}
+
+func watchEvents(t *testing.T, policy Policy) []types.APIEvent {
+	t.Helper()
+	input := make(chan types.APIEvent, 2)
+	for _, obj := range objects() {
+		input <- types.APIEvent{Name: types.ChangeAPIEvent, Object: obj}
+	}
+	close(input)
+
+	schema := guardedSchema(fake.NewFakeStore().OnWatch(input, nil), policy)
+	c, err := schema.Store.Watch(nil, schema, types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []types.APIEvent
+	for event := range c {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestWatchPolicies(t *testing.T) {
+	if events := watchEvents(t, Skip); len(events) != 1 || events[0].Object.ID != "good" {
+		t.Errorf("got %v, want only the event of the good object", events)
+	}
+
+	events := watchEvents(t, Fail)
+	if len(events) != 2 || events[0].Object.ID != "good" {
+		t.Fatalf("got %v, want the good object and an error in place of the bad one", events)
+	}
+	if events[1].Name != "resource.error" {
+		t.Errorf("got %s, want an error event", events[1].Name)
+	}
+	expectMapperFailed(t, events[1].Error)
+}
@@ -0,0 +1,257 @@
+// Package memory is a types.Store that keeps objects in memory, for tests that need a working store without a
+// Kubernetes cluster.
+package memory
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+// Store keeps the objects of every schema it serves keyed by namespace/name. Every mutation increments the
+// resourceVersion of the store, which is set on the mutated object and sent to watchers.
+type Store struct {
+	lock     sync.Mutex
+	objects  map[string]map[string]map[string]interface{}
+	revision int64
+	watchers map[*watcher]bool
+}
+
+type watcher struct {
+	schemaID  string
+	namespace string
+	selector  labels.Selector
+	events    chan types.APIEvent
+}
+
+func NewMemoryStore() types.Store {
+	return &Store{
+		objects:  map[string]map[string]map[string]interface{}{},
+		watchers: map[*watcher]bool{},
+	}
+}
+
+func key(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// splitID returns the namespace and name of an id, the namespace of the request is used if the id has none.
+func splitID(apiOp *types.APIRequest, id string) (string, string) {
+	if i := strings.Index(id, "/"); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+	return apiOp.Namespace, id
+}
+
+func copyObject(obj map[string]interface{}) map[string]interface{} {
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	result := map[string]interface{}{}
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return obj
+	}
+	return result
+}
+
+func toAPI(schema *types.APISchema, obj map[string]interface{}) types.APIObject {
+	u := &unstructured.Unstructured{Object: copyObject(obj)}
+	return types.APIObject{
+		Type:   schema.ID,
+		ID:     key(u.GetNamespace(), u.GetName()),
+		Object: u,
+	}
+}
+
+func notFound(schema *types.APISchema, id string) error {
+	return apierror.NewAPIError(validation.NotFound, schema.ID+" "+id+" not found")
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	obj, ok := s.objects[schema.ID][key(splitID(apiOp, id))]
+	if !ok {
+		return types.APIObject{}, notFound(schema, id)
+	}
+	return toAPI(schema, obj), nil
+}
+
+// List returns the objects in the namespace of the request, or every namespace if it has none, that match the
+// labelSelector query parameter. Objects are sorted by namespace and name.
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	selector := labels.Everything()
+	if apiOp.Request != nil {
+		var err error
+		if selector, err = labels.Parse(apiOp.Request.URL.Query().Get("labelSelector")); err != nil {
+			return types.APIObjectList{}, apierror.NewAPIError(validation.InvalidFormat, err.Error())
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := types.APIObjectList{
+		Revision: strconv.FormatInt(s.revision, 10),
+	}
+	for _, obj := range s.objects[schema.ID] {
+		if matches(obj, apiOp.Namespace, selector) {
+			result.Objects = append(result.Objects, toAPI(schema, obj))
+		}
+	}
+	sort.Slice(result.Objects, func(i, j int) bool {
+		return result.Objects[i].ID < result.Objects[j].ID
+	})
+	return result, nil
+}
+
+func matches(obj map[string]interface{}, namespace string, selector labels.Selector) bool {
+	u := unstructured.Unstructured{Object: obj}
+	if namespace != "" && u.GetNamespace() != namespace {
+		return false
+	}
+	return selector.Matches(labels.Set(u.GetLabels()))
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, params types.APIObject) (types.APIObject, error) {
+	obj := copyObject(params.Data())
+	u := &unstructured.Unstructured{Object: obj}
+	if u.GetName() == "" {
+		if u.GetGenerateName() == "" {
+			return types.APIObject{}, apierror.NewAPIError(validation.MissingRequired, "metadata.name is required")
+		}
+		u.SetName(u.GetGenerateName() + rand.String(5))
+	}
+	if u.GetNamespace() == "" && apiOp.Namespace != "" {
+		u.SetNamespace(apiOp.Namespace)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	k := key(u.GetNamespace(), u.GetName())
+	if _, ok := s.objects[schema.ID][k]; ok {
+		return types.APIObject{}, apierror.NewAPIError(validation.Conflict, schema.ID+" "+k+" already exists")
+	}
+	if s.objects[schema.ID] == nil {
+		s.objects[schema.ID] = map[string]map[string]interface{}{}
+	}
+
+	s.revision++
+	u.SetResourceVersion(strconv.FormatInt(s.revision, 10))
+	s.objects[schema.ID][k] = obj
+	s.notify(schema, types.CreateAPIEvent, obj)
+	return toAPI(schema, obj), nil
+}
+
+// Update replaces the object. If the input has a resourceVersion it must match the stored one.
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, params types.APIObject, id string) (types.APIObject, error) {
+	namespace, name := splitID(apiOp, id)
+
+	obj := copyObject(params.Data())
+	data.PutValue(obj, name, "metadata", "name")
+	if namespace != "" {
+		data.PutValue(obj, namespace, "metadata", "namespace")
+	}
+	u := &unstructured.Unstructured{Object: obj}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	k := key(namespace, name)
+	existing, ok := s.objects[schema.ID][k]
+	if !ok {
+		return types.APIObject{}, notFound(schema, id)
+	}
+	if rv := u.GetResourceVersion(); rv != "" && rv != (&unstructured.Unstructured{Object: existing}).GetResourceVersion() {
+		return types.APIObject{}, apierror.NewAPIError(validation.Conflict, schema.ID+" "+k+" has been modified")
+	}
+
+	s.revision++
+	u.SetResourceVersion(strconv.FormatInt(s.revision, 10))
+	s.objects[schema.ID][k] = obj
+	s.notify(schema, types.ChangeAPIEvent, obj)
+	return toAPI(schema, obj), nil
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	k := key(splitID(apiOp, id))
+	obj, ok := s.objects[schema.ID][k]
+	if !ok {
+		return types.APIObject{}, notFound(schema, id)
+	}
+
+	s.revision++
+	delete(s.objects[schema.ID], k)
+	obj = copyObject(obj)
+	(&unstructured.Unstructured{Object: obj}).SetResourceVersion(strconv.FormatInt(s.revision, 10))
+	s.notify(schema, types.RemoveAPIEvent, obj)
+	return toAPI(schema, obj), nil
+}
+
+// Watch sends the changes to the objects of the schema matching the namespace of the request and the selector,
+// until the request context is done. Past events are not replayed, the revision of the request is ignored.
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, wr types.WatchRequest) (chan types.APIEvent, error) {
+	selector, err := labels.Parse(wr.Selector)
+	if err != nil {
+		return nil, apierror.NewAPIError(validation.InvalidFormat, err.Error())
+	}
+
+	w := &watcher{
+		schemaID:  schema.ID,
+		namespace: apiOp.Namespace,
+		selector:  selector,
+		events:    make(chan types.APIEvent, 100),
+	}
+
+	s.lock.Lock()
+	s.watchers[w] = true
+	s.lock.Unlock()
+
+	go func() {
+		<-apiOp.Context().Done()
+		s.lock.Lock()
+		delete(s.watchers, w)
+		close(w.events)
+		s.lock.Unlock()
+	}()
+
+	return w.events, nil
+}
+
+// notify must be called with the lock held, watchers that don't keep up lose events rather than block the store.
+func (s *Store) notify(schema *types.APISchema, name string, obj map[string]interface{}) {
+	for w := range s.watchers {
+		if w.schemaID != schema.ID || !matches(obj, w.namespace, w.selector) {
+			continue
+		}
+		event := types.APIEvent{
+			Name:         name,
+			ResourceType: schema.ID,
+			Revision:     strconv.FormatInt(s.revision, 10),
+			Object:       toAPI(schema, obj),
+		}
+		select {
+		case w.events <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package memory
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"github.com/rancher/wrangler/pkg/schemas"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) conformance.Setup {
+		schema := &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+		attributes.SetNamespaced(schema, true)
+		return conformance.Setup{
+			Store:  NewMemoryStore(),
+			Schema: schema,
+		}
+	})
+}
+
+var (
+	widgets = &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+	gadgets = &types.APISchema{Schema: &schemas.Schema{ID: "gadget"}}
+)
+
+func request(ctx context.Context, namespace string, query url.Values) *types.APIRequest {
+	return &types.APIRequest{
+		Namespace: namespace,
+		Request:   httptest.NewRequest(http.MethodGet, "/v1/widget?"+query.Encode(), nil).WithContext(ctx),
+	}
+}
+
+func widget(namespace, name string, labels map[string]interface{}) types.APIObject {
+	return types.APIObject{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+			"labels":    labels,
+		},
+		"spec": map[string]interface{}{"size": "large"},
+	}}
+}
+
+func expectStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok || apiErr.Code.Status != status {
+		t.Errorf("got %v, want an API error with status %d", err, status)
+	}
+}
+
+func resourceVersion(obj types.APIObject) string {
+	return obj.Data().String("metadata", "resourceVersion")
+}
+
+func TestCRUD(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	created, err := s.Create(request(ctx, "default", nil), widgets, widget("default", "a", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != "default/a" || resourceVersion(created) != "1" {
+		t.Errorf("got id %s at %s, want default/a at 1", created.ID, resourceVersion(created))
+	}
+
+	got, err := s.ByID(request(ctx, "default", nil), widgets, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Data().String("spec", "size") != "large" {
+		t.Errorf("got %v, want the created object", got.Data())
+	}
+
+	input := got.Data()
+	input.SetNested("small", "spec", "size")
+	updated, err := s.Update(request(ctx, "default", nil), widgets, types.APIObject{Object: map[string]interface{}(input)}, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resourceVersion(updated) != "2" || updated.Data().String("spec", "size") != "small" {
+		t.Errorf("got %v, want the update at resourceVersion 2", updated.Data())
+	}
+
+	// the input still has the resourceVersion of the create
+	if _, err := s.Update(request(ctx, "default", nil), widgets, types.APIObject{Object: map[string]interface{}(input)}, "a"); err == nil {
+		t.Error("got no error for an update of a stale object")
+	} else {
+		expectStatus(t, err, http.StatusConflict)
+	}
+
+	deleted, err := s.Delete(request(ctx, "default", nil), widgets, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resourceVersion(deleted) != "3" {
+		t.Errorf("got resourceVersion %s for the delete, want 3", resourceVersion(deleted))
+	}
+	_, err = s.ByID(request(ctx, "default", nil), widgets, "a")
+	expectStatus(t, err, http.StatusNotFound)
+	_, err = s.Update(request(ctx, "default", nil), widgets, widget("default", "a", nil), "a")
+	expectStatus(t, err, http.StatusNotFound)
+}
+
+func TestCreateNames(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	_, err := s.Create(request(ctx, "default", nil), widgets, widget("default", "", nil))
+	expectStatus(t, err, http.StatusUnprocessableEntity)
+
+	obj := widget("", "", nil)
+	obj.Data().SetNested("w-", "metadata", "generateName")
+	created, err := s.Create(request(ctx, "team", nil), widgets, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := created.Data().String("metadata", "name"); len(name) != len("w-")+5 || name[:2] != "w-" {
+		t.Errorf("got name %q, want one generated from w-", name)
+	}
+	if created.Data().String("metadata", "namespace") != "team" {
+		t.Errorf("got %v, want the namespace of the request", created.Data())
+	}
+}
+
+func TestSchemasAreKeptApart(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	if _, err := s.Create(request(ctx, "default", nil), widgets, widget("default", "a", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(request(ctx, "default", nil), gadgets, widget("default", "a", nil)); err != nil {
+		t.Fatalf("got %v, want the same name allowed in another schema", err)
+	}
+
+	list, err := s.List(request(ctx, "", nil), gadgets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Objects) != 1 || list.Objects[0].Type != "gadget" {
+		t.Errorf("got %v, want only the gadget", list.Objects)
+	}
+}
+
+func TestListFiltersAndSorts(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	for _, obj := range []types.APIObject{
+		widget("b", "z", map[string]interface{}{"tier": "web"}),
+		widget("a", "y", map[string]interface{}{"tier": "web"}),
+		widget("a", "x", map[string]interface{}{"tier": "db"}),
+	} {
+		if _, err := s.Create(request(ctx, "", nil), widgets, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := s.List(request(ctx, "", nil), widgets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, obj := range list.Objects {
+		ids = append(ids, obj.ID)
+	}
+	if len(ids) != 3 || ids[0] != "a/x" || ids[1] != "a/y" || ids[2] != "b/z" {
+		t.Errorf("got %v, want every widget sorted by id", ids)
+	}
+	if list.Revision != "3" {
+		t.Errorf("got revision %s, want 3", list.Revision)
+	}
+
+	list, err = s.List(request(ctx, "a", url.Values{"labelSelector": {"tier!=db"}}), widgets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Objects) != 1 || list.Objects[0].ID != "a/y" {
+		t.Errorf("got %v, want a/y", list.Objects)
+	}
+
+	_, err = s.List(request(ctx, "", url.Values{"labelSelector": {"tier in ("}}), widgets)
+	expectStatus(t, err, http.StatusUnprocessableEntity)
+}
+
+func nextEvent(t *testing.T, c chan types.APIEvent) types.APIEvent {
+	t.Helper()
+	select {
+	case event, ok := <-c:
+		if !ok {
+			t.Fatal("the watch closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return types.APIEvent{}
+}
+
+func TestWatch(t *testing.T) {
+	s := NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := s.Watch(request(ctx, "default", nil), widgets, types.WatchRequest{Selector: "tier=web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := request(context.Background(), "default", nil)
+	web := map[string]interface{}{"tier": "web"}
+	if _, err := s.Create(write, widgets, widget("other", "a", web)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(write, widgets, widget("default", "db", map[string]interface{}{"tier": "db"})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(write, gadgets, widget("default", "a", web)); err != nil {
+		t.Fatal(err)
+	}
+	created, err := s.Create(write, widgets, widget("default", "a", web))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := nextEvent(t, c)
+	if event.Name != types.CreateAPIEvent || event.Object.ID != "default/a" || event.Revision != resourceVersion(created) {
+		t.Errorf("got %s of %s at %s, want only the create of the matching widget", event.Name, event.Object.ID, event.Revision)
+	}
+
+	input := created.Data()
+	input.SetNested("small", "spec", "size")
+	if _, err := s.Update(write, widgets, types.APIObject{Object: map[string]interface{}(input)}, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, c); event.Name != types.ChangeAPIEvent || event.Object.Data().String("spec", "size") != "small" {
+		t.Errorf("got %s %v, want the change", event.Name, event.Object.Data())
+	}
+
+	if _, err := s.Delete(write, widgets, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, c); event.Name != types.RemoveAPIEvent || event.Object.ID != "default/a" {
+		t.Errorf("got %s of %s, want the remove", event.Name, event.Object.ID)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-c:
+		if ok {
+			t.Error("got an event after the request was canceled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the watch did not close")
+	}
+}
@@ -0,0 +1,186 @@
+// Package mirror sends reads to a primary store and, in the background, to a shadow store so the results of a
+// new store implementation can be compared with the current one before switching a schema over.
+package mirror
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	shadowTimeout = 30 * time.Second
+	maxInFlight   = 20
+)
+
+// StoreFactory returns a function for Template.StoreFactory that mirrors reads of the template's store to shadow.
+// Fields are dot separated paths, such as metadata.resourceVersion, compared for every object in both results.
+func StoreFactory(shadow types.Store, fields ...string) func(types.Store) types.Store {
+	return func(primary types.Store) types.Store {
+		return NewStore(primary, shadow, fields...)
+	}
+}
+
+// Store returns the primary result to the client unchanged. Writes and watches only go to the primary.
+type Store struct {
+	types.Store
+	shadow   types.Store
+	fields   [][]string
+	inFlight chan struct{}
+
+	compared   int64
+	mismatched int64
+	skipped    int64
+}
+
+func NewStore(primary, shadow types.Store, fields ...string) *Store {
+	s := &Store{
+		Store:    primary,
+		shadow:   shadow,
+		inFlight: make(chan struct{}, maxInFlight),
+	}
+	for _, field := range fields {
+		s.fields = append(s.fields, strings.Split(field, "."))
+	}
+	return s
+}
+
+// Stats returns how many reads were compared, how many of those did not match, and how many were not
+// mirrored because too many shadow reads were already running.
+func (s *Store) Stats() (compared, mismatched, skipped int64) {
+	return atomic.LoadInt64(&s.compared), atomic.LoadInt64(&s.mismatched), atomic.LoadInt64(&s.skipped)
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	s.mirror(apiOp, s.snapshot(err, obj), func(shadowOp *types.APIRequest) result {
+		shadowObj, shadowErr := s.shadow.ByID(shadowOp, schema, id)
+		return s.snapshot(shadowErr, shadowObj)
+	}, schema, "byID "+id)
+	return obj, err
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := s.Store.List(apiOp, schema)
+	s.mirror(apiOp, s.snapshot(err, list.Objects...), func(shadowOp *types.APIRequest) result {
+		shadowList, shadowErr := s.shadow.List(shadowOp, schema)
+		return s.snapshot(shadowErr, shadowList.Objects...)
+	}, schema, "list")
+	return list, err
+}
+
+// result is a copy of what is compared so the primary objects can be handed to the client, which may modify
+// them while formatting, before the shadow read finishes.
+type result struct {
+	err    error
+	fields map[string][]interface{}
+}
+
+func (s *Store) snapshot(err error, objs ...types.APIObject) result {
+	r := result{
+		err: err,
+	}
+	if err != nil {
+		return r
+	}
+
+	r.fields = map[string][]interface{}{}
+	for _, obj := range objs {
+		objData := obj.Data()
+		var values []interface{}
+		for _, field := range s.fields {
+			val, _ := data.GetValue(objData, field...)
+			values = append(values, val)
+		}
+		r.fields[obj.ID] = values
+	}
+	return r
+}
+
+func (s *Store) mirror(apiOp *types.APIRequest, primary result, shadow func(*types.APIRequest) result, schema *types.APISchema, op string) {
+	select {
+	case s.inFlight <- struct{}{}:
+	default:
+		atomic.AddInt64(&s.skipped, 1)
+		return
+	}
+
+	// the shadow read keeps the request values, such as the user, but must outlive the client request
+	ctx, cancel := context.WithTimeout(detached{apiOp.Context()}, shadowTimeout)
+	shadowOp := apiOp.Clone().WithContext(ctx)
+
+	go func() {
+		defer func() { <-s.inFlight }()
+		defer cancel()
+		s.compare(schema, op, primary, shadow(shadowOp))
+	}()
+}
+
+func (s *Store) compare(schema *types.APISchema, op string, primary, shadow result) {
+	atomic.AddInt64(&s.compared, 1)
+
+	var diffs []string
+	switch {
+	case (primary.err == nil) != (shadow.err == nil):
+		diffs = append(diffs, "error: primary ["+errString(primary.err)+"] shadow ["+errString(shadow.err)+"]")
+	case primary.err == nil:
+		diffs = s.diff(primary, shadow)
+	}
+
+	if len(diffs) == 0 {
+		return
+	}
+	atomic.AddInt64(&s.mismatched, 1)
+	logrus.Warnf("mirror mismatch for %s %s: %s", schema.ID, op, strings.Join(diffs, "; "))
+}
+
+func (s *Store) diff(primary, shadow result) (diffs []string) {
+	if len(primary.fields) != len(shadow.fields) {
+		diffs = append(diffs, "count: primary "+strconv.Itoa(len(primary.fields))+" shadow "+strconv.Itoa(len(shadow.fields)))
+	}
+
+	ids := sets.StringKeySet(primary.fields)
+	shadowIDs := sets.StringKeySet(shadow.fields)
+	if missing := ids.Difference(shadowIDs); missing.Len() > 0 {
+		diffs = append(diffs, "missing from shadow: "+strings.Join(missing.List(), ","))
+	}
+	if extra := shadowIDs.Difference(ids); extra.Len() > 0 {
+		diffs = append(diffs, "only in shadow: "+strings.Join(extra.List(), ","))
+	}
+
+	for _, id := range ids.Intersection(shadowIDs).List() {
+		a, b := primary.fields[id], shadow.fields[id]
+		for i, field := range s.fields {
+			if !reflect.DeepEqual(a[i], b[i]) {
+				diffs = append(diffs, id+" "+strings.Join(field, ".")+" differs")
+			}
+		}
+	}
+
+	return diffs
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "none"
+	}
+	return err.Error()
+}
+
+// detached keeps the values of the parent context but is never cancelled with it.
+type detached struct {
+	parent context.Context
+}
+
+func (d detached) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detached) Done() <-chan struct{}             { return nil }
+func (d detached) Err() error                        { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
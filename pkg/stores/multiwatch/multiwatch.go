@@ -0,0 +1,137 @@
+// Package multiwatch merges the watches of several schemas into one stream, for clients that follow many types
+// over a single connection.
+package multiwatch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// TaggedAPIObject is an event of a multi-schema watch, SchemaID is the schema whose watch sent it.
+type TaggedAPIObject struct {
+	SchemaID string
+	Name     string
+	Object   types.APIObject
+	Error    error
+}
+
+// Control changes the schemas of a running multi-schema watch. Schemas already watched are not added twice and
+// removing a schema that is not watched does nothing.
+type Control struct {
+	Add    []*types.APISchema
+	Remove []string
+}
+
+// MultiSchemaWatch runs the Store.Watch of every schema with wr and sends their events, tagged with the schema,
+// on the returned channel. Schemas are added and removed with the control channel while the watch runs, a schema
+// that fails to start is reported with an event carrying the error. The watch ends, and the event channel is
+// closed, when the context of apiOp is done or the control channel is closed. A schema without a store, or a
+// failure to start the initial watches, fails the whole call.
+func MultiSchemaWatch(apiOp *types.APIRequest, schemas []*types.APISchema, wr types.WatchRequest) (<-chan TaggedAPIObject, chan<- Control, error) {
+	ctx, cancel := context.WithCancel(apiOp.Context())
+	m := &multiWatch{
+		apiOp:   apiOp,
+		wr:      wr,
+		ctx:     ctx,
+		result:  make(chan TaggedAPIObject),
+		watches: map[string]context.CancelFunc{},
+	}
+
+	for _, schema := range schemas {
+		if err := m.add(schema); err != nil {
+			cancel()
+			m.wg.Wait()
+			return nil, nil, err
+		}
+	}
+
+	control := make(chan Control)
+	go func() {
+		defer close(m.result)
+		defer m.wg.Wait()
+		defer cancel()
+		m.run(control)
+	}()
+
+	return m.result, control, nil
+}
+
+type multiWatch struct {
+	apiOp   *types.APIRequest
+	wr      types.WatchRequest
+	ctx     context.Context
+	result  chan TaggedAPIObject
+	watches map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func (m *multiWatch) run(control <-chan Control) {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case change, ok := <-control:
+			if !ok {
+				return
+			}
+			for _, id := range change.Remove {
+				if stop, ok := m.watches[id]; ok {
+					stop()
+					delete(m.watches, id)
+				}
+			}
+			for _, schema := range change.Add {
+				if err := m.add(schema); err != nil {
+					m.send(m.ctx, TaggedAPIObject{
+						SchemaID: schema.ID,
+						Error:    err,
+					})
+				}
+			}
+		}
+	}
+}
+
+// add starts watching schema unless it is watched already.
+func (m *multiWatch) add(schema *types.APISchema) error {
+	if _, ok := m.watches[schema.ID]; ok {
+		return nil
+	}
+	if schema.Store == nil {
+		return fmt.Errorf("schema %s can not be watched, it has no store", schema.ID)
+	}
+
+	ctx, stop := context.WithCancel(m.ctx)
+	events, err := schema.Store.Watch(m.apiOp.WithContext(ctx), schema, m.wr)
+	if err != nil {
+		stop()
+		return err
+	}
+	m.watches[schema.ID] = stop
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer stop()
+		// the store closes events once ctx is done, until then it is drained so the store never blocks
+		for event := range events {
+			m.send(ctx, TaggedAPIObject{
+				SchemaID: schema.ID,
+				Name:     event.Name,
+				Object:   event.Object,
+				Error:    event.Error,
+			})
+		}
+	}()
+	return nil
+}
+
+func (m *multiWatch) send(ctx context.Context, event TaggedAPIObject) {
+	select {
+	case <-ctx.Done():
+	case m.result <- event:
+	}
+}
@@ -0,0 +1,219 @@
+package multiwatch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas"
+)
+
+// fakeWatch is a watch of a fakeStore, its events channel is closed once its context is done like the stores do.
+type fakeWatch struct {
+	ctx    context.Context
+	lock   sync.Mutex
+	closed bool
+	events chan types.APIEvent
+}
+
+func (w *fakeWatch) send(event types.APIEvent) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return false
+	}
+	w.events <- event
+	return true
+}
+
+type fakeStore struct {
+	types.Store
+	lock    sync.Mutex
+	watches []*fakeWatch
+	err     error
+}
+
+func (f *fakeStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	watch := &fakeWatch{
+		ctx:    apiOp.Context(),
+		events: make(chan types.APIEvent),
+	}
+	go func() {
+		<-watch.ctx.Done()
+		watch.lock.Lock()
+		defer watch.lock.Unlock()
+		watch.closed = true
+		close(watch.events)
+	}()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.watches = append(f.watches, watch)
+	return watch.events, nil
+}
+
+func (f *fakeStore) started() []*fakeWatch {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]*fakeWatch(nil), f.watches...)
+}
+
+func watchedSchema(id string) (*types.APISchema, *fakeStore) {
+	store := &fakeStore{}
+	return &types.APISchema{Schema: &schemas.Schema{ID: id}, Store: store}, store
+}
+
+func request(ctx context.Context) *types.APIRequest {
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscribe", nil)
+	return &types.APIRequest{Request: req.WithContext(ctx)}
+}
+
+func next(t *testing.T, c <-chan TaggedAPIObject) TaggedAPIObject {
+	t.Helper()
+	select {
+	case event, ok := <-c:
+		if !ok {
+			t.Fatal("the multi-schema watch closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return TaggedAPIObject{}
+}
+
+func waitStopped(t *testing.T, w *fakeWatch) {
+	t.Helper()
+	select {
+	case <-w.ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watch was not stopped")
+	}
+}
+
+func TestThreeSchemasAreMerged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		watched []*types.APISchema
+		stores  = map[string]*fakeStore{}
+	)
+	for _, id := range []string{"pod", "configmap", "secret"} {
+		schema, store := watchedSchema(id)
+		watched = append(watched, schema)
+		stores[id] = store
+	}
+
+	events, _, err := MultiSchemaWatch(request(ctx), watched, types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"secret", "pod", "configmap"} {
+		if !stores[id].started()[0].send(types.APIEvent{Name: types.ChangeAPIEvent, Object: types.APIObject{ID: "default/" + id}}) {
+			t.Fatalf("the watch of %s is closed", id)
+		}
+		event := next(t, events)
+		if event.SchemaID != id || event.Name != types.ChangeAPIEvent || event.Object.ID != "default/"+id {
+			t.Errorf("got %+v, want the change of %s tagged with its schema", event, id)
+		}
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("got an event after the context ended, want the channel closed")
+	}
+	for id, store := range stores {
+		if w := store.started()[0]; w.ctx.Err() == nil {
+			t.Errorf("the watch of %s still runs after the multi-schema watch ended", id)
+		}
+	}
+}
+
+func TestSchemasAreAddedAndRemoved(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pods, podStore := watchedSchema("pod")
+	secrets, secretStore := watchedSchema("secret")
+	configMaps, configMapStore := watchedSchema("configmap")
+
+	events, control, err := MultiSchemaWatch(request(ctx), []*types.APISchema{pods, secrets}, types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	control <- Control{Remove: []string{"secret", "unknown"}, Add: []*types.APISchema{configMaps, pods}}
+	waitStopped(t, secretStore.started()[0])
+
+	for start := time.Now(); len(configMapStore.started()) == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the added schema is not watched")
+		}
+	}
+	if n := len(podStore.started()); n != 1 {
+		t.Errorf("got %d watches of the pods, want adding a watched schema to do nothing", n)
+	}
+	configMapStore.started()[0].send(types.APIEvent{Name: types.CreateAPIEvent, Object: types.APIObject{ID: "default/a"}})
+	if event := next(t, events); event.SchemaID != "configmap" || event.Name != types.CreateAPIEvent {
+		t.Errorf("got %+v, want the create of the added schema", event)
+	}
+	podStore.started()[0].send(types.APIEvent{Name: types.RemoveAPIEvent, Object: types.APIObject{ID: "default/web"}})
+	if event := next(t, events); event.SchemaID != "pod" || event.Name != types.RemoveAPIEvent {
+		t.Errorf("got %+v, want the remove of the kept schema", event)
+	}
+
+	// a removed schema can be watched again
+	control <- Control{Add: []*types.APISchema{secrets}}
+	for start := time.Now(); len(secretStore.started()) < 2; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the removed schema is not watched again")
+		}
+	}
+
+	close(control)
+	if _, ok := <-events; ok {
+		t.Error("got an event after the control channel closed, want the watch ended")
+	}
+	waitStopped(t, podStore.started()[0])
+	waitStopped(t, configMapStore.started()[0])
+}
+
+func TestFailedSchemasAreReported(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pods, podStore := watchedSchema("pod")
+	noStore := &types.APISchema{Schema: &schemas.Schema{ID: "nostore"}}
+	failing, failingStore := watchedSchema("failing")
+	failingStore.err = errors.New("watch refused")
+
+	if _, _, err := MultiSchemaWatch(request(ctx), []*types.APISchema{pods, noStore}, types.WatchRequest{}); err == nil {
+		t.Error("got no error for an initial schema without a store")
+	}
+	waitStopped(t, podStore.started()[0])
+
+	events, control, err := MultiSchemaWatch(request(ctx), []*types.APISchema{pods}, types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	control <- Control{Add: []*types.APISchema{failing}}
+	if event := next(t, events); event.SchemaID != "failing" || event.Error == nil || event.Error.Error() != "watch refused" {
+		t.Errorf("got %+v, want the error of the added schema", event)
+	}
+	control <- Control{Add: []*types.APISchema{noStore}}
+	if event := next(t, events); event.SchemaID != "nostore" || event.Error == nil {
+		t.Errorf("got %+v, want an error for an added schema without a store", event)
+	}
+
+	// the other watches keep running
+	podStore.started()[1].send(types.APIEvent{Name: types.ChangeAPIEvent})
+	if event := next(t, events); event.SchemaID != "pod" {
+		t.Errorf("got %+v, want the pods still watched", event)
+	}
+}
@@ -0,0 +1,86 @@
+// Package nslabel limits the lists and watches of a schema to the namespaces with given labels, for resources that
+// only matter in some namespaces, such as the ones of a service mesh in namespaces with istio-injection=enabled.
+package nslabel
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// NamespaceLister lists namespaces, a wrangler NamespaceCache implements it.
+type NamespaceLister = proxy.NamespaceLister
+
+// Matching returns the names of the namespaces that have every label of filter.
+func Matching(lister NamespaceLister, filter map[string]string) (sets.String, error) {
+	namespaces, err := lister.List(labels.SelectorFromSet(filter))
+	if err != nil {
+		return nil, err
+	}
+	result := sets.NewString()
+	for _, namespace := range namespaces {
+		result.Insert(namespace.Name)
+	}
+	return result, nil
+}
+
+// Store limits lists and watches of a namespaced schema with attributes.NamespaceLabelFilter to the namespaces
+// that match the filter. A list of a namespace that doesn't match is empty and a watch of one sends nothing.
+// Requests for single objects are passed through, RBAC decides about them. This never grants access.
+type Store struct {
+	types.Store
+	namespaces NamespaceLister
+}
+
+func NewStore(store types.Store, namespaces NamespaceLister) *Store {
+	return &Store{
+		Store:      store,
+		namespaces: namespaces,
+	}
+}
+
+// constrain returns a copy of apiOp limited to the matching namespaces, or false when the namespace of apiOp
+// doesn't match.
+func (s *Store) constrain(apiOp *types.APIRequest, schema *types.APISchema) (*types.APIRequest, bool, error) {
+	filter := attributes.NamespaceLabelFilter(schema)
+	if len(filter) == 0 || !attributes.Namespaced(schema) {
+		return apiOp, true, nil
+	}
+	matching, err := Matching(s.namespaces, filter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if apiOp.Namespace != "" {
+		return apiOp, matching.Has(apiOp.Namespace), nil
+	}
+	apiOp = apiOp.Clone()
+	apiOp.Request = proxy.IntersectNamespaceConstraint(apiOp.Request, matching.List()...)
+	return apiOp, true, nil
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	apiOp, ok, err := s.constrain(apiOp, schema)
+	if err != nil || !ok {
+		return types.APIObjectList{}, err
+	}
+	return s.Store.List(apiOp, schema)
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	constrained, ok, err := s.constrain(apiOp, schema)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		result := make(chan types.APIEvent)
+		go func() {
+			<-apiOp.Context().Done()
+			close(result)
+		}()
+		return result, nil
+	}
+	return s.Store.Watch(constrained, schema, w)
+}
@@ -0,0 +1,249 @@
+package nslabel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// namespaceList holds the namespaces of the cluster like the namespace cache.
+type namespaceList []*v1.Namespace
+
+func (n namespaceList) List(selector labels.Selector) ([]*v1.Namespace, error) {
+	var result []*v1.Namespace
+	for _, namespace := range n {
+		if selector.Matches(labels.Set(namespace.Labels)) {
+			result = append(result, namespace)
+		}
+	}
+	return result, nil
+}
+
+// meshNamespaces has the injected namespaces mesh-a and mesh-b, plain without the label and off with it disabled.
+var meshNamespaces = namespaceList{
+	{ObjectMeta: metav1.ObjectMeta{Name: "mesh-a", Labels: map[string]string{"istio-injection": "enabled"}}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "mesh-b", Labels: map[string]string{"istio-injection": "enabled", "team": "web"}}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "plain"}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "off", Labels: map[string]string{"istio-injection": "disabled"}}},
+}
+
+// dynamicClientGetter gives the proxy store the clients of a fake dynamic client.
+type dynamicClientGetter struct {
+	proxy.ClientGetter
+	client dynamic.Interface
+}
+
+func (d *dynamicClientGetter) Client(apiOp *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return d.client.Resource(attributes.GVR(schema)).Namespace(namespace), nil
+}
+
+func (d *dynamicClientGetter) AdminClient(apiOp *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return d.Client(apiOp, schema, namespace)
+}
+
+func (d *dynamicClientGetter) TableClient(apiOp *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return d.Client(apiOp, schema, namespace)
+}
+
+func (d *dynamicClientGetter) TableAdminClient(apiOp *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return d.Client(apiOp, schema, namespace)
+}
+
+func (d *dynamicClientGetter) TableClientForWatch(apiOp *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return d.Client(apiOp, schema, namespace)
+}
+
+func (d *dynamicClientGetter) TableAdminClientForWatch(apiOp *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return d.Client(apiOp, schema, namespace)
+}
+
+type allAccess struct{}
+
+func (allAccess) AccessFor(user user.Info) *accesscontrol.AccessSet {
+	set := &accesscontrol.AccessSet{ID: "all"}
+	set.Add(accesscontrol.All, schema.GroupResource{Group: accesscontrol.All, Resource: accesscontrol.All}, accesscontrol.Access{
+		Namespace:    accesscontrol.All,
+		ResourceName: accesscontrol.All,
+	})
+	return set
+}
+
+func configMap(namespace, name string) runtime.Object {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+// filteredStore is the proxy store of the config maps of the fake cluster, limited to the namespaces with
+// istio-injection=enabled. Every namespace has a config map named after it.
+func filteredStore() (*Store, *types.APISchema, *dynamicfake.FakeDynamicClient) {
+	var objects []runtime.Object
+	for _, namespace := range meshNamespaces {
+		objects = append(objects, configMap(namespace.Name, namespace.Name))
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapsGVR: "ConfigMapList"}, objects...)
+
+	s := &types.APISchema{Schema: &schemas.Schema{ID: "configmap"}}
+	attributes.SetGVK(s, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	attributes.SetResource(s, "configmaps")
+	attributes.SetNamespaced(s, true)
+	attributes.SetNamespaceLabelFilter(s, map[string]string{"istio-injection": "enabled"})
+	access := accesscontrol.AccessListByVerb{}
+	for _, verb := range []string{"get", "list", "watch"} {
+		access[verb] = accesscontrol.AccessList{{Namespace: accesscontrol.All, ResourceName: accesscontrol.All}}
+	}
+	attributes.SetAccess(s, access)
+
+	store := NewStore(proxy.NewProxyStore(&dynamicClientGetter{client: client}, nil, allAccess{}), meshNamespaces)
+	return store, s, client
+}
+
+func listRequest(ctx context.Context, s *types.APISchema, namespace string) *types.APIRequest {
+	req := httptest.NewRequest(http.MethodGet, "/v1/configmaps", nil)
+	req = req.WithContext(request.WithUser(ctx, &user.DefaultInfo{
+		Name:   "admin",
+		Groups: []string{user.SystemPrivilegedGroup, user.AllAuthenticated},
+	}))
+	return &types.APIRequest{
+		Type:      s.ID,
+		Namespace: namespace,
+		Method:    http.MethodGet,
+		Schema:    s,
+		Request:   req,
+		Response:  httptest.NewRecorder(),
+	}
+}
+
+func listedNamespaces(t *testing.T, store types.Store, s *types.APISchema, namespace string) []string {
+	t.Helper()
+	list, err := store.List(listRequest(context.Background(), s, namespace), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	for _, obj := range list.Objects {
+		result = append(result, obj.Data().String("metadata", "namespace"))
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestListOnlyReturnsLabeledNamespaces(t *testing.T) {
+	store, s, _ := filteredStore()
+
+	if got := listedNamespaces(t, store, s, ""); len(got) != 2 || got[0] != "mesh-a" || got[1] != "mesh-b" {
+		t.Errorf("got config maps of %v, want only the labeled namespaces", got)
+	}
+	if got := listedNamespaces(t, store, s, "mesh-b"); len(got) != 1 || got[0] != "mesh-b" {
+		t.Errorf("got config maps of %v, want the labeled namespace listed", got)
+	}
+	for _, namespace := range []string{"plain", "off"} {
+		if got := listedNamespaces(t, store, s, namespace); len(got) != 0 {
+			t.Errorf("%s: got config maps of %v, want none in a namespace without the label", namespace, got)
+		}
+	}
+
+	// without a filter every namespace is listed
+	attributes.SetNamespaceLabelFilter(s, nil)
+	if got := listedNamespaces(t, store, s, ""); len(got) != len(meshNamespaces) {
+		t.Errorf("got config maps of %v, want every namespace without a filter", got)
+	}
+}
+
+// watchers makes the watches of client fake watchers that it hands out on the returned channel, the events sent to
+// them are not shared with the objects of the fake client.
+func watchers(client *dynamicfake.FakeDynamicClient) chan *watch.FakeWatcher {
+	result := make(chan *watch.FakeWatcher, 10)
+	client.PrependWatchReactor("configmaps", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w := watch.NewFakeWithChanSize(10, false)
+		result <- w
+		return true, w, nil
+	})
+	return result
+}
+
+func TestWatchOfUnlabeledNamespaceSendsNothing(t *testing.T) {
+	store, s, client := filteredStore()
+	started := watchers(client)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c, err := store.Watch(listRequest(ctx, s, "plain"), s, types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-c:
+		t.Errorf("got %s of %s, want no events of an unlabeled namespace", event.Name, event.Object.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if len(started) != 0 {
+		t.Error("got a watch of the cluster, want an unlabeled namespace not watched")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-c:
+		if ok {
+			t.Error("got an event after the watch ended")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the watch did not close with its context")
+	}
+}
+
+func TestWatchOfLabeledNamespaceSendsEvents(t *testing.T) {
+	store, s, client := filteredStore()
+	started := watchers(client)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := store.Watch(listRequest(ctx, s, "mesh-a"), s, types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w *watch.FakeWatcher
+	select {
+	case w = <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the labeled namespace is not watched")
+	}
+	w.Add(configMap("mesh-a", "new"))
+
+	for deadline := time.After(5 * time.Second); ; {
+		select {
+		case event := <-c:
+			if event.Object.ID == "mesh-a/new" {
+				return
+			}
+		case <-deadline:
+			t.Fatal("no event of the labeled namespace")
+		}
+	}
+}
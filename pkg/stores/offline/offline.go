@@ -0,0 +1,268 @@
+// Package offline keeps a copy of successful reads in a SQLite database and serves reads from it while the
+// Kubernetes apiserver is unreachable.
+package offline
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	// registers the "sqlite" driver, it is pure Go so the binary doesn't need cgo
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DriverName = "sqlite"
+
+	// CachedAtHeader is set to the time the served data was stored when a read is served from the database.
+	CachedAtHeader = "X-Steve-Cached-At"
+
+	// maxPendingSaves caps the copies waiting to be written, reads whose copy doesn't fit are not saved. SQLite
+	// has a single writer so saves are written in the background instead of holding up the read.
+	maxPendingSaves = 8
+
+	// maxSavedSize is the largest object or list, in bytes of JSON, that is saved.
+	maxSavedSize = 16 << 20
+)
+
+const createTables = `
+CREATE TABLE IF NOT EXISTS objects (
+	scope TEXT NOT NULL,
+	schema_id TEXT NOT NULL,
+	id TEXT NOT NULL,
+	data TEXT NOT NULL,
+	updated INTEGER NOT NULL,
+	PRIMARY KEY (scope, schema_id, id)
+);
+CREATE TABLE IF NOT EXISTS lists (
+	scope TEXT NOT NULL,
+	schema_id TEXT NOT NULL,
+	query TEXT NOT NULL,
+	data TEXT NOT NULL,
+	updated INTEGER NOT NULL,
+	PRIMARY KEY (scope, schema_id, query)
+);`
+
+// ScopeFunc returns the key cached data is partitioned by, usually the ID of the access set of the user, so a
+// user is only ever served data that was read with the same permissions.
+type ScopeFunc func(apiOp *types.APIRequest) string
+
+// Open opens, creating it if needed, the SQLite database at path.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open(DriverName, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	// SQLite only allows a single writer
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(createTables); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Store persists the results of ByID and List and serves them, with "cached": true set on every object, when
+// the wrapped store fails because the apiserver can't be reached. Writes and watches are passed through.
+type Store struct {
+	types.Store
+
+	db      *sql.DB
+	scope   ScopeFunc
+	saves   chan struct{}
+	pending sync.WaitGroup
+}
+
+func NewStore(store types.Store, db *sql.DB, scope ScopeFunc) *Store {
+	return &Store{
+		Store: store,
+		db:    db,
+		scope: scope,
+		saves: make(chan struct{}, maxPendingSaves),
+	}
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	scope := s.scope(apiOp)
+	if err == nil {
+		s.saveObject(scope, schema, id, obj)
+		return obj, nil
+	}
+	if !Unreachable(err) {
+		return obj, err
+	}
+
+	var (
+		data    string
+		updated int64
+	)
+	row := s.db.QueryRow("SELECT data, updated FROM objects WHERE scope = ? AND schema_id = ? AND id = ?", scope, schema.ID, id)
+	if dbErr := row.Scan(&data, &updated); dbErr != nil {
+		if dbErr != sql.ErrNoRows {
+			logrus.Errorf("failed to read offline copy of %s %s: %v", schema.ID, id, dbErr)
+		}
+		return obj, err
+	}
+
+	cached, dbErr := toCached(schema, []byte(data), updated)
+	if dbErr != nil {
+		return obj, err
+	}
+	setCachedAt(apiOp, updated)
+	return cached, nil
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := s.Store.List(apiOp, schema)
+	scope, query := s.scope(apiOp), listQuery(apiOp)
+	if err == nil {
+		s.saveList(scope, schema, query, list)
+		return list, nil
+	}
+	if !Unreachable(err) {
+		return list, err
+	}
+
+	var (
+		data    string
+		updated int64
+	)
+	row := s.db.QueryRow("SELECT data, updated FROM lists WHERE scope = ? AND schema_id = ? AND query = ?", scope, schema.ID, query)
+	if dbErr := row.Scan(&data, &updated); dbErr != nil {
+		if dbErr != sql.ErrNoRows {
+			logrus.Errorf("failed to read offline copy of %s list: %v", schema.ID, dbErr)
+		}
+		return list, err
+	}
+
+	var objects []json.RawMessage
+	if dbErr := json.Unmarshal([]byte(data), &objects); dbErr != nil {
+		return list, err
+	}
+
+	result := types.APIObjectList{}
+	for _, data := range objects {
+		obj, dbErr := toCached(schema, data, updated)
+		if dbErr != nil {
+			return list, err
+		}
+		result.Objects = append(result.Objects, obj)
+	}
+	setCachedAt(apiOp, updated)
+	return result, nil
+}
+
+func (s *Store) saveObject(scope string, schema *types.APISchema, id string, obj types.APIObject) {
+	data, err := json.Marshal(obj.Data())
+	if err != nil {
+		return
+	}
+	s.save(schema.ID+" "+id, "INSERT OR REPLACE INTO objects (scope, schema_id, id, data, updated) VALUES (?, ?, ?, ?, ?)",
+		scope, schema.ID, id, data)
+}
+
+func (s *Store) saveList(scope string, schema *types.APISchema, query string, list types.APIObjectList) {
+	objects := make([]map[string]interface{}, 0, len(list.Objects))
+	for _, obj := range list.Objects {
+		objects = append(objects, obj.Data())
+	}
+	data, err := json.Marshal(objects)
+	if err != nil {
+		return
+	}
+	s.save(schema.ID+" list", "INSERT OR REPLACE INTO lists (scope, schema_id, query, data, updated) VALUES (?, ?, ?, ?, ?)",
+		scope, schema.ID, query, data)
+}
+
+// save writes data in the background. The data is encoded before the read returns because the returned
+// objects are still changed by the formatters of the request.
+func (s *Store) save(what, statement, scope, schemaID, key string, data []byte) {
+	if len(data) > maxSavedSize {
+		logrus.Debugf("not saving offline copy of %s, %d bytes is above the limit", what, len(data))
+		return
+	}
+
+	select {
+	case s.saves <- struct{}{}:
+	default:
+		logrus.Debugf("not saving offline copy of %s, too many saves pending", what)
+		return
+	}
+
+	s.pending.Add(1)
+	go func() {
+		defer func() {
+			<-s.saves
+			s.pending.Done()
+		}()
+		if _, err := s.db.Exec(statement, scope, schemaID, key, string(data), time.Now().Unix()); err != nil {
+			logrus.Errorf("failed to save offline copy of %s: %v", what, err)
+		}
+	}()
+}
+
+// listQuery identifies a list by namespace and query, Encode sorts the parameters so equal queries match.
+func listQuery(apiOp *types.APIRequest) string {
+	return apiOp.Namespace + "?" + apiOp.Request.URL.Query().Encode()
+}
+
+func toCached(schema *types.APISchema, data []byte, updated int64) (types.APIObject, error) {
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(data, &obj.Object); err != nil {
+		return types.APIObject{}, err
+	}
+	obj.Object["cached"] = true
+	obj.Object["cachedAt"] = time.Unix(updated, 0).UTC().Format(time.RFC3339)
+
+	id := obj.GetName()
+	if ns := obj.GetNamespace(); ns != "" {
+		id = ns + "/" + id
+	}
+	return types.APIObject{
+		Type:   schema.ID,
+		ID:     id,
+		Object: obj,
+	}, nil
+}
+
+func setCachedAt(apiOp *types.APIRequest, updated int64) {
+	if apiOp.Response != nil {
+		apiOp.Response.Header().Set(CachedAtHeader, time.Unix(updated, 0).UTC().Format(http.TimeFormat))
+	}
+}
+
+// Unreachable returns true if err means the apiserver could not be reached, as opposed to it answering with
+// an error.
+func Unreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return true
+	}
+	if apierrors.IsServiceUnavailable(err) || apierrors.IsTimeout(err) {
+		return true
+	}
+	// errors translated by the error store only keep the message
+	msg := err.Error()
+	return strings.Contains(msg, "connection refused") || strings.Contains(msg, "no such host") ||
+		strings.Contains(msg, "i/o timeout")
+}
@@ -0,0 +1,167 @@
+package offline
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/fake"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var widgets = &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+
+var errUnreachable = &url.Error{Op: "Get", URL: "https://kubernetes/api", Err: errors.New("dial tcp: connection refused")}
+
+func newStore(t *testing.T, next types.Store, scope string) *Store {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "offline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewStore(next, db, func(*types.APIRequest) string { return scope })
+}
+
+func newRequest() *types.APIRequest {
+	return &types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodGet, "/v1/widgets", nil),
+		Response: httptest.NewRecorder(),
+	}
+}
+
+func widget(name string) types.APIObject {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	return types.APIObject{Type: "widget", ID: "default/" + name, Object: obj}
+}
+
+func TestOpenFailsForUnusablePath(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing", "offline.db")); err == nil {
+		t.Error("opened a database in a directory that doesn't exist")
+	}
+}
+
+func TestByIDServedWhileUnreachable(t *testing.T) {
+	next := fake.NewFakeStore().OnByID("default/w1", widget("w1"), nil)
+	s := newStore(t, next, "user1")
+
+	if _, err := s.ByID(newRequest(), widgets, "default/w1"); err != nil {
+		t.Fatal(err)
+	}
+	s.pending.Wait()
+
+	next.OnByID("default/w1", types.APIObject{}, errUnreachable)
+	apiOp := newRequest()
+	obj, err := s.ByID(apiOp, widgets, "default/w1")
+	if err != nil {
+		t.Fatalf("got %v, want the offline copy", err)
+	}
+	if cached, _ := obj.Data()["cached"].(bool); !cached || obj.Name() != "w1" {
+		t.Errorf("got %v, want the cached w1", obj.Data())
+	}
+	if apiOp.Response.Header().Get(CachedAtHeader) == "" {
+		t.Errorf("%s is not set", CachedAtHeader)
+	}
+}
+
+func TestListServedWhileUnreachable(t *testing.T) {
+	next := fake.NewFakeStore().OnList(types.APIObjectList{
+		Objects: []types.APIObject{widget("w1"), widget("w2")},
+	}, nil)
+	s := newStore(t, next, "user1")
+
+	if _, err := s.List(newRequest(), widgets); err != nil {
+		t.Fatal(err)
+	}
+	s.pending.Wait()
+
+	next.OnList(types.APIObjectList{}, errUnreachable)
+	list, err := s.List(newRequest(), widgets)
+	if err != nil {
+		t.Fatalf("got %v, want the offline copy", err)
+	}
+	if len(list.Objects) != 2 {
+		t.Fatalf("got %d objects, want 2", len(list.Objects))
+	}
+	for _, obj := range list.Objects {
+		if cached, _ := obj.Data()["cached"].(bool); !cached {
+			t.Errorf("%s is not marked cached", obj.ID)
+		}
+	}
+}
+
+func TestCopiesAreKeptPerScope(t *testing.T) {
+	next := fake.NewFakeStore().OnByID("default/w1", widget("w1"), nil)
+	s := newStore(t, next, "user1")
+	if _, err := s.ByID(newRequest(), widgets, "default/w1"); err != nil {
+		t.Fatal(err)
+	}
+	s.pending.Wait()
+
+	next.OnByID("default/w1", types.APIObject{}, errUnreachable)
+	s.scope = func(*types.APIRequest) string { return "user2" }
+	if _, err := s.ByID(newRequest(), widgets, "default/w1"); err != errUnreachable {
+		t.Errorf("got %v, want the error of the apiserver for a user with other permissions", err)
+	}
+}
+
+func TestErrorsOfTheApiserverAreNotHidden(t *testing.T) {
+	next := fake.NewFakeStore().OnByID("default/w1", widget("w1"), nil)
+	s := newStore(t, next, "user1")
+	if _, err := s.ByID(newRequest(), widgets, "default/w1"); err != nil {
+		t.Fatal(err)
+	}
+	s.pending.Wait()
+
+	forbidden := errors.New("forbidden")
+	next.OnByID("default/w1", types.APIObject{}, forbidden)
+	if _, err := s.ByID(newRequest(), widgets, "default/w1"); err != forbidden {
+		t.Errorf("got %v, want the error of the apiserver", err)
+	}
+}
+
+func TestSavesAreBounded(t *testing.T) {
+	next := fake.NewFakeStore().OnByID("default/w1", widget("w1"), nil)
+	s := newStore(t, next, "user1")
+
+	// fill every slot, the read must return without saving instead of waiting for one
+	for i := 0; i < maxPendingSaves; i++ {
+		s.saves <- struct{}{}
+	}
+	if _, err := s.ByID(newRequest(), widgets, "default/w1"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxPendingSaves; i++ {
+		<-s.saves
+	}
+	s.pending.Wait()
+
+	next.OnByID("default/w1", types.APIObject{}, errUnreachable)
+	if _, err := s.ByID(newRequest(), widgets, "default/w1"); err != errUnreachable {
+		t.Errorf("got %v, want no copy saved while the saves were full", err)
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errUnreachable, want: true},
+		{err: errors.New("dial tcp 10.0.0.1:443: i/o timeout"), want: true},
+		{err: errors.New("widgets \"w1\" not found"), want: false},
+	}
+	for _, test := range tests {
+		if got := Unreachable(test.err); got != test.want {
+			t.Errorf("Unreachable(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
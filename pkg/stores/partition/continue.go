@@ -0,0 +1,43 @@
+package partition
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// PaginationResetHeader is set to "true" on a list that was asked to continue from an expired token and returned
+// the first page instead, see Store.RestartExpired. Clients should drop the pages they already have.
+const PaginationResetHeader = "X-Steve-Pagination-Reset"
+
+// ErrContinueExpired is returned for a list whose continue token has expired. The apiserver only keeps the
+// revision of a paginated list for a few minutes, after that the list has to be started again.
+var ErrContinueExpired = validation.ErrorCode{
+	Code:   "ContinueExpired",
+	Status: http.StatusGone,
+}
+
+func continueExpired() error {
+	return apierror.NewAPIError(ErrContinueExpired, "the continue token has expired, restart the list by requesting it again without continue")
+}
+
+// restartList runs list from the first page when it failed because the continue token of the request expired
+// and s.RestartExpired is set, marking the response with PaginationResetHeader. Other errors are passed on.
+func (s *Store) restartList(apiOp *types.APIRequest, list func(resume string) (types.APIObjectList, error), resume string) (types.APIObjectList, error) {
+	result, err := list(resume)
+	if err == nil || resume == "" || (!apierrors.IsResourceExpired(err) && !apierrors.IsGone(err)) {
+		return result, err
+	}
+	if !s.RestartExpired {
+		return result, continueExpired()
+	}
+
+	result, err = list("")
+	if err == nil && apiOp.Response != nil {
+		apiOp.Response.Header().Set(PaginationResetHeader, "true")
+	}
+	return result, err
+}
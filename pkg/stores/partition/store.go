@@ -17,6 +17,9 @@ type Partitioner interface {
 
 type Store struct {
 	Partitioner Partitioner
+	// RestartExpired returns the first page of a list whose continue token expired instead of failing it with
+	// ErrContinueExpired.
+	RestartExpired bool
 }
 
 func (s *Store) getStore(apiOp *types.APIRequest, schema *types.APISchema, verb, id string) (types.Store, error) {
@@ -70,15 +73,22 @@ func (s *Store) listPartition(ctx context.Context, apiOp *types.APIRequest, sche
 }
 
 func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
-	var (
-		result types.APIObjectList
-	)
-
 	paritions, err := s.Partitioner.All(apiOp, schema, "list", "")
 	if err != nil {
-		return result, err
+		return types.APIObjectList{}, err
 	}
 
+	resume := apiOp.Request.URL.Query().Get("continue")
+	return s.restartList(apiOp, func(resume string) (types.APIObjectList, error) {
+		return s.list(apiOp, schema, paritions, resume)
+	}, resume)
+}
+
+func (s *Store) list(apiOp *types.APIRequest, schema *types.APISchema, paritions []Partition, resume string) (types.APIObjectList, error) {
+	var (
+		result types.APIObjectList
+	)
+
 	lister := ParallelPartitionLister{
 		Lister: func(ctx context.Context, partition Partition, cont string, revision string, limit int) (types.APIObjectList, error) {
 			return s.listPartition(ctx, apiOp, schema, partition, cont, revision, limit)
@@ -87,7 +97,6 @@ func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.AP
 		Partitions:  paritions,
 	}
 
-	resume := apiOp.Request.URL.Query().Get("continue")
 	limit := getLimit(apiOp.Request)
 
 	list, err := lister.List(apiOp.Context(), limit, resume)
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+type adminAccessKey struct{}
+
+// WithAdminAccess returns a context that makes the store send the requests made with it as steve itself instead
+// of impersonating the user, for stores that authorize the user by other means than RBAC, such as shared ownership.
+// It must never be derived from anything the client sends.
+func WithAdminAccess(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminAccessKey{}, true)
+}
+
+// HasAdminAccess reports whether ctx was returned by WithAdminAccess.
+func HasAdminAccess(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminAccessKey{}).(bool)
+	return admin
+}
+
+func hasAdminAccess(apiOp *types.APIRequest) bool {
+	return HasAdminAccess(apiOp.Context())
+}
+
+// adminClientGetter hands out the admin clients for requests with admin access.
+type adminClientGetter struct {
+	ClientGetter
+}
+
+func (a *adminClientGetter) K8sInterface(ctx *types.APIRequest) (kubernetes.Interface, error) {
+	if hasAdminAccess(ctx) {
+		return a.ClientGetter.AdminK8sInterface()
+	}
+	return a.ClientGetter.K8sInterface(ctx)
+}
+
+func (a *adminClientGetter) Client(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	if hasAdminAccess(ctx) {
+		return a.ClientGetter.AdminClient(ctx, schema, namespace)
+	}
+	return a.ClientGetter.Client(ctx, schema, namespace)
+}
+
+func (a *adminClientGetter) TableClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	if hasAdminAccess(ctx) {
+		return a.ClientGetter.TableAdminClient(ctx, schema, namespace)
+	}
+	return a.ClientGetter.TableClient(ctx, schema, namespace)
+}
+
+func (a *adminClientGetter) TableClientForWatch(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	if hasAdminAccess(ctx) {
+		return a.ClientGetter.TableAdminClientForWatch(ctx, schema, namespace)
+	}
+	return a.ClientGetter.TableClientForWatch(ctx, schema, namespace)
+}
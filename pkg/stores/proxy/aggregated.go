@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var (
+	ErrAggregatedAPIUnavailable = validation.ErrorCode{
+		Code:   "AggregatedAPIUnavailable",
+		Status: http.StatusServiceUnavailable,
+	}
+
+	// aggregatorUnavailableMessages are the messages the kube-aggregator uses when the backend of an
+	// APIService can not be reached.
+	aggregatorUnavailableMessages = []string{
+		"the server is currently unable to handle the request",
+		"service unavailable",
+		"no endpoints available for service",
+		"error trying to reach service",
+	}
+)
+
+// translateAggregatedError returns a retryable 503 naming the APIService if err says the backend of an aggregated
+// API is down, otherwise nil.
+func translateAggregatedError(err error, schema *types.APISchema) error {
+	if err == nil || schema == nil {
+		return nil
+	}
+
+	gvk := attributes.GVK(schema)
+	if gvk.Group == "" {
+		return nil
+	}
+
+	// the aggregator reports a down backend as ServiceUnavailable, but depending on the version it can also
+	// surface as an internal error, so the message is what identifies it
+	if !apierrors.IsServiceUnavailable(err) && !apierrors.IsInternalError(err) {
+		return nil
+	}
+	if !isAggregatorUnavailableMessage(err.Error()) {
+		return nil
+	}
+
+	apiService := gvk.Version + "." + gvk.Group
+	return apierror.NewAPIError(ErrAggregatedAPIUnavailable,
+		fmt.Sprintf("APIService %s is unavailable, the backend serving %s is down or unreachable, retry later: %v", apiService, schema.ID, err))
+}
+
+func isAggregatorUnavailableMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, pattern := range aggregatorUnavailableMessages {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// failingStore fails every get with err.
+type failingStore struct {
+	types.Store
+	err error
+}
+
+func (f *failingStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	return types.APIObject{}, f.err
+}
+
+func schemaOf(id string, gvk schema.GroupVersionKind) *types.APISchema {
+	s := &types.APISchema{Schema: &schemas.Schema{ID: id}}
+	attributes.SetGVK(s, gvk)
+	return s
+}
+
+func TestUnavailableAggregatedAPIIsRetryable(t *testing.T) {
+	nodeMetrics := schemaOf("metrics.k8s.io.nodemetrics", schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "NodeMetrics"})
+	pod := schemaOf("pod", schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+
+	for _, test := range []struct {
+		name   string
+		schema *types.APISchema
+		err    error
+		code   string
+		status int
+	}{
+		{
+			name:   "backend down",
+			schema: nodeMetrics,
+			err:    apierrors.NewServiceUnavailable("the server is currently unable to handle the request"),
+			code:   ErrAggregatedAPIUnavailable.Code,
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "backend unreachable",
+			schema: nodeMetrics,
+			err:    apierrors.NewInternalError(errors.New("error trying to reach service: dial tcp 10.43.0.10:443: connect: connection refused")),
+			code:   ErrAggregatedAPIUnavailable.Code,
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "no endpoints",
+			schema: nodeMetrics,
+			err:    apierrors.NewServiceUnavailable(`no endpoints available for service "kube-system/metrics-server"`),
+			code:   ErrAggregatedAPIUnavailable.Code,
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "other internal error",
+			schema: nodeMetrics,
+			err:    apierrors.NewInternalError(errors.New("etcdserver: request timed out")),
+			code:   "InternalError",
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "core API",
+			schema: pod,
+			err:    apierrors.NewServiceUnavailable("the server is currently unable to handle the request"),
+			code:   "ServiceUnavailable",
+			status: http.StatusServiceUnavailable,
+		},
+	} {
+		store := &errorStore{Store: &failingStore{err: test.err}}
+		_, err := store.ByID(timeoutRequest(context.Background()), test.schema, "node1")
+		apiErr, ok := err.(*apierror.APIError)
+		if !ok {
+			t.Errorf("%s: got %v, want an API error", test.name, err)
+			continue
+		}
+		if apiErr.Code.Code != test.code || apiErr.Code.Status != test.status {
+			t.Errorf("%s: got %s %d, want %s %d", test.name, apiErr.Code.Code, apiErr.Code.Status, test.code, test.status)
+		}
+		if test.code == ErrAggregatedAPIUnavailable.Code && !strings.Contains(apiErr.Message, "APIService v1beta1.metrics.k8s.io") {
+			t.Errorf("%s: got %q, want the message to name the APIService", test.name, apiErr.Message)
+		}
+	}
+}
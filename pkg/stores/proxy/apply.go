@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"strconv"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/clusterversion"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// patchType returns the patch type of the Content-Type of a PATCH, fallback for any other content type.
+func patchType(apiOp *types.APIRequest, fallback apitypes.PatchType) apitypes.PatchType {
+	switch apiOp.Request.Header.Get("content-type") {
+	case string(apitypes.JSONPatchType):
+		return apitypes.JSONPatchType
+	case string(apitypes.ApplyPatchType):
+		return apitypes.ApplyPatchType
+	}
+	return fallback
+}
+
+// applyFallback turns a server-side apply into a merge patch of the same fields, converted from YAML to JSON, for
+// clusters without server-side apply. Unlike an apply, the merge patch doesn't remove the fields the field manager
+// applied before and left out now.
+func (s *Store) applyFallback(pType apitypes.PatchType, body []byte, opts *metav1.PatchOptions) (apitypes.PatchType, []byte, error) {
+	if pType != apitypes.ApplyPatchType || s.clusterVersion.Supports(clusterversion.ServerSideApply) {
+		return pType, body, nil
+	}
+	data, err := yaml.ToJSON(body)
+	if err != nil {
+		return pType, nil, apierror.NewAPIError(ErrBadRequest, "invalid apply body: "+err.Error())
+	}
+	logrus.Debugf("sending server-side apply as a merge patch, the cluster does not support server-side apply")
+	// force only applies to applies, the apiserver rejects it with a merge patch
+	opts.Force = nil
+	return apitypes.MergePatchType, data, nil
+}
+
+// applyOptions completes the options of a server-side apply: the field manager defaults to the one of the store
+// and ?force=true makes the apply take every conflicting field from the managers that own it. Those managers lose
+// the fields without being told, a controller that owned one will either fight over it on its next apply or stop
+// managing it, so force should only be used to take over fields deliberately. Force is rejected by the apiserver for
+// other patch types, so it is only sent with applies.
+func (s *Store) applyOptions(apiOp *types.APIRequest, pType apitypes.PatchType, opts *metav1.PatchOptions) error {
+	if pType != apitypes.ApplyPatchType {
+		if opts.Force != nil && *opts.Force {
+			return apierror.NewAPIError(ErrBadRequest, "force=true can only be used with a server-side apply, Content-Type "+
+				string(apitypes.ApplyPatchType))
+		}
+		opts.Force = nil
+		return nil
+	}
+
+	if opts.FieldManager == "" {
+		// the apiserver rejects an apply without a field manager
+		opts.FieldManager = s.fieldManager
+	}
+	if value := apiOp.Request.URL.Query().Get("force"); value != "" {
+		force, err := strconv.ParseBool(value)
+		if err != nil {
+			return apierror.NewAPIError(ErrBadRequest, "force must be true or false, not "+value)
+		}
+		opts.Force = &force
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"encoding/json"
+
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"github.com/rancher/wrangler/pkg/data"
+)
+
+// LastAppliedAnnotation is the annotation kubectl apply keeps the last applied configuration in.
+const LastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// setLastApplied stores the input of a create or update as the last applied configuration, like kubectl apply
+// does, so kubectl apply and diff work on objects written through steve. Server set metadata and the
+// annotation itself are left out of the recorded configuration.
+func setLastApplied(input data.Object) error {
+	bytes, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	applied := data.Object{}
+	if err := jsonnumber.Unmarshal(bytes, &applied); err != nil {
+		return err
+	}
+
+	delete(applied, "status")
+	if metadata := applied.Map("metadata"); metadata != nil {
+		for _, field := range []string{"resourceVersion", "uid", "creationTimestamp", "generation", "managedFields", "selfLink"} {
+			delete(metadata, field)
+		}
+		if annotations := metadata.Map("annotations"); annotations != nil {
+			delete(annotations, LastAppliedAnnotation)
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+
+	bytes, err = json.Marshal(applied)
+	if err != nil {
+		return err
+	}
+	input.SetNested(string(bytes), "metadata", "annotations", LastAppliedAnnotation)
+	return nil
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"github.com/rancher/steve/pkg/stores/conformance"
+)
+
+func lastApplied(t *testing.T, cluster *fakeCluster, name string) map[string]interface{} {
+	t.Helper()
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	value, ok := cluster.objects[clusterKey("default", name)].GetAnnotations()[LastAppliedAnnotation]
+	if !ok {
+		t.Fatalf("%s has no %s annotation", name, LastAppliedAnnotation)
+	}
+	applied := map[string]interface{}{}
+	if err := jsonnumber.Unmarshal([]byte(value), &applied); err != nil {
+		t.Fatal(err)
+	}
+	return applied
+}
+
+func TestLastAppliedMatchesTheInput(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}, WithApplyAnnotation(true))
+
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":        "a",
+			"namespace":   "default",
+			"labels":      map[string]interface{}{"app": "web"},
+			"annotations": map[string]interface{}{"team": "platform"},
+		},
+		"data": map[string]interface{}{"size": json.Number("9007199254740993")},
+	}
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPost, "default", nil)
+	if _, err := store.Create(apiOp, schema, types.APIObject{Object: obj}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":        "a",
+			"namespace":   "default",
+			"labels":      map[string]interface{}{"app": "web"},
+			"annotations": map[string]interface{}{"team": "platform"},
+		},
+		"data": map[string]interface{}{"size": json.Number("9007199254740993")},
+	}
+	if got := lastApplied(t, cluster, "a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got the last applied configuration\n%v\nwant the input\n%v", got, want)
+	}
+
+	// the update records its own input, not the server set fields nor the previous annotation
+	cluster.lock.Lock()
+	current := cluster.objects[clusterKey("default", "a")].DeepCopy().Object
+	cluster.lock.Unlock()
+	current["data"] = map[string]interface{}{"size": "small"}
+	apiOp = conformance.DefaultRequest(schema)(context.Background(), http.MethodPut, "default", nil)
+	if _, err := store.Update(apiOp, schema, types.APIObject{Object: current}, "a"); err != nil {
+		t.Fatal(err)
+	}
+	want["data"] = map[string]interface{}{"size": "small"}
+	if got := lastApplied(t, cluster, "a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got the last applied configuration\n%v\nwant the input of the update\n%v", got, want)
+	}
+}
+
+func TestLastAppliedIsOffByDefault(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	if _, err := createConfigMap(NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}), schema, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cluster.objects[clusterKey("default", "a")].GetAnnotations()[LastAppliedAnnotation]; ok {
+		t.Errorf("got the %s annotation without WithApplyAnnotation", LastAppliedAnnotation)
+	}
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultFieldManager is the field manager of server-side applies that don't name one, unless WithFieldManager
+// is given.
+const DefaultFieldManager = "steve"
+
+// ErrFieldManagerConflict is returned for a server-side apply that would take fields owned by another field
+// manager, as opposed to a Conflict from a stale resourceVersion. The client can apply again with ?force=true.
+var ErrFieldManagerConflict = validation.ErrorCode{
+	Code:   string(metav1.CauseTypeFieldManagerConflict),
+	Status: http.StatusConflict,
+}
+
+// conflictManagerRegexp matches the manager in the message of a field manager conflict cause, for example:
+// conflict with "kubelet" using v1
+var conflictManagerRegexp = regexp.MustCompile(`conflict with "([^"]*)"`)
+
+// ApplyConflict is a field owned by another field manager.
+type ApplyConflict struct {
+	Field   string `json:"field"`
+	Manager string `json:"manager,omitempty"`
+	Message string `json:"message"`
+}
+
+// ApplyConflictError is a server-side apply the apiserver rejected because other field managers own some of the
+// applied fields. The errors returned by the store wrap it, use AsApplyConflictError or errors.As to get it.
+type ApplyConflictError struct {
+	Message   string
+	Conflicts []ApplyConflict
+}
+
+func (a *ApplyConflictError) Error() string {
+	return a.Message
+}
+
+// AsApplyConflictError returns the ApplyConflictError err holds, if any.
+func AsApplyConflictError(err error) (*ApplyConflictError, bool) {
+	var result *ApplyConflictError
+	if errors.As(err, &result) {
+		return result, true
+	}
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) && apiErr.Cause != nil {
+		return AsApplyConflictError(apiErr.Cause)
+	}
+	return nil, false
+}
+
+// toApplyConflictError returns the ApplyConflictError of a Conflict status with field manager conflict causes,
+// otherwise nil.
+func toApplyConflictError(err error) *ApplyConflictError {
+	if !apierrors.IsConflict(err) {
+		return nil
+	}
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return nil
+	}
+
+	result := &ApplyConflictError{
+		Message: status.Status().Message,
+	}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		conflict := ApplyConflict{
+			Field:   strings.TrimPrefix(cause.Field, "."),
+			Message: cause.Message,
+		}
+		if m := conflictManagerRegexp.FindStringSubmatch(cause.Message); len(m) == 2 {
+			conflict.Manager = m[1]
+		}
+		result.Conflicts = append(result.Conflicts, conflict)
+	}
+	if len(result.Conflicts) == 0 {
+		return nil
+	}
+	return result
+}
+
+// translateApplyConflict turns a field manager conflict into a 409 FieldManagerConflict that wraps the
+// ApplyConflictError, and records the conflicting fields for the response when the request collects field errors.
+func translateApplyConflict(ctx context.Context, err error) error {
+	a := toApplyConflictError(err)
+	if a == nil {
+		return nil
+	}
+
+	fields := make([]FieldError, 0, len(a.Conflicts))
+	for _, conflict := range a.Conflicts {
+		fields = append(fields, FieldError{
+			Field:   conflict.Field,
+			Message: conflict.Message,
+			Reason:  string(metav1.CauseTypeFieldManagerConflict),
+		})
+	}
+	if collector, ok := ctx.Value(fieldErrorsKey{}).(*fieldErrors); ok {
+		collector.set(fields)
+	}
+
+	return &apierror.APIError{
+		Code:      ErrFieldManagerConflict,
+		Message:   a.Message,
+		FieldName: a.Conflicts[0].Field,
+		Cause:     a,
+	}
+}
@@ -0,0 +1,252 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// applyResource server-side applies like the apiserver, to the data of the config maps or, for the status
+// subresource, to their status. It tracks the field manager of every applied field: applying a field another
+// manager set to a different value is a conflict unless forced.
+type applyResource struct {
+	*fakeResource
+	owners   map[string]string
+	managers []string
+}
+
+func (a *applyResource) Patch(ctx context.Context, name string, pt apitypes.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if pt != apitypes.ApplyPatchType {
+		return a.fakeResource.Patch(ctx, name, pt, data, options, subresources...)
+	}
+	if options.FieldManager == "" {
+		return nil, apierrors.NewBadRequest("PatchOptions.fieldManager is required for apply requests")
+	}
+	a.managers = append(a.managers, options.FieldManager)
+	section := "data"
+	if len(subresources) > 0 && subresources[0] == "status" {
+		section = "status"
+	}
+
+	a.cluster.lock.Lock()
+	defer a.cluster.lock.Unlock()
+	live, err := a.get(name)
+	if err != nil {
+		return nil, err
+	}
+	body, err := yaml.ToJSON(data)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	applied := map[string]interface{}{}
+	if err := json.Unmarshal(body, &applied); err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	fields, _, _ := unstructured.NestedMap(applied, section)
+	liveFields, _, _ := unstructured.NestedMap(live.Object, section)
+
+	force := options.Force != nil && *options.Force
+	var causes []metav1.StatusCause
+	for field, value := range fields {
+		path := section + "." + field
+		owner, owned := a.owners[path]
+		if owned && owner != options.FieldManager && !reflect.DeepEqual(liveFields[field], value) && !force {
+			causes = append(causes, metav1.StatusCause{
+				Type:    metav1.CauseTypeFieldManagerConflict,
+				Message: fmt.Sprintf("conflict with %q using v1", owner),
+				Field:   "." + path,
+			})
+		}
+	}
+	if len(causes) > 0 {
+		return nil, apierrors.NewApplyConflict(causes, fmt.Sprintf("Apply failed with %d conflict(s)", len(causes)))
+	}
+
+	if liveFields == nil {
+		liveFields = map[string]interface{}{}
+	}
+	for field, value := range fields {
+		liveFields[field] = value
+		a.owners[section+"."+field] = options.FieldManager
+	}
+	live.Object[section] = liveFields
+	return a.cluster.write(watch.Modified, live), nil
+}
+
+type applyClusterGetter struct {
+	*fakeClusterGetter
+	resource *applyResource
+}
+
+func (a *applyClusterGetter) Client(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return a.resource, nil
+}
+
+func (a *applyClusterGetter) TableClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return a.resource, nil
+}
+
+func newApplyClusterGetter() *applyClusterGetter {
+	cluster := newFakeCluster()
+	cluster.lock.Lock()
+	cluster.write(watch.Added, &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "a", "namespace": "default"},
+		"spec":     map[string]interface{}{"replicas": int64(1)},
+	}})
+	cluster.lock.Unlock()
+	return &applyClusterGetter{
+		fakeClusterGetter: &fakeClusterGetter{cluster: cluster},
+		resource: &applyResource{
+			fakeResource: &fakeResource{cluster: cluster, namespace: "default"},
+			owners:       map[string]string{},
+		},
+	}
+}
+
+// applyStatus server-side applies body to the status of the config map a with the query parameters of query.
+func applyStatus(store types.Store, query url.Values, body string) (*types.APIRequest, error) {
+	schema := configMapSchema()
+	apiOp := conformance.DefaultRequest(schema)(WithFieldErrors(context.Background()), http.MethodPatch, "default", query)
+	apiOp.Request = WithStatusSubresource(apiOp.Request)
+	apiOp.Request.Header.Set("Content-Type", string(apitypes.ApplyPatchType))
+	apiOp.Request.Body = ioutil.NopCloser(strings.NewReader(body))
+	_, err := store.Update(apiOp, schema, types.APIObject{Object: map[string]interface{}{}}, "a")
+	return apiOp, err
+}
+
+func liveStatus(t *testing.T, cluster *fakeCluster) map[string]interface{} {
+	t.Helper()
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	status, _, _ := unstructured.NestedMap(cluster.objects[clusterKey("default", "a")].Object, "status")
+	return status
+}
+
+func TestTwoFieldManagersApplyTheirOwnStatusFields(t *testing.T) {
+	getter := newApplyClusterGetter()
+	store := NewProxyStore(getter, nil, fakeAccessSetLookup{})
+
+	if _, err := applyStatus(store, url.Values{"fieldManager": {"scheduler"}}, "status:\n  phase: Scheduled\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyStatus(store, url.Values{"fieldManager": {"kubelet"}}, "status:\n  ready: \"true\"\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := liveStatus(t, getter.cluster); !reflect.DeepEqual(status, map[string]interface{}{"phase": "Scheduled", "ready": "true"}) {
+		t.Errorf("got status %v, want the fields of both managers", status)
+	}
+	if want := map[string]string{"status.phase": "scheduler", "status.ready": "kubelet"}; !reflect.DeepEqual(getter.resource.owners, want) {
+		t.Errorf("got owners %v, want %v", getter.resource.owners, want)
+	}
+	cluster := getter.cluster
+	cluster.lock.Lock()
+	replicas, _, _ := unstructured.NestedInt64(cluster.objects[clusterKey("default", "a")].Object, "spec", "replicas")
+	cluster.lock.Unlock()
+	if replicas != 1 {
+		t.Errorf("got %d replicas in the spec, want the spec untouched by a status apply", replicas)
+	}
+}
+
+func TestApplyOfAFieldOfAnotherManagerIsAConflict(t *testing.T) {
+	getter := newApplyClusterGetter()
+	store := NewProxyStore(getter, nil, fakeAccessSetLookup{})
+	if _, err := applyStatus(store, url.Values{"fieldManager": {"scheduler"}}, "status:\n  phase: Scheduled\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	apiOp, err := applyStatus(store, url.Values{"fieldManager": {"kubelet"}}, "status:\n  phase: Running\n")
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok || apiErr.Code != ErrFieldManagerConflict || apiErr.Code.Status != http.StatusConflict {
+		t.Fatalf("got %v, want a 409 field manager conflict", err)
+	}
+	conflict, ok := AsApplyConflictError(err)
+	if !ok {
+		t.Fatalf("got %v, want an ApplyConflictError", err)
+	}
+	if want := []ApplyConflict{{Field: "status.phase", Manager: "scheduler", Message: `conflict with "scheduler" using v1`}}; !reflect.DeepEqual(conflict.Conflicts, want) {
+		t.Errorf("got conflicts %v, want %v", conflict.Conflicts, want)
+	}
+	want := []FieldError{{Field: "status.phase", Message: `conflict with "scheduler" using v1`, Reason: string(metav1.CauseTypeFieldManagerConflict)}}
+	if fields := FieldErrors(apiOp.Context()); !reflect.DeepEqual(fields, want) {
+		t.Errorf("got field errors %v, want %v", fields, want)
+	}
+	if status := liveStatus(t, getter.cluster); status["phase"] != "Scheduled" {
+		t.Errorf("got phase %v, want the phase of the scheduler kept", status["phase"])
+	}
+
+	// applying the value the other manager set is no conflict
+	if _, err := applyStatus(store, url.Values{"fieldManager": {"kubelet"}}, "status:\n  phase: Scheduled\n"); err != nil {
+		t.Errorf("got %v applying the same value, want no conflict", err)
+	}
+}
+
+func TestForcedApplyTakesTheField(t *testing.T) {
+	getter := newApplyClusterGetter()
+	store := NewProxyStore(getter, nil, fakeAccessSetLookup{})
+	if _, err := applyStatus(store, url.Values{"fieldManager": {"scheduler"}}, "status:\n  phase: Scheduled\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := applyStatus(store, url.Values{"fieldManager": {"kubelet"}, "force": {"true"}}, "status:\n  phase: Running\n"); err != nil {
+		t.Fatal(err)
+	}
+	if status := liveStatus(t, getter.cluster); status["phase"] != "Running" {
+		t.Errorf("got phase %v, want the forced phase", status["phase"])
+	}
+	if owner := getter.resource.owners["status.phase"]; owner != "kubelet" {
+		t.Errorf("got owner %q, want the forced apply to take the field", owner)
+	}
+
+	if _, err := applyStatus(store, url.Values{"force": {"maybe"}}, "status:\n  phase: Running\n"); err == nil {
+		t.Error("got no error for an invalid force, want a bad request")
+	}
+}
+
+func TestStatusApplyFieldManagerDefaults(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "default", want: DefaultFieldManager},
+		{name: "store option", opts: []Option{WithFieldManager("controller")}, want: "controller"},
+		{name: "empty option", opts: []Option{WithFieldManager("")}, want: DefaultFieldManager},
+	} {
+		getter := newApplyClusterGetter()
+		store := NewProxyStore(getter, nil, fakeAccessSetLookup{}, test.opts...)
+		if _, err := applyStatus(store, nil, "status:\n  phase: Running\n"); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if managers := getter.resource.managers; len(managers) != 1 || managers[0] != test.want {
+			t.Errorf("%s: got field managers %v, want %s", test.name, managers, test.want)
+		}
+	}
+}
+
+func TestStaleResourceVersionIsNotAnApplyConflict(t *testing.T) {
+	conflict := apierrors.NewConflict(configMapResource, "a", fmt.Errorf("the object has been modified"))
+	if err := translateApplyConflict(context.Background(), conflict); err != nil {
+		t.Errorf("got %v, want a Conflict without field manager causes left alone", err)
+	}
+	if _, ok := AsApplyConflictError(conflict); ok {
+		t.Error("got an ApplyConflictError from a stale resourceVersion conflict")
+	}
+}
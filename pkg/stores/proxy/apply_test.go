@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	apitypes "k8s.io/apimachinery/pkg/types"
+)
+
+// applyObject patches the config map a with body of Content-Type contentType and the query parameters of query.
+func applyObject(store types.Store, contentType string, query url.Values, body string) error {
+	schema := configMapSchema()
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPatch, "default", query)
+	// the apiserver parses the query of the request into Query
+	apiOp.Query = apiOp.Request.URL.Query()
+	apiOp.Request.Header.Set("Content-Type", contentType)
+	apiOp.Request.Body = ioutil.NopCloser(strings.NewReader(body))
+	_, err := store.Update(apiOp, schema, types.APIObject{Object: map[string]interface{}{}}, "a")
+	return err
+}
+
+func liveData(t *testing.T, getter *applyClusterGetter) map[string]interface{} {
+	t.Helper()
+	getter.cluster.lock.Lock()
+	defer getter.cluster.lock.Unlock()
+	data, _ := getter.cluster.objects[clusterKey("default", "a")].Object["data"].(map[string]interface{})
+	return data
+}
+
+func TestForcedApplyResolvesTheConflictOfTwoManagers(t *testing.T) {
+	getter := newApplyClusterGetter()
+	store := NewProxyStore(getter, nil, fakeAccessSetLookup{})
+	apply := string(apitypes.ApplyPatchType)
+
+	if err := applyObject(store, apply, url.Values{"fieldManager": {"helm"}}, "data:\n  replicas: \"1\"\n  image: web\n"); err != nil {
+		t.Fatal(err)
+	}
+	err := applyObject(store, apply, url.Values{"fieldManager": {"autoscaler"}}, "data:\n  replicas: \"3\"\n")
+	if apiErr, ok := err.(*apierror.APIError); !ok || apiErr.Code != ErrFieldManagerConflict {
+		t.Fatalf("got %v, want a field manager conflict without force", err)
+	}
+	if data := liveData(t, getter); data["replicas"] != "1" {
+		t.Fatalf("got replicas %v after the conflict, want the ones of helm kept", data["replicas"])
+	}
+
+	if err := applyObject(store, apply, url.Values{"fieldManager": {"autoscaler"}, "force": {"true"}}, "data:\n  replicas: \"3\"\n"); err != nil {
+		t.Fatalf("got %v, want force to resolve the conflict", err)
+	}
+	if data := liveData(t, getter); !reflect.DeepEqual(data, map[string]interface{}{"replicas": "3", "image": "web"}) {
+		t.Errorf("got data %v, want the forced replicas and the image of helm", data)
+	}
+	want := map[string]string{"data.replicas": "autoscaler", "data.image": "helm"}
+	if owners := getter.resource.owners; !reflect.DeepEqual(owners, want) {
+		t.Errorf("got owners %v, want the conflicting field owned by the forcing manager", owners)
+	}
+
+	// force=false is an apply that stops at conflicts again
+	err = applyObject(store, apply, url.Values{"fieldManager": {"helm"}, "force": {"false"}}, "data:\n  replicas: \"1\"\n")
+	if _, ok := AsApplyConflictError(err); !ok {
+		t.Errorf("got %v, want a conflict with force=false", err)
+	}
+}
+
+func TestForceNeedsAnApply(t *testing.T) {
+	getter := newApplyClusterGetter()
+	store := NewProxyStore(getter, nil, fakeAccessSetLookup{})
+
+	for _, contentType := range []string{string(apitypes.MergePatchType), string(apitypes.StrategicMergePatchType), string(apitypes.JSONPatchType)} {
+		err := applyObject(store, contentType, url.Values{"force": {"true"}}, `{"data":{"replicas":"2"}}`)
+		if apiErr, ok := err.(*apierror.APIError); !ok || apiErr.Code.Status != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want a bad request for force without an apply", contentType, err)
+		}
+	}
+	if len(getter.resource.managers) != 0 {
+		t.Errorf("got applies of %v, want none sent", getter.resource.managers)
+	}
+}
+
+func TestApplyCanNotReplace(t *testing.T) {
+	getter := newApplyClusterGetter()
+	store := NewProxyStore(getter, nil, fakeAccessSetLookup{})
+	err := applyObject(store, string(apitypes.ApplyPatchType), url.Values{"_replace": {"true"}}, "data:\n  replicas: \"2\"\n")
+	if apiErr, ok := err.(*apierror.APIError); !ok || apiErr.Code.Status != http.StatusBadRequest {
+		t.Errorf("got %v, want a bad request for an apply with _replace=true", err)
+	}
+}
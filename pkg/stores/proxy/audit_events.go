@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// AuditEventComponent is the source and reporting component of the audit events.
+const AuditEventComponent = "steve"
+
+// auditEvent records a Kubernetes Event for a create, update or delete of obj when the schema has
+// attributes.AuditEvents set. reason is Created, Updated or Deleted, the message names the user. Events are sent
+// in the background by the recorder, failing to send one never fails the write.
+func (s *Store) auditEvent(apiOp *types.APIRequest, schema *types.APISchema, reason string, written interface{}) {
+	obj, ok := written.(runtime.Object)
+	if !attributes.AuditEvents(schema) || !ok {
+		return
+	}
+	recorder := s.auditRecorder()
+	if recorder == nil {
+		return
+	}
+
+	who := "unknown user"
+	if user, ok := request.UserFrom(apiOp.Context()); ok {
+		who = user.GetName()
+	}
+	recorder.Event(obj, corev1.EventTypeNormal, reason, fmt.Sprintf("%s %s by %s through steve", schema.ID, reason, who))
+}
+
+// auditRecorder returns the recorder given with WithEventRecorder or, on first use, one that sends events to the
+// apiserver as steve itself, users may not be allowed to create events.
+func (s *Store) auditRecorder() record.EventRecorder {
+	s.recorderOnce.Do(func() {
+		if s.recorder != nil {
+			return
+		}
+		k8s, err := s.clientGetter.AdminK8sInterface()
+		if err != nil {
+			logrus.Errorf("audit events disabled, failed to get client: %v", err)
+			return
+		}
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(reportingSink{EventSink: &typedcorev1.EventSinkImpl{
+			Interface: k8s.CoreV1().Events(""),
+		}})
+		s.recorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
+			Component: AuditEventComponent,
+		})
+	})
+	return s.recorder
+}
+
+// reportingSink sets the reporting component of the events it sends, the recorder only sets their source.
+type reportingSink struct {
+	record.EventSink
+}
+
+func (r reportingSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	if event.ReportingController == "" {
+		event.ReportingController = AuditEventComponent
+	}
+	return r.EventSink.Create(event)
+}
+
+// deletedObject is the reference to an object of schema that was deleted, for the involvedObject of its event.
+func deletedObject(apiOp *types.APIRequest, schema *types.APISchema, id string) runtime.Object {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(attributes.GVK(schema))
+	obj.SetNamespace(apiOp.Namespace)
+	obj.SetName(id)
+	return obj
+}
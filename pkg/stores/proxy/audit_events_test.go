@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+type recordedEvent struct {
+	object    runtime.Object
+	eventType string
+	reason    string
+	message   string
+}
+
+// captureRecorder keeps the events it is given instead of sending them.
+type captureRecorder struct {
+	lock   sync.Mutex
+	events []recordedEvent
+}
+
+func (c *captureRecorder) Event(object runtime.Object, eventType, reason, message string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.events = append(c.events, recordedEvent{object: object, eventType: eventType, reason: reason, message: message})
+}
+
+func (c *captureRecorder) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	c.Event(object, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (c *captureRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventType, reason, messageFmt string, args ...interface{}) {
+	c.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+func auditedConfigMapSchema() *types.APISchema {
+	schema := configMapSchema()
+	attributes.SetAuditEvents(schema, true)
+	return schema
+}
+
+func TestWritesOfAuditedSchemasAreRecorded(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := auditedConfigMapSchema()
+	recorder := &captureRecorder{}
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}, WithEventRecorder(recorder))
+
+	if _, err := createConfigMap(store, schema, "a"); err != nil {
+		t.Fatal(err)
+	}
+	resourceVersion := cluster.objects[clusterKey("default", "a")].GetResourceVersion()
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "a", "namespace": "default", "resourceVersion": resourceVersion},
+		"data":     map[string]interface{}{"key": "changed"},
+	}
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPut, "default", nil)
+	if _, err := store.Update(apiOp, schema, types.APIObject{Object: obj}, "a"); err != nil {
+		t.Fatal(err)
+	}
+	apiOp = conformance.DefaultRequest(schema)(context.Background(), http.MethodDelete, "default", nil)
+	// a delete answered with a Status is reported as a 204 ErrorCode
+	if _, err := store.Delete(apiOp, schema, "a"); err != nil {
+		if code, ok := err.(validation.ErrorCode); !ok || code.Status != http.StatusNoContent {
+			t.Fatal(err)
+		}
+	}
+
+	if len(recorder.events) != 3 {
+		t.Fatalf("got %d events, want one for the create, the update and the delete", len(recorder.events))
+	}
+	for i, reason := range []string{"Created", "Updated", "Deleted"} {
+		event := recorder.events[i]
+		if event.reason != reason || event.eventType != corev1.EventTypeNormal {
+			t.Errorf("got a %s %s event, want a Normal %s", event.eventType, event.reason, reason)
+		}
+		if want := "configmap " + reason + " by admin through steve"; event.message != want {
+			t.Errorf("%s: got message %q, want %q", reason, event.message, want)
+		}
+		accessor, err := meta.Accessor(event.object)
+		if err != nil {
+			t.Fatalf("%s: %v", reason, err)
+		}
+		gvk := event.object.GetObjectKind().GroupVersionKind()
+		if accessor.GetName() != "a" || accessor.GetNamespace() != "default" || gvk.Kind != "ConfigMap" || gvk.Version != "v1" {
+			t.Errorf("%s: got the event of %s %s/%s, want the config map default/a", reason, gvk, accessor.GetNamespace(), accessor.GetName())
+		}
+	}
+}
+
+func TestWritesOfOtherSchemasAreNotRecorded(t *testing.T) {
+	recorder := &captureRecorder{}
+	store := NewProxyStore(&fakeClusterGetter{cluster: newFakeCluster()}, nil, fakeAccessSetLookup{}, WithEventRecorder(recorder))
+	if _, err := createConfigMap(store, configMapSchema(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.events) != 0 {
+		t.Errorf("got events %v, want none for a schema without audit events", recorder.events)
+	}
+}
+
+// eventClusterGetter is a fake cluster with a fake clientset for the events.
+type eventClusterGetter struct {
+	*fakeClusterGetter
+	k8s *fake.Clientset
+}
+
+func (e *eventClusterGetter) AdminK8sInterface() (kubernetes.Interface, error) {
+	return e.k8s, nil
+}
+
+func TestAuditEventsAreSentToTheAPIServer(t *testing.T) {
+	k8s := fake.NewSimpleClientset()
+	// the recorder creates events through Events(""), the apiserver puts them in the namespace of the event but the
+	// fake clientset would create them at the cluster scope
+	k8s.PrependReactor("create", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		event := action.(k8stesting.CreateAction).GetObject().(*corev1.Event)
+		return true, event, k8s.Tracker().Create(corev1.SchemeGroupVersion.WithResource("events"), event, event.Namespace)
+	})
+	getter := &eventClusterGetter{fakeClusterGetter: &fakeClusterGetter{cluster: newFakeCluster()}, k8s: k8s}
+	store := NewProxyStore(getter, nil, fakeAccessSetLookup{})
+	if _, err := createConfigMap(store, auditedConfigMapSchema(), "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []corev1.Event
+	for start := time.Now(); len(events) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("no event was sent")
+		}
+		list, err := getter.k8s.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = list.Items
+	}
+
+	event := events[0]
+	if event.Reason != "Created" || event.Message != "configmap Created by admin through steve" {
+		t.Errorf("got %s %q, want the create", event.Reason, event.Message)
+	}
+	involved := event.InvolvedObject
+	if involved.Kind != "ConfigMap" || involved.APIVersion != "v1" || involved.Namespace != "default" || involved.Name != "a" {
+		t.Errorf("got involved object %+v, want the config map default/a", involved)
+	}
+	if event.Source.Component != AuditEventComponent || event.ReportingController != AuditEventComponent {
+		t.Errorf("got source %q and reporting component %q, want %s", event.Source.Component, event.ReportingController, AuditEventComponent)
+	}
+}
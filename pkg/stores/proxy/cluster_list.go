@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/partition"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NamespaceLister lists namespaces, a wrangler NamespaceCache implements it.
+type NamespaceLister interface {
+	List(selector labels.Selector) ([]*v1.Namespace, error)
+}
+
+// clusterListPartition replaces the per namespace partitions of a list across namespaces with a single cluster wide
+// list, filtered to partitions, when the partitions cover at least the cluster list ratio of the namespaces. One
+// list is cheaper than a request per namespace once a user can see most of them.
+func (s *Store) clusterListPartition(apiOp *types.APIRequest, schema *types.APISchema, partitions []partition.Partition) ([]partition.Partition, bool) {
+	if s.clusterListRatio <= 0 || s.namespaces == nil || apiOp.Namespace != "" || !attributes.Namespaced(schema) || len(partitions) < 2 {
+		return nil, false
+	}
+
+	namespaces, err := s.namespaces.List(labels.Everything())
+	if err != nil {
+		logrus.Debugf("failed to count namespaces, listing %s per namespace: %v", schema.ID, err)
+		return nil, false
+	}
+	if len(namespaces) == 0 || float64(len(partitions))/float64(len(namespaces)) < s.clusterListRatio {
+		return nil, false
+	}
+
+	allowed := make(map[string]Partition, len(partitions))
+	for _, p := range partitions {
+		p := p.(Partition)
+		allowed[p.Namespace] = p
+	}
+	return []partition.Partition{
+		Partition{
+			Allowed: allowed,
+		},
+	}, true
+}
+
+// listClusterFiltered lists every namespace with the admin client and keeps the objects the user may list. The user
+// can't list cluster wide, allowed, computed from the access set of the user, is what authorizes the result.
+// Namespaces are not capped by the namespace item limit.
+func (s *Store) listClusterFiltered(apiOp *types.APIRequest, schema *types.APISchema, allowed map[string]Partition) (types.APIObjectList, error) {
+	adminOp := apiOp.WithContext(WithAdminAccess(apiOp.Context()))
+	adminOp.Namespace = ""
+
+	list, err := s.List(adminOp, schema)
+	if err != nil {
+		return list, err
+	}
+
+	objects := list.Objects[:0]
+	for _, obj := range list.Objects {
+		meta := obj.Data().Map("metadata")
+		p, ok := allowed[meta.String("namespace")]
+		if !ok || (!p.All && !p.Names.Has(meta.String("name"))) {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	list.Objects = objects
+	return list, nil
+}
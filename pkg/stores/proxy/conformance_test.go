@@ -0,0 +1,351 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"github.com/rancher/wrangler/pkg/schemas"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+var configMapResource = schema.GroupResource{Resource: "configmaps"}
+
+// fakeCluster holds the config maps of the conformance tests the way the apiserver does: every write gets a new
+// resourceVersion and is sent to the open watches of the namespace.
+type fakeCluster struct {
+	lock     sync.Mutex
+	revision int
+	objects  map[string]*unstructured.Unstructured
+	watchers map[*fakeWatcher]bool
+	// patches counts the patches sent
+	patches int
+}
+
+func newFakeCluster() *fakeCluster {
+	return &fakeCluster{
+		objects:  map[string]*unstructured.Unstructured{},
+		watchers: map[*fakeWatcher]bool{},
+	}
+}
+
+func clusterKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// write stores obj with the next resourceVersion, or removes it for a delete, and sends the event to the watchers.
+// The caller holds the lock.
+func (c *fakeCluster) write(eventType watch.EventType, obj *unstructured.Unstructured) *unstructured.Unstructured {
+	c.revision++
+	obj.SetResourceVersion(strconv.Itoa(c.revision))
+	if obj.GetUID() == "" {
+		obj.SetUID(apitypes.UID(fmt.Sprintf("uid-%d", c.revision)))
+	}
+
+	key := clusterKey(obj.GetNamespace(), obj.GetName())
+	if eventType == watch.Deleted {
+		delete(c.objects, key)
+	} else {
+		c.objects[key] = obj.DeepCopy()
+	}
+	for w := range c.watchers {
+		w.send(eventType, obj)
+	}
+	return obj.DeepCopy()
+}
+
+// fakeResource is the dynamic client of the config maps of a namespace, or of every namespace.
+type fakeResource struct {
+	dynamic.ResourceInterface
+	cluster   *fakeCluster
+	namespace string
+}
+
+func (r *fakeResource) Create(ctx context.Context, obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.cluster.lock.Lock()
+	defer r.cluster.lock.Unlock()
+
+	obj = obj.DeepCopy()
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(r.namespace)
+	}
+	if obj.GetName() == "" && obj.GetGenerateName() != "" {
+		obj.SetName(obj.GetGenerateName() + strconv.Itoa(r.cluster.revision+1))
+	}
+	if obj.GetName() == "" {
+		return nil, apierrors.NewBadRequest("metadata.name is required")
+	}
+	if _, ok := r.cluster.objects[clusterKey(obj.GetNamespace(), obj.GetName())]; ok {
+		return nil, apierrors.NewAlreadyExists(configMapResource, obj.GetName())
+	}
+	return r.cluster.write(watch.Added, obj), nil
+}
+
+func (r *fakeResource) get(name string) (*unstructured.Unstructured, error) {
+	obj, ok := r.cluster.objects[clusterKey(r.namespace, name)]
+	if !ok {
+		return nil, apierrors.NewNotFound(configMapResource, name)
+	}
+	return obj.DeepCopy(), nil
+}
+
+func (r *fakeResource) Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.cluster.lock.Lock()
+	defer r.cluster.lock.Unlock()
+	return r.get(name)
+}
+
+func (r *fakeResource) Update(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.cluster.lock.Lock()
+	defer r.cluster.lock.Unlock()
+
+	live, err := r.get(obj.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if rv := obj.GetResourceVersion(); rv != "" && rv != live.GetResourceVersion() {
+		return nil, apierrors.NewConflict(configMapResource, obj.GetName(), fmt.Errorf("the object has been modified"))
+	}
+	obj = obj.DeepCopy()
+	obj.SetNamespace(live.GetNamespace())
+	obj.SetUID(live.GetUID())
+	return r.cluster.write(watch.Modified, obj), nil
+}
+
+// Patch applies merge patches, a resourceVersion in the patch has to match the live object. A patch of the status
+// subresource only changes the status, like the apiserver.
+func (r *fakeResource) Patch(ctx context.Context, name string, pt apitypes.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.cluster.lock.Lock()
+	defer r.cluster.lock.Unlock()
+
+	r.cluster.patches++
+	live, err := r.get(name)
+	if err != nil {
+		return nil, err
+	}
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	if rv, _, _ := unstructured.NestedString(patch, "metadata", "resourceVersion"); rv != "" && rv != live.GetResourceVersion() {
+		return nil, apierrors.NewConflict(configMapResource, name, fmt.Errorf("the object has been modified"))
+	}
+	if len(subresources) > 0 && subresources[0] == "status" {
+		patch = map[string]interface{}{"status": patch["status"]}
+	}
+	live.Object = mergePatch(live.Object, patch)
+	return r.cluster.write(watch.Modified, live), nil
+}
+
+func mergePatch(obj, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		if value == nil {
+			delete(obj, key)
+			continue
+		}
+		if patchMap, ok := value.(map[string]interface{}); ok {
+			if objMap, ok := obj[key].(map[string]interface{}); ok {
+				obj[key] = mergePatch(objMap, patchMap)
+				continue
+			}
+		}
+		obj[key] = value
+	}
+	return obj
+}
+
+func (r *fakeResource) Delete(ctx context.Context, name string, options metav1.DeleteOptions, subresources ...string) error {
+	r.cluster.lock.Lock()
+	defer r.cluster.lock.Unlock()
+
+	obj, err := r.get(name)
+	if err != nil {
+		return err
+	}
+	r.cluster.write(watch.Deleted, obj)
+	return nil
+}
+
+// matching returns the objects of the namespace of the client matching selector sorted by namespace and name.
+// The caller holds the lock.
+func (r *fakeResource) matching(selector labels.Selector) []*unstructured.Unstructured {
+	var keys []string
+	for key, obj := range r.cluster.objects {
+		if (r.namespace == "" || obj.GetNamespace() == r.namespace) && selector.Matches(labels.Set(obj.GetLabels())) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := make([]*unstructured.Unstructured, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, r.cluster.objects[key].DeepCopy())
+	}
+	return result
+}
+
+// List pages with continue tokens holding the index of the next object.
+func (r *fakeResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	r.cluster.lock.Lock()
+	defer r.cluster.lock.Unlock()
+
+	objs := r.matching(selector)
+	start := 0
+	if opts.Continue != "" {
+		if start, err = strconv.Atoi(opts.Continue); err != nil || start > len(objs) {
+			return nil, apierrors.NewBadRequest("invalid continue token " + opts.Continue)
+		}
+	}
+	end := len(objs)
+	if opts.Limit > 0 && start+int(opts.Limit) < end {
+		end = start + int(opts.Limit)
+	}
+
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	list.SetResourceVersion(strconv.Itoa(r.cluster.revision))
+	if end < len(objs) {
+		list.SetContinue(strconv.Itoa(end))
+	}
+	for _, obj := range objs[start:end] {
+		list.Items = append(list.Items, *obj)
+	}
+	return list, nil
+}
+
+// Watch sends the objects that exist as added when no resourceVersion is given, like the apiserver.
+func (r *fakeResource) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	r.cluster.lock.Lock()
+	defer r.cluster.lock.Unlock()
+
+	w := &fakeWatcher{
+		cluster:   r.cluster,
+		namespace: r.namespace,
+		selector:  selector,
+		result:    make(chan watch.Event, 100),
+	}
+	if opts.ResourceVersion == "" {
+		for _, obj := range r.matching(selector) {
+			w.send(watch.Added, obj)
+		}
+	}
+	r.cluster.watchers[w] = true
+	return w, nil
+}
+
+type fakeWatcher struct {
+	cluster   *fakeCluster
+	namespace string
+	selector  labels.Selector
+	result    chan watch.Event
+}
+
+// send queues the event if it matches the watch, the caller holds the lock of the cluster.
+func (w *fakeWatcher) send(eventType watch.EventType, obj *unstructured.Unstructured) {
+	if w.namespace != "" && obj.GetNamespace() != w.namespace {
+		return
+	}
+	if !w.selector.Matches(labels.Set(obj.GetLabels())) {
+		return
+	}
+	select {
+	case w.result <- watch.Event{Type: eventType, Object: obj.DeepCopy()}:
+	default:
+	}
+}
+
+func (w *fakeWatcher) Stop() {
+	w.cluster.lock.Lock()
+	defer w.cluster.lock.Unlock()
+	if w.cluster.watchers[w] {
+		delete(w.cluster.watchers, w)
+		close(w.result)
+	}
+}
+
+func (w *fakeWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// fakeClusterGetter hands out clients of the fake cluster for users and for steve alike.
+type fakeClusterGetter struct {
+	ClientGetter
+	cluster *fakeCluster
+}
+
+func (f *fakeClusterGetter) client(namespace string) dynamic.ResourceInterface {
+	return &fakeResource{cluster: f.cluster, namespace: namespace}
+}
+
+func (f *fakeClusterGetter) Client(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return f.client(namespace), nil
+}
+
+func (f *fakeClusterGetter) AdminClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return f.client(namespace), nil
+}
+
+func (f *fakeClusterGetter) TableClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return f.client(namespace), nil
+}
+
+func (f *fakeClusterGetter) TableAdminClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return f.client(namespace), nil
+}
+
+func (f *fakeClusterGetter) TableClientForWatch(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return f.client(namespace), nil
+}
+
+func (f *fakeClusterGetter) TableAdminClientForWatch(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return f.client(namespace), nil
+}
+
+// configMapSchema is the schema of the config maps of the fake cluster with every verb granted in every namespace.
+func configMapSchema() *types.APISchema {
+	s := &types.APISchema{Schema: &schemas.Schema{ID: "configmap"}}
+	attributes.SetGVK(s, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	attributes.SetResource(s, "configmaps")
+	attributes.SetNamespaced(s, true)
+
+	access := accesscontrol.AccessListByVerb{}
+	for _, verb := range []string{"get", "list", "watch", "create", "update", "patch", "delete"} {
+		access[verb] = accesscontrol.AccessList{{Namespace: accesscontrol.All, ResourceName: accesscontrol.All}}
+	}
+	attributes.SetAccess(s, access)
+	return s
+}
+
+func TestProxyStoreConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) conformance.Setup {
+		getter := &fakeClusterGetter{cluster: newFakeCluster()}
+		return conformance.Setup{
+			Store:  NewProxyStore(getter, nil, fakeAccessSetLookup{}),
+			Schema: configMapSchema(),
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/rancher/wrangler/pkg/slice"
+)
+
+var patternCache sync.Map
+
+// constraintValidator checks write input against the enum and pattern constraints recorded on the schema
+// and its sub-schemas so that bad input is rejected with a message that says what is allowed, instead of
+// the raw apiserver validation error.
+type constraintValidator struct {
+	schemas      *types.APISchemas
+	descriptions map[string]string
+}
+
+func (s *Store) validateConstraints(apiOp *types.APIRequest, schema *types.APISchema, input map[string]interface{}) error {
+	if !s.validateInput || apiOp.Schemas == nil {
+		return nil
+	}
+	v := constraintValidator{
+		schemas:      apiOp.Schemas,
+		descriptions: s.patternDescriptions,
+	}
+	return v.validateObject("", schema, input)
+}
+
+func (v *constraintValidator) validateObject(path string, schema *types.APISchema, obj map[string]interface{}) error {
+	if schema == nil {
+		return nil
+	}
+
+	constraints := attributes.FieldConstraints(schema)
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	// sorted so the first reported error is stable between requests
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field, ok := schema.ResourceFields[key]
+		if !ok {
+			continue
+		}
+		value := obj[key]
+		fieldPath := joinPath(path, key)
+
+		if constraint, ok := constraints[key]; ok {
+			if err := v.validateConstraint(fieldPath, constraint, value); err != nil {
+				return err
+			}
+		}
+
+		if err := v.validateNested(fieldPath, field.Type, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *constraintValidator) validateNested(path, fieldType string, value interface{}) error {
+	switch {
+	case strings.HasPrefix(fieldType, "array["):
+		values, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		elemType := strings.TrimSuffix(strings.TrimPrefix(fieldType, "array["), "]")
+		for i, elem := range values {
+			if err := v.validateNested(fmt.Sprintf("%s[%d]", path, i), elemType, elem); err != nil {
+				return err
+			}
+		}
+	case strings.HasPrefix(fieldType, "map["):
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		elemType := strings.TrimSuffix(strings.TrimPrefix(fieldType, "map["), "]")
+		for key, elem := range values {
+			if err := v.validateNested(fmt.Sprintf("%s[%s]", path, key), elemType, elem); err != nil {
+				return err
+			}
+		}
+	default:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		return v.validateObject(path, v.schemas.LookupSchema(fieldType), obj)
+	}
+	return nil
+}
+
+func (v *constraintValidator) validateConstraint(path string, constraint attributes.FieldConstraint, value interface{}) error {
+	if !constraint.Items {
+		return v.validateValue(path, constraint, value)
+	}
+
+	switch values := value.(type) {
+	case []interface{}:
+		for i, elem := range values {
+			if err := v.validateValue(fmt.Sprintf("%s[%d]", path, i), constraint, elem); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for key, elem := range values {
+			if err := v.validateValue(fmt.Sprintf("%s[%s]", path, key), constraint, elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *constraintValidator) validateValue(path string, constraint attributes.FieldConstraint, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return nil
+	}
+
+	str := convert.ToString(value)
+	if len(constraint.Enum) > 0 && !slice.ContainsString(constraint.Enum, str) {
+		return apierror.NewFieldAPIError(validation.InvalidOption, path,
+			fmt.Sprintf("%q is not a valid value for %s, must be one of: %s", str, path, strings.Join(constraint.Enum, ", ")))
+	}
+
+	if constraint.Pattern == "" {
+		return nil
+	}
+	re := compilePattern(constraint.Pattern)
+	if re == nil || re.MatchString(str) {
+		return nil
+	}
+
+	description, ok := v.descriptions[constraint.Pattern]
+	if !ok {
+		description = "match the pattern " + constraint.Pattern
+	}
+	return apierror.NewFieldAPIError(validation.InvalidFormat, path,
+		fmt.Sprintf("%q is not a valid value for %s, must %s", str, path, description))
+}
+
+// compilePattern returns nil for patterns Go can not compile, those are left for the apiserver to enforce.
+func compilePattern(pattern string) *regexp.Regexp {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	patternCache.Store(pattern, re)
+	return re
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apiserver/pkg/storage/names"
+	"k8s.io/client-go/dynamic"
+)
+
+// create sends the create request, retrying transient failures when create retries are enabled. Failures to
+// call an admission webhook are retried separately by createWithWebhookRetry, they are known not to be persisted.
+//
+// Creates are not idempotent, so a request that failed from the client's point of view may still have
+// been applied by the apiserver. A retry is only attempted after a get for the object's name returns
+// NotFound, which confirms the previous attempt was not persisted. That requires the name to be known
+// up front: when only generateName is set the name is generated here instead of by the apiserver. If
+// the object is found, or its existence can not be checked, the original error is returned because a
+// retry could create a duplicate or hide a genuine conflict.
+func (s *Store) create(ctx context.Context, client dynamic.ResourceInterface, obj *unstructured.Unstructured, opts metav1.CreateOptions) (*unstructured.Unstructured, error) {
+	if s.createRetries <= 0 {
+		return s.createWithWebhookRetry(ctx, client, obj, opts)
+	}
+
+	name := obj.GetName()
+	if name == "" && obj.GetGenerateName() != "" {
+		name = names.SimpleNameGenerator.GenerateName(obj.GetGenerateName())
+		obj.SetName(name)
+	}
+
+	backoff := s.createRetryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := s.createWithWebhookRetry(ctx, client, obj, opts)
+		if err == nil || name == "" || attempt >= s.createRetries || !isTransientCreateError(err) {
+			return resp, err
+		}
+
+		if _, getErr := client.Get(ctx, name, metav1.GetOptions{}); !apierrors.IsNotFound(getErr) {
+			return resp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func isTransientCreateError(err error) bool {
+	if apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsTooManyRequests(err) {
+		return true
+	}
+
+	if utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err) {
+		return true
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/data"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/transport"
+)
+
+// CreatorIDAnnotation records the user that created an object, as in Rancher.
+const CreatorIDAnnotation = "field.cattle.io/creatorId"
+
+// CreatorIDSource returns the name of the user to record as the creator of an object created by apiOp, an empty
+// name leaves the object unstamped.
+type CreatorIDSource func(apiOp *types.APIRequest) string
+
+// AuthenticatedUser is the CreatorIDSource for steve authenticating its clients itself, it returns the user of
+// the request context. Requests to the apiserver impersonate this user, never steve's own service account, so it
+// matches the user the apiserver authorizes.
+func AuthenticatedUser(apiOp *types.APIRequest) string {
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return ""
+	}
+	return user.GetName()
+}
+
+// ImpersonatedUser is the CreatorIDSource for steve running behind a proxy that authenticates as its own service
+// account and names the end user in an Impersonate-User header. It returns that user, and the authenticated user
+// when the header is not set.
+func ImpersonatedUser(apiOp *types.APIRequest) string {
+	if apiOp.Request != nil {
+		if name := apiOp.Request.Header.Get(transport.ImpersonateUserHeader); name != "" {
+			return name
+		}
+	}
+	return AuthenticatedUser(apiOp)
+}
+
+// setCreatorID stamps input with the creator returned by source, replacing a creator sent by the client.
+func setCreatorID(apiOp *types.APIRequest, source CreatorIDSource, input data.Object) {
+	if source == nil {
+		return
+	}
+	if creator := source(apiOp); creator != "" {
+		input.SetNested(creator, "metadata", "annotations", CreatorIDAnnotation)
+	}
+}
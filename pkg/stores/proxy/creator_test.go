@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/transport"
+)
+
+var proxyAccount = &user.DefaultInfo{Name: "system:serviceaccount:cattle-system:proxy", Groups: []string{user.AllAuthenticated}}
+
+// createAs creates the config map name through an impersonating proxy, authenticated as its service account, for
+// the end user impersonated, and returns the creator stamped on it.
+func createAs(t *testing.T, source CreatorIDSource, name, impersonated string) string {
+	t.Helper()
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}, WithCreatorID(source))
+
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPost, "default", nil)
+	apiOp = apiOp.WithContext(request.WithUser(apiOp.Context(), proxyAccount))
+	if impersonated != "" {
+		apiOp.Request.Header.Set(transport.ImpersonateUserHeader, impersonated)
+	}
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+			// a client can't claim to be the creator
+			"annotations": map[string]interface{}{CreatorIDAnnotation: "mallory"},
+		},
+	}
+	if _, err := store.Create(apiOp, schema, types.APIObject{Object: obj}); err != nil {
+		t.Fatal(err)
+	}
+	return cluster.objects[clusterKey("default", name)].GetAnnotations()[CreatorIDAnnotation]
+}
+
+func TestCreatorIsTheImpersonatedEndUser(t *testing.T) {
+	if creator := createAs(t, ImpersonatedUser, "a", "alice"); creator != "alice" {
+		t.Errorf("got creator %q, want the end user alice and not the proxy's service account", creator)
+	}
+	if creator := createAs(t, ImpersonatedUser, "a", ""); creator != proxyAccount.Name {
+		t.Errorf("got creator %q without impersonation, want the authenticated user", creator)
+	}
+}
+
+func TestCreatorIsTheAuthenticatedUser(t *testing.T) {
+	if creator := createAs(t, AuthenticatedUser, "a", "alice"); creator != proxyAccount.Name {
+		t.Errorf("got creator %q, want the authenticated user whatever the headers", creator)
+	}
+}
@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+const defaultDeduplicationWindow = 50 * time.Millisecond
+
+type pendingEvent struct {
+	event types.APIEvent
+	timer *time.Timer
+}
+
+// DeduplicatingWatcher collapses bursts of change events for the same object. The first change for an object is
+// held for the window and every change arriving in the meantime replaces it, so only the latest is emitted. A
+// create followed by changes is emitted as a create with the latest object. Removes flush the held event first so
+// the order for an object is kept. All other events are passed through immediately.
+type DeduplicatingWatcher struct {
+	sync.Mutex
+
+	window  time.Duration
+	input   chan types.APIEvent
+	result  chan types.APIEvent
+	pending map[string]*pendingEvent
+	closed  bool
+}
+
+func NewDeduplicatingWatcher(input chan types.APIEvent, window time.Duration) *DeduplicatingWatcher {
+	if window <= 0 {
+		window = defaultDeduplicationWindow
+	}
+	d := &DeduplicatingWatcher{
+		window:  window,
+		input:   input,
+		result:  make(chan types.APIEvent),
+		pending: map[string]*pendingEvent{},
+	}
+	go d.run()
+	return d
+}
+
+func (d *DeduplicatingWatcher) ResultChan() chan types.APIEvent {
+	return d.result
+}
+
+func (d *DeduplicatingWatcher) run() {
+	for event := range d.input {
+		d.add(event)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	for key := range d.pending {
+		d.flush(key)
+	}
+	d.closed = true
+	close(d.result)
+}
+
+func dedupeKey(event types.APIEvent) string {
+	if event.Error != nil || event.Object.ID == "" {
+		return ""
+	}
+	return event.Object.Type + "/" + event.Object.ID
+}
+
+func (d *DeduplicatingWatcher) add(event types.APIEvent) {
+	d.Lock()
+	defer d.Unlock()
+
+	key := dedupeKey(event)
+	if key == "" {
+		d.result <- event
+		return
+	}
+
+	switch event.Name {
+	case types.ChangeAPIEvent, types.CreateAPIEvent:
+		if p, ok := d.pending[key]; ok {
+			if p.event.Name == types.CreateAPIEvent {
+				event.Name = types.CreateAPIEvent
+			}
+			p.event = event
+			return
+		}
+		d.pending[key] = &pendingEvent{
+			event: event,
+			timer: time.AfterFunc(d.window, func() {
+				d.Lock()
+				defer d.Unlock()
+				if !d.closed {
+					d.flush(key)
+				}
+			}),
+		}
+	default:
+		d.flush(key)
+		d.result <- event
+	}
+}
+
+// flush must be called with the lock held.
+func (d *DeduplicatingWatcher) flush(key string) {
+	p, ok := d.pending[key]
+	if !ok {
+		return
+	}
+	p.timer.Stop()
+	delete(d.pending, key)
+	d.result <- p.event
+}
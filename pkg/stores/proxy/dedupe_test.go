@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+func podEvent(name, id string, revision int) types.APIEvent {
+	return types.APIEvent{
+		Name:     name,
+		Revision: strconv.Itoa(revision),
+		Object:   types.APIObject{Type: "pod", ID: id},
+	}
+}
+
+// collect returns the events of c until it is closed.
+func collect(t *testing.T, c chan types.APIEvent) []types.APIEvent {
+	t.Helper()
+	var result []types.APIEvent
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-c:
+			if !ok {
+				return result
+			}
+			result = append(result, event)
+		case <-timeout:
+			t.Fatalf("the watcher did not close, got %v", result)
+		}
+	}
+}
+
+func TestDeduplicatingWatcherCollapsesABurst(t *testing.T) {
+	input := make(chan types.APIEvent, 10)
+	result := NewDeduplicatingWatcher(input, 50*time.Millisecond).ResultChan()
+
+	// the five changes arrive well within 10ms, the window is 50ms
+	for i := 1; i <= 5; i++ {
+		input <- podEvent(types.ChangeAPIEvent, "default/pod-1", i)
+	}
+
+	select {
+	case event := <-result:
+		if event.Name != types.ChangeAPIEvent || event.Revision != "5" {
+			t.Errorf("got %s at revision %s, want the last change", event.Name, event.Revision)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event after the window")
+	}
+
+	close(input)
+	if events := collect(t, result); len(events) != 0 {
+		t.Errorf("got %d more events, want the burst collapsed to 1", len(events))
+	}
+}
+
+func TestDeduplicatingWatcherKeepsObjectsApart(t *testing.T) {
+	input := make(chan types.APIEvent, 10)
+	result := NewDeduplicatingWatcher(input, time.Minute).ResultChan()
+
+	input <- podEvent(types.ChangeAPIEvent, "default/pod-1", 1)
+	input <- podEvent(types.ChangeAPIEvent, "default/pod-2", 2)
+	input <- podEvent(types.ChangeAPIEvent, "default/pod-1", 3)
+	close(input)
+
+	revisions := map[string]string{}
+	for _, event := range collect(t, result) {
+		revisions[event.Object.ID] = event.Revision
+	}
+	if len(revisions) != 2 || revisions["default/pod-1"] != "3" || revisions["default/pod-2"] != "2" {
+		t.Errorf("got %v, want the latest change of each pod", revisions)
+	}
+}
+
+func TestDeduplicatingWatcherKeepsCreates(t *testing.T) {
+	input := make(chan types.APIEvent, 10)
+	result := NewDeduplicatingWatcher(input, time.Minute).ResultChan()
+
+	input <- podEvent(types.CreateAPIEvent, "default/pod-1", 1)
+	input <- podEvent(types.ChangeAPIEvent, "default/pod-1", 2)
+	close(input)
+
+	events := collect(t, result)
+	if len(events) != 1 || events[0].Name != types.CreateAPIEvent || events[0].Revision != "2" {
+		t.Errorf("got %v, want one create with the latest object", events)
+	}
+}
+
+func TestDeduplicatingWatcherFlushesBeforeRemove(t *testing.T) {
+	input := make(chan types.APIEvent, 10)
+	result := NewDeduplicatingWatcher(input, time.Minute).ResultChan()
+
+	input <- podEvent(types.ChangeAPIEvent, "default/pod-1", 1)
+	input <- podEvent(types.RemoveAPIEvent, "default/pod-1", 2)
+	close(input)
+
+	events := collect(t, result)
+	if len(events) != 2 || events[0].Name != types.ChangeAPIEvent || events[1].Name != types.RemoveAPIEvent {
+		t.Errorf("got %v, want the held change before the remove", events)
+	}
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// ErrObjectDeleting is returned for an update of an object that has a deletionTimestamp, it only exists until its
+// finalizers are done so other changes would be lost. Updates that only change the finalizers are let through.
+var ErrObjectDeleting = validation.ErrorCode{
+	Code:   "ObjectDeleting",
+	Status: http.StatusConflict,
+}
+
+var (
+	// ignoredUpdateFields are the top level fields of an update input that never count as a change, they are
+	// either read only or added by steve to the objects it returns.
+	ignoredUpdateFields = map[string]bool{
+		"apiVersion": true,
+		"kind":       true,
+		"metadata":   true,
+		"status":     true,
+		"id":         true,
+		"type":       true,
+		"links":      true,
+		"actions":    true,
+	}
+	// updatedMetadataFields are the fields of metadata that an update other than of the finalizers can change.
+	updatedMetadataFields = []string{"labels", "annotations", "ownerReferences"}
+)
+
+// checkNotDeleting fails an update of input with ErrObjectDeleting when the live object is being deleted and input
+// changes more than its finalizers. Fields missing from input count as unchanged, which is accurate for merges
+// and errs on the side of letting a replacement through.
+func checkNotDeleting(apiOp *types.APIRequest, client dynamic.ResourceInterface, id string, input map[string]interface{}) error {
+	live, err := client.Get(apiOp.Context(), id, metav1.GetOptions{})
+	if err != nil {
+		// the update reports the error
+		return nil
+	}
+	rowToObject(live)
+	if live.GetDeletionTimestamp() == nil {
+		return nil
+	}
+
+	for key, value := range input {
+		if !ignoredUpdateFields[key] && !sameJSON(value, live.Object[key]) {
+			return objectDeleting(live.GetName(), live.GetFinalizers())
+		}
+	}
+	metadata, _ := input["metadata"].(map[string]interface{})
+	liveMetadata, _ := live.Object["metadata"].(map[string]interface{})
+	for _, key := range updatedMetadataFields {
+		if value, ok := metadata[key]; ok && !sameJSON(value, liveMetadata[key]) {
+			return objectDeleting(live.GetName(), live.GetFinalizers())
+		}
+	}
+	return nil
+}
+
+func objectDeleting(name string, finalizers []string) error {
+	return apierror.NewAPIError(ErrObjectDeleting, fmt.Sprintf("object %s is being deleted, it waits for the finalizers %v and only they can be updated", name, finalizers))
+}
+
+// sameJSON compares a and b as JSON, input decoded from a request holds float64 numbers where objects from the
+// apiserver hold int64.
+func sameJSON(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizeJSON(a), normalizeJSON(b))
+}
+
+func normalizeJSON(value interface{}) interface{} {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var result interface{}
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return value
+	}
+	return result
+}
+
+// warnTerminating adds a warning to a list response that counts the listed objects being deleted.
+func warnTerminating(apiOp *types.APIRequest, schema *types.APISchema, list types.APIObjectList) {
+	terminating := 0
+	for _, obj := range list.Objects {
+		if obj.Object != nil && obj.Data().String("metadata", "deletionTimestamp") != "" {
+			terminating++
+		}
+	}
+	if terminating > 0 {
+		addWarning(apiOp, fmt.Sprintf("%d %s listed are being deleted", terminating, schema.ID))
+	}
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// deletingConfigMap writes the config map name to the cluster mid-deletion: it has a deletionTimestamp and
+// finalizers still to run. It returns the resourceVersion of the config map.
+func deletingConfigMap(cluster *fakeCluster, name string) string {
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name, "namespace": "default"},
+		"data":     map[string]interface{}{"key": "value"},
+	}}
+	obj.SetFinalizers([]string{"example.com/cleanup", "example.com/backup"})
+	deleted := metav1.Now()
+	obj.SetDeletionTimestamp(&deleted)
+	return cluster.write(watch.Added, obj).GetResourceVersion()
+}
+
+func TestUpdateOfAnObjectBeingDeletedIsRejected(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{})
+	resourceVersion := deletingConfigMap(cluster, "a")
+
+	for _, test := range []struct {
+		name     string
+		metadata map[string]interface{}
+		data     interface{}
+	}{
+		{name: "data", data: map[string]interface{}{"key": "changed"}},
+		{name: "labels", metadata: map[string]interface{}{"labels": map[string]interface{}{"app": "web"}}},
+	} {
+		metadata := map[string]interface{}{"name": "a", "namespace": "default", "resourceVersion": resourceVersion}
+		for key, value := range test.metadata {
+			metadata[key] = value
+		}
+		obj := map[string]interface{}{"metadata": metadata, "data": map[string]interface{}{"key": "value"}}
+		if test.data != nil {
+			obj["data"] = test.data
+		}
+
+		apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPut, "default", nil)
+		_, err := store.Update(apiOp, schema, types.APIObject{Object: obj}, "a")
+		apiErr, ok := err.(*apierror.APIError)
+		if !ok || apiErr.Code != ErrObjectDeleting || apiErr.Code.Status != http.StatusConflict {
+			t.Errorf("%s: got %v, want a 409 ObjectDeleting", test.name, err)
+			continue
+		}
+		if !strings.Contains(apiErr.Message, "example.com/cleanup") {
+			t.Errorf("%s: got message %q, want the pending finalizers named", test.name, apiErr.Message)
+		}
+	}
+
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	if live := cluster.objects[clusterKey("default", "a")]; live.GetResourceVersion() != resourceVersion {
+		t.Error("got the object updated, want the rejected updates not sent")
+	}
+}
+
+func TestFinalizerUpdateOfAnObjectBeingDeletedIsAllowed(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{})
+	resourceVersion := deletingConfigMap(cluster, "a")
+
+	// the input carries the fields steve added to the object it returned, they don't count as changes
+	obj := map[string]interface{}{
+		"id":   "default/a",
+		"type": "configmap",
+		"metadata": map[string]interface{}{
+			"name":            "a",
+			"namespace":       "default",
+			"resourceVersion": resourceVersion,
+			"finalizers":      []interface{}{"example.com/backup"},
+			"state":           map[string]interface{}{"name": "removing"},
+		},
+		"data": map[string]interface{}{"key": "value"},
+	}
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPut, "default", nil)
+	if _, err := store.Update(apiOp, schema, types.APIObject{Object: obj}, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	if finalizers := cluster.objects[clusterKey("default", "a")].GetFinalizers(); !reflect.DeepEqual(finalizers, []string{"example.com/backup"}) {
+		t.Errorf("got finalizers %v, want the finalizer update applied", finalizers)
+	}
+}
+
+func TestPatchOfAnObjectBeingDeleted(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{})
+	deletingConfigMap(cluster, "a")
+
+	for _, test := range []struct {
+		name     string
+		patch    string
+		rejected bool
+	}{
+		{name: "data", patch: `{"data":{"key":"changed"}}`, rejected: true},
+		{name: "annotations", patch: `{"metadata":{"annotations":{"note":"x"}}}`, rejected: true},
+		{name: "same data", patch: `{"data":{"key":"value"}}`},
+		{name: "finalizers", patch: `{"metadata":{"finalizers":null}}`},
+	} {
+		apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPatch, "default", nil)
+		apiOp.Request.Body = ioutil.NopCloser(strings.NewReader(test.patch))
+		_, err := store.Update(apiOp, schema, types.APIObject{Object: map[string]interface{}{}}, "a")
+		if apiErr, ok := err.(*apierror.APIError); test.rejected && (!ok || apiErr.Code != ErrObjectDeleting) {
+			t.Errorf("%s: got %v, want ObjectDeleting", test.name, err)
+		} else if !test.rejected && err != nil {
+			t.Errorf("%s: got %v, want the patch let through", test.name, err)
+		}
+	}
+}
+
+func TestListWarnsOfTerminatingObjects(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{})
+	if _, err := createConfigMap(store, schema, "live"); err != nil {
+		t.Fatal(err)
+	}
+
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodGet, "default", nil)
+	if _, err := store.List(apiOp, schema); err != nil {
+		t.Fatal(err)
+	}
+	if warnings := apiOp.Response.Header()["Warning"]; len(warnings) != 0 {
+		t.Errorf("got warnings %v, want none without terminating objects", warnings)
+	}
+
+	deletingConfigMap(cluster, "a")
+	deletingConfigMap(cluster, "b")
+	apiOp = conformance.DefaultRequest(schema)(context.Background(), http.MethodGet, "default", nil)
+	list, err := store.List(apiOp, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Objects) != 3 {
+		t.Fatalf("got %d objects, want the terminating ones listed too", len(list.Objects))
+	}
+	want := []string{`299 - "2 configmap listed are being deleted"`}
+	if warnings := apiOp.Response.Header()["Warning"]; !reflect.DeepEqual(warnings, want) {
+		t.Errorf("got warnings %v, want %v", warnings, want)
+	}
+}
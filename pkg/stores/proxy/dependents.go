@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	previewPageSize = 500
+	// maxPreviewScan caps how many candidate objects are inspected for a single preview.
+	maxPreviewScan = 5000
+)
+
+// dependentKinds lists the kinds the garbage collector will cascade a delete to for well known owners.
+var dependentKinds = map[schema.GroupKind][]schema.GroupKind{
+	{Group: "apps", Kind: "Deployment"}:  {{Group: "apps", Kind: "ReplicaSet"}},
+	{Group: "apps", Kind: "ReplicaSet"}:  {{Kind: "Pod"}},
+	{Group: "apps", Kind: "StatefulSet"}: {{Kind: "Pod"}, {Group: "apps", Kind: "ControllerRevision"}},
+	{Group: "apps", Kind: "DaemonSet"}:   {{Kind: "Pod"}, {Group: "apps", Kind: "ControllerRevision"}},
+	{Group: "batch", Kind: "Job"}:        {{Kind: "Pod"}},
+	{Group: "batch", Kind: "CronJob"}:    {{Group: "batch", Kind: "Job"}},
+}
+
+type DependentsPreview struct {
+	Dependents []Dependent `json:"dependents"`
+	// Truncated is set when the traversal cap was reached and the list may be incomplete.
+	Truncated bool `json:"truncated,omitempty"`
+	// Skipped are the types of dependents the user is not allowed to list.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+type Dependent struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func previewDependents(apiOp *types.APIRequest) bool {
+	return convert.ToBool(apiOp.Request.URL.Query().Get("previewDependents"))
+}
+
+// previewDelete returns the objects one ownerReference level below the object that a delete would cascade to,
+// without deleting anything. Dependents are listed with the user's own client so the preview only shows what the
+// user could see.
+func (s *Store) previewDelete(apiOp *types.APIRequest, apiSchema *types.APISchema, id string) (types.APIObject, error) {
+	owner, err := s.byID(apiOp, apiSchema, id)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+
+	preview := &DependentsPreview{
+		Dependents: []Dependent{},
+	}
+	scanned := 0
+
+	for _, gk := range dependentKinds[attributes.GVK(apiSchema).GroupKind()] {
+		depSchema := schemaForGroupKind(apiOp.Schemas, gk)
+		if depSchema == nil {
+			continue
+		}
+
+		client, err := s.clientGetter.Client(apiOp, depSchema, owner.GetNamespace())
+		if err != nil {
+			return types.APIObject{}, err
+		}
+
+		opts := metav1.ListOptions{Limit: previewPageSize}
+		for {
+			list, err := client.List(apiOp.Context(), opts)
+			if apierrors.IsForbidden(err) {
+				preview.Skipped = append(preview.Skipped, depSchema.ID)
+				break
+			} else if err != nil {
+				return types.APIObject{}, err
+			}
+
+			for _, obj := range list.Items {
+				for _, ref := range obj.GetOwnerReferences() {
+					if ref.UID != owner.GetUID() {
+						continue
+					}
+					dep := Dependent{
+						Type:      depSchema.ID,
+						ID:        obj.GetName(),
+						Name:      obj.GetName(),
+						Namespace: obj.GetNamespace(),
+					}
+					if dep.Namespace != "" {
+						dep.ID = dep.Namespace + "/" + dep.Name
+					}
+					preview.Dependents = append(preview.Dependents, dep)
+					break
+				}
+			}
+
+			scanned += len(list.Items)
+			if scanned >= maxPreviewScan {
+				preview.Truncated = list.GetContinue() != ""
+				break
+			}
+			if list.GetContinue() == "" {
+				break
+			}
+			opts.Continue = list.GetContinue()
+		}
+
+		if scanned >= maxPreviewScan {
+			break
+		}
+	}
+
+	return types.APIObject{
+		Type:   "dependentsPreview",
+		ID:     id,
+		Object: preview,
+	}, nil
+}
+
+func schemaForGroupKind(schemas *types.APISchemas, gk schema.GroupKind) *types.APISchema {
+	if schemas == nil {
+		return nil
+	}
+	for _, s := range schemas.Schemas {
+		if attributes.GVK(s).GroupKind() == gk && attributes.PreferredVersion(s) == "" {
+			return s
+		}
+	}
+	return nil
+}
@@ -13,38 +13,53 @@ type errorStore struct {
 
 func (e *errorStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
 	data, err := e.Store.ByID(apiOp, schema, id)
-	return data, translateError(err)
+	return data, translateError(err, schema)
 }
 
 func (e *errorStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
 	data, err := e.Store.List(apiOp, schema)
-	return data, translateError(err)
+	if err == nil {
+		warnTerminating(apiOp, schema, data)
+	}
+	return data, translateError(err, schema)
 }
 
 func (e *errorStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
 	data, err := e.Store.Create(apiOp, schema, data)
-	return data, translateError(err)
+	if validationErr := translateValidationError(apiOp.Context(), err); validationErr != nil {
+		return data, validationErr
+	}
+	return data, translateError(err, schema)
 
 }
 
 func (e *errorStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
 	data, err := e.Store.Update(apiOp, schema, data, id)
-	return data, translateError(err)
+	if conflictErr := translateApplyConflict(apiOp.Context(), err); conflictErr != nil {
+		return data, conflictErr
+	}
+	if validationErr := translateValidationError(apiOp.Context(), err); validationErr != nil {
+		return data, validationErr
+	}
+	return data, translateError(err, schema)
 
 }
 
 func (e *errorStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
 	data, err := e.Store.Delete(apiOp, schema, id)
-	return data, translateError(err)
+	return data, translateError(err, schema)
 
 }
 
 func (e *errorStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, wr types.WatchRequest) (chan types.APIEvent, error) {
 	data, err := e.Store.Watch(apiOp, schema, wr)
-	return data, translateError(err)
+	return data, translateError(err, schema)
 }
 
-func translateError(err error) error {
+func translateError(err error, schema *types.APISchema) error {
+	if aggregatedErr := translateAggregatedError(err, schema); aggregatedErr != nil {
+		return aggregatedErr
+	}
 	if apiError, ok := err.(errors.APIStatus); ok {
 		status := apiError.Status()
 		return apierror.NewAPIError(validation.ErrorCode{
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type eventTypesKey struct{}
+
+// WithWatchEventTypes returns a context for watches that only send events of the given types, for example a
+// garbage collector that only needs watch.Deleted. Clients can ask for the same with ?eventTypes=ADDED,DELETED.
+// Bookmarks and errors are always sent, and no types sends every event.
+func WithWatchEventTypes(ctx context.Context, eventTypes ...watch.EventType) context.Context {
+	return context.WithValue(ctx, eventTypesKey{}, eventTypes)
+}
+
+// watchEventTypes returns the event types the watch of apiOp wants, nil for all of them.
+func watchEventTypes(apiOp *types.APIRequest) map[watch.EventType]bool {
+	eventTypes, _ := apiOp.Context().Value(eventTypesKey{}).([]watch.EventType)
+	if len(eventTypes) == 0 && apiOp.Request != nil {
+		for _, value := range apiOp.Request.URL.Query()["eventTypes"] {
+			for _, eventType := range strings.Split(value, ",") {
+				if eventType = strings.ToUpper(strings.TrimSpace(eventType)); eventType != "" {
+					eventTypes = append(eventTypes, watch.EventType(eventType))
+				}
+			}
+		}
+	}
+	if len(eventTypes) == 0 {
+		return nil
+	}
+
+	result := map[watch.EventType]bool{
+		watch.Bookmark: true,
+		watch.Error:    true,
+	}
+	for _, eventType := range eventTypes {
+		result[eventType] = true
+	}
+	return result
+}
+
+// sendEvent sends the event to result unless the watch filtered out its type, which then skips the conversion too.
+func (s *Store) sendEvent(apiOp *types.APIRequest, schema *types.APISchema, result chan types.APIEvent, eventTypes map[watch.EventType]bool,
+	et watch.EventType, obj runtime.Object) {
+	if eventTypes != nil && !eventTypes[et] {
+		return
+	}
+	result <- s.toAPIEvent(apiOp, schema, et, obj)
+}
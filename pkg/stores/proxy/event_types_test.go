@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// watchedEvents opens a watch of the config maps of default, creates, modifies and deletes one and returns the
+// names of the events the watch sent.
+func watchedEvents(t *testing.T, ctx context.Context, query url.Values, want int) []string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{})
+	apiOp := conformance.DefaultRequest(schema)(ctx, http.MethodGet, "default", query)
+	c, err := store.Watch(apiOp, schema, types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		cluster.lock.Lock()
+		watchers := len(cluster.watchers)
+		cluster.lock.Unlock()
+		if watchers > 0 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the watch did not start")
+		}
+	}
+
+	cluster.lock.Lock()
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "a", "namespace": "default"},
+	}}
+	cluster.write(watch.Added, obj)
+	obj.Object["data"] = map[string]interface{}{"key": "value"}
+	cluster.write(watch.Modified, obj)
+	cluster.write(watch.Deleted, obj)
+	cluster.lock.Unlock()
+
+	var names []string
+	for len(names) < want {
+		names = append(names, nextWatchEvent(t, c).Name)
+	}
+	select {
+	case event := <-c:
+		names = append(names, event.Name)
+	case <-time.After(100 * time.Millisecond):
+	}
+	return names
+}
+
+func TestWatchEventTypes(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		eventTypes []watch.EventType
+		want       []string
+	}{
+		{
+			name: "all",
+			want: []string{types.CreateAPIEvent, types.ChangeAPIEvent, types.RemoveAPIEvent},
+		},
+		{
+			name:       "added",
+			eventTypes: []watch.EventType{watch.Added},
+			want:       []string{types.CreateAPIEvent},
+		},
+		{
+			name:       "modified",
+			eventTypes: []watch.EventType{watch.Modified},
+			want:       []string{types.ChangeAPIEvent},
+		},
+		{
+			name:       "deleted",
+			eventTypes: []watch.EventType{watch.Deleted},
+			want:       []string{types.RemoveAPIEvent},
+		},
+		{
+			name:       "added and modified",
+			eventTypes: []watch.EventType{watch.Added, watch.Modified},
+			want:       []string{types.CreateAPIEvent, types.ChangeAPIEvent},
+		},
+		{
+			name:       "added and deleted",
+			eventTypes: []watch.EventType{watch.Added, watch.Deleted},
+			want:       []string{types.CreateAPIEvent, types.RemoveAPIEvent},
+		},
+		{
+			name:       "modified and deleted",
+			eventTypes: []watch.EventType{watch.Modified, watch.Deleted},
+			want:       []string{types.ChangeAPIEvent, types.RemoveAPIEvent},
+		},
+		{
+			name:       "every type",
+			eventTypes: []watch.EventType{watch.Added, watch.Modified, watch.Deleted},
+			want:       []string{types.CreateAPIEvent, types.ChangeAPIEvent, types.RemoveAPIEvent},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := WithWatchEventTypes(context.Background(), test.eventTypes...)
+			if got := watchedEvents(t, ctx, nil, len(test.want)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got events %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWatchEventTypesQuery(t *testing.T) {
+	query := url.Values{"eventTypes": {"deleted, added"}}
+	want := []string{types.CreateAPIEvent, types.RemoveAPIEvent}
+	if got := watchedEvents(t, context.Background(), query, len(want)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+
+	// the types of the context win over the query
+	ctx := WithWatchEventTypes(context.Background(), watch.Modified)
+	want = []string{types.ChangeAPIEvent}
+	if got := watchedEvents(t, ctx, query, len(want)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+}
+
+func TestWatchEventTypesAlwaysKeepBookmarksAndErrors(t *testing.T) {
+	apiOp := conformance.DefaultRequest(configMapSchema())(WithWatchEventTypes(context.Background(), watch.Deleted),
+		http.MethodGet, "default", nil)
+	want := map[watch.EventType]bool{watch.Deleted: true, watch.Bookmark: true, watch.Error: true}
+	if got := watchEventTypes(apiOp); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	apiOp = conformance.DefaultRequest(configMapSchema())(context.Background(), http.MethodGet, "default", nil)
+	if got := watchEventTypes(apiOp); got != nil {
+		t.Errorf("got %v, want every event type without a filter", got)
+	}
+}
@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	ErrResourceVersionUnavailable = validation.ErrorCode{
+		Code:   "ResourceVersionUnavailable",
+		Status: http.StatusGone,
+	}
+)
+
+// exactResourceVersion returns the requested resourceVersion if the request asked for resourceVersionMatch=Exact.
+func exactResourceVersion(apiOp *types.APIRequest) (string, bool, error) {
+	q := apiOp.Request.URL.Query()
+	if metav1.ResourceVersionMatch(q.Get("resourceVersionMatch")) != metav1.ResourceVersionMatchExact {
+		return "", false, nil
+	}
+
+	rv := q.Get("resourceVersion")
+	if rv == "" || rv == "0" {
+		return "", false, apierror.NewAPIError(validation.MissingRequired,
+			"resourceVersion must be set to a specific version when resourceVersionMatch=Exact")
+	}
+	return rv, true, nil
+}
+
+// byIDExact reads a single object at exactly the given resourceVersion. Get does not support exact reads so this
+// is done as a list filtered down to the single name.
+func (s *Store) byIDExact(apiOp *types.APIRequest, client dynamic.ResourceInterface, id, rv string) (*unstructured.Unstructured, error) {
+	list, err := client.List(apiOp.Context(), metav1.ListOptions{
+		FieldSelector:        fields.OneTermEqualSelector("metadata.name", id).String(),
+		ResourceVersion:      rv,
+		ResourceVersionMatch: metav1.ResourceVersionMatchExact,
+	})
+	if err != nil {
+		return nil, exactReadError(err, rv)
+	}
+
+	tableToList(list)
+	if len(list.Items) == 0 {
+		return nil, apierror.NewAPIError(validation.NotFound, "object "+id+" did not exist at resourceVersion "+rv)
+	}
+	return &list.Items[0], nil
+}
+
+func exactReadError(err error, rv string) error {
+	if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+		return apierror.NewAPIError(ErrResourceVersionUnavailable,
+			"resourceVersion "+rv+" is no longer available, it has been compacted by the apiserver")
+	}
+	return err
+}
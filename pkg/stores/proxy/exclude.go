@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DefaultExcludedFields are left out of listed and watched objects unless asked for with ?include=. They are
+// written by the apiserver and kubectl on every change and usually make up most of the size of an object.
+var DefaultExcludedFields = []string{
+	"metadata.managedFields",
+	"metadata.annotations[" + LastAppliedAnnotation + "]",
+}
+
+// fieldPath is an excluded field, in the dot notation of DefaultExcludedFields, split into its keys.
+type fieldPath struct {
+	path string
+	keys []string
+}
+
+// parseFieldPaths splits paths such as metadata.annotations[example.com/key] into their keys, a key in brackets
+// can contain dots.
+func parseFieldPaths(paths []string) []fieldPath {
+	var result []fieldPath
+	for _, path := range paths {
+		var keys []string
+		rest := path
+		for rest != "" {
+			switch {
+			case rest[0] == '.':
+				rest = rest[1:]
+			case rest[0] == '[':
+				end := strings.Index(rest, "]")
+				if end < 0 {
+					end = len(rest)
+					rest += "]"
+				}
+				keys = append(keys, rest[1:end])
+				rest = rest[end+1:]
+			default:
+				end := strings.IndexAny(rest, ".[")
+				if end < 0 {
+					end = len(rest)
+				}
+				keys = append(keys, rest[:end])
+				rest = rest[end:]
+			}
+		}
+		if len(keys) > 0 {
+			result = append(result, fieldPath{path: path, keys: keys})
+		}
+	}
+	return result
+}
+
+// exclusions returns the excluded fields for the request, without those named in ?include=, which takes a comma
+// separated list and can be repeated. A watch whose request sets ?raw=true excludes nothing.
+func (s *Store) exclusions(apiOp *types.APIRequest) []fieldPath {
+	if len(s.excludedFields) == 0 || apiOp.Request == nil {
+		return s.excludedFields
+	}
+
+	query := apiOp.Request.URL.Query()
+	if query.Get("raw") == "true" {
+		return nil
+	}
+
+	include := map[string]bool{}
+	for _, value := range query["include"] {
+		for _, path := range strings.Split(value, ",") {
+			include[strings.TrimSpace(path)] = true
+		}
+	}
+	if len(include) == 0 {
+		return s.excludedFields
+	}
+
+	var result []fieldPath
+	for _, field := range s.excludedFields {
+		if !include[field.path] {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// excludeFields removes the fields from obj in place, obj must come straight from the apiserver response.
+func excludeFields(obj *unstructured.Unstructured, fields []fieldPath) {
+	if obj == nil {
+		return
+	}
+	for _, field := range fields {
+		parent := obj.Object
+		for _, key := range field.keys[:len(field.keys)-1] {
+			parent, _ = parent[key].(map[string]interface{})
+			if parent == nil {
+				break
+			}
+		}
+		if parent != nil {
+			delete(parent, field.keys[len(field.keys)-1])
+		}
+	}
+}
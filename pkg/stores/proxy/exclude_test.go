@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"github.com/rancher/wrangler/pkg/data"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// recordedPod reads testdata/pod.json, a pod of a deployment applied with kubectl in the form the apiserver returns, with
+// the managedFields of kubectl and the kubelet and the last-applied annotation.
+func recordedPod(tb testing.TB) *unstructured.Unstructured {
+	tb.Helper()
+	content, err := ioutil.ReadFile("testdata/pod.json")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(content, &obj.Object); err != nil {
+		tb.Fatal(err)
+	}
+	return obj
+}
+
+func TestFieldPathsAreParsed(t *testing.T) {
+	got := parseFieldPaths([]string{"metadata.managedFields", "metadata.annotations[example.com/a.b]", "status", ""})
+	want := [][]string{{"metadata", "managedFields"}, {"metadata", "annotations", "example.com/a.b"}, {"status"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i].keys, want[i]) {
+			t.Errorf("got keys %v of %s, want %v", got[i].keys, got[i].path, want[i])
+		}
+	}
+}
+
+// watchedPod watches the config maps of default with query, writes the recorded pod to the cluster as one and
+// returns the object of the event.
+func watchedPod(t *testing.T, query url.Values) map[string]interface{} {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{})
+
+	cluster.lock.Lock()
+	cluster.write(watch.Added, recordedPod(t))
+	cluster.lock.Unlock()
+
+	apiOp := conformance.DefaultRequest(schema)(ctx, http.MethodGet, "default", query)
+	c, err := store.Watch(apiOp, schema, types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := nextWatchEvent(t, c)
+	return event.Object.Data()
+}
+
+func TestWatchedObjectsLeaveOutTheExcludedFields(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		query       url.Values
+		managed     bool
+		lastApplied bool
+	}{
+		{name: "default"},
+		{name: "include", query: url.Values{"include": {"metadata.managedFields"}}, managed: true},
+		{
+			name:        "include both",
+			query:       url.Values{"include": {"metadata.managedFields, metadata.annotations[" + LastAppliedAnnotation + "]"}},
+			managed:     true,
+			lastApplied: true,
+		},
+		{name: "raw", query: url.Values{"raw": {"true"}}, managed: true, lastApplied: true},
+	} {
+		obj := data.Object(watchedPod(t, test.query))
+		if managed := obj.Map("metadata")["managedFields"] != nil; managed != test.managed {
+			t.Errorf("%s: got managedFields %v, want %v", test.name, managed, test.managed)
+		}
+		annotations := obj.Map("metadata", "annotations")
+		if _, lastApplied := annotations[LastAppliedAnnotation]; lastApplied != test.lastApplied {
+			t.Errorf("%s: got the last-applied annotation %v, want %v", test.name, lastApplied, test.lastApplied)
+		}
+		if annotations["prometheus.io/scrape"] != "true" || obj.String("status", "phase") != "Running" {
+			t.Errorf("%s: got %v, want the other fields of the pod kept", test.name, obj)
+		}
+	}
+}
+
+// BenchmarkWatchEventPayload turns modifications of the recorded pod into watch events and reports the size of
+// the object each one sends, with the default exclusions and raw.
+func BenchmarkWatchEventPayload(b *testing.B) {
+	pod := recordedPod(b)
+	schema := configMapSchema()
+	for _, test := range []struct {
+		name  string
+		query url.Values
+	}{
+		{name: "excluded"},
+		{name: "raw", query: url.Values{"raw": {"true"}}},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			s := &Store{excludedFields: parseFieldPaths(DefaultExcludedFields)}
+			apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodGet, "default", test.query)
+			var size int
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				event := s.toAPIEvent(apiOp, schema, watch.Modified, pod.DeepCopy())
+				content, err := json.Marshal(event.Object.Object)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(content)
+			}
+			b.ReportMetric(float64(size), "bytes/event")
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/client"
+	"github.com/rancher/steve/pkg/clusterversion"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// DefaultFieldValidation is the fieldValidation of writes that don't set one, unless WithFieldValidation is given.
+// Unknown and duplicate fields are then accepted with a warning instead of being dropped silently.
+const DefaultFieldValidation = "Warn"
+
+// withFieldValidation returns apiOp with a context that sends ?fieldValidation= of the request, or the default of
+// the store, with the writes to the apiserver. Nothing is sent to apiservers the cluster version doesn't support it
+// for. The returned func adds the warnings of the apiserver to the response, it is called once the write is done.
+func (s *Store) withFieldValidation(apiOp *types.APIRequest) (*types.APIRequest, func(), error) {
+	value := s.fieldValidation
+	if apiOp.Request != nil {
+		if requested := apiOp.Request.URL.Query().Get("fieldValidation"); requested != "" {
+			value = requested
+		}
+	}
+
+	switch strings.ToLower(value) {
+	case "":
+		return apiOp, func() {}, nil
+	case "strict", "warn", "ignore":
+		if !s.clusterVersion.Supports(clusterversion.FieldValidation) {
+			return apiOp, func() {}, nil
+		}
+	}
+
+	switch strings.ToLower(value) {
+	case "strict":
+		value = "Strict"
+	case "warn":
+		value = "Warn"
+	case "ignore":
+		value = "Ignore"
+	default:
+		return nil, nil, apierror.NewAPIError(validation.InvalidOption, "fieldValidation must be Strict, Warn or Ignore, not "+value)
+	}
+
+	ctx, warnings := client.WithFieldValidation(apiOp.Context(), value)
+	return apiOp.WithContext(ctx), func() {
+		if apiOp.Response == nil {
+			return
+		}
+		for _, warning := range warnings.List() {
+			apiOp.Response.Header().Add("Warning", warning)
+		}
+	}, nil
+}
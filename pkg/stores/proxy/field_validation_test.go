@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/clusterversion"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+type fakeServerVersion string
+
+func (f fakeServerVersion) ServerVersion() (*version.Info, error) {
+	return &version.Info{GitVersion: string(f)}, nil
+}
+
+func clusterVersion(t *testing.T, gitVersion string) *clusterversion.Tracker {
+	t.Helper()
+	tracker := clusterversion.New(fakeServerVersion(gitVersion))
+	if err := tracker.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	return tracker
+}
+
+func writeRequest(query string) *types.APIRequest {
+	return &types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodPut, "/v1/widgets/w1"+query, nil),
+		Response: httptest.NewRecorder(),
+	}
+}
+
+func TestWithFieldValidationGatedOnClusterVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		sent    bool
+	}{
+		{name: "supported", version: "v1.25.3", sent: true},
+		{name: "too old", version: "v1.24.9", sent: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Store{fieldValidation: DefaultFieldValidation, clusterVersion: clusterVersion(t, test.version)}
+			apiOp := writeRequest("")
+			got, addWarnings, err := s.withFieldValidation(apiOp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addWarnings()
+			if sent := got != apiOp; sent != test.sent {
+				t.Errorf("got fieldValidation sent %v, want %v", sent, test.sent)
+			}
+		})
+	}
+}
+
+func TestWithFieldValidationRejectsUnknownValues(t *testing.T) {
+	s := &Store{fieldValidation: DefaultFieldValidation, clusterVersion: clusterVersion(t, "v1.26.0")}
+	if _, _, err := s.withFieldValidation(writeRequest("?fieldValidation=Loose")); err == nil {
+		t.Error("got no error for fieldValidation=Loose")
+	}
+}
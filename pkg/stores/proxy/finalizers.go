@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/data"
+)
+
+// createFinalizers returns the finalizers every created object of the schema gets, from the schema attributes,
+// usually set in a Template.Customize, and from WithCreateFinalizers.
+func (s *Store) createFinalizers(schema *types.APISchema) []string {
+	var finalizers []string
+	finalizers = append(finalizers, attributes.CreateFinalizers(schema)...)
+	return append(finalizers, s.finalizers[schema.ID]...)
+}
+
+// injectFinalizers adds the create finalizers of the schema that input doesn't already have. Finalizers are only
+// added on create, a controller may remove its finalizer from an object on purpose and updates must not restore it.
+func (s *Store) injectFinalizers(schema *types.APISchema, input data.Object) {
+	finalizers := s.createFinalizers(schema)
+	if len(finalizers) == 0 {
+		return
+	}
+
+	existing, _ := input.Map("metadata")["finalizers"].([]interface{})
+	has := map[string]bool{}
+	for _, finalizer := range existing {
+		if name, ok := finalizer.(string); ok {
+			has[name] = true
+		}
+	}
+	for _, finalizer := range finalizers {
+		if !has[finalizer] {
+			has[finalizer] = true
+			existing = append(existing, finalizer)
+		}
+	}
+	input.SetNested(existing, "metadata", "finalizers")
+}
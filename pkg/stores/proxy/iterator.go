@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+const defaultIteratorChunkSize = 500
+
+// Iterator returns the objects of a list one at a time. Next returns false once the list is done or failed, Err
+// then tells which. Close releases the iterator before it is done.
+type Iterator interface {
+	Next() (types.APIObject, bool)
+	Err() error
+	Close()
+}
+
+// ListIterable is implemented by the store returned by NewProxyStore.
+type ListIterable interface {
+	ListIterator(apiOp *types.APIRequest, schema *types.APISchema, opts metav1.ListOptions) (Iterator, error)
+}
+
+var _ ListIterable = &logStore{}
+
+// ListIterator lists the objects of schema in the namespace of apiOp in chunks of opts.Limit, 500 when it is not
+// set, so only one chunk is held in memory at a time. The list is not filtered by the access set of the user, the
+// request impersonates the user so the apiserver only returns what they can list.
+func (s *Store) ListIterator(apiOp *types.APIRequest, schema *types.APISchema, opts metav1.ListOptions) (Iterator, error) {
+	return NewListIterator(s.clientGetter, apiOp, schema, opts)
+}
+
+func (l *logStore) ListIterator(apiOp *types.APIRequest, schema *types.APISchema, opts metav1.ListOptions) (Iterator, error) {
+	start := time.Now()
+	it, err := l.proxyStore.ListIterator(apiOp, schema, opts)
+	l.log(apiOp, schema, "list", "", start, err, nil)
+	return it, translateError(err, schema)
+}
+
+// NewListIterator is ListIterator for callers that only have a ClientGetter.
+func NewListIterator(clientGetter ClientGetter, apiOp *types.APIRequest, schema *types.APISchema, opts metav1.ListOptions) (Iterator, error) {
+	client, err := clientGetter.Client(apiOp, schema, apiOp.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultIteratorChunkSize
+	}
+	return &listIterator{
+		apiOp:  apiOp,
+		schema: schema,
+		client: client,
+		opts:   opts,
+	}, nil
+}
+
+type listIterator struct {
+	apiOp  *types.APIRequest
+	schema *types.APISchema
+	client dynamic.ResourceInterface
+	opts   metav1.ListOptions
+
+	items   []unstructured.Unstructured
+	fetched bool
+	done    bool
+	err     error
+}
+
+func (l *listIterator) Next() (types.APIObject, bool) {
+	for len(l.items) == 0 {
+		if l.done || (l.fetched && l.opts.Continue == "") {
+			l.done = true
+			return types.APIObject{}, false
+		}
+		if !l.fetch() {
+			return types.APIObject{}, false
+		}
+	}
+
+	item := l.items[0]
+	// drop the reference so the chunk can be collected as it is consumed
+	l.items[0] = unstructured.Unstructured{}
+	l.items = l.items[1:]
+	obj := toAPI(l.schema, &item)
+	promoteFields(l.schema, obj)
+	return obj, true
+}
+
+// fetch gets the next chunk, the continue token of the apiserver keeps every chunk at the revision of the first.
+func (l *listIterator) fetch() bool {
+	list, err := l.client.List(l.apiOp.Context(), l.opts)
+	if err != nil {
+		l.err = err
+		l.done = true
+		return false
+	}
+	l.fetched = true
+	l.items = list.Items
+	l.opts.Continue = list.GetContinue()
+	// the resourceVersion can not be combined with a continue token
+	l.opts.ResourceVersion = ""
+	return true
+}
+
+func (l *listIterator) Err() error {
+	return l.err
+}
+
+func (l *listIterator) Close() {
+	l.done = true
+	l.items = nil
+}
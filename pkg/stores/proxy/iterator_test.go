@@ -0,0 +1,250 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+const benchmarkObjects = 100000
+
+// chunkedClient serves a list of count generated objects, in chunks when a limit is set. Objects are generated
+// for each call so the client itself holds none of them.
+type chunkedClient struct {
+	dynamic.ResourceInterface
+
+	lock  sync.Mutex
+	count int
+	calls int
+	err   error
+}
+
+func (c *chunkedClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	c.lock.Lock()
+	c.calls++
+	c.lock.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	start := 0
+	if opts.Continue != "" {
+		start, _ = strconv.Atoi(opts.Continue)
+	}
+	end := c.count
+	if opts.Limit > 0 && start+int(opts.Limit) < end {
+		end = start + int(opts.Limit)
+	}
+
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	list.SetResourceVersion("100")
+	if end < c.count {
+		list.SetContinue(strconv.Itoa(end))
+	}
+	for i := start; i < end; i++ {
+		list.Items = append(list.Items, generatedObject(i))
+	}
+	return list, nil
+}
+
+func generatedObject(i int) unstructured.Unstructured {
+	obj := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"data": map[string]interface{}{
+			"key": "value-" + strconv.Itoa(i),
+		},
+	}}
+	obj.SetNamespace("default")
+	obj.SetName("cm-" + strconv.Itoa(i))
+	obj.SetLabels(map[string]string{"app": "bench"})
+	return obj
+}
+
+type chunkedClientGetter struct {
+	ClientGetter
+	client dynamic.ResourceInterface
+}
+
+func (c *chunkedClientGetter) Client(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return c.client, nil
+}
+
+func (c *chunkedClientGetter) TableClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return c.client, nil
+}
+
+func iteratorRequest() *types.APIRequest {
+	return &types.APIRequest{
+		Request: httptest.NewRequest(http.MethodGet, "/v1/configmaps", nil),
+	}
+}
+
+var configMaps = &types.APISchema{Schema: &schemas.Schema{ID: "configmap"}}
+
+func TestProxyStoreIsListIterable(t *testing.T) {
+	if _, ok := NewProxyStore(nil, nil, nil).(ListIterable); !ok {
+		t.Fatal("the store returned by NewProxyStore does not implement ListIterable")
+	}
+}
+
+func TestListIteratorReadsEveryChunk(t *testing.T) {
+	client := &chunkedClient{count: 1234}
+	it, err := NewListIterator(&chunkedClientGetter{client: client}, iteratorRequest(), configMaps, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	count := 0
+	for {
+		obj, ok := it.Next()
+		if !ok {
+			break
+		}
+		if want := "cm-" + strconv.Itoa(count); obj.Name() != want {
+			t.Fatalf("got %s, want %s", obj.Name(), want)
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1234 {
+		t.Errorf("got %d objects, want 1234", count)
+	}
+	if client.calls != 3 {
+		t.Errorf("got %d list calls, want 3 chunks of %d", client.calls, defaultIteratorChunkSize)
+	}
+}
+
+func TestListIteratorEmptyList(t *testing.T) {
+	client := &chunkedClient{}
+	it, err := NewListIterator(&chunkedClientGetter{client: client}, iteratorRequest(), configMaps, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := it.Next(); ok {
+		t.Error("got an object from an empty list")
+	}
+	if _, ok := it.Next(); ok || client.calls != 1 {
+		t.Errorf("got %d list calls, want the list to be done after the first", client.calls)
+	}
+}
+
+func TestListIteratorError(t *testing.T) {
+	listErr := errors.New("list failed")
+	it, err := NewListIterator(&chunkedClientGetter{client: &chunkedClient{err: listErr}}, iteratorRequest(), configMaps, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := it.Next(); ok {
+		t.Fatal("got an object from a failed list")
+	}
+	if it.Err() != listErr {
+		t.Errorf("got %v, want the list error", it.Err())
+	}
+}
+
+func TestListIteratorClose(t *testing.T) {
+	client := &chunkedClient{count: 1000}
+	it, err := NewListIterator(&chunkedClientGetter{client: client}, iteratorRequest(), configMaps, metav1.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := it.Next(); !ok {
+		t.Fatal("got no object")
+	}
+	it.Close()
+	if _, ok := it.Next(); ok {
+		t.Error("got an object after Close")
+	}
+	if client.calls != 1 {
+		t.Errorf("got %d list calls, want no list after Close", client.calls)
+	}
+}
+
+// heapInUse returns the bytes of the live heap after a collection.
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+// heapGrowth returns how much the live heap grew since base.
+func heapGrowth(base uint64) uint64 {
+	if now := heapInUse(); now > base {
+		return now - base
+	}
+	return 0
+}
+
+// BenchmarkListMemory lists every object at once, peak-MB is the heap held by the materialized list.
+func BenchmarkListMemory(b *testing.B) {
+	s := &Store{clientGetter: &chunkedClientGetter{client: &chunkedClient{count: benchmarkObjects}}}
+	var peak uint64
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		base := heapInUse()
+		list, err := s.List(iteratorRequest(), configMaps)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(list.Objects) != benchmarkObjects {
+			b.Fatalf("got %d objects, want %d", len(list.Objects), benchmarkObjects)
+		}
+		if used := heapGrowth(base); used > peak {
+			peak = used
+		}
+		runtime.KeepAlive(list)
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+}
+
+// BenchmarkListIteratorMemory reads the same objects one at a time, peak-MB is the largest heap held while
+// iterating, sampled every 1000 objects.
+func BenchmarkListIteratorMemory(b *testing.B) {
+	getter := &chunkedClientGetter{client: &chunkedClient{count: benchmarkObjects}}
+	var peak uint64
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		base := heapInUse()
+		it, err := NewListIterator(getter, iteratorRequest(), configMaps, metav1.ListOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		count := 0
+		for {
+			_, ok := it.Next()
+			if !ok {
+				break
+			}
+			count++
+			if count%1000 == 0 {
+				if used := heapGrowth(base); used > peak {
+					peak = used
+				}
+			}
+		}
+		if err := it.Err(); err != nil {
+			b.Fatal(err)
+		}
+		if count != benchmarkObjects {
+			b.Fatalf("got %d objects, want %d", count, benchmarkObjects)
+		}
+		it.Close()
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+}
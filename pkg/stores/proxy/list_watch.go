@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// ListWatcher is implemented by stores that can return the current list and a watch started exactly at the
+// revision of that list, so callers don't race between separate List and Watch calls.
+type ListWatcher interface {
+	ListAndWatch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (types.APIObjectList, chan types.APIEvent, error)
+}
+
+var _ ListWatcher = &logStore{}
+
+// ListAndWatch is implemented by the outermost store returned by NewProxyStore so the list and the watch go
+// through every layer, error translation and timeouts included, like separate List and Watch calls.
+func (l *logStore) ListAndWatch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (types.APIObjectList, chan types.APIEvent, error) {
+	return ListAndWatch(l, apiOp, schema, w)
+}
+
+// ListAndWatch lists with the given store and starts a watch at the revision of the returned list. The
+// revision in w is ignored. If the list succeeds but the watch can not be started the list is still returned
+// and the error is delivered as the only event on a closed watch channel.
+func ListAndWatch(store types.Store, apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (types.APIObjectList, chan types.APIEvent, error) {
+	list, err := store.List(apiOp, schema)
+	if err != nil {
+		return list, nil, err
+	}
+
+	w.Revision = list.Revision
+	c, err := store.Watch(apiOp, schema, w)
+	if err != nil {
+		result := make(chan types.APIEvent, 1)
+		returnErr(errors.Wrapf(translateError(err, schema), "starting watch for %s at revision %s", schema.ID, list.Revision), result)
+		close(result)
+		return list, result, nil
+	}
+
+	return list, c, nil
+}
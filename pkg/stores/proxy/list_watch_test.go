@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/fake"
+	"github.com/rancher/wrangler/pkg/schemas"
+)
+
+func TestProxyStoreIsListWatcher(t *testing.T) {
+	if _, ok := NewProxyStore(nil, nil, nil).(ListWatcher); !ok {
+		t.Fatal("the store returned by NewProxyStore does not implement ListWatcher")
+	}
+}
+
+func TestListAndWatchStartsAtListRevision(t *testing.T) {
+	schema := &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+	c := make(chan types.APIEvent)
+	next := fake.NewFakeStore().
+		OnList(types.APIObjectList{Revision: "7"}, nil).
+		OnWatch(c, nil)
+
+	list, watch, err := (&logStore{Store: next}).ListAndWatch(revisionRequest(), schema, types.WatchRequest{Revision: "1", Selector: "app=web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Revision != "7" || watch != c {
+		t.Errorf("got revision %s and another channel, want the list and watch of the store", list.Revision)
+	}
+	next.AssertCalled(t, fake.Watch, types.WatchRequest{Revision: "7", Selector: "app=web"})
+}
+
+func TestListAndWatchListError(t *testing.T) {
+	schema := &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+	listErr := errors.New("list failed")
+	next := fake.NewFakeStore().OnList(types.APIObjectList{}, listErr)
+
+	if _, _, err := (&logStore{Store: next}).ListAndWatch(revisionRequest(), schema, types.WatchRequest{}); err != listErr {
+		t.Errorf("got %v, want the list error", err)
+	}
+	next.AssertNotCalled(t, fake.Watch)
+}
+
+func TestListAndWatchWatchErrorIsAnEvent(t *testing.T) {
+	schema := &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+	next := fake.NewFakeStore().
+		OnList(types.APIObjectList{Revision: "7"}, nil).
+		OnWatch(nil, errors.New("watch failed"))
+
+	list, watch, err := (&logStore{Store: next}).ListAndWatch(revisionRequest(), schema, types.WatchRequest{})
+	if err != nil {
+		t.Fatalf("got %v, want the list with the error as an event", err)
+	}
+	if list.Revision != "7" {
+		t.Errorf("got revision %s, want 7", list.Revision)
+	}
+	var events []types.APIEvent
+	for event := range watch {
+		events = append(events, event)
+	}
+	if len(events) != 1 || events[0].Error == nil {
+		t.Errorf("got events %v, want a single error", events)
+	}
+}
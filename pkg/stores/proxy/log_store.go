@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// logStore logs every operation at debug level with the user, verb, GVR, namespace, duration and outcome.
+// Object bodies are only logged, at trace level, if logBodies is set because they can hold secrets. Operations that
+// take at least slowThreshold are also recorded in SlowOperations.
+type logStore struct {
+	types.Store
+	logBodies     bool
+	slowThreshold time.Duration
+	// proxyStore serves the operations that are not part of types.Store
+	proxyStore *Store
+}
+
+func (l *logStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	start := time.Now()
+	obj, err := l.Store.ByID(apiOp, schema, id)
+	l.log(apiOp, schema, "get", id, start, err, obj.Object)
+	return obj, err
+}
+
+func (l *logStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	start := time.Now()
+	list, err := l.Store.List(apiOp, schema)
+	if entry := l.log(apiOp, schema, "list", "", start, err, nil); entry != nil {
+		entry.WithField("count", len(list.Objects)).Debug("listed objects")
+	}
+	return list, err
+}
+
+func (l *logStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	start := time.Now()
+	obj, err := l.Store.Create(apiOp, schema, data)
+	l.log(apiOp, schema, "create", obj.ID, start, err, data.Object)
+	return obj, err
+}
+
+func (l *logStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	start := time.Now()
+	obj, err := l.Store.Update(apiOp, schema, data, id)
+	l.log(apiOp, schema, "update", id, start, err, data.Object)
+	return obj, err
+}
+
+func (l *logStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	start := time.Now()
+	obj, err := l.Store.Delete(apiOp, schema, id)
+	l.log(apiOp, schema, "delete", id, start, err, nil)
+	return obj, err
+}
+
+func (l *logStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	start := time.Now()
+	c, err := l.Store.Watch(apiOp, schema, w)
+	l.log(apiOp, schema, "watch", "", start, err, nil)
+	return c, err
+}
+
+// log writes the operation log entry and returns it, or nil when debug logging is off so callers can add to it.
+func (l *logStore) log(apiOp *types.APIRequest, schema *types.APISchema, verb, id string, start time.Time, err error, body interface{}) *logrus.Entry {
+	if duration := time.Since(start); l.slowThreshold > 0 && duration >= l.slowThreshold {
+		SlowOperations.Record(verb, schema.ID, apiOp.Namespace, duration, err)
+	}
+
+	if !logrus.IsLevelEnabled(logrus.DebugLevel) {
+		return nil
+	}
+
+	fields := logrus.Fields{
+		"verb":      verb,
+		"gvr":       attributes.GVR(schema).String(),
+		"namespace": apiOp.Namespace,
+		"duration":  time.Since(start).String(),
+		"outcome":   outcome(err),
+	}
+	if user, ok := request.UserFrom(apiOp.Context()); ok {
+		fields["user"] = user.GetName()
+	}
+	if id != "" {
+		fields["id"] = id
+	}
+
+	entry := logrus.WithFields(fields)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Debugf("%s %s", verb, schema.ID)
+
+	if l.logBodies && body != nil && logrus.IsLevelEnabled(logrus.TraceLevel) {
+		entry.WithField("body", body).Tracef("%s %s body", verb, schema.ID)
+	}
+	return entry
+}
+
+func outcome(err error) string {
+	if err == nil {
+		return "success"
+	}
+	if apiErr, ok := err.(*apierror.APIError); ok {
+		return apiErr.Code.Code
+	}
+	return "error"
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+)
+
+// NameValidatorFunc returns the rules a name of the given schema must satisfy, or nil if names of the schema
+// should not be validated before they are sent to the apiserver.
+type NameValidatorFunc func(schema *types.APISchema) apivalidation.ValidateNameFunc
+
+// DefaultNameValidator validates every name as a DNS-1123 subdomain, which is what most resources require.
+func DefaultNameValidator(schema *types.APISchema) apivalidation.ValidateNameFunc {
+	return apivalidation.NameIsDNSSubdomain
+}
+
+func (s *Store) validateName(schema *types.APISchema, input data.Object) error {
+	if s.nameValidator == nil {
+		return nil
+	}
+	validate := s.nameValidator(schema)
+	if validate == nil {
+		return nil
+	}
+
+	if name := input.String("metadata", "name"); name != "" {
+		if err := checkName("metadata.name", name, false, validate); err != nil {
+			return err
+		}
+	}
+	if generateName := input.String("metadata", "generateName"); generateName != "" {
+		if err := checkName("metadata.generateName", generateName, true, validate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkName(field, name string, prefix bool, validate apivalidation.ValidateNameFunc) error {
+	msgs := validate(name, prefix)
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("invalid %s %q: %s", field, name, strings.Join(msgs, "; "))
+	if i, c, ok := firstInvalidChar(name); ok {
+		msg = fmt.Sprintf("invalid %s %q: character %q at position %d is not allowed: %s", field, name, c, i, strings.Join(msgs, "; "))
+	}
+	return apierror.NewFieldAPIError(validation.InvalidFormat, field, msg)
+}
+
+// firstInvalidChar finds the first character that no kubernetes DNS name allows, so the error can point at it.
+func firstInvalidChar(name string) (int, rune, bool) {
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '.':
+		default:
+			return i, c, true
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+const defaultDeleteParallelism = 5
+
+// DeleteReport is the outcome of DeleteNamespaceContents by schema ID. Failed counts the resources that could not be
+// deleted, including every resource of a schema that could not be listed.
+type DeleteReport struct {
+	Namespace string                         `json:"namespace"`
+	Schemas   map[string]*SchemaDeleteReport `json:"schemas"`
+	Failed    int                            `json:"failed"`
+}
+
+// SchemaDeleteReport is the outcome for one schema, Error is set when its resources could not be listed.
+type SchemaDeleteReport struct {
+	Resources []DeleteResult `json:"resources,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// DeleteResult is the outcome for one resource. FinalizersRemoved is set when the finalizers were stripped
+// before the delete.
+type DeleteResult struct {
+	Name              string `json:"name"`
+	Deleted           bool   `json:"deleted"`
+	FinalizersRemoved bool   `json:"finalizersRemoved,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+// NamespaceContentsDeleter is implemented by the store returned by NewProxyStore.
+type NamespaceContentsDeleter interface {
+	DeleteNamespaceContents(apiOp *types.APIRequest, namespace string, schemas []*types.APISchema, parallelism int) (DeleteReport, error)
+}
+
+var _ NamespaceContentsDeleter = &logStore{}
+
+func (l *logStore) DeleteNamespaceContents(apiOp *types.APIRequest, namespace string, schemas []*types.APISchema, parallelism int) (DeleteReport, error) {
+	return l.proxyStore.DeleteNamespaceContents(apiOp, namespace, schemas, parallelism)
+}
+
+type deleteTask struct {
+	client     dynamic.ResourceInterface
+	report     *SchemaDeleteReport
+	name       string
+	finalizers bool
+}
+
+// DeleteNamespaceContents deletes every resource of the namespaced schemas in namespace, up to parallelism at a
+// time, so a namespace can be deleted afterwards without waiting on its contents. With ?forceFinalizers=true the
+// finalizers of a resource are removed before it is deleted, which skips the cleanup its controllers would do and
+// should only be used when those controllers are gone. The requests impersonate the user, resources they can't
+// delete are reported as failed. Resources already gone are reported as deleted.
+func (s *Store) DeleteNamespaceContents(apiOp *types.APIRequest, namespace string, schemas []*types.APISchema, parallelism int) (DeleteReport, error) {
+	if parallelism <= 0 {
+		parallelism = defaultDeleteParallelism
+	}
+	force := apiOp.Request != nil && apiOp.Request.URL.Query().Get("forceFinalizers") == "true"
+
+	report := DeleteReport{
+		Namespace: namespace,
+		Schemas:   map[string]*SchemaDeleteReport{},
+	}
+
+	var tasks []deleteTask
+	for _, schema := range schemas {
+		if !attributes.Namespaced(schema) {
+			continue
+		}
+		schemaReport := &SchemaDeleteReport{}
+		report.Schemas[schema.ID] = schemaReport
+
+		client, err := s.clientGetter.Client(apiOp, schema, namespace)
+		if err != nil {
+			schemaReport.Error = err.Error()
+			report.Failed++
+			continue
+		}
+
+		opts := metav1.ListOptions{Limit: defaultIteratorChunkSize}
+		for {
+			list, err := client.List(apiOp.Context(), opts)
+			if err != nil {
+				schemaReport.Error = err.Error()
+				report.Failed++
+				break
+			}
+			for _, item := range list.Items {
+				tasks = append(tasks, deleteTask{
+					client:     client,
+					report:     schemaReport,
+					name:       item.GetName(),
+					finalizers: len(item.GetFinalizers()) > 0,
+				})
+			}
+			if opts.Continue = list.GetContinue(); opts.Continue == "" {
+				break
+			}
+		}
+	}
+
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, parallelism)
+	)
+	for _, task := range tasks {
+		task := task
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result := deleteResource(apiOp, task, force)
+
+			lock.Lock()
+			defer lock.Unlock()
+			task.report.Resources = append(task.report.Resources, result)
+			if !result.Deleted {
+				report.Failed++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if report.Failed > 0 {
+		return report, fmt.Errorf("%d resources in namespace %s could not be deleted", report.Failed, namespace)
+	}
+	return report, nil
+}
+
+func deleteResource(apiOp *types.APIRequest, task deleteTask, force bool) DeleteResult {
+	result := DeleteResult{
+		Name: task.name,
+	}
+
+	if force && task.finalizers {
+		_, err := task.client.Patch(apiOp.Context(), task.name, apitypes.MergePatchType,
+			[]byte(`{"metadata":{"finalizers":null}}`), metav1.PatchOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			result.Error = err.Error()
+			return result
+		}
+		result.FinalizersRemoved = err == nil
+	}
+
+	err := task.client.Delete(apiOp.Context(), task.name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		result.Error = err.Error()
+		return result
+	}
+	result.Deleted = true
+	return result
+}
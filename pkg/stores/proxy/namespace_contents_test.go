@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// namespaceClient holds the objects of one resource in a namespace, deletes of names in forbidden fail.
+type namespaceClient struct {
+	dynamic.ResourceInterface
+
+	lock      sync.Mutex
+	resource  string
+	objects   map[string]*unstructured.Unstructured
+	forbidden map[string]bool
+	patched   []string
+}
+
+func newNamespaceClient(resource string, count int, finalizers bool) *namespaceClient {
+	c := &namespaceClient{
+		resource:  resource,
+		objects:   map[string]*unstructured.Unstructured{},
+		forbidden: map[string]bool{},
+	}
+	for i := 0; i < count; i++ {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetNamespace("team")
+		obj.SetName(resource + "-" + strconv.Itoa(i))
+		if finalizers {
+			obj.SetFinalizers([]string{"example.com/cleanup"})
+		}
+		c.objects[obj.GetName()] = obj
+	}
+	return c
+}
+
+func (c *namespaceClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	list := &unstructured.UnstructuredList{}
+	for _, obj := range c.objects {
+		list.Items = append(list.Items, *obj.DeepCopy())
+	}
+	return list, nil
+}
+
+func (c *namespaceClient) Patch(ctx context.Context, name string, pt apitypes.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	obj, ok := c.objects[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: c.resource}, name)
+	}
+	obj.SetFinalizers(nil)
+	c.patched = append(c.patched, name)
+	return obj, nil
+}
+
+func (c *namespaceClient) Delete(ctx context.Context, name string, options metav1.DeleteOptions, subresources ...string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.forbidden[name] {
+		return apierrors.NewForbidden(schema.GroupResource{Resource: c.resource}, name, nil)
+	}
+	if _, ok := c.objects[name]; !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: c.resource}, name)
+	}
+	delete(c.objects, name)
+	return nil
+}
+
+type namespaceClientGetter struct {
+	ClientGetter
+	clients map[string]*namespaceClient
+}
+
+func (n *namespaceClientGetter) Client(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return n.clients[schema.ID], nil
+}
+
+func namespacedSchema(id string) *types.APISchema {
+	s := &types.APISchema{Schema: &schemas.Schema{ID: id}}
+	attributes.SetNamespaced(s, true)
+	return s
+}
+
+func newNamespaceContents(finalizers bool) (*Store, []*types.APISchema, map[string]*namespaceClient) {
+	clients := map[string]*namespaceClient{}
+	var result []*types.APISchema
+	for _, id := range []string{"configmap", "secret", "widget"} {
+		clients[id] = newNamespaceClient(id, 5, finalizers)
+		result = append(result, namespacedSchema(id))
+	}
+	return &Store{clientGetter: &namespaceClientGetter{clients: clients}}, result, clients
+}
+
+func deleteRequest(query string) *types.APIRequest {
+	return &types.APIRequest{
+		Request: httptest.NewRequest(http.MethodDelete, "/v1/namespaces/team"+query, nil),
+	}
+}
+
+func TestProxyStoreIsNamespaceContentsDeleter(t *testing.T) {
+	if _, ok := NewProxyStore(nil, nil, nil).(NamespaceContentsDeleter); !ok {
+		t.Fatal("the store returned by NewProxyStore does not implement NamespaceContentsDeleter")
+	}
+}
+
+func TestDeleteNamespaceContents(t *testing.T) {
+	s, schemaList, clients := newNamespaceContents(false)
+
+	report, err := s.DeleteNamespaceContents(deleteRequest(""), "team", schemaList, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Namespace != "team" || report.Failed != 0 || len(report.Schemas) != 3 {
+		t.Fatalf("got report %+v, want 3 schemas and no failures", report)
+	}
+	for id, client := range clients {
+		if len(client.objects) != 0 {
+			t.Errorf("%s: %d resources left", id, len(client.objects))
+		}
+		schemaReport := report.Schemas[id]
+		if schemaReport == nil || len(schemaReport.Resources) != 5 {
+			t.Fatalf("%s: got report %+v, want 5 resources", id, schemaReport)
+		}
+		for _, result := range schemaReport.Resources {
+			if !result.Deleted || result.Error != "" || result.FinalizersRemoved {
+				t.Errorf("%s: got %+v, want it deleted", id, result)
+			}
+		}
+	}
+}
+
+func TestDeleteNamespaceContentsReportsFailures(t *testing.T) {
+	s, schemaList, clients := newNamespaceContents(false)
+	clients["secret"].forbidden["secret-3"] = true
+
+	report, err := s.DeleteNamespaceContents(deleteRequest(""), "team", schemaList, 5)
+	if err == nil {
+		t.Fatal("got no error for a resource that could not be deleted")
+	}
+	if report.Failed != 1 {
+		t.Errorf("got %d failures, want 1", report.Failed)
+	}
+	for _, result := range report.Schemas["secret"].Resources {
+		if want := result.Name != "secret-3"; result.Deleted != want {
+			t.Errorf("%s: got deleted %v, want %v", result.Name, result.Deleted, want)
+		}
+		if result.Name == "secret-3" && result.Error == "" {
+			t.Errorf("%s: the error is not reported", result.Name)
+		}
+	}
+	if _, ok := clients["secret"].objects["secret-3"]; !ok {
+		t.Error("the forbidden resource was deleted")
+	}
+}
+
+func TestDeleteNamespaceContentsForceFinalizers(t *testing.T) {
+	s, schemaList, clients := newNamespaceContents(true)
+
+	report, err := s.DeleteNamespaceContents(deleteRequest("?forceFinalizers=true"), "team", schemaList, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, client := range clients {
+		if len(client.patched) != 5 {
+			t.Errorf("%s: got finalizers removed from %d resources, want 5", id, len(client.patched))
+		}
+		for _, result := range report.Schemas[id].Resources {
+			if !result.FinalizersRemoved || !result.Deleted {
+				t.Errorf("%s: got %+v, want the finalizers removed and the resource deleted", id, result)
+			}
+		}
+	}
+}
+
+func TestDeleteNamespaceContentsKeepsFinalizersByDefault(t *testing.T) {
+	s, schemaList, clients := newNamespaceContents(true)
+
+	if _, err := s.DeleteNamespaceContents(deleteRequest(""), "team", schemaList, 5); err != nil {
+		t.Fatal(err)
+	}
+	for id, client := range clients {
+		if len(client.patched) != 0 {
+			t.Errorf("%s: finalizers were removed without forceFinalizers", id)
+		}
+	}
+}
+
+func TestDeleteNamespaceContentsSkipsClusterScopedSchemas(t *testing.T) {
+	s, schemaList, clients := newNamespaceContents(false)
+	attributes.SetNamespaced(schemaList[2], false)
+
+	report, err := s.DeleteNamespaceContents(deleteRequest(""), "team", schemaList, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := report.Schemas["widget"]; ok {
+		t.Error("a cluster scoped schema is in the report")
+	}
+	if len(clients["widget"].objects) != 5 {
+		t.Error("resources of a cluster scoped schema were deleted")
+	}
+}
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// warningLock serializes adding warnings to a response, partitions are listed concurrently.
+var warningLock sync.Mutex
+
+// addWarning adds a Kubernetes style Warning header to the response.
+func addWarning(apiOp *types.APIRequest, warning string) {
+	if apiOp.Response == nil {
+		return
+	}
+	warningLock.Lock()
+	defer warningLock.Unlock()
+	apiOp.Response.Header().Add("Warning", fmt.Sprintf("299 - %q", warning))
+}
+
+// listNamespaceCapped lists a single namespace of a multi-namespace fan-out with at most s.namespaceItemLimit
+// items. A namespace with more items is truncated and named in a warning on the response so the client knows the
+// view is partial. Requests whose page size is already below the limit are not capped.
+func (s *Store) listNamespaceCapped(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	limit := s.namespaceItemLimit
+	query := apiOp.Request.URL.Query()
+	if pageSize, err := strconv.Atoi(query.Get("limit")); err == nil && pageSize > 0 && pageSize < limit {
+		return s.List(apiOp, schema)
+	}
+
+	req := apiOp.Request.Clone(apiOp.Context())
+	query.Set("limit", strconv.Itoa(limit))
+	req.URL.RawQuery = query.Encode()
+	capped := apiOp.Clone()
+	capped.Request = req
+
+	list, err := s.List(capped, schema)
+	if err != nil || list.Continue == "" {
+		return list, err
+	}
+
+	// the rest of the namespace is dropped, clearing continue ends this partition
+	list.Continue = ""
+	warning := fmt.Sprintf("%s in namespace %s truncated to %d items", schema.ID, apiOp.Namespace, limit)
+	logrus.Debug(warning)
+	addWarning(apiOp, warning)
+	return list, nil
+}
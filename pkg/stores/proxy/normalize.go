@@ -0,0 +1,25 @@
+package proxy
+
+import (
+	"github.com/rancher/wrangler/pkg/data"
+)
+
+var serverManagedFields = [][]string{
+	{"metadata", "uid"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"status"},
+}
+
+// NormalizeCreateInput removes fields that are owned by the apiserver so that an object copied
+// from another cluster can be created as is. Without this the apiserver rejects the request with a
+// confusing 422 error.
+func NormalizeCreateInput(input map[string]interface{}) {
+	if input == nil {
+		return
+	}
+	for _, path := range serverManagedFields {
+		data.RemoveValue(input, path...)
+	}
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"github.com/rancher/wrangler/pkg/data"
+)
+
+func copiedConfigMap() map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":              "copied",
+			"namespace":         "default",
+			"labels":            map[string]interface{}{"app": "copied"},
+			"uid":               "from-the-other-cluster",
+			"resourceVersion":   "1234",
+			"generation":        int64(3),
+			"creationTimestamp": "2020-01-01T00:00:00Z",
+		},
+		"data":   map[string]interface{}{"key": "value"},
+		"status": map[string]interface{}{"phase": "Copied"},
+	}
+}
+
+func TestNormalizeCreateInputStripsServerManagedFields(t *testing.T) {
+	for _, path := range serverManagedFields {
+		input := copiedConfigMap()
+		NormalizeCreateInput(input)
+		if _, ok := data.GetValue(input, path...); ok {
+			t.Errorf("%v was not stripped", path)
+		}
+	}
+
+	input := copiedConfigMap()
+	NormalizeCreateInput(input)
+	if data.Object(input).String("metadata", "name") != "copied" ||
+		data.Object(input).String("metadata", "labels", "app") != "copied" ||
+		data.Object(input).String("data", "key") != "value" {
+		t.Errorf("got %v, want the fields set by the user kept", input)
+	}
+}
+
+func TestNormalizeCreateInputNil(t *testing.T) {
+	NormalizeCreateInput(nil)
+}
+
+func createCopied(t *testing.T, opts ...Option) map[string]interface{} {
+	t.Helper()
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}, opts...)
+
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPost, "default", nil)
+	if _, err := store.Create(apiOp, schema, types.APIObject{Object: copiedConfigMap()}); err != nil {
+		t.Fatal(err)
+	}
+	return cluster.objects[clusterKey("default", "copied")].Object
+}
+
+func TestCreateStripsServerManagedFields(t *testing.T) {
+	sent := createCopied(t)
+	if uid := data.Object(sent).String("metadata", "uid"); uid == "from-the-other-cluster" {
+		t.Error("the uid of the other cluster was sent")
+	}
+	if rv := data.Object(sent).String("metadata", "resourceVersion"); rv == "1234" {
+		t.Error("the resourceVersion of the other cluster was sent")
+	}
+	for _, path := range [][]string{{"metadata", "generation"}, {"metadata", "creationTimestamp"}, {"status"}} {
+		if _, ok := data.GetValue(sent, path...); ok {
+			t.Errorf("%v was sent", path)
+		}
+	}
+}
+
+func TestCreateNormalizationDisabled(t *testing.T) {
+	sent := createCopied(t, WithCreateNormalization(false))
+	for _, path := range [][]string{{"metadata", "generation"}, {"metadata", "creationTimestamp"}, {"status"}} {
+		if _, ok := data.GetValue(sent, path...); !ok {
+			t.Errorf("%v was stripped with normalization disabled", path)
+		}
+	}
+}
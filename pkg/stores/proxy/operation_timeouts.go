@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	ErrOperationTimeout = validation.ErrorCode{
+		Code:   "OperationTimeout",
+		Status: http.StatusServiceUnavailable,
+	}
+)
+
+// OperationTimeouts are the deadlines of store operations by type, set with WithOperationTimeouts. A zero timeout
+// sets no deadline, the operation is then only bounded by the context of the request, which ends with the HTTP
+// server's timeouts or when the client goes away, and by the Timeout of the rest.Config of the clients. A deadline
+// is only useful below those, a longer one never fires.
+type OperationTimeouts struct {
+	// Get bounds a get of a single object.
+	Get time.Duration
+	// List bounds a whole list, every request of a list that fans out over namespaces included.
+	List time.Duration
+	// Write bounds a create, update or delete, with its retries. An update also stays within WithUpdateTimeout.
+	Write time.Duration
+	// WatchEstablish bounds the time until the apiserver accepts a watch. Once the watch is open it runs until the
+	// apiserver ends it after its TimeoutSeconds, or it is renewed, see WatchKeepaliveExtender.
+	WatchEstablish time.Duration
+}
+
+// DefaultOperationTimeouts sets no deadlines, operations are bounded by the request and client timeouts only.
+var DefaultOperationTimeouts = OperationTimeouts{}
+
+// timeoutStore runs each operation within the timeout of its type.
+type timeoutStore struct {
+	types.Store
+	timeouts OperationTimeouts
+}
+
+// withTimeout returns apiOp with a context that ends after timeout, and the cancel func of that context.
+func withTimeout(apiOp *types.APIRequest, timeout time.Duration) (*types.APIRequest, context.Context, func()) {
+	if timeout <= 0 {
+		return apiOp, apiOp.Context(), func() {}
+	}
+	ctx, cancel := context.WithTimeout(apiOp.Context(), timeout)
+	return apiOp.WithContext(ctx), ctx, cancel
+}
+
+// timeoutError replaces err with an ErrOperationTimeout when the deadline of ctx was what failed the operation.
+func timeoutError(ctx context.Context, err error, operation string, timeout time.Duration) error {
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return apierror.NewAPIError(ErrOperationTimeout, operation+" did not complete within "+timeout.String()+", retry the request")
+	}
+	return err
+}
+
+func (t *timeoutStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	apiOp, ctx, cancel := withTimeout(apiOp, t.timeouts.Get)
+	defer cancel()
+	obj, err := t.Store.ByID(apiOp, schema, id)
+	return obj, timeoutError(ctx, err, "get of "+schema.ID+" "+id, t.timeouts.Get)
+}
+
+func (t *timeoutStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	apiOp, ctx, cancel := withTimeout(apiOp, t.timeouts.List)
+	defer cancel()
+	list, err := t.Store.List(apiOp, schema)
+	return list, timeoutError(ctx, err, "list of "+schema.ID, t.timeouts.List)
+}
+
+func (t *timeoutStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	apiOp, ctx, cancel := withTimeout(apiOp, t.timeouts.Write)
+	defer cancel()
+	obj, err := t.Store.Create(apiOp, schema, data)
+	return obj, timeoutError(ctx, err, "create of "+schema.ID, t.timeouts.Write)
+}
+
+func (t *timeoutStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	apiOp, ctx, cancel := withTimeout(apiOp, t.timeouts.Write)
+	defer cancel()
+	obj, err := t.Store.Update(apiOp, schema, data, id)
+	return obj, timeoutError(ctx, err, "update of "+schema.ID+" "+id, t.timeouts.Write)
+}
+
+func (t *timeoutStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	apiOp, ctx, cancel := withTimeout(apiOp, t.timeouts.Write)
+	defer cancel()
+	obj, err := t.Store.Delete(apiOp, schema, id)
+	return obj, timeoutError(ctx, err, "delete of "+schema.ID+" "+id, t.timeouts.Write)
+}
+
+// openWatch starts a watch that must be accepted by the apiserver within the watch establish timeout. The
+// deadline can't be set on the context of the watch, it would also end the stream, so the context is canceled
+// if the apiserver takes too long and otherwise when the watch is stopped.
+func (s *Store) openWatch(apiOp *types.APIRequest, k8sClient dynamic.ResourceInterface, opts metav1.ListOptions) (watch.Interface, error) {
+	timeout := s.timeouts.WatchEstablish
+	if timeout <= 0 {
+		return k8sClient.Watch(apiOp.Context(), opts)
+	}
+
+	ctx, cancel := context.WithCancel(apiOp.Context())
+	timer := time.AfterFunc(timeout, cancel)
+	watcher, err := k8sClient.Watch(ctx, opts)
+	if !timer.Stop() {
+		if watcher != nil {
+			watcher.Stop()
+		}
+		cancel()
+		return nil, apierror.NewAPIError(ErrOperationTimeout, "watch was not accepted within "+timeout.String()+", retry the request")
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelOnStop{
+		Interface: watcher,
+		cancel:    cancel,
+	}, nil
+}
+
+// cancelOnStop cancels the context of a watch when it is stopped.
+type cancelOnStop struct {
+	watch.Interface
+	cancel func()
+}
+
+func (c *cancelOnStop) Stop() {
+	c.Interface.Stop()
+	c.cancel()
+}
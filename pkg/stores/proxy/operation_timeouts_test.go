@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// blockingStore blocks every get until the context of the request ends.
+type blockingStore struct {
+	types.Store
+}
+
+func (b *blockingStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	<-apiOp.Context().Done()
+	return types.APIObject{}, apiOp.Context().Err()
+}
+
+func timeoutRequest(ctx context.Context) *types.APIRequest {
+	return &types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodGet, "/v1/widget", nil).WithContext(ctx),
+		Response: httptest.NewRecorder(),
+	}
+}
+
+func expectOperationTimeout(t *testing.T, err error) {
+	t.Helper()
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok || apiErr.Code != ErrOperationTimeout {
+		t.Errorf("got %v, want an operation timeout", err)
+	}
+}
+
+func TestTimeoutStoreEndsSlowOperations(t *testing.T) {
+	s := &timeoutStore{
+		Store:    &blockingStore{},
+		timeouts: OperationTimeouts{Get: 20 * time.Millisecond},
+	}
+	schema := &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+
+	_, err := s.ByID(timeoutRequest(context.Background()), schema, "a")
+	expectOperationTimeout(t, err)
+
+	// a request that is canceled by the client isn't reported as a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.ByID(timeoutRequest(ctx), schema, "a"); err != context.Canceled {
+		t.Errorf("got %v, want the cancellation of the request", err)
+	}
+}
+
+func TestTimeoutStoreWithoutTimeoutSetsNoDeadline(t *testing.T) {
+	apiOp := timeoutRequest(context.Background())
+	withDeadline, ctx, cancel := withTimeout(apiOp, 0)
+	defer cancel()
+	if withDeadline != apiOp {
+		t.Error("got a new request for a zero timeout")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("got a deadline for a zero timeout")
+	}
+}
+
+// watchResource accepts watches after delay, or never when delay is negative, and keeps the context of the last
+// accepted watch.
+type watchResource struct {
+	dynamic.ResourceInterface
+	delay time.Duration
+	ctx   context.Context
+}
+
+func (w *watchResource) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	if w.delay < 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	time.Sleep(w.delay)
+	w.ctx = ctx
+	return watch.NewFake(), nil
+}
+
+func TestOpenWatchTimesOutBeforeTheWatchIsAccepted(t *testing.T) {
+	s := &Store{timeouts: OperationTimeouts{WatchEstablish: 20 * time.Millisecond}}
+	_, err := s.openWatch(timeoutRequest(context.Background()), &watchResource{delay: -1}, metav1.ListOptions{})
+	expectOperationTimeout(t, err)
+}
+
+func TestOpenWatchDoesNotEndTheStream(t *testing.T) {
+	s := &Store{timeouts: OperationTimeouts{WatchEstablish: 20 * time.Millisecond}}
+	resource := &watchResource{}
+	watcher, err := s.openWatch(timeoutRequest(context.Background()), resource, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if resource.ctx.Err() != nil {
+		t.Fatal("the context of the watch ended with the establish timeout")
+	}
+	watcher.Stop()
+	if resource.ctx.Err() != context.Canceled {
+		t.Error("the context of the watch was not canceled when it was stopped")
+	}
+}
+
+func TestOpenWatchWithoutTimeout(t *testing.T) {
+	s := &Store{}
+	resource := &watchResource{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := s.openWatch(timeoutRequest(ctx), resource, metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if resource.ctx != ctx {
+		t.Error("got a new context, want the watch on the context of the request")
+	}
+}
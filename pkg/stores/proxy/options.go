@@ -0,0 +1,314 @@
+package proxy
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/rancher/steve/pkg/clusterversion"
+	"github.com/rancher/steve/pkg/idle"
+	"k8s.io/client-go/tools/record"
+)
+
+// Option configures optional behavior of the proxy Store.
+type Option func(*Store)
+
+// WithCreateNormalization controls whether server-managed fields are stripped
+// from the input of Create before it is sent to the apiserver. Enabled by default.
+func WithCreateNormalization(enabled bool) Option {
+	return func(s *Store) {
+		s.normalizeCreate = enabled
+	}
+}
+
+// WithEventReplay keeps a per-schema ring buffer of the last size watch events, no older than maxAge, so that
+// a watch resuming from a recent revision is replayed from memory instead of forcing the client to list again.
+// A maxAge of zero keeps events until they are pushed out of the buffer. The watches feeding the buffers stop when
+// ctx is done.
+func WithEventReplay(ctx context.Context, size int, maxAge time.Duration) Option {
+	return func(s *Store) {
+		if size > 0 {
+			s.eventReplay = newEventReplay(ctx, size, maxAge)
+		}
+	}
+}
+
+// WithCreateRetry retries a Create up to attempts times, starting with the given backoff and doubling it,
+// when the apiserver fails with a transient error and a follow-up get confirms the object was not created.
+// Disabled by default because creates are not idempotent; see Store.create for the safety reasoning.
+func WithCreateRetry(attempts int, backoff time.Duration) Option {
+	return func(s *Store) {
+		s.createRetries = attempts
+		s.createRetryBackoff = backoff
+	}
+}
+
+// WithInputValidation controls whether Create, Update and merge Patch input is checked against the enum and
+// pattern constraints from the OpenAPI schema before it is sent to the apiserver. Enabled by default.
+func WithInputValidation(enabled bool) Option {
+	return func(s *Store) {
+		s.validateInput = enabled
+	}
+}
+
+// WithPatternDescriptions sets human readable descriptions, keyed by pattern, used in the error message when a
+// value does not match a schema pattern. The description completes the sentence "must ...", for example
+// "be a lowercase RFC 1123 label". Patterns without a description are reported as is.
+func WithPatternDescriptions(descriptions map[string]string) Option {
+	return func(s *Store) {
+		s.patternDescriptions = descriptions
+	}
+}
+
+// WithNameValidation checks metadata.name and metadata.generateName on Create, and a name supplied to Update,
+// before the request is sent to the apiserver so invalid names fail with an error pointing at the offending
+// character. The validator picks the naming rules per schema; nil uses DefaultNameValidator. Disabled by default.
+func WithNameValidation(validator NameValidatorFunc) Option {
+	return func(s *Store) {
+		if validator == nil {
+			validator = DefaultNameValidator
+		}
+		s.nameValidator = validator
+	}
+}
+
+// WithClusterQuota rejects a Create with 429 Too Many Requests when the quota has no remaining capacity for the
+// schema in the target namespace. NewResourceQuotaCapacity provides an implementation based on ResourceQuotas.
+func WithClusterQuota(quota ClusterQuota) Option {
+	return func(s *Store) {
+		s.quota = quota
+	}
+}
+
+// WithDeduplication collapses consecutive change events for the same object that arrive within window into the
+// latest one, see DeduplicatingWatcher. A window of zero uses the 50ms default.
+func WithDeduplication(window time.Duration) Option {
+	return func(s *Store) {
+		if window <= 0 {
+			window = defaultDeduplicationWindow
+		}
+		s.dedupeWindow = window
+	}
+}
+
+// WithUpdateTimeout limits the total time of an Update, across every request it sends to the apiserver, to d.
+// An update that runs out of time fails with 503 Service Unavailable so the client knows to retry.
+func WithUpdateTimeout(d time.Duration) Option {
+	return func(s *Store) {
+		s.updateTimeout = d
+	}
+}
+
+// WithReadAfterWrite makes Create and Update confirm, before responding, that the apiserver watch cache has
+// caught up with the write so that an immediate list includes the new object. The written resourceVersion is
+// also returned in the WrittenResourceVersionHeader so clients can ask for a list at least that fresh instead.
+// Off by default, every write then costs an extra list that can take up to timeout when the cache lags; a
+// timeout of zero or less uses 3 seconds.
+func WithReadAfterWrite(timeout time.Duration) Option {
+	return func(s *Store) {
+		if timeout <= 0 {
+			timeout = defaultReadAfterWriteTimeout
+		}
+		s.readAfterWrite = timeout
+	}
+}
+
+// WithOfflineMode saves the results of reads to db and serves reads from it, marked with "cached": true, while
+// the apiserver is unreachable. Cached data is kept per access set so users only see what they could read
+// themselves. Open db with offline.Open so a database that can't be used fails at startup.
+func WithOfflineMode(db *sql.DB) Option {
+	return func(s *Store) {
+		s.offlineDB = db
+	}
+}
+
+// WithVerboseLogging adds the object bodies of creates, updates and gets to the per operation log, at trace
+// level. Bodies can hold secrets so they are never logged unless this is enabled.
+func WithVerboseLogging(enabled bool) Option {
+	return func(s *Store) {
+		s.logBodies = enabled
+	}
+}
+
+// WithNamespaceItemLimit caps the items listed from each namespace when a list fans out over the namespaces a
+// user has access to, so one namespace with a huge number of objects can't crowd out the others. Truncated
+// namespaces are named in a Warning header. Lists of a single namespace are never capped. Zero disables the cap.
+func WithNamespaceItemLimit(limit int) Option {
+	return func(s *Store) {
+		s.namespaceItemLimit = limit
+	}
+}
+
+// WithApplyAnnotation records the input of every Create and Update in the kubectl.kubernetes.io/last-applied-configuration
+// annotation, as kubectl apply does, so kubectl apply and diff can be used on the same objects. Disabled by default.
+func WithApplyAnnotation(enabled bool) Option {
+	return func(s *Store) {
+		s.applyAnnotation = enabled
+	}
+}
+
+// WithWebhookRetry sets how a Create that fails because an admission webhook could not be called, or with 503 Service
+// Unavailable, is retried, see WebhookRetry. Retries stop early at the deadline of the request. Enabled by default with
+// DefaultWebhookRetry, an attempts of zero disables it.
+func WithWebhookRetry(attempts int, backoff time.Duration) Option {
+	return func(s *Store) {
+		s.webhookRetry = WebhookRetry{
+			Attempts: attempts,
+			Backoff:  backoff,
+		}
+	}
+}
+
+// WithCreatorID stamps every created object with the CreatorIDAnnotation, naming the user returned by source.
+// Use AuthenticatedUser when steve authenticates users itself and ImpersonatedUser when it is reached through an
+// impersonating proxy, otherwise the proxy's service account is recorded. Disabled by default.
+func WithCreatorID(source CreatorIDSource) Option {
+	return func(s *Store) {
+		s.creatorID = source
+	}
+}
+
+// WithWatchResync resumes a watch whose revision expired, instead of failing it with a resync required error, by
+// listing again and sending the ADDED, MODIFIED and DELETED events between the objects the watch last sent and the
+// list. Every watch then remembers the identity and resourceVersion of up to maxObjects objects, a few hundred
+// bytes each, and falls back to the resync required error once it would hold more. Disabled by default.
+func WithWatchResync(maxObjects int) Option {
+	return func(s *Store) {
+		s.watchResync = maxObjects
+	}
+}
+
+// WithExcludedFields replaces DefaultExcludedFields as the fields left out of listed and watched objects. Paths are
+// in dot notation, with keys that contain dots in brackets: metadata.annotations[example.com/key]. Clients get a
+// field back by naming it in ?include=, and subscriptions opened with ?raw=true get objects unchanged. No paths
+// disables the exclusion.
+func WithExcludedFields(paths ...string) Option {
+	return func(s *Store) {
+		s.excludedFields = parseFieldPaths(paths)
+	}
+}
+
+// WithSlowOperationThreshold records every store operation that takes at least d in SlowOperations, which is served
+// at GET /debug/slow-ops. Defaults to DefaultSlowOperationThreshold, zero disables the recording.
+func WithSlowOperationThreshold(d time.Duration) Option {
+	return func(s *Store) {
+		s.slowThreshold = d
+	}
+}
+
+// WithFieldManager sets the field manager of server-side applies, PATCH with Content-Type application/apply-patch+yaml
+// to an object or its status, that don't name one with ?fieldManager=. Controllers sharing an object should each use their own so they don't clobber each other's fields.
+// Defaults to DefaultFieldManager.
+func WithFieldManager(name string) Option {
+	return func(s *Store) {
+		if name != "" {
+			s.fieldManager = name
+		}
+	}
+}
+
+// WithAccessForgetters registers the caches keyed by access set ID, such as the schema collection, to drop when a
+// list fanned out over namespaces is forbidden because the access set of the user was stale. The access set lookup
+// is forgotten too when it implements idle.Forgetter.
+func WithAccessForgetters(forgetters ...idle.Forgetter) Option {
+	return func(s *Store) {
+		s.accessForgetters = append(s.accessForgetters, forgetters...)
+	}
+}
+
+// WithFieldValidation sets the fieldValidation, Strict, Warn or Ignore, sent with creates, updates and patches that
+// don't set ?fieldValidation= themselves. The warnings of the apiserver are passed on in Warning headers. The option
+// is dropped for apiservers older than 1.25. Defaults to DefaultFieldValidation, empty sends no fieldValidation.
+func WithFieldValidation(value string) Option {
+	return func(s *Store) {
+		s.fieldValidation = value
+	}
+}
+
+// WithCreateFinalizers adds finalizers, by schema ID, to every object of the schema created through the store, so a
+// controller's cleanup runs even when the object is deleted before the controller could add its finalizer. Finalizers
+// can also be set per schema with attributes.SetCreateFinalizers in a Template.Customize.
+func WithCreateFinalizers(finalizers map[string][]string) Option {
+	return func(s *Store) {
+		s.finalizers = finalizers
+	}
+}
+
+// WithContinueRestart answers a list whose continue token expired with its first page, and the
+// partition.PaginationResetHeader, instead of failing it with partition.ErrContinueExpired. Clients that can't
+// handle the reset should use exact revisions to page through lists. Disabled by default.
+func WithContinueRestart(enabled bool) Option {
+	return func(s *Store) {
+		s.restartExpired = enabled
+	}
+}
+
+// WithEventRecorder sets the recorder of the audit events of schemas with attributes.AuditEvents. By default events
+// are sent to the apiserver with the admin client, with the AuditEventComponent as their source.
+func WithEventRecorder(recorder record.EventRecorder) Option {
+	return func(s *Store) {
+		s.recorder = recorder
+	}
+}
+
+// WithWatchSnapshots sends the changes seen by watches of the schemas, by ID, or of every schema when none are named,
+// once per interval with only the latest state of each changed object, see SnapshotWatcher. Creates and removes are
+// still sent immediately. This trades up to interval of latency on changes for a bounded event rate on chatty
+// resources such as events. Disabled by default.
+func WithWatchSnapshots(interval time.Duration, schemaIDs ...string) Option {
+	return func(s *Store) {
+		s.snapshots.interval = interval
+		s.snapshots.schemas = map[string]bool{}
+		for _, id := range schemaIDs {
+			s.snapshots.schemas[id] = true
+		}
+	}
+}
+
+// WithWatchKeepalive renews watches margin before the apiserver would close them, so clients don't see the stream
+// end every 30 minutes, see WatchKeepaliveExtender. A margin of zero uses DefaultWatchRenewMargin. Disabled by
+// default.
+func WithWatchKeepalive(margin time.Duration) Option {
+	return func(s *Store) {
+		s.keepalive = WatchKeepaliveExtender{
+			Enabled: true,
+			Margin:  margin,
+		}
+	}
+}
+
+// WithClusterListRatio lists a resource across the namespaces a user can see with a single cluster wide list, filtered
+// to the objects the user may list, instead of a list per namespace, once the user can list in at least ratio of the
+// namespaces of the cluster, for example 0.8. The cluster wide list is sent as steve itself so the filtering of what
+// the access set of the user grants is what authorizes the result. It needs the lister of WithNamespaceLister.
+// Disabled by default.
+func WithClusterListRatio(ratio float64) Option {
+	return func(s *Store) {
+		s.clusterListRatio = ratio
+	}
+}
+
+// WithNamespaceLister sets the lister used to count the namespaces of the cluster for WithClusterListRatio.
+func WithNamespaceLister(lister NamespaceLister) Option {
+	return func(s *Store) {
+		s.namespaces = lister
+	}
+}
+
+// WithClusterVersion gates the features that depend on the version of the cluster on tracker: watches only ask for
+// bookmarks, writes only send fieldValidation, and server-side applies are only sent as such, instead of as a merge
+// patch, when the cluster supports them. Without a tracker every feature is used.
+func WithClusterVersion(tracker *clusterversion.Tracker) Option {
+	return func(s *Store) {
+		s.clusterVersion = tracker
+	}
+}
+
+// WithOperationTimeouts sets deadlines for gets, lists, writes and the start of watches, see OperationTimeouts, so
+// gets can fail fast while slow lists are tolerated. An operation that runs out of time fails with 503 Service
+// Unavailable. Defaults to DefaultOperationTimeouts, which sets none.
+func WithOperationTimeouts(timeouts OperationTimeouts) Option {
+	return func(s *Store) {
+		s.timeouts = timeouts
+	}
+}
@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// pathStep is one step of a promotion path: a field, an array index or an array filter on a field value.
+type pathStep struct {
+	field       string
+	index       int
+	filterField string
+	filterValue string
+	kind        stepKind
+}
+
+type stepKind int
+
+const (
+	fieldStep stepKind = iota
+	indexStep
+	filterStep
+)
+
+var compiledPaths sync.Map
+
+// parsePath compiles the subset of JSONPath supported by field promotion: field access (a.b), array indexes
+// (a[0]) and array filters by field value (a[?type==Ready] or a[?(@.type=="Ready")]). A leading $ or
+// surrounding braces are ignored.
+func parsePath(path string) ([]pathStep, error) {
+	if cached, ok := compiledPaths.Load(path); ok {
+		return cached.([]pathStep), nil
+	}
+
+	expr := strings.TrimSpace(path)
+	expr = strings.TrimSuffix(strings.TrimPrefix(expr, "{"), "}")
+	expr = strings.TrimPrefix(strings.TrimPrefix(expr, "$"), ".")
+
+	var steps []pathStep
+	for expr != "" {
+		switch {
+		case expr[0] == '.':
+			expr = expr[1:]
+		case expr[0] == '[':
+			end := strings.Index(expr, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in %s", path)
+			}
+			step, err := parseBracket(expr[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", path, err)
+			}
+			steps = append(steps, step)
+			expr = expr[end+1:]
+		default:
+			end := strings.IndexAny(expr, ".[")
+			if end < 0 {
+				end = len(expr)
+			}
+			steps = append(steps, pathStep{kind: fieldStep, field: expr[:end]})
+			expr = expr[end:]
+		}
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	compiledPaths.Store(path, steps)
+	return steps, nil
+}
+
+func parseBracket(expr string) (pathStep, error) {
+	if !strings.HasPrefix(expr, "?") {
+		index, err := strconv.Atoi(expr)
+		if err != nil {
+			return pathStep{}, fmt.Errorf("invalid index %s", expr)
+		}
+		return pathStep{kind: indexStep, index: index}, nil
+	}
+
+	expr = strings.TrimPrefix(expr, "?")
+	expr = strings.TrimSuffix(strings.TrimPrefix(expr, "("), ")")
+	expr = strings.TrimPrefix(expr, "@.")
+	parts := strings.SplitN(expr, "==", 2)
+	if len(parts) != 2 {
+		return pathStep{}, fmt.Errorf("invalid filter %s, must be field==value", expr)
+	}
+	return pathStep{
+		kind:        filterStep,
+		filterField: strings.TrimSpace(parts[0]),
+		filterValue: strings.Trim(strings.TrimSpace(parts[1]), `"'`),
+	}, nil
+}
+
+// evalPath returns the first value at path in obj.
+func evalPath(obj interface{}, steps []pathStep) (interface{}, bool) {
+	current := obj
+	for _, step := range steps {
+		switch step.kind {
+		case fieldStep:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = m[step.field]; !ok {
+				return nil, false
+			}
+		case indexStep:
+			items, ok := current.([]interface{})
+			if !ok || step.index < 0 || step.index >= len(items) {
+				return nil, false
+			}
+			current = items[step.index]
+		case filterStep:
+			items, ok := current.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			found := false
+			for _, item := range items {
+				m, ok := item.(map[string]interface{})
+				if ok && fmt.Sprint(m[step.filterField]) == step.filterValue {
+					current, found = item, true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		}
+	}
+	return current, true
+}
+
+// promoteFields adds the field promotions of the schema to obj. Fields that already exist on the object are
+// never overwritten and invalid paths are logged and skipped.
+func promoteFields(schema *types.APISchema, obj types.APIObject) {
+	promotions := attributes.FieldPromotions(schema)
+	if len(promotions) == 0 {
+		return
+	}
+	u, ok := obj.Object.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	for field, path := range promotions {
+		if _, exists := u.Object[field]; exists {
+			continue
+		}
+		steps, err := parsePath(path)
+		if err != nil {
+			logrus.Errorf("invalid field promotion %s for %s: %v", field, schema.ID, err)
+			continue
+		}
+		if value, ok := evalPath(u.Object, steps); ok {
+			u.Object[field] = value
+		}
+	}
+}
+
+// FieldValue returns the value at path in obj, path uses the JSONPath subset of field promotions.
+func FieldValue(obj map[string]interface{}, path string) (interface{}, bool, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := evalPath(obj, steps)
+	return value, ok, nil
+}
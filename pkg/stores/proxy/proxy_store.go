@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,12 +10,19 @@ import (
 	"net/http"
 	"reflect"
 	"regexp"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/clusterversion"
+	"github.com/rancher/steve/pkg/idle"
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"github.com/rancher/steve/pkg/stores/offline"
 	"github.com/rancher/steve/pkg/stores/partition"
+	"github.com/rancher/steve/pkg/watchevent"
 	"github.com/rancher/wrangler/pkg/data"
 	"github.com/rancher/wrangler/pkg/schemas/validation"
 	"github.com/rancher/wrangler/pkg/summary"
@@ -27,8 +35,10 @@ import (
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 var (
@@ -59,28 +69,111 @@ type RelationshipNotifier interface {
 }
 
 type Store struct {
-	clientGetter ClientGetter
-	notifier     RelationshipNotifier
+	clientGetter    ClientGetter
+	notifier        RelationshipNotifier
+	asl             accesscontrol.AccessSetLookup
+	normalizeCreate bool
+	eventReplay     *eventReplay
+
+	validateInput       bool
+	patternDescriptions map[string]string
+	nameValidator       NameValidatorFunc
+	quota               ClusterQuota
+	dedupeWindow        time.Duration
+	updateTimeout       time.Duration
+	readAfterWrite      time.Duration
+	offlineDB           *sql.DB
+	logBodies           bool
+	namespaceItemLimit  int
+	applyAnnotation     bool
+	watchResync         int
+	creatorID           CreatorIDSource
+	excludedFields      []fieldPath
+	slowThreshold       time.Duration
+	fieldManager        string
+	accessForgetters    []idle.Forgetter
+	fieldValidation     string
+	finalizers          map[string][]string
+	restartExpired      bool
+	recorder            record.EventRecorder
+	recorderOnce        sync.Once
+	snapshots           watchSnapshots
+	keepalive           WatchKeepaliveExtender
+	clusterListRatio    float64
+	namespaces          NamespaceLister
+	clusterVersion      *clusterversion.Tracker
+	timeouts            OperationTimeouts
+
+	createRetries      int
+	createRetryBackoff time.Duration
+	webhookRetry       WebhookRetry
 }
 
-func NewProxyStore(clientGetter ClientGetter, notifier RelationshipNotifier, lookup accesscontrol.AccessSetLookup) types.Store {
-	return &errorStore{
-		Store: &WatchRefresh{
-			Store: &partition.Store{
-				Partitioner: &rbacPartitioner{
-					proxyStore: &Store{
-						clientGetter: clientGetter,
-						notifier:     notifier,
+func NewProxyStore(clientGetter ClientGetter, notifier RelationshipNotifier, lookup accesscontrol.AccessSetLookup, opts ...Option) types.Store {
+	proxyStore := &Store{
+		clientGetter:    &adminClientGetter{ClientGetter: clientGetter},
+		notifier:        notifier,
+		asl:             lookup,
+		normalizeCreate: true,
+		validateInput:   true,
+		webhookRetry:    DefaultWebhookRetry,
+		excludedFields:  parseFieldPaths(DefaultExcludedFields),
+		slowThreshold:   DefaultSlowOperationThreshold,
+		fieldManager:    DefaultFieldManager,
+		fieldValidation: DefaultFieldValidation,
+		timeouts:        DefaultOperationTimeouts,
+	}
+	for _, opt := range opts {
+		opt(proxyStore)
+	}
+
+	var store types.Store = &timeoutStore{
+		Store: &verbStore{
+			Store: &errorStore{
+				Store: &staleAccessStore{
+					Store: &WatchRefresh{
+						Store: &partition.Store{
+							Partitioner: &rbacPartitioner{
+								proxyStore: proxyStore,
+							},
+							RestartExpired: proxyStore.restartExpired,
+						},
+						asl: lookup,
 					},
+					asl:        lookup,
+					forgetters: proxyStore.accessForgetters,
 				},
 			},
-			asl: lookup,
 		},
+		timeouts: proxyStore.timeouts,
+	}
+	if proxyStore.offlineDB != nil {
+		store = offline.NewStore(store, proxyStore.offlineDB, proxyStore.accessScope)
+	}
+
+	// logStore stays the outermost store, it serves ListAndWatch and the other operations outside types.Store
+	return &logStore{
+		Store:         store,
+		logBodies:     proxyStore.logBodies,
+		slowThreshold: proxyStore.slowThreshold,
+		proxyStore:    proxyStore,
 	}
 }
 
+// accessScope partitions offline data by the access set of the user.
+func (s *Store) accessScope(apiOp *types.APIRequest) string {
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok || s.asl == nil {
+		return ""
+	}
+	return s.asl.AccessFor(user).ID
+}
+
 func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
 	result, err := s.byID(apiOp, schema, id)
+	if IsRawRequest(apiOp) {
+		return toRawAPI(schema, result), err
+	}
 	return toAPI(schema, result), err
 }
 
@@ -123,14 +216,23 @@ func (s *Store) byID(apiOp *types.APIRequest, schema *types.APISchema, id string
 		return nil, err
 	}
 
+	if rv, exact, err := exactResourceVersion(apiOp); err != nil {
+		return nil, err
+	} else if exact {
+		return s.byIDExact(apiOp, k8sClient, id, rv)
+	}
+
 	opts := metav1.GetOptions{}
 	if err := decodeParams(apiOp, &opts); err != nil {
 		return nil, err
 	}
 
 	obj, err := k8sClient.Get(apiOp.Context(), id, opts)
+	if err != nil {
+		return obj, err
+	}
 	rowToObject(obj)
-	return obj, err
+	return statusResult(schema, obj)
 }
 
 func moveFromUnderscore(obj map[string]interface{}) map[string]interface{} {
@@ -229,6 +331,13 @@ func (s *Store) ByNames(apiOp *types.APIRequest, schema *types.APISchema, names
 		return types.APIObjectList{}, err
 	}
 
+	if IsTableRequest(apiOp.Request) {
+		for _, obj := range objs.Objects {
+			filterTableRows(obj.Data(), names)
+		}
+		return objs, nil
+	}
+
 	var filtered []types.APIObject
 	for _, obj := range objs.Objects {
 		if names.Has(obj.Name()) {
@@ -254,11 +363,27 @@ func (s *Store) list(apiOp *types.APIRequest, schema *types.APISchema, client dy
 		return types.APIObjectList{}, nil
 	}
 
+	rv, exact, err := exactResourceVersion(apiOp)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+
+	if err := checkTableSupport(apiOp, schema); err != nil {
+		return types.APIObjectList{}, err
+	}
+
 	resultList, err := client.List(apiOp.Context(), opts)
 	if err != nil {
+		if exact {
+			return types.APIObjectList{}, exactReadError(err, rv)
+		}
 		return types.APIObjectList{}, err
 	}
 
+	if IsTableRequest(apiOp.Request) {
+		return tableList(schema, resultList)
+	}
+
 	tableToList(resultList)
 
 	result := types.APIObjectList{
@@ -266,8 +391,19 @@ func (s *Store) list(apiOp *types.APIRequest, schema *types.APISchema, client dy
 		Continue: resultList.GetContinue(),
 	}
 
+	if IsRawRequest(apiOp) {
+		for i := range resultList.Items {
+			result.Objects = append(result.Objects, toRawAPI(schema, &resultList.Items[i]))
+		}
+		return result, nil
+	}
+
+	exclusions := s.exclusions(apiOp)
 	for i := range resultList.Items {
-		result.Objects = append(result.Objects, toAPI(schema, &resultList.Items[i]))
+		excludeFields(&resultList.Items[i], exclusions)
+		obj := toAPI(schema, &resultList.Items[i])
+		promoteFields(schema, obj)
+		result.Objects = append(result.Objects, obj)
 	}
 
 	return result, nil
@@ -275,35 +411,55 @@ func (s *Store) list(apiOp *types.APIRequest, schema *types.APISchema, client dy
 
 func returnErr(err error, c chan types.APIEvent) {
 	c <- types.APIEvent{
-		Name:  "resource.error",
+		Name:  watchevent.ErrorAPIEvent,
 		Error: err,
 	}
 }
 
-func (s *Store) listAndWatch(apiOp *types.APIRequest, k8sClient dynamic.ResourceInterface, schema *types.APISchema, w types.WatchRequest, result chan types.APIEvent) {
+// listAndWatch streams the events of one apiserver watch to result. It returns watchExpired when the revision of
+// the watch expired and the tracker can resync, and watchRenew with the revision to resume from when the watch
+// was closed to be renewed, see WatchKeepaliveExtender.
+func (s *Store) listAndWatch(apiOp *types.APIRequest, k8sClient dynamic.ResourceInterface, schema *types.APISchema, w types.WatchRequest,
+	tracker *watchTracker, result chan types.APIEvent) (watchEnd, string) {
 	rev := w.Revision
 	if rev == "-1" || rev == "0" {
 		rev = ""
 	}
 
-	timeout := int64(60 * 30)
-	watcher, err := k8sClient.Watch(apiOp.Context(), metav1.ListOptions{
-		Watch:           true,
-		TimeoutSeconds:  &timeout,
-		ResourceVersion: rev,
-		LabelSelector:   w.Selector,
+	timeout := int64(watchTimeout / time.Second)
+	watcher, err := s.openWatch(apiOp, k8sClient, metav1.ListOptions{
+		Watch:               true,
+		TimeoutSeconds:      &timeout,
+		ResourceVersion:     rev,
+		LabelSelector:       w.Selector,
+		AllowWatchBookmarks: s.clusterVersion.Supports(clusterversion.WatchBookmarks),
 	})
 	if err != nil {
+		if expired := expiredRevision(schema, err); expired != nil {
+			if tracker.usable() {
+				return watchExpired, ""
+			}
+			returnErr(expired, result)
+			return watchClosed, ""
+		}
 		returnErr(errors.Wrapf(err, "stopping watch for %s: %v", schema.ID, err), result)
-		return
+		return watchClosed, ""
 	}
 	defer watcher.Stop()
 	logrus.Debugf("opening watcher for %s", schema.ID)
 
+	eventTypes := watchEventTypes(apiOp)
 	eg, ctx := errgroup.WithContext(apiOp.Context())
 
+	renew, stopRenew := s.keepalive.renewAfter(watchTimeout)
+	defer stopRenew()
+	renewed := make(chan struct{})
 	go func() {
-		<-ctx.Done()
+		select {
+		case <-ctx.Done():
+		case <-renew:
+			close(renewed)
+		}
 		watcher.Stop()
 	}()
 
@@ -312,25 +468,48 @@ func (s *Store) listAndWatch(apiOp *types.APIRequest, k8sClient dynamic.Resource
 			for rel := range s.notifier.OnInboundRelationshipChange(ctx, schema, apiOp.Namespace) {
 				obj, err := s.byID(apiOp, schema, rel.Name)
 				if err == nil {
-					result <- s.toAPIEvent(apiOp, schema, watch.Modified, obj)
+					s.sendEvent(apiOp, schema, result, eventTypes, watch.Modified, obj)
 				}
 			}
 			return fmt.Errorf("closed")
 		})
 	}
 
+	expired := false
+	lastRev := rev
 	eg.Go(func() error {
 		for event := range watcher.ResultChan() {
 			if event.Type == watch.Error {
+				if isGoneEvent(event) {
+					if tracker.usable() {
+						expired = true
+						return fmt.Errorf("expired")
+					}
+					returnErr(resyncRequired(schema), result)
+				}
 				continue
 			}
-			result <- s.toAPIEvent(apiOp, schema, event.Type, event.Object)
+			s.sendEvent(apiOp, schema, result, eventTypes, event.Type, event.Object)
+			tracker.observe(event.Type, event.Object)
+			if m, err := meta.Accessor(event.Object); err == nil && m.GetResourceVersion() != "" {
+				lastRev = m.GetResourceVersion()
+			}
 		}
 		return fmt.Errorf("closed")
 	})
 
 	_ = eg.Wait()
-	return
+	if expired {
+		return watchExpired, ""
+	}
+	select {
+	case <-renewed:
+		if lastRev != "" && apiOp.Context().Err() == nil {
+			return watchRenew, lastRev
+		}
+	default:
+	}
+	return watchClosed, ""
 }
 
 func (s *Store) WatchNames(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest, names sets.String) (chan types.APIEvent, error) {
@@ -367,13 +546,56 @@ func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.
 func (s *Store) watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest, client dynamic.ResourceInterface) (chan types.APIEvent, error) {
 	result := make(chan types.APIEvent)
 	go func() {
-		s.listAndWatch(apiOp, client, schema, w, result)
+		var err error
+		if w, err = s.replay(apiOp, schema, w, result); err != nil {
+			returnErr(err, result)
+			close(result)
+			return
+		}
+		tracker := s.newWatchTracker()
+		for {
+			end, rev := s.listAndWatch(apiOp, client, schema, w, tracker, result)
+			if end == watchRenew {
+				w.Revision = rev
+				result <- types.APIEvent{
+					Name:     watchevent.ResumedAPIEvent,
+					Revision: rev,
+				}
+				continue
+			}
+			if end != watchExpired {
+				break
+			}
+			rev, ok := s.resync(apiOp, client, schema, w, tracker, result)
+			if !ok {
+				returnErr(resyncRequired(schema), result)
+				break
+			}
+			w.Revision = rev
+		}
 		logrus.Debugf("closing watcher for %s", schema.ID)
 		close(result)
 	}()
+	if s.dedupeWindow > 0 {
+		result = NewDeduplicatingWatcher(result, s.dedupeWindow).ResultChan()
+	}
+	if interval := s.snapshotInterval(schema); interval > 0 {
+		result = NewSnapshotWatcher(result, interval).ResultChan()
+	}
 	return result, nil
 }
 
+// snapshotInterval is the interval of the SnapshotWatcher of watches of schema, zero when they stream every event.
+func (s *Store) snapshotInterval(schema *types.APISchema) time.Duration {
+	if s.snapshots.interval <= 0 {
+		return 0
+	}
+	if len(s.snapshots.schemas) > 0 && !s.snapshots.schemas[schema.ID] {
+		return 0
+	}
+	return s.snapshots.interval
+}
+
 func (s *Store) toAPIEvent(apiOp *types.APIRequest, schema *types.APISchema, et watch.EventType, obj runtime.Object) types.APIEvent {
 	name := types.ChangeAPIEvent
 	switch et {
@@ -381,16 +603,20 @@ func (s *Store) toAPIEvent(apiOp *types.APIRequest, schema *types.APISchema, et
 		name = types.RemoveAPIEvent
 	case watch.Added:
 		name = types.CreateAPIEvent
+	case watch.Bookmark:
+		name = watchevent.BookmarkAPIEvent
 	}
 
 	if unstr, ok := obj.(*unstructured.Unstructured); ok {
 		rowToObject(unstr)
+		excludeFields(unstr, s.exclusions(apiOp))
 	}
 
 	event := types.APIEvent{
 		Name:   name,
 		Object: toAPI(schema, obj),
 	}
+	promoteFields(schema, event.Object)
 
 	m, err := meta.Accessor(obj)
 	if err != nil {
@@ -406,12 +632,30 @@ func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, params
 		resp *unstructured.Unstructured
 	)
 
+	apiOp, addWarnings, err := s.withFieldValidation(apiOp)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	defer addWarnings()
+
 	input := params.Data()
 
 	if input == nil {
 		input = data.Object{}
 	}
 
+	if s.normalizeCreate {
+		NormalizeCreateInput(input)
+	}
+
+	if err := s.validateConstraints(apiOp, schema, input); err != nil {
+		return types.APIObject{}, err
+	}
+
+	if err := s.validateName(schema, input); err != nil {
+		return types.APIObject{}, err
+	}
+
 	name := types.Name(input)
 	ns := types.Namespace(input)
 	if name == "" && input.String("metadata", "generateName") == "" {
@@ -422,9 +666,23 @@ func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, params
 		input.SetNested(ns, "metadata", "namespace")
 	}
 
+	if err := s.checkQuota(apiOp, schema, ns); err != nil {
+		return types.APIObject{}, err
+	}
+
 	gvk := attributes.GVK(schema)
 	input["apiVersion"], input["kind"] = gvk.ToAPIVersionAndKind()
 
+	if s.applyAnnotation {
+		if err := setLastApplied(input); err != nil {
+			return types.APIObject{}, err
+		}
+	}
+
+	setCreatorID(apiOp, s.creatorID, input)
+	s.injectFinalizers(schema, input)
+	setRevisions(apiOp, schema, input, nil)
+
 	k8sClient, err := s.clientGetter.TableClient(apiOp, schema, ns)
 	if err != nil {
 		return types.APIObject{}, err
@@ -435,52 +693,86 @@ func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, params
 		return types.APIObject{}, err
 	}
 
-	resp, err = k8sClient.Create(apiOp.Context(), &unstructured.Unstructured{Object: input}, opts)
+	resp, err = s.create(apiOp.Context(), k8sClient, &unstructured.Unstructured{Object: input}, opts)
+	if err != nil {
+		return types.APIObject{}, err
+	}
 	rowToObject(resp)
-	return toAPI(schema, resp), err
+	result := toAPI(schema, resp)
+	s.confirmWrite(apiOp, schema, result)
+	s.auditEvent(apiOp, schema, "Created", result.Object)
+	return result, nil
 }
 
-func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, params types.APIObject, id string) (types.APIObject, error) {
+func (s *Store) update(apiOp *types.APIRequest, schema *types.APISchema, params types.APIObject, id string) (types.APIObject, error) {
 	var (
 		err   error
 		input = params.Data()
 	)
 
 	ns := types.Namespace(input)
+	if ns == "" {
+		// the body of a patch is not parsed, it has no namespace
+		ns = apiOp.Namespace
+	}
+	if apiOp.Method == http.MethodPatch && isStatusSubresource(apiOp) {
+		return s.patchStatus(apiOp, schema, ns, id)
+	}
+
 	k8sClient, err := s.clientGetter.TableClient(apiOp, schema, ns)
 	if err != nil {
 		return types.APIObject{}, err
 	}
 
 	if apiOp.Method == http.MethodPatch {
-		bytes, err := ioutil.ReadAll(io.LimitReader(apiOp.Request.Body, 2<<20))
+		bytes, err := ioutil.ReadAll(io.LimitReader(apiOp.Request.Body, 3<<20))
 		if err != nil {
 			return types.APIObject{}, err
 		}
 
-		pType := apitypes.StrategicMergePatchType
-		if apiOp.Request.Header.Get("content-type") == string(apitypes.JSONPatchType) {
-			pType = apitypes.JSONPatchType
+		pType := patchType(apiOp, apitypes.StrategicMergePatchType)
+		if err := checkReplacePatch(apiOp, pType); err != nil {
+			return types.APIObject{}, err
 		}
 
 		opts := metav1.PatchOptions{}
 		if err := decodeParams(apiOp, &opts); err != nil {
 			return types.APIObject{}, err
 		}
+		if err := s.applyOptions(apiOp, pType, &opts); err != nil {
+			return types.APIObject{}, err
+		}
+		if pType, bytes, err = s.applyFallback(pType, bytes, &opts); err != nil {
+			return types.APIObject{}, err
+		}
 
 		if pType == apitypes.StrategicMergePatchType {
 			data := map[string]interface{}{}
-			if err := json.Unmarshal(bytes, &data); err != nil {
+			if err := jsonnumber.Unmarshal(bytes, &data); err != nil {
 				return types.APIObject{}, err
 			}
 			data = moveFromUnderscore(data)
+			if err := checkNotDeleting(apiOp, k8sClient, id, data); err != nil {
+				return types.APIObject{}, err
+			}
+			if err := s.validateConstraints(apiOp, schema, data); err != nil {
+				return types.APIObject{}, err
+			}
+			if replaceRequested(apiOp) {
+				resp, err := s.replace(apiOp, schema, k8sClient, id, data)
+				if err != nil {
+					return types.APIObject{}, err
+				}
+				rowToObject(resp)
+				return toAPI(schema, resp), nil
+			}
 			bytes, err = json.Marshal(data)
 			if err != nil {
 				return types.APIObject{}, err
 			}
 		}
 
-		resp, err := k8sClient.Patch(apiOp.Context(), id, pType, bytes, opts)
+		resp, err := s.patchWithRevisions(apiOp, schema, k8sClient, id, pType, bytes, opts)
 		if err != nil {
 			return types.APIObject{}, err
 		}
@@ -493,12 +785,35 @@ func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, params
 		return types.APIObject{}, fmt.Errorf("metadata.resourceVersion is required for update")
 	}
 
-	opts := metav1.UpdateOptions{}
-	if err := decodeParams(apiOp, &opts); err != nil {
+	if err := s.validateConstraints(apiOp, schema, input); err != nil {
+		return types.APIObject{}, err
+	}
+
+	if err := s.validateName(schema, input); err != nil {
 		return types.APIObject{}, err
 	}
 
-	resp, err := k8sClient.Update(apiOp.Context(), &unstructured.Unstructured{Object: moveFromUnderscore(input)}, metav1.UpdateOptions{})
+	// moveFromUnderscore changes the map it is given so it gets a copy, input is converted again when it is sent
+	proposed := map[string]interface{}{}
+	for k, v := range input {
+		proposed[k] = v
+	}
+	if err := checkNotDeleting(apiOp, k8sClient, id, moveFromUnderscore(proposed)); err != nil {
+		return types.APIObject{}, err
+	}
+
+	if s.applyAnnotation {
+		if err := setLastApplied(input); err != nil {
+			return types.APIObject{}, err
+		}
+	}
+
+	var resp *unstructured.Unstructured
+	if replaceMode(apiOp, schema) {
+		resp, err = s.replace(apiOp, schema, k8sClient, id, moveFromUnderscore(input))
+	} else {
+		resp, err = s.merge(apiOp, schema, k8sClient, id, moveFromUnderscore(input))
+	}
 	if err != nil {
 		return types.APIObject{}, err
 	}
@@ -508,6 +823,10 @@ func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, params
 }
 
 func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	if previewDependents(apiOp) {
+		return s.previewDelete(apiOp, schema, id)
+	}
+
 	opts := metav1.DeleteOptions{}
 	if err := decodeParams(apiOp, &opts); err != nil {
 		return types.APIObject{}, nil
@@ -521,10 +840,11 @@ func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id stri
 	if err := k8sClient.Delete(apiOp.Context(), id, opts); err != nil {
 		return types.APIObject{}, err
 	}
+	s.auditEvent(apiOp, schema, "Deleted", deletedObject(apiOp, schema, id))
 
 	obj, err := s.byID(apiOp, schema, id)
 	if err != nil {
-		// ignore lookup error
+		// ignore lookup error, this includes the success status returned once the object is gone
 		return types.APIObject{}, validation.ErrorCode{
 			Status: http.StatusNoContent,
 		}
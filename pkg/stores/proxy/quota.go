@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const defaultQuotaRetryAfter = 10 * time.Second
+
+var (
+	ErrQuotaExceeded = validation.ErrorCode{
+		Code:   "QuotaExceeded",
+		Status: http.StatusTooManyRequests,
+	}
+)
+
+// ClusterQuota limits how many objects of a schema can exist in a namespace, or in the cluster when namespace is
+// empty, independently of the requesting user.
+type ClusterQuota interface {
+	RemainingCapacity(ctx context.Context, schema *types.APISchema, namespace string) (int64, error)
+}
+
+// quotaRetryAfter can be implemented by a ClusterQuota to tell clients how long until its usage is refreshed.
+type quotaRetryAfter interface {
+	RetryAfter() time.Duration
+}
+
+func (s *Store) checkQuota(apiOp *types.APIRequest, schema *types.APISchema, namespace string) error {
+	if s.quota == nil {
+		return nil
+	}
+
+	remaining, err := s.quota.RemainingCapacity(apiOp.Context(), schema, namespace)
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return nil
+	}
+
+	retryAfter := defaultQuotaRetryAfter
+	if r, ok := s.quota.(quotaRetryAfter); ok {
+		retryAfter = r.RetryAfter()
+	}
+	if apiOp.Response != nil {
+		apiOp.Response.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+
+	msg := "quota for " + schema.ID + " is exhausted"
+	if namespace != "" {
+		msg += " in namespace " + namespace
+	}
+	return apierror.NewAPIError(ErrQuotaExceeded, msg)
+}
+
+// ResourceQuotaCapacity is a ClusterQuota backed by the object count limits of the ResourceQuota objects in the
+// namespace, for example count/deployments.apps or pods. The remaining capacity is the lowest across all quotas.
+type ResourceQuotaCapacity struct {
+	clientGetter ClientGetter
+	// Refresh is how often the quota controller is expected to recalculate usage, it is sent as Retry-After.
+	Refresh time.Duration
+}
+
+func NewResourceQuotaCapacity(clientGetter ClientGetter) *ResourceQuotaCapacity {
+	return &ResourceQuotaCapacity{
+		clientGetter: clientGetter,
+		Refresh:      defaultQuotaRetryAfter,
+	}
+}
+
+func (r *ResourceQuotaCapacity) RetryAfter() time.Duration {
+	return r.Refresh
+}
+
+func (r *ResourceQuotaCapacity) RemainingCapacity(ctx context.Context, schema *types.APISchema, namespace string) (int64, error) {
+	if namespace == "" {
+		return math.MaxInt64, nil
+	}
+
+	client, err := r.clientGetter.AdminK8sInterface()
+	if err != nil {
+		return 0, err
+	}
+
+	quotas, err := client.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	names := quotaResourceNames(schema)
+	remaining := int64(math.MaxInt64)
+	for _, quota := range quotas.Items {
+		for _, name := range names {
+			hard, ok := quota.Status.Hard[name]
+			if !ok {
+				hard, ok = quota.Spec.Hard[name]
+			}
+			if !ok {
+				continue
+			}
+			used := quota.Status.Used[name]
+			if left := hard.Value() - used.Value(); left < remaining {
+				remaining = left
+			}
+		}
+	}
+
+	return remaining, nil
+}
+
+func quotaResourceNames(schema *types.APISchema) []corev1.ResourceName {
+	gr := attributes.GR(schema)
+	if gr.Group == "" {
+		return []corev1.ResourceName{
+			corev1.ResourceName("count/" + gr.Resource),
+			corev1.ResourceName(gr.Resource),
+		}
+	}
+	return []corev1.ResourceName{
+		corev1.ResourceName("count/" + gr.Resource + "." + gr.Group),
+	}
+}
@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// quotaClientGetter serves the ResourceQuotas of k8s next to the config maps of the fake cluster.
+type quotaClientGetter struct {
+	*fakeClusterGetter
+	k8s kubernetes.Interface
+}
+
+func (q *quotaClientGetter) AdminK8sInterface() (kubernetes.Interface, error) {
+	return q.k8s, nil
+}
+
+// configMapQuota allows two config maps in the namespace, two of them exist.
+func configMapQuota() *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "config-maps", Namespace: "default"},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: corev1.ResourceList{"count/configmaps": resource.MustParse("2")},
+		},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{"count/configmaps": resource.MustParse("2")},
+			Used: corev1.ResourceList{"count/configmaps": resource.MustParse("2")},
+		},
+	}
+}
+
+func createConfigMap(store types.Store, schema *types.APISchema, name string) (*types.APIRequest, error) {
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPost, "default", nil)
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name, "namespace": "default"},
+	}
+	_, err := store.Create(apiOp, schema, types.APIObject{Object: obj})
+	return apiOp, err
+}
+
+func TestCreateRejectedWhenTheQuotaIsExhausted(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+
+	for i := 0; i < 2; i++ {
+		if _, err := createConfigMap(NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}), schema, fmt.Sprintf("existing-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	getter := &quotaClientGetter{
+		fakeClusterGetter: &fakeClusterGetter{cluster: cluster},
+		k8s:               fake.NewSimpleClientset(configMapQuota()),
+	}
+	quota := NewResourceQuotaCapacity(getter)
+	quota.Refresh = 30 * time.Second
+	store := NewProxyStore(getter, nil, fakeAccessSetLookup{}, WithClusterQuota(quota))
+
+	apiOp, err := createConfigMap(store, schema, "third")
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok || apiErr.Code.Status != http.StatusTooManyRequests {
+		t.Fatalf("got %v, want a 429 for the third config map", err)
+	}
+	if retryAfter := apiOp.Response.Header().Get("Retry-After"); retryAfter != "30" {
+		t.Errorf("got Retry-After %q, want the refresh interval of the quota", retryAfter)
+	}
+	if _, ok := cluster.objects[clusterKey("default", "third")]; ok {
+		t.Error("the third config map was created")
+	}
+}
+
+func TestCreateAllowedWithRemainingQuota(t *testing.T) {
+	quota := configMapQuota()
+	quota.Status.Used["count/configmaps"] = resource.MustParse("1")
+
+	cluster := newFakeCluster()
+	getter := &quotaClientGetter{
+		fakeClusterGetter: &fakeClusterGetter{cluster: cluster},
+		k8s:               fake.NewSimpleClientset(quota),
+	}
+	store := NewProxyStore(getter, nil, fakeAccessSetLookup{}, WithClusterQuota(NewResourceQuotaCapacity(getter)))
+
+	if _, err := createConfigMap(store, configMapSchema(), "second"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cluster.objects[clusterKey("default", "second")]; !ok {
+		t.Error("the config map was not created")
+	}
+}
+
+func TestResourceQuotaCapacityIgnoresClusterScopedCreates(t *testing.T) {
+	getter := &quotaClientGetter{k8s: fake.NewSimpleClientset(configMapQuota())}
+	remaining, err := NewResourceQuotaCapacity(getter).RemainingCapacity(context.Background(), configMapSchema(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining <= 0 {
+		t.Errorf("got %d remaining without a namespace, want no limit", remaining)
+	}
+}
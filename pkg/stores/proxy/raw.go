@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type rawObjectsKey struct{}
+
+// WithRawObjects marks a get or list so the store returns the objects as the apiserver returned them: reserved
+// fields are not moved to underscore names, promoted fields are not added and excluded fields are kept. Field
+// redaction still applies.
+func WithRawObjects(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), rawObjectsKey{}, true))
+}
+
+// IsRawRequest is whether apiOp was marked with WithRawObjects, stores that reshape objects should leave them as is.
+func IsRawRequest(apiOp *types.APIRequest) bool {
+	raw, _ := apiOp.Context().Value(rawObjectsKey{}).(bool)
+	return raw
+}
+
+// toRawAPI is toAPI without moving the reserved fields.
+func toRawAPI(schema *types.APISchema, obj *unstructured.Unstructured) types.APIObject {
+	if obj == nil {
+		return types.APIObject{}
+	}
+	id := obj.GetName()
+	if ns := obj.GetNamespace(); ns != "" {
+		id = ns + "/" + id
+	}
+	return types.APIObject{
+		Type:   schema.ID,
+		ID:     id,
+		Object: obj,
+	}
+}
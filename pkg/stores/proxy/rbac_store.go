@@ -28,6 +28,16 @@ func AddNamespaceConstraint(req *http.Request, names ...string) *http.Request {
 	return req.WithContext(ctx)
 }
 
+// IntersectNamespaceConstraint is AddNamespaceConstraint but if the request is already constrained the result is
+// limited to the namespaces in both sets.
+func IntersectNamespaceConstraint(req *http.Request, names ...string) *http.Request {
+	existing, ok := getNamespaceConstraint(req)
+	if !ok {
+		return AddNamespaceConstraint(req, names...)
+	}
+	return AddNamespaceConstraint(req, existing.Intersection(sets.NewString(names...)).List()...)
+}
+
 func getNamespaceConstraint(req *http.Request) (sets.String, bool) {
 	set, ok := req.Context().Value(filterKey{}).(sets.String)
 	return set, ok
@@ -38,6 +48,8 @@ type Partition struct {
 	All         bool
 	Passthrough bool
 	Names       sets.String
+	// Allowed, when set, makes the partition a cluster wide list filtered to the objects of these partitions.
+	Allowed map[string]Partition
 }
 
 func (p Partition) Name() string {
@@ -83,6 +95,11 @@ func (p *rbacPartitioner) All(apiOp *types.APIRequest, schema *types.APISchema,
 		if passthrough {
 			return passthroughPartitions, nil
 		}
+		if verb == "list" {
+			if cluster, ok := p.proxyStore.clusterListPartition(apiOp, schema, partitions); ok {
+				return cluster, nil
+			}
+		}
 		sort.Slice(partitions, func(i, j int) bool {
 			return partitions[i].(Partition).Namespace < partitions[j].(Partition).Namespace
 		})
@@ -108,9 +125,17 @@ func (b *byNameOrNamespaceStore) List(apiOp *types.APIRequest, schema *types.API
 	if b.partition.Passthrough {
 		return b.Store.List(apiOp, schema)
 	}
+	if b.partition.Allowed != nil {
+		return b.Store.listClusterFiltered(apiOp, schema, b.partition.Allowed)
+	}
 
+	// the per namespace limit only protects fan-outs, not requests for a single namespace
+	fanOut := apiOp.Namespace == ""
 	apiOp.Namespace = b.partition.Namespace
 	if b.partition.All {
+		if fanOut && b.Store.namespaceItemLimit > 0 {
+			return b.Store.listNamespaceCapped(apiOp, schema)
+		}
 		return b.Store.List(apiOp, schema)
 	}
 	return b.Store.ByNames(apiOp, schema, b.partition.Names)
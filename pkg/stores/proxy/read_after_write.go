@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"context"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// WrittenResourceVersionHeader is set on create and update responses when read-after-write is enabled. A client
+// that lists with resourceVersion set to it and resourceVersionMatch=NotOlderThan is guaranteed to see the write.
+const WrittenResourceVersionHeader = "X-Steve-Written-Resource-Version"
+
+// defaultReadAfterWriteTimeout bounds the confirmation when WithReadAfterWrite is given no timeout.
+const defaultReadAfterWriteTimeout = 3 * time.Second
+
+// confirmWrite waits, up to s.readAfterWrite, until a list of the written object served from the apiserver watch
+// cache is at least as fresh as the write, so a list the client makes right after includes it. The wait is
+// best-effort, the write already succeeded so a failed confirmation is only logged.
+func (s *Store) confirmWrite(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject) {
+	if s.readAfterWrite <= 0 || obj.Object == nil {
+		return
+	}
+
+	m, err := meta.Accessor(obj.Object)
+	if err != nil || m.GetResourceVersion() == "" {
+		return
+	}
+	rv := m.GetResourceVersion()
+	apiOp.Response.Header().Set(WrittenResourceVersionHeader, rv)
+
+	client, err := s.clientGetter.TableClient(apiOp, schema, m.GetNamespace())
+	if err != nil {
+		logrus.Debugf("failed to confirm write of %s %s: %v", schema.ID, obj.ID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(apiOp.Context(), s.readAfterWrite)
+	defer cancel()
+
+	_, err = client.List(ctx, metav1.ListOptions{
+		FieldSelector:        fields.OneTermEqualSelector("metadata.name", m.GetName()).String(),
+		ResourceVersion:      rv,
+		ResourceVersionMatch: metav1.ResourceVersionMatchNotOlderThan,
+	})
+	if err != nil {
+		logrus.Debugf("failed to confirm write of %s %s at resourceVersion %s: %v", schema.ID, obj.ID, rv, err)
+	}
+}
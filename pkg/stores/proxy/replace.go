@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	ErrBadRequest = validation.ErrorCode{
+		Code:   "BadRequest",
+		Status: http.StatusBadRequest,
+	}
+
+	// serverMetadataFields are owned by the apiserver and kept from the live object on a replace.
+	serverMetadataFields = []string{"uid", "creationTimestamp", "generation", "selfLink", "managedFields", "deletionTimestamp", "deletionGracePeriodSeconds"}
+)
+
+// replaceMode returns whether the request replaces the object. The replace option of the request wins, otherwise
+// the schema default from attributes.ReplaceOnUpdate is used.
+//
+// With replace=true the submitted object replaces everything but the server managed metadata of the live object,
+// and the status if the submitted object has none. With replace=false the submitted object is merged into the
+// live object using JSON merge patch semantics: maps are merged key by key while arrays, such as containers, are
+// always replaced as a whole and null removes a key.
+func replaceMode(apiOp *types.APIRequest, schema *types.APISchema) bool {
+	if opt := apiOp.Option("replace"); opt != "" {
+		return convert.ToBool(opt)
+	}
+	return attributes.ReplaceOnUpdate(schema)
+}
+
+func replaceRequested(apiOp *types.APIRequest) bool {
+	return convert.ToBool(apiOp.Option("replace"))
+}
+
+func checkReplacePatch(apiOp *types.APIRequest, pType apitypes.PatchType) error {
+	if pType == apitypes.JSONPatchType && replaceRequested(apiOp) {
+		return apierror.NewAPIError(ErrBadRequest, "replace=true can not be combined with a JSON patch")
+	}
+	if pType == apitypes.ApplyPatchType && replaceRequested(apiOp) {
+		return apierror.NewAPIError(ErrBadRequest, "replace=true can not be combined with a server-side apply")
+	}
+	return nil
+}
+
+// replace overwrites the live object with input, keeping the server managed metadata and, if input has none,
+// the status of the live object.
+func (s *Store) replace(apiOp *types.APIRequest, schema *types.APISchema, client dynamic.ResourceInterface, id string, input map[string]interface{}) (*unstructured.Unstructured, error) {
+	live, err := client.Get(apiOp.Context(), id, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	rowToObject(live)
+
+	for _, field := range serverMetadataFields {
+		if val, ok := data.GetValue(live.Object, "metadata", field); ok {
+			data.PutValue(input, val, "metadata", field)
+		}
+	}
+	if _, ok := input["status"]; !ok {
+		if status, ok := live.Object["status"]; ok {
+			input["status"] = status
+		}
+	}
+	if data.Object(input).String("metadata", "resourceVersion") == "" {
+		data.PutValue(input, live.GetResourceVersion(), "metadata", "resourceVersion")
+	}
+	input["apiVersion"], input["kind"] = live.GetAPIVersion(), live.GetKind()
+	setRevisions(apiOp, schema, input, live)
+
+	opts := metav1.UpdateOptions{}
+	if err := decodeParams(apiOp, &opts); err != nil {
+		return nil, err
+	}
+	return client.Update(apiOp.Context(), &unstructured.Unstructured{Object: input}, opts)
+}
+
+// merge applies input to the live object with JSON merge patch semantics and sends the result as an update, not
+// as a patch, so that a merge needs the same update verb as a replace. The update is conditional on the
+// resourceVersion of input.
+func (s *Store) merge(apiOp *types.APIRequest, schema *types.APISchema, client dynamic.ResourceInterface, id string, input map[string]interface{}) (*unstructured.Unstructured, error) {
+	live, err := client.Get(apiOp.Context(), id, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	rowToObject(live)
+
+	merged := mergeObject(live.Object, input)
+	if data.Object(merged).String("metadata", "resourceVersion") == "" {
+		data.PutValue(merged, live.GetResourceVersion(), "metadata", "resourceVersion")
+	}
+	merged["apiVersion"], merged["kind"] = live.GetAPIVersion(), live.GetKind()
+	setRevisions(apiOp, schema, merged, live)
+
+	opts := metav1.UpdateOptions{}
+	if err := decodeParams(apiOp, &opts); err != nil {
+		return nil, err
+	}
+	return client.Update(apiOp.Context(), &unstructured.Unstructured{Object: merged}, opts)
+}
+
+// mergeObject returns patch merged into obj as RFC 7386 does: maps are merged key by key, a null removes the key
+// and any other value, arrays included, replaces the value of obj. Neither obj nor patch is changed.
+func mergeObject(obj, patch map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		result[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		if patchMap, ok := asMap(v); ok {
+			objMap, _ := asMap(result[k])
+			result[k] = mergeObject(objMap, patchMap)
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v, true
+	case data.Object:
+		return v, true
+	}
+	return nil, false
+}
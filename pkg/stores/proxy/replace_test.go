@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/conformance"
+)
+
+func TestMergeObject(t *testing.T) {
+	obj := map[string]interface{}{
+		"data": map[string]interface{}{"a": "1", "b": "2"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "web", "image": "nginx"},
+				map[string]interface{}{"name": "sidecar", "image": "envoy"},
+			},
+			"ports": []interface{}{int64(80), int64(443)},
+		},
+	}
+	patch := map[string]interface{}{
+		"data": map[string]interface{}{"b": "3", "a": nil},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "web"},
+			},
+			"ports": []interface{}{},
+		},
+		"status": map[string]interface{}{"ready": true, "removed": nil},
+	}
+
+	merged := mergeObject(obj, patch)
+	want := map[string]interface{}{
+		"data": map[string]interface{}{"b": "3"},
+		"spec": map[string]interface{}{
+			// arrays are replaced as a whole, nothing of the live containers is kept
+			"containers": []interface{}{
+				map[string]interface{}{"name": "web"},
+			},
+			"ports": []interface{}{},
+		},
+		"status": map[string]interface{}{"ready": true},
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("got %v, want %v", merged, want)
+	}
+	if len(obj["data"].(map[string]interface{})) != 2 || len(obj["spec"].(map[string]interface{})["containers"].([]interface{})) != 2 {
+		t.Errorf("the live object was changed: %v", obj)
+	}
+}
+
+func arrayConfigMap() map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "arrays", "namespace": "default"},
+		"data":     map[string]interface{}{"keep": "yes", "change": "old"},
+		"spec": map[string]interface{}{
+			"items": []interface{}{"a", "b", "c"},
+		},
+	}
+}
+
+func updateArrays(t *testing.T, store types.Store, schema *types.APISchema, query url.Values, change func(input map[string]interface{})) (types.APIObject, error) {
+	t.Helper()
+	request := conformance.DefaultRequest(schema)
+	created, err := store.Create(request(context.Background(), http.MethodPost, "default", nil), schema, types.APIObject{Object: arrayConfigMap()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "arrays",
+			"namespace":       "default",
+			"resourceVersion": created.Data().String("metadata", "resourceVersion"),
+		},
+	}
+	change(input)
+	return store.Update(request(context.Background(), http.MethodPut, "default", query), schema, types.APIObject{Object: input}, "arrays")
+}
+
+func TestMergeUpdateReplacesArrays(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	attributes.SetReplaceOnUpdate(schema, false)
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{})
+
+	updated, err := updateArrays(t, store, schema, nil, func(input map[string]interface{}) {
+		input["data"] = map[string]interface{}{"change": "new"}
+		input["spec"] = map[string]interface{}{"items": []interface{}{"c"}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := updated.Data()
+	if items := obj.Map("spec")["items"]; !reflect.DeepEqual(items, []interface{}{"c"}) {
+		t.Errorf("got items %v, want the array replaced by [c]", items)
+	}
+	if obj.String("data", "keep") != "yes" || obj.String("data", "change") != "new" {
+		t.Errorf("got data %v, want the maps merged", obj.Map("data"))
+	}
+	if cluster.patches != 0 {
+		t.Errorf("got %d patches, want the merge sent as an update that only needs the update verb", cluster.patches)
+	}
+}
+
+func TestMergeUpdateEmptiesAndRemovesArrays(t *testing.T) {
+	schema := configMapSchema()
+	attributes.SetReplaceOnUpdate(schema, false)
+
+	for name, test := range map[string]struct {
+		items interface{}
+		want  []interface{}
+	}{
+		"empty":   {items: []interface{}{}, want: []interface{}{}},
+		"removed": {items: nil},
+	} {
+		store := NewProxyStore(&fakeClusterGetter{cluster: newFakeCluster()}, nil, fakeAccessSetLookup{})
+		updated, err := updateArrays(t, store, schema, nil, func(input map[string]interface{}) {
+			input["spec"] = map[string]interface{}{"items": test.items}
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		items, ok := updated.Data().Map("spec")["items"]
+		if test.want == nil && ok {
+			t.Errorf("%s: got items %v, want them removed", name, items)
+		}
+		if test.want != nil && !reflect.DeepEqual(items, test.want) {
+			t.Errorf("%s: got items %v, want %v", name, items, test.want)
+		}
+	}
+}
+
+func TestMergeUpdateOfAStaleObjectConflicts(t *testing.T) {
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: newFakeCluster()}, nil, fakeAccessSetLookup{})
+
+	_, err := updateArrays(t, store, schema, url.Values{"replace": {"false"}}, func(input map[string]interface{}) {
+		input["metadata"].(map[string]interface{})["resourceVersion"] = "0"
+		input["spec"] = map[string]interface{}{"items": []interface{}{"c"}}
+	})
+	if apiErr, ok := err.(*apierror.APIError); !ok || apiErr.Code.Status != http.StatusConflict {
+		t.Errorf("got %v, want a conflict", err)
+	}
+}
+
+func TestReplaceUpdateDropsMissingFields(t *testing.T) {
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: newFakeCluster()}, nil, fakeAccessSetLookup{})
+
+	updated, err := updateArrays(t, store, schema, nil, func(input map[string]interface{}) {
+		input["data"] = map[string]interface{}{"change": "new"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := updated.Data()
+	if _, ok := obj["spec"]; ok {
+		t.Errorf("got spec %v, want it dropped by the replace", obj["spec"])
+	}
+	if obj.String("data", "keep") != "" || obj.String("data", "change") != "new" {
+		t.Errorf("got data %v, want only the submitted data", obj.Map("data"))
+	}
+}
@@ -0,0 +1,368 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+var (
+	ErrResyncRequired = validation.ErrorCode{
+		Code:   "ResyncRequired",
+		Status: http.StatusGone,
+	}
+)
+
+var errReplayIdle = errors.New("replay buffer is idle")
+
+func resyncRequired(schema *types.APISchema) error {
+	return apierror.NewAPIError(ErrResyncRequired, "revision is too old for "+schema.ID+", list again to resync")
+}
+
+type replayEvent struct {
+	revision  uint64
+	timestamp time.Time
+	eventType watch.EventType
+	obj       *unstructured.Unstructured
+}
+
+const (
+	// replayIdleTimeout stops the watch feeding the buffer of a schema no watch resumed from for this long, the
+	// buffer is started again by the next resume.
+	replayIdleTimeout = 10 * time.Minute
+	replayRetryDelay  = 5 * time.Second
+)
+
+// replayResult says whether a buffer could replay the events after a revision.
+type replayResult int
+
+const (
+	// replayed means every event after the revision is in the buffer.
+	replayed replayResult = iota
+	// replayUnknown means the buffer started after the revision, the apiserver may still have its events.
+	replayUnknown
+	// replayTooOld means the buffer had the events after the revision but dropped them.
+	replayTooOld
+)
+
+// eventBuffer is a ring buffer of the most recent watch events for a single schema. Every event
+// with a revision greater than start is guaranteed to be in the buffer, origin is the revision the
+// buffer started from.
+type eventBuffer struct {
+	sync.Mutex
+
+	ready    bool
+	origin   uint64
+	start    uint64
+	latest   uint64
+	maxAge   time.Duration
+	events   []replayEvent
+	head     int
+	count    int
+	lastUsed time.Time
+}
+
+func newEventBuffer(size int, maxAge time.Duration) *eventBuffer {
+	return &eventBuffer{
+		maxAge:   maxAge,
+		events:   make([]replayEvent, size),
+		lastUsed: time.Now(),
+	}
+}
+
+func (b *eventBuffer) reset(revision uint64) {
+	b.Lock()
+	defer b.Unlock()
+	b.ready = revision > 0
+	b.origin = revision
+	b.start = revision
+	b.latest = revision
+	b.head = 0
+	b.count = 0
+}
+
+func (b *eventBuffer) invalidate() {
+	b.reset(0)
+}
+
+func (b *eventBuffer) add(event replayEvent) {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.ready || event.revision <= b.latest {
+		return
+	}
+
+	if b.count == len(b.events) {
+		b.evict()
+	}
+	b.events[(b.head+b.count)%len(b.events)] = event
+	b.count++
+	b.latest = event.revision
+}
+
+func (b *eventBuffer) evict() {
+	b.start = b.events[b.head].revision
+	b.events[b.head] = replayEvent{}
+	b.head = (b.head + 1) % len(b.events)
+	b.count--
+}
+
+func (b *eventBuffer) expire(now time.Time) {
+	if b.maxAge <= 0 {
+		return
+	}
+	for b.count > 0 && now.Sub(b.events[b.head].timestamp) > b.maxAge {
+		b.evict()
+	}
+}
+
+// since returns all buffered events after revision and the latest revision the buffer has seen. Unless the
+// result is replayed the buffer can not guarantee it has every event after revision.
+func (b *eventBuffer) since(revision uint64) ([]replayEvent, uint64, replayResult) {
+	b.Lock()
+	defer b.Unlock()
+
+	now := time.Now()
+	b.lastUsed = now
+	b.expire(now)
+	if !b.ready || revision < b.origin || revision > b.latest {
+		return nil, 0, replayUnknown
+	}
+	if revision < b.start {
+		return nil, 0, replayTooOld
+	}
+
+	var result []replayEvent
+	for i := 0; i < b.count; i++ {
+		event := b.events[(b.head+i)%len(b.events)]
+		if event.revision > revision {
+			result = append(result, event)
+		}
+	}
+	return result, b.latest, replayed
+}
+
+// idle returns true if no watch resumed from the buffer for longer than replayIdleTimeout.
+func (b *eventBuffer) idle(now time.Time) bool {
+	b.Lock()
+	defer b.Unlock()
+	return now.Sub(b.lastUsed) > replayIdleTimeout
+}
+
+// eventReplay keeps the buffers of the schemas watches resumed from. The watch feeding a buffer runs until ctx
+// is done, the schema is no longer served by the apiserver or the buffer goes idle.
+type eventReplay struct {
+	sync.Mutex
+
+	ctx     context.Context
+	size    int
+	maxAge  time.Duration
+	buffers map[string]*eventBuffer
+}
+
+func newEventReplay(ctx context.Context, size int, maxAge time.Duration) *eventReplay {
+	return &eventReplay{
+		ctx:     ctx,
+		size:    size,
+		maxAge:  maxAge,
+		buffers: map[string]*eventBuffer{},
+	}
+}
+
+// buffer returns the event buffer for the schema, starting an admin watch to feed it on first use.
+func (r *eventReplay) buffer(apiOp *types.APIRequest, s *Store, schema *types.APISchema) *eventBuffer {
+	r.Lock()
+	defer r.Unlock()
+
+	buf, ok := r.buffers[schema.ID]
+	if ok {
+		return buf
+	}
+
+	buf = newEventBuffer(r.size, r.maxAge)
+	r.buffers[schema.ID] = buf
+	go r.feed(apiOp, s, schema, buf)
+	return buf
+}
+
+func (r *eventReplay) feed(apiOp *types.APIRequest, s *Store, schema *types.APISchema, buf *eventBuffer) {
+	defer r.remove(schema.ID, buf)
+	for {
+		err := r.watch(r.ctx, apiOp, s, schema, buf)
+		buf.invalidate()
+		switch {
+		case r.ctx.Err() != nil:
+			return
+		case err == errReplayIdle:
+			logrus.Debugf("stopping idle replay buffer watch for %s", schema.ID)
+			return
+		case apierrors.IsNotFound(err):
+			logrus.Debugf("stopping replay buffer watch for %s, the resource is gone", schema.ID)
+			return
+		case err != nil:
+			logrus.Debugf("replay buffer watch for %s failed: %v", schema.ID, err)
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(replayRetryDelay):
+		}
+		if buf.idle(time.Now()) {
+			return
+		}
+	}
+}
+
+// remove drops the buffer of a schema if it is still buf, the next resume starts a new one.
+func (r *eventReplay) remove(schemaID string, buf *eventBuffer) {
+	r.Lock()
+	defer r.Unlock()
+	if r.buffers[schemaID] == buf {
+		delete(r.buffers, schemaID)
+	}
+}
+
+func (r *eventReplay) watch(ctx context.Context, apiOp *types.APIRequest, s *Store, schema *types.APISchema, buf *eventBuffer) error {
+	client, err := s.clientGetter.TableAdminClientForWatch(apiOp, schema, "")
+	if err != nil {
+		return err
+	}
+
+	// a single item list is the cheapest way to learn the current revision
+	list, err := client.List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return err
+	}
+	start, err := strconv.ParseUint(list.GetResourceVersion(), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	timeout := int64(60 * 30)
+	watcher, err := client.Watch(ctx, metav1.ListOptions{
+		Watch:           true,
+		TimeoutSeconds:  &timeout,
+		ResourceVersion: list.GetResourceVersion(),
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	buf.reset(start)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		var event watch.Event
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if buf.idle(now) {
+				return errReplayIdle
+			}
+			continue
+		case e, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			event = e
+		}
+
+		if event.Type == watch.Error {
+			return apierror.NewAPIError(validation.ServerError, "replay buffer watch returned an error")
+		}
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		rowToObject(obj)
+		revision, err := strconv.ParseUint(obj.GetResourceVersion(), 10, 64)
+		if err != nil {
+			continue
+		}
+		buf.add(replayEvent{
+			revision:  revision,
+			timestamp: time.Now(),
+			eventType: event.Type,
+			obj:       obj,
+		})
+	}
+}
+
+// replay sends the buffered events newer than the requested revision that the user can currently see and returns
+// the watch request updated to resume from the latest buffered revision. If the buffer started after the revision
+// the watch request is returned unmodified, if the buffer dropped the events after it a resync required error is
+// returned.
+func (s *Store) replay(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest, result chan types.APIEvent) (types.WatchRequest, error) {
+	if s.eventReplay == nil || s.asl == nil || w.Revision == "" {
+		return w, nil
+	}
+
+	revision, err := strconv.ParseUint(w.Revision, 10, 64)
+	if err != nil || revision == 0 {
+		return w, nil
+	}
+
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return w, nil
+	}
+
+	selector, err := labels.Parse(w.Selector)
+	if err != nil {
+		return w, nil
+	}
+
+	events, latest, state := s.eventReplay.buffer(apiOp, s, schema).since(revision)
+	switch state {
+	case replayUnknown:
+		return w, nil
+	case replayTooOld:
+		return w, resyncRequired(schema)
+	}
+
+	access := s.asl.AccessFor(user)
+	gr := attributes.GR(schema)
+	eventTypes := watchEventTypes(apiOp)
+	for _, event := range events {
+		m, err := meta.Accessor(event.obj)
+		if err != nil {
+			continue
+		}
+		if apiOp.Namespace != "" && m.GetNamespace() != apiOp.Namespace {
+			continue
+		}
+		if !selector.Matches(labels.Set(m.GetLabels())) {
+			continue
+		}
+		if !access.Grants("watch", gr, m.GetNamespace(), m.GetName()) {
+			continue
+		}
+		if eventTypes != nil && !eventTypes[event.eventType] {
+			continue
+		}
+		result <- s.toAPIEvent(apiOp, schema, event.eventType, event.obj.DeepCopy())
+	}
+
+	w.Revision = strconv.FormatUint(latest, 10)
+	return w, nil
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func bufferedEvent(revision uint64) replayEvent {
+	return replayEvent{
+		revision:  revision,
+		timestamp: time.Now(),
+		eventType: watch.Modified,
+		obj:       &unstructured.Unstructured{Object: map[string]interface{}{}},
+	}
+}
+
+func TestEventBufferSince(t *testing.T) {
+	buf := newEventBuffer(3, 0)
+	buf.reset(10)
+	for revision := uint64(11); revision <= 15; revision++ {
+		buf.add(bufferedEvent(revision))
+	}
+
+	tests := []struct {
+		name     string
+		revision uint64
+		want     replayResult
+		events   int
+	}{
+		{name: "before the buffer started", revision: 9, want: replayUnknown},
+		{name: "dropped from the buffer", revision: 11, want: replayTooOld},
+		{name: "first kept revision", revision: 12, want: replayed, events: 3},
+		{name: "recent revision", revision: 14, want: replayed, events: 1},
+		{name: "latest revision", revision: 15, want: replayed, events: 0},
+		{name: "newer than the buffer", revision: 16, want: replayUnknown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, latest, got := buf.since(test.revision)
+			if got != test.want {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			if got != replayed {
+				return
+			}
+			if len(events) != test.events {
+				t.Errorf("got %d events, want %d", len(events), test.events)
+			}
+			if latest != 15 {
+				t.Errorf("got latest %d, want 15", latest)
+			}
+		})
+	}
+}
+
+func TestEventBufferExpiredEventsAreTooOld(t *testing.T) {
+	buf := newEventBuffer(10, time.Minute)
+	buf.reset(10)
+	old := bufferedEvent(11)
+	old.timestamp = time.Now().Add(-2 * time.Minute)
+	buf.add(old)
+	buf.add(bufferedEvent(12))
+
+	if _, _, got := buf.since(10); got != replayTooOld {
+		t.Errorf("got %v for a revision whose next event expired, want too old", got)
+	}
+	if events, _, got := buf.since(11); got != replayed || len(events) != 1 {
+		t.Errorf("got %v with %d events, want the event 12 replayed", got, len(events))
+	}
+}
+
+func TestEventBufferNotReady(t *testing.T) {
+	buf := newEventBuffer(3, 0)
+	if _, _, got := buf.since(5); got != replayUnknown {
+		t.Errorf("got %v before the buffer started, want unknown", got)
+	}
+	buf.reset(10)
+	buf.invalidate()
+	if _, _, got := buf.since(10); got != replayUnknown {
+		t.Errorf("got %v after the buffer was invalidated, want unknown", got)
+	}
+}
+
+func TestEventBufferIdle(t *testing.T) {
+	buf := newEventBuffer(3, 0)
+	if buf.idle(time.Now()) {
+		t.Error("a new buffer is idle")
+	}
+	if !buf.idle(time.Now().Add(replayIdleTimeout + time.Second)) {
+		t.Error("the buffer is not idle after the idle timeout")
+	}
+	buf.since(1)
+	if buf.idle(time.Now()) {
+		t.Error("the buffer is idle right after it was used")
+	}
+}
+
+func TestEventReplayRemove(t *testing.T) {
+	r := newEventReplay(context.Background(), 3, 0)
+	first := newEventBuffer(3, 0)
+	second := newEventBuffer(3, 0)
+	r.buffers["widget"] = second
+
+	r.remove("widget", first)
+	if r.buffers["widget"] != second {
+		t.Fatal("a stopped feed removed the buffer that replaced it")
+	}
+	r.remove("widget", second)
+	if _, ok := r.buffers["widget"]; ok {
+		t.Error("the buffer was not removed")
+	}
+}
+
+func TestReplayTooOldRevision(t *testing.T) {
+	schema := &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+	s := &Store{
+		eventReplay: newEventReplay(context.Background(), 3, 0),
+		asl:         fakeAccessSetLookup{},
+	}
+	buf := newEventBuffer(3, 0)
+	buf.reset(10)
+	for revision := uint64(11); revision <= 15; revision++ {
+		buf.add(bufferedEvent(revision))
+	}
+	s.eventReplay.buffers["widget"] = buf
+
+	_, err := s.replay(revisionRequest(), schema, types.WatchRequest{Revision: "11"}, make(chan types.APIEvent, 10))
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok || apiErr.Code != ErrResyncRequired {
+		t.Fatalf("got %v, want a resync required error", err)
+	}
+
+	w, err := s.replay(revisionRequest(), schema, types.WatchRequest{Revision: "5"}, make(chan types.APIEvent, 10))
+	if err != nil || w.Revision != "5" {
+		t.Errorf("got %v and revision %s, want the watch to go to the apiserver unchanged", err, w.Revision)
+	}
+}
+
+type fakeAccessSetLookup struct{}
+
+func (fakeAccessSetLookup) AccessFor(user user.Info) *accesscontrol.AccessSet {
+	return &accesscontrol.AccessSet{}
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Resuming a watch
+//
+// Resource versions are global to the cluster, not to a steve replica, so a client that persists the revision
+// of the last event it processed can resume its watch on any replica by sending that revision again. The event
+// replay buffer is only an optimization local to a replica: a revision it does not cover, for example because
+// the replica just started or the client last talked to another replica, is passed on to the apiserver watch
+// as is. A revision the buffer covered but has since dropped, because it was pushed out or aged past maxAge, gets
+// a resync required error right away. When the apiserver no longer has the revision the client gets the same
+// error, it must list again and resume from the revision of that list. Bookmarks are requested so the revision keeps advancing on
+// quiet watches and clients don't fall behind the apiserver history while nothing changes.
+
+// expiredRevision returns a resync required error if the apiserver refused to start a watch because the revision
+// is too old, like it is reported when the watch fails after it started, and nil for any other error.
+func expiredRevision(schema *types.APISchema, err error) error {
+	if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+		return resyncRequired(schema)
+	}
+	return nil
+}
+
+// isGoneEvent returns true if a watch error event says the revision is too old. The error can be decoded as
+// a Status or, by the dynamic client, as an unstructured Status.
+func isGoneEvent(event watch.Event) bool {
+	switch obj := event.Object.(type) {
+	case *metav1.Status:
+		return obj.Code == http.StatusGone || obj.Reason == metav1.StatusReasonExpired || obj.Reason == metav1.StatusReasonGone
+	case *unstructured.Unstructured:
+		if obj.GetKind() != "Status" {
+			return false
+		}
+		code, _, _ := unstructured.NestedInt64(obj.Object, "code")
+		reason, _, _ := unstructured.NestedString(obj.Object, "reason")
+		return code == http.StatusGone || reason == string(metav1.StatusReasonExpired) || reason == string(metav1.StatusReasonGone)
+	}
+	return false
+}
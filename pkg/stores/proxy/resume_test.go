@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"github.com/rancher/steve/pkg/watchevent"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// clusterHistory keeps every event of the fake cluster so watches can start at a past revision, like the
+// apiserver does until the revision is compacted.
+type clusterHistory struct {
+	cluster   *fakeCluster
+	recorder  *fakeWatcher
+	events    []watch.Event
+	compacted int
+}
+
+func newClusterHistory(cluster *fakeCluster) *clusterHistory {
+	recorder := &fakeWatcher{cluster: cluster, selector: labels.Everything(), result: make(chan watch.Event, 100)}
+	cluster.lock.Lock()
+	cluster.watchers[recorder] = true
+	cluster.lock.Unlock()
+	return &clusterHistory{cluster: cluster, recorder: recorder}
+}
+
+// since returns the events after revision, the caller holds the lock of the cluster.
+func (h *clusterHistory) since(revision int) (result []watch.Event) {
+	for {
+		select {
+		case event := <-h.recorder.result:
+			h.events = append(h.events, event)
+			continue
+		default:
+		}
+		break
+	}
+	for _, event := range h.events {
+		if rv, _ := strconv.Atoi(event.Object.(*unstructured.Unstructured).GetResourceVersion()); rv > revision {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// historyResource starts the watches with a resourceVersion at that revision of the history.
+type historyResource struct {
+	*fakeResource
+	history *clusterHistory
+}
+
+func (h *historyResource) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	if opts.ResourceVersion == "" {
+		return h.fakeResource.Watch(ctx, opts)
+	}
+	revision, err := strconv.Atoi(opts.ResourceVersion)
+	if err != nil {
+		return nil, apierrors.NewBadRequest("invalid resourceVersion " + opts.ResourceVersion)
+	}
+
+	h.cluster.lock.Lock()
+	defer h.cluster.lock.Unlock()
+	if revision < h.history.compacted {
+		return nil, apierrors.NewResourceExpired("too old resource version: " + opts.ResourceVersion)
+	}
+	w := &fakeWatcher{cluster: h.cluster, namespace: h.namespace, selector: labels.Everything(), result: make(chan watch.Event, 100)}
+	for _, event := range h.history.since(revision) {
+		w.send(event.Type, event.Object.(*unstructured.Unstructured))
+	}
+	h.cluster.watchers[w] = true
+	return w, nil
+}
+
+type historyClusterGetter struct {
+	*fakeClusterGetter
+	history *clusterHistory
+}
+
+func (h *historyClusterGetter) TableClientForWatch(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return &historyResource{fakeResource: &fakeResource{cluster: h.cluster, namespace: namespace}, history: h.history}, nil
+}
+
+// replica is a steve instance of its own over the shared cluster.
+func replica(cluster *fakeCluster, history *clusterHistory) types.Store {
+	return NewProxyStore(&historyClusterGetter{fakeClusterGetter: &fakeClusterGetter{cluster: cluster}, history: history}, nil,
+		fakeAccessSetLookup{}, WithEventReplay(context.Background(), 10, 0))
+}
+
+func watchFrom(t *testing.T, ctx context.Context, store types.Store, revision string) chan types.APIEvent {
+	t.Helper()
+	schema := configMapSchema()
+	c, err := store.Watch(conformance.DefaultRequest(schema)(ctx, http.MethodGet, "default", nil), schema, types.WatchRequest{Revision: revision})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func expectNoWatchEvent(t *testing.T, c chan types.APIEvent) {
+	t.Helper()
+	select {
+	case event := <-c:
+		t.Errorf("got the event %s of %s, want none", event.Name, event.Object.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatchResumesOnAnotherReplica(t *testing.T) {
+	cluster := newFakeCluster()
+	history := newClusterHistory(cluster)
+	schema := configMapSchema()
+	first, second := replica(cluster, history), replica(cluster, history)
+
+	if _, err := createConfigMap(first, schema, "a"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, disconnect := context.WithCancel(context.Background())
+	created := nextWatchEvent(t, watchFrom(t, ctx, first, ""))
+	if created.Name != types.CreateAPIEvent || created.Object.ID != "default/a" {
+		t.Fatalf("got %s of %s, want a created", created.Name, created.Object.ID)
+	}
+	// the client persists the revision and loses the connection to the first replica
+	revision := created.Revision
+	disconnect()
+
+	if _, err := createConfigMap(first, schema, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := watchFrom(t, ctx, second, revision)
+	if resumed := nextWatchEvent(t, c); resumed.Name != types.CreateAPIEvent || resumed.Object.ID != "default/b" {
+		t.Fatalf("got %s of %s, want the create of b missed while disconnected", resumed.Name, resumed.Object.ID)
+	}
+	expectNoWatchEvent(t, c)
+
+	if _, err := createConfigMap(second, schema, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if live := nextWatchEvent(t, c); live.Name != types.CreateAPIEvent || live.Object.ID != "default/c" {
+		t.Errorf("got %s of %s, want the create of c", live.Name, live.Object.ID)
+	}
+}
+
+func TestCompactedRevisionOnAnotherReplicaRequiresAResync(t *testing.T) {
+	cluster := newFakeCluster()
+	history := newClusterHistory(cluster)
+	schema := configMapSchema()
+	first, second := replica(cluster, history), replica(cluster, history)
+
+	for _, name := range []string{"a", "b"} {
+		if _, err := createConfigMap(first, schema, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cluster.lock.Lock()
+	history.compacted = cluster.revision
+	cluster.lock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	expired := nextWatchEvent(t, watchFrom(t, ctx, second, "1"))
+	apiErr, ok := expired.Error.(*apierror.APIError)
+	if expired.Name != watchevent.ErrorAPIEvent || !ok || apiErr.Code != ErrResyncRequired {
+		t.Fatalf("got %s %v, want a resync required error", expired.Name, expired.Error)
+	}
+
+	// the client lists again and resumes from the revision of the list, on whichever replica
+	list, err := second.List(conformance.DefaultRequest(schema)(ctx, http.MethodGet, "default", nil), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Objects) != 2 {
+		t.Errorf("got %d config maps, want 2", len(list.Objects))
+	}
+	c := watchFrom(t, ctx, first, list.Revision)
+	if _, err := createConfigMap(second, schema, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if live := nextWatchEvent(t, c); live.Name != types.CreateAPIEvent || live.Object.ID != "default/c" {
+		t.Errorf("got %s of %s, want only the create of c after the list", live.Name, live.Object.ID)
+	}
+}
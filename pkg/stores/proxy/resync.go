@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// watchTracker remembers the objects a watch has sent so that, when the apiserver expires the revision of the
+// watch, a fresh list can be turned into the ADDED, MODIFIED and DELETED events the client missed instead of
+// failing the watch with a resync required error. Only the identity and resourceVersion of every object are kept,
+// about a few hundred bytes each, and tracking is given up for the rest of the watch once it holds more than max
+// objects. Objects that did not change since the watch started were never sent so they are reported as ADDED.
+type watchTracker struct {
+	max      int
+	overflow bool
+	objects  map[string]*unstructured.Unstructured
+}
+
+func (s *Store) newWatchTracker() *watchTracker {
+	if s.watchResync <= 0 {
+		return nil
+	}
+	return &watchTracker{
+		max:     s.watchResync,
+		objects: map[string]*unstructured.Unstructured{},
+	}
+}
+
+// usable is whether an expired watch can be resumed from the tracked objects.
+func (t *watchTracker) usable() bool {
+	return t != nil && !t.overflow
+}
+
+func (t *watchTracker) observe(et watch.EventType, obj runtime.Object) {
+	if !t.usable() {
+		return
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	key := trackerKey(u)
+	switch et {
+	case watch.Deleted:
+		delete(t.objects, key)
+	case watch.Added, watch.Modified:
+		t.objects[key] = trackedObject(u)
+	}
+
+	if len(t.objects) > t.max {
+		t.overflow = true
+		t.objects = nil
+	}
+}
+
+func trackerKey(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// trackedObject keeps what a DELETED event needs to identify the object.
+func trackedObject(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	result := &unstructured.Unstructured{}
+	result.SetAPIVersion(obj.GetAPIVersion())
+	result.SetKind(obj.GetKind())
+	result.SetNamespace(obj.GetNamespace())
+	result.SetName(obj.GetName())
+	result.SetUID(obj.GetUID())
+	result.SetResourceVersion(obj.GetResourceVersion())
+	return result
+}
+
+// resync lists the objects the watch selects and sends the difference to the tracked objects as events. It
+// returns the revision of the list to watch from, or false when the watch must fail with resync required.
+func (s *Store) resync(apiOp *types.APIRequest, client dynamic.ResourceInterface, schema *types.APISchema, w types.WatchRequest,
+	tracker *watchTracker, result chan types.APIEvent) (string, bool) {
+	if !tracker.usable() {
+		return "", false
+	}
+
+	list, err := client.List(apiOp.Context(), metav1.ListOptions{
+		LabelSelector: w.Selector,
+	})
+	if err != nil {
+		logrus.Debugf("failed to resync expired watch for %s: %v", schema.ID, err)
+		return "", false
+	}
+	tableToList(list)
+
+	eventTypes := watchEventTypes(apiOp)
+	remaining := tracker.objects
+	tracker.objects = map[string]*unstructured.Unstructured{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		key := trackerKey(obj)
+		previous, known := remaining[key]
+		delete(remaining, key)
+
+		switch {
+		case !known:
+			s.sendEvent(apiOp, schema, result, eventTypes, watch.Added, obj)
+		case previous.GetResourceVersion() != obj.GetResourceVersion():
+			s.sendEvent(apiOp, schema, result, eventTypes, watch.Modified, obj)
+		}
+		tracker.objects[key] = trackedObject(obj)
+	}
+	for _, obj := range remaining {
+		s.sendEvent(apiOp, schema, result, eventTypes, watch.Deleted, obj)
+	}
+
+	if len(tracker.objects) > tracker.max {
+		tracker.overflow = true
+		tracker.objects = nil
+	}
+	return list.GetResourceVersion(), true
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// RevisionsAnnotation holds the JSON list of the last writes made through steve to an object of a schema with
+	// attributes.RevisionHistory, oldest first. Get an object as it was before one of them with
+	// ?resourceVersion={rv}&resourceVersionMatch=Exact, as long as the apiserver hasn't compacted the revision. The
+	// annotation is written by the write it records, PATCHes of status and server-side applies are not recorded.
+	RevisionsAnnotation = "steve.cattle.io/revisions"
+
+	// maxRevisions caps the history limit of a schema so the annotation stays well below the annotation size limit.
+	maxRevisions = 50
+
+	// maxRevisionConflicts caps how often a patch is retried when the object changed after its history was read.
+	maxRevisionConflicts = 5
+)
+
+// Revision is a write recorded in the RevisionsAnnotation. ResourceVersion is the version of the object the write
+// replaced, so reading it shows the object as it was before the write, it is empty for the write that created it.
+type Revision struct {
+	ResourceVersion string    `json:"rv,omitempty"`
+	User            string    `json:"user,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// revisionsOf returns the RevisionsAnnotation value for a write by the user of apiOp that replaces live, live is nil
+// for a create. The second value is false if the schema keeps no history.
+func revisionsOf(apiOp *types.APIRequest, schema *types.APISchema, live *unstructured.Unstructured) (string, bool) {
+	limit := attributes.RevisionHistory(schema)
+	if limit <= 0 {
+		return "", false
+	}
+	if limit > maxRevisions {
+		limit = maxRevisions
+	}
+
+	var revisions []Revision
+	revision := Revision{
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+	}
+	if live != nil {
+		if existing := live.GetAnnotations()[RevisionsAnnotation]; existing != "" {
+			if err := json.Unmarshal([]byte(existing), &revisions); err != nil {
+				logrus.Debugf("dropping unreadable %s of %s %s: %v", RevisionsAnnotation, schema.ID, live.GetName(), err)
+				revisions = nil
+			}
+		}
+		revision.ResourceVersion = live.GetResourceVersion()
+	}
+	if user, ok := request.UserFrom(apiOp.Context()); ok {
+		revision.User = user.GetName()
+	}
+	revisions = append(revisions, revision)
+	if len(revisions) > limit {
+		revisions = revisions[len(revisions)-limit:]
+	}
+
+	value, err := json.Marshal(revisions)
+	if err != nil {
+		return "", false
+	}
+	return string(value), true
+}
+
+// setRevisions adds the write of input over live to the RevisionsAnnotation of input, live is nil for a create.
+// The annotation is sent with the write itself so recording it neither changes the resourceVersion again nor
+// races other writes, any value of the annotation in input is replaced.
+func setRevisions(apiOp *types.APIRequest, schema *types.APISchema, input map[string]interface{}, live *unstructured.Unstructured) {
+	if value, ok := revisionsOf(apiOp, schema, live); ok {
+		data.PutValue(input, value, "metadata", "annotations", RevisionsAnnotation)
+	}
+}
+
+// patchWithRevisions sends a patch with the RevisionsAnnotation added to it. The patch is made conditional on the
+// resourceVersion the history was computed from and is retried on a conflict, unless the patch already sets a
+// resourceVersion the user asked for. Server-side applies are sent unchanged because the annotation would become
+// a field owned by the user's field manager.
+func (s *Store) patchWithRevisions(apiOp *types.APIRequest, schema *types.APISchema, client dynamic.ResourceInterface, id string,
+	pType apitypes.PatchType, patch []byte, opts metav1.PatchOptions) (*unstructured.Unstructured, error) {
+	if attributes.RevisionHistory(schema) <= 0 || pType == apitypes.ApplyPatchType {
+		return client.Patch(apiOp.Context(), id, pType, patch, opts)
+	}
+
+	for attempt := 0; ; attempt++ {
+		live, err := client.Get(apiOp.Context(), id, metav1.GetOptions{})
+		if err != nil {
+			// the patch reports the error
+			return client.Patch(apiOp.Context(), id, pType, patch, opts)
+		}
+		rowToObject(live)
+
+		value, _ := revisionsOf(apiOp, schema, live)
+		withRevisions, conditional, err := addRevisionsToPatch(pType, patch, value, live)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Patch(apiOp.Context(), id, pType, withRevisions, opts)
+		if apierrors.IsConflict(err) && !conditional && attempt < maxRevisionConflicts {
+			continue
+		}
+		return resp, err
+	}
+}
+
+// addRevisionsToPatch returns patch with the annotation set to value and the resourceVersion of live as a
+// precondition. The second value is true if patch already set a resourceVersion, that precondition is kept.
+func addRevisionsToPatch(pType apitypes.PatchType, patch []byte, value string, live *unstructured.Unstructured) ([]byte, bool, error) {
+	if pType == apitypes.JSONPatchType {
+		var ops []map[string]interface{}
+		if err := json.Unmarshal(patch, &ops); err != nil {
+			return nil, false, apierror.NewAPIError(ErrBadRequest, "invalid JSON patch: "+err.Error())
+		}
+		conditional := false
+		for _, op := range ops {
+			if op["path"] == "/metadata/resourceVersion" {
+				conditional = true
+			}
+		}
+		if live.GetAnnotations() == nil {
+			ops = append(ops, map[string]interface{}{"op": "add", "path": "/metadata/annotations",
+				"value": map[string]interface{}{RevisionsAnnotation: value}})
+		} else {
+			ops = append(ops, map[string]interface{}{"op": "add",
+				"path": "/metadata/annotations/" + strings.Replace(RevisionsAnnotation, "/", "~1", -1), "value": value})
+		}
+		if !conditional {
+			ops = append(ops, map[string]interface{}{"op": "replace", "path": "/metadata/resourceVersion",
+				"value": live.GetResourceVersion()})
+		}
+		result, err := json.Marshal(ops)
+		return result, conditional, err
+	}
+
+	input := map[string]interface{}{}
+	if err := jsonnumber.Unmarshal(patch, &input); err != nil {
+		return nil, false, err
+	}
+	conditional := data.Object(input).String("metadata", "resourceVersion") != ""
+	data.PutValue(input, value, "metadata", "annotations", RevisionsAnnotation)
+	if !conditional {
+		data.PutValue(input, live.GetResourceVersion(), "metadata", "resourceVersion")
+	}
+	result, err := json.Marshal(input)
+	return result, conditional, err
+}
@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func revisionSchema(limit int) *types.APISchema {
+	s := &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+	attributes.SetRevisionHistory(s, limit)
+	return s
+}
+
+func revisionRequest() *types.APIRequest {
+	ctx := request.WithUser(request.NewContext(), &user.DefaultInfo{Name: "alice"})
+	return &types.APIRequest{
+		Request: httptest.NewRequest(http.MethodPut, "/v1/widgets/w1", nil).WithContext(ctx),
+	}
+}
+
+func liveWidget(rv string, revisions ...Revision) *unstructured.Unstructured {
+	live := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	live.SetName("w1")
+	live.SetResourceVersion(rv)
+	if len(revisions) > 0 {
+		value, _ := json.Marshal(revisions)
+		live.SetAnnotations(map[string]string{RevisionsAnnotation: string(value)})
+	}
+	return live
+}
+
+func decodeRevisions(t *testing.T, value string) []Revision {
+	t.Helper()
+	var revisions []Revision
+	if err := json.Unmarshal([]byte(value), &revisions); err != nil {
+		t.Fatalf("invalid %s %q: %v", RevisionsAnnotation, value, err)
+	}
+	return revisions
+}
+
+func TestRevisionsOfWithoutHistory(t *testing.T) {
+	if _, ok := revisionsOf(revisionRequest(), revisionSchema(0), liveWidget("5")); ok {
+		t.Error("got revisions for a schema without a history")
+	}
+}
+
+func TestRevisionsOfAppendsTheReplacedVersion(t *testing.T) {
+	value, ok := revisionsOf(revisionRequest(), revisionSchema(10), liveWidget("5", Revision{ResourceVersion: "3"}))
+	if !ok {
+		t.Fatal("got no revisions")
+	}
+	revisions := decodeRevisions(t, value)
+	if len(revisions) != 2 || revisions[0].ResourceVersion != "3" || revisions[1].ResourceVersion != "5" {
+		t.Fatalf("got %+v, want the revisions 3 and 5", revisions)
+	}
+	if revisions[1].User != "alice" {
+		t.Errorf("got user %q, want alice", revisions[1].User)
+	}
+}
+
+func TestRevisionsOfCapsTheHistory(t *testing.T) {
+	var existing []Revision
+	for i := 0; i < 3; i++ {
+		existing = append(existing, Revision{ResourceVersion: strconv.Itoa(i)})
+	}
+	value, _ := revisionsOf(revisionRequest(), revisionSchema(3), liveWidget("9", existing...))
+	revisions := decodeRevisions(t, value)
+	if len(revisions) != 3 || revisions[0].ResourceVersion != "1" || revisions[2].ResourceVersion != "9" {
+		t.Fatalf("got %+v, want the revisions 1, 2 and 9", revisions)
+	}
+}
+
+func TestSetRevisionsReplacesTheSubmittedHistory(t *testing.T) {
+	input := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{RevisionsAnnotation: "[]"},
+		},
+	}
+	setRevisions(revisionRequest(), revisionSchema(10), input, liveWidget("5", Revision{ResourceVersion: "3"}))
+
+	annotations := input["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	revisions := decodeRevisions(t, annotations[RevisionsAnnotation].(string))
+	if len(revisions) != 2 {
+		t.Fatalf("got %+v, want the history of the live object and this write", revisions)
+	}
+}
+
+func TestSetRevisionsOnCreate(t *testing.T) {
+	input := map[string]interface{}{}
+	setRevisions(revisionRequest(), revisionSchema(10), input, nil)
+
+	annotations := input["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	revisions := decodeRevisions(t, annotations[RevisionsAnnotation].(string))
+	if len(revisions) != 1 || revisions[0].ResourceVersion != "" {
+		t.Fatalf("got %+v, want one revision without a resourceVersion", revisions)
+	}
+}
+
+func TestAddRevisionsToMergePatch(t *testing.T) {
+	patch, conditional, err := addRevisionsToPatch(apitypes.MergePatchType, []byte(`{"spec":{"replicas":2}}`), "[]", liveWidget("5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conditional {
+		t.Error("got a patch the user made conditional")
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(patch, &result); err != nil {
+		t.Fatal(err)
+	}
+	metadata := result["metadata"].(map[string]interface{})
+	if metadata["resourceVersion"] != "5" {
+		t.Errorf("got resourceVersion %v, want the precondition 5", metadata["resourceVersion"])
+	}
+	if metadata["annotations"].(map[string]interface{})[RevisionsAnnotation] != "[]" {
+		t.Errorf("got annotations %v, want the history", metadata["annotations"])
+	}
+	if result["spec"].(map[string]interface{})["replicas"] != float64(2) {
+		t.Errorf("got spec %v, want the patch of the user", result["spec"])
+	}
+}
+
+func TestAddRevisionsToMergePatchKeepsUserPrecondition(t *testing.T) {
+	patch, conditional, err := addRevisionsToPatch(apitypes.MergePatchType, []byte(`{"metadata":{"resourceVersion":"4"}}`), "[]", liveWidget("5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conditional {
+		t.Error("the resourceVersion of the user was not reported")
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(patch, &result); err != nil {
+		t.Fatal(err)
+	}
+	if rv := result["metadata"].(map[string]interface{})["resourceVersion"]; rv != "4" {
+		t.Errorf("got resourceVersion %v, want the one of the user", rv)
+	}
+}
+
+func TestAddRevisionsToJSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		live *unstructured.Unstructured
+		path string
+	}{
+		{name: "without annotations", live: liveWidget("5"), path: "/metadata/annotations"},
+		{name: "with annotations", live: liveWidget("5", Revision{ResourceVersion: "3"}), path: "/metadata/annotations/steve.cattle.io~1revisions"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, _, err := addRevisionsToPatch(apitypes.JSONPatchType, []byte(`[{"op":"replace","path":"/spec/replicas","value":2}]`), "[]", test.live)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ops []map[string]interface{}
+			if err := json.Unmarshal(patch, &ops); err != nil {
+				t.Fatal(err)
+			}
+			if len(ops) != 3 {
+				t.Fatalf("got %v, want the op of the user, the history and the precondition", ops)
+			}
+			if ops[1]["path"] != test.path {
+				t.Errorf("got path %v, want %s", ops[1]["path"], test.path)
+			}
+			if ops[2]["path"] != "/metadata/resourceVersion" || ops[2]["value"] != "5" {
+				t.Errorf("got %v, want the precondition 5", ops[2])
+			}
+		})
+	}
+}
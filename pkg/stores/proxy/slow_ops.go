@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSlowOperationThreshold is the duration from which store operations are recorded as slow, unless
+	// WithSlowOperationThreshold is given.
+	DefaultSlowOperationThreshold = 500 * time.Millisecond
+
+	slowOperationLogSize = 1000
+)
+
+// SlowOperations holds the slow operations of every proxy Store of the process and is served at /debug/slow-ops.
+var SlowOperations = NewSlowOperationLog(slowOperationLogSize)
+
+// SlowOperation is a store operation that took at least the slow operation threshold.
+type SlowOperation struct {
+	Time      time.Time     `json:"time"`
+	Operation string        `json:"operation"`
+	SchemaID  string        `json:"schemaId"`
+	Namespace string        `json:"namespace,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// SlowOperationLog keeps the last slow operations in a ring buffer, older ones are overwritten.
+type SlowOperationLog struct {
+	lock    sync.Mutex
+	entries []SlowOperation
+	next    int
+	full    bool
+}
+
+func NewSlowOperationLog(size int) *SlowOperationLog {
+	return &SlowOperationLog{
+		entries: make([]SlowOperation, size),
+	}
+}
+
+// Record adds an operation to the log, the caller decides whether it was slow.
+func (l *SlowOperationLog) Record(op, schemaID, namespace string, duration time.Duration, err error) {
+	entry := SlowOperation{
+		Time:      time.Now(),
+		Operation: op,
+		SchemaID:  schemaID,
+		Namespace: namespace,
+		Duration:  duration,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if len(l.entries) == 0 {
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Operations returns the recorded operations, oldest first.
+func (l *SlowOperationLog) Operations() []SlowOperation {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.full {
+		return append([]SlowOperation(nil), l.entries[:l.next]...)
+	}
+	return append(append([]SlowOperation(nil), l.entries[l.next:]...), l.entries[:l.next]...)
+}
+
+// ServeHTTP writes the recorded operations as a JSON array, oldest first.
+func (l *SlowOperationLog) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(l.Operations())
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// slowResource takes delay for every get, like an overloaded apiserver.
+type slowResource struct {
+	*fakeResource
+	delay time.Duration
+}
+
+func (s *slowResource) Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	time.Sleep(s.delay)
+	return s.fakeResource.Get(ctx, name, options, subresources...)
+}
+
+type slowClusterGetter struct {
+	*fakeClusterGetter
+	delay time.Duration
+}
+
+func (s *slowClusterGetter) TableClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return &slowResource{fakeResource: &fakeResource{cluster: s.cluster, namespace: namespace}, delay: s.delay}, nil
+}
+
+func TestSlowOperationLogKeepsTheLastOperations(t *testing.T) {
+	log := NewSlowOperationLog(3)
+	if ops := log.Operations(); len(ops) != 0 {
+		t.Fatalf("got %v, want no operations in a new log", ops)
+	}
+	for _, op := range []string{"get", "list", "create", "update", "delete"} {
+		log.Record(op, "configmap", "default", time.Second, nil)
+	}
+
+	var got []string
+	for _, op := range log.Operations() {
+		got = append(got, op.Operation)
+	}
+	if len(got) != 3 || got[0] != "create" || got[1] != "update" || got[2] != "delete" {
+		t.Errorf("got operations %v, want the last three oldest first", got)
+	}
+
+	log.Record("watch", "configmap", "", time.Second, errors.New("timed out"))
+	ops := log.Operations()
+	if last := ops[len(ops)-1]; last.Operation != "watch" || last.Error != "timed out" {
+		t.Errorf("got last operation %+v, want the failed watch", last)
+	}
+
+	// a log without room records nothing
+	empty := NewSlowOperationLog(0)
+	empty.Record("get", "configmap", "default", time.Second, nil)
+	if ops := empty.Operations(); len(ops) != 0 {
+		t.Errorf("got %v, want no operations in an empty log", ops)
+	}
+}
+
+func TestSlowOperationsAreRecorded(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	if _, err := createConfigMap(NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}), schema, "a"); err != nil {
+		t.Fatal(err)
+	}
+	getter := &slowClusterGetter{fakeClusterGetter: &fakeClusterGetter{cluster: cluster}, delay: 50 * time.Millisecond}
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodGet, "default", nil)
+
+	for _, test := range []struct {
+		name      string
+		threshold time.Duration
+		recorded  bool
+	}{
+		{name: "below the threshold", threshold: time.Hour},
+		{name: "above the threshold", threshold: 10 * time.Millisecond, recorded: true},
+		{name: "disabled", threshold: 0},
+	} {
+		before := len(SlowOperations.Operations())
+		store := NewProxyStore(getter, nil, fakeAccessSetLookup{}, WithSlowOperationThreshold(test.threshold))
+		if _, err := store.ByID(apiOp, schema, "a"); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		ops := SlowOperations.Operations()
+		if !test.recorded {
+			if len(ops) != before {
+				t.Errorf("%s: got %v, want the get not recorded", test.name, ops[before:])
+			}
+			continue
+		}
+		if len(ops) != before+1 {
+			t.Fatalf("%s: got %d new slow operations, want one", test.name, len(ops)-before)
+		}
+		op := ops[len(ops)-1]
+		if op.Operation != "get" || op.SchemaID != schema.ID || op.Namespace != "default" || op.Error != "" {
+			t.Errorf("%s: got %+v, want the get of the configmap in default", test.name, op)
+		}
+		if op.Duration < getter.delay {
+			t.Errorf("%s: got duration %v, want at least %v", test.name, op.Duration, getter.delay)
+		}
+	}
+}
+
+func TestSlowOperationLogIsServedAsJSON(t *testing.T) {
+	log := NewSlowOperationLog(10)
+	log.Record("list", "configmap", "default", 2*time.Second, nil)
+
+	rw := httptest.NewRecorder()
+	log.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/slow-ops", nil))
+	if rw.Code != http.StatusOK || rw.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("got %d %s, want a JSON response", rw.Code, rw.Header().Get("Content-Type"))
+	}
+	var ops []SlowOperation
+	if err := json.NewDecoder(rw.Body).Decode(&ops); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].Operation != "list" || ops[0].SchemaID != "configmap" || ops[0].Duration != 2*time.Second {
+		t.Errorf("got %+v, want the list", ops)
+	}
+
+	rw = httptest.NewRecorder()
+	log.ServeHTTP(rw, httptest.NewRequest(http.MethodDelete, "/debug/slow-ops", nil))
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d for a delete, want 405", rw.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// maxSnapshotPending is the number of changed objects a SnapshotWatcher holds before it sends them early, so the
+// state of a watch stays bounded however chatty the resource is.
+const maxSnapshotPending = 5000
+
+// watchSnapshots configures the SnapshotWatcher, schemas limits it to the schemas by ID.
+type watchSnapshots struct {
+	interval time.Duration
+	schemas  map[string]bool
+}
+
+// SnapshotWatcher bounds the event rate of a watch on a chatty resource, such as events or objects with a status
+// that is updated every few seconds. Changes are held and only the latest state of every changed object is sent
+// once per interval, creates and removes are sent right away, removes dropping the held change of the object.
+// Clients see a change up to interval late in exchange for at most one change per object per interval.
+type SnapshotWatcher struct {
+	interval time.Duration
+	input    chan types.APIEvent
+	result   chan types.APIEvent
+	pending  map[string]types.APIEvent
+	order    []string
+}
+
+func NewSnapshotWatcher(input chan types.APIEvent, interval time.Duration) *SnapshotWatcher {
+	s := &SnapshotWatcher{
+		interval: interval,
+		input:    input,
+		result:   make(chan types.APIEvent),
+		pending:  map[string]types.APIEvent{},
+	}
+	go s.run()
+	return s
+}
+
+func (s *SnapshotWatcher) ResultChan() chan types.APIEvent {
+	return s.result
+}
+
+func (s *SnapshotWatcher) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	defer close(s.result)
+
+	for {
+		select {
+		case event, ok := <-s.input:
+			if !ok {
+				s.flush()
+				return
+			}
+			s.add(event)
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+func (s *SnapshotWatcher) add(event types.APIEvent) {
+	key := dedupeKey(event)
+	if key == "" || event.Name != types.ChangeAPIEvent {
+		if event.Name == types.RemoveAPIEvent {
+			delete(s.pending, key)
+		}
+		s.result <- event
+		return
+	}
+
+	if _, ok := s.pending[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	s.pending[key] = event
+	if len(s.pending) >= maxSnapshotPending {
+		s.flush()
+	}
+}
+
+// flush sends the latest change of every object changed since the last flush, in the order they first changed.
+func (s *SnapshotWatcher) flush() {
+	for _, key := range s.order {
+		if event, ok := s.pending[key]; ok {
+			s.result <- event
+		}
+	}
+	s.pending = map[string]types.APIEvent{}
+	s.order = s.order[:0]
+}
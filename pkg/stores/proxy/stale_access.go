@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"sync/atomic"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/idle"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+var staleAccessRetries int64
+
+// StaleAccessRetries counts the lists fanned out over namespaces that were retried with a fresh access set, see
+// staleAccessStore.
+func StaleAccessRetries() int64 {
+	return atomic.LoadInt64(&staleAccessRetries)
+}
+
+// staleAccessStore heals lists that fan out over the namespaces of a stale access set. When RBAC changed moments
+// ago the cached access set and schemas of the user still name namespaces the apiserver now forbids, and the first
+// forbidden namespace fails the whole list. The cached access set and schemas are then dropped and the list is
+// retried once over the namespaces of a freshly computed access set.
+type staleAccessStore struct {
+	types.Store
+	asl        accesscontrol.AccessSetLookup
+	forgetters []idle.Forgetter
+}
+
+func (s *staleAccessStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := s.Store.List(apiOp, schema)
+	if !apierrors.IsForbidden(err) || !isNamespaceFanOut(apiOp, schema) {
+		return list, err
+	}
+
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return list, err
+	}
+
+	stale := s.asl.AccessFor(user)
+	if forgetter, ok := s.asl.(idle.Forgetter); ok {
+		forgetter.Forget(stale.ID)
+	}
+	for _, forgetter := range s.forgetters {
+		forgetter.Forget(stale.ID)
+	}
+	fresh := s.asl.AccessFor(user)
+
+	atomic.AddInt64(&staleAccessRetries, 1)
+	logrus.Debugf("retrying list of %s for %s with a fresh access set after: %v", schema.ID, user.GetName(), err)
+	return s.Store.List(apiOp, withAccess(schema, fresh))
+}
+
+// isNamespaceFanOut is whether a list of the schema is split into lists of the namespaces the user may list.
+func isNamespaceFanOut(apiOp *types.APIRequest, schema *types.APISchema) bool {
+	if apiOp.Namespace != "" || !attributes.Namespaced(schema) {
+		return false
+	}
+	access, _ := attributes.Access(schema).(accesscontrol.AccessListByVerb)
+	return !access.All("list") && len(access.Granted("list")) > 1
+}
+
+// withAccess returns a copy of schema with the access of the access set, schemas are shared between requests.
+func withAccess(schema *types.APISchema, access *accesscontrol.AccessSet) *types.APISchema {
+	gr := attributes.GR(schema)
+	verbAccess := accesscontrol.AccessListByVerb{}
+	for _, verb := range attributes.Verbs(schema) {
+		if list := access.AccessListFor(verb, gr); len(list) > 0 {
+			verbAccess[verb] = list
+		}
+	}
+
+	copied := schema.DeepCopy()
+	copied.Attributes = make(map[string]interface{}, len(schema.Attributes))
+	for k, v := range schema.Attributes {
+		copied.Attributes[k] = v
+	}
+	attributes.SetAccess(copied, verbAccess)
+	return copied
+}
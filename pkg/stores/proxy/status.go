@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// isStatus returns true if the apiserver answered with a metav1.Status instead of an object of the schema.
+func isStatus(schema *types.APISchema, obj *unstructured.Unstructured) bool {
+	if obj == nil || obj.GetKind() != "Status" || obj.GetAPIVersion() != metav1.SchemeGroupVersion.Version {
+		return false
+	}
+	// the schema for Status itself, if there ever is one, must be left alone
+	return attributes.Kind(schema) != "Status"
+}
+
+// statusResult turns a metav1.Status returned in place of an object into an error, so it is never sent to the
+// client as if it were the resource. A failure status becomes the matching typed error. A success status is
+// only expected on deletes, those callers treat the error as the object being gone.
+func statusResult(schema *types.APISchema, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if !isStatus(schema, obj) {
+		return obj, nil
+	}
+
+	status := &metav1.Status{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, status); err != nil {
+		return nil, apierror.NewAPIError(validation.ServerError,
+			fmt.Sprintf("failed to decode status returned for %s: %v", schema.ID, err))
+	}
+
+	if status.Status != metav1.StatusSuccess {
+		return nil, apierrors.FromObject(status)
+	}
+	return nil, apierror.NewAPIError(validation.ServerError,
+		fmt.Sprintf("apiserver returned a success status instead of %s: %s", schema.ID, status.Message))
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+)
+
+type statusSubresourceKey struct{}
+
+// WithStatusSubresource marks a PATCH request so Update patches the status subresource of the object instead of the
+// object, the body is then a merge patch, or a JSON patch with Content-Type application/json-patch+json, of the
+// status only. With Content-Type application/apply-patch+yaml the body is server-side applied to the status, as the
+// field manager from ?fieldManager= or the one of the store, and ?force=true takes fields owned by other managers.
+// The caller must have checked the user may update the status.
+func WithStatusSubresource(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), statusSubresourceKey{}, true))
+}
+
+func isStatusSubresource(apiOp *types.APIRequest) bool {
+	status, _ := apiOp.Context().Value(statusSubresourceKey{}).(bool)
+	return status
+}
+
+// patchStatus sends the body of the request to the status subresource, the apiserver ignores any change it makes
+// outside of status so the spec can't be modified this way.
+func (s *Store) patchStatus(apiOp *types.APIRequest, schema *types.APISchema, namespace, name string) (types.APIObject, error) {
+	k8sClient, err := s.clientGetter.Client(apiOp, schema, namespace)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+
+	bytes, err := ioutil.ReadAll(io.LimitReader(apiOp.Request.Body, 3<<20))
+	if err != nil {
+		return types.APIObject{}, err
+	}
+
+	pType := patchType(apiOp, apitypes.MergePatchType)
+
+	opts := metav1.PatchOptions{}
+	if err := decodeParams(apiOp, &opts); err != nil {
+		return types.APIObject{}, err
+	}
+	if err := s.applyOptions(apiOp, pType, &opts); err != nil {
+		return types.APIObject{}, err
+	}
+	if pType, bytes, err = s.applyFallback(pType, bytes, &opts); err != nil {
+		return types.APIObject{}, err
+	}
+
+	resp, err := k8sClient.Patch(apiOp.Context(), name, pType, bytes, opts, "status")
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	return toAPI(schema, resp), nil
+}
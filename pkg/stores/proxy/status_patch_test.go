@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestStatusPatchLeavesTheSpec(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{})
+
+	cluster.lock.Lock()
+	cluster.write(watch.Added, &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "a", "namespace": "default"},
+		"spec":     map[string]interface{}{"replicas": int64(1)},
+		"status":   map[string]interface{}{"phase": "Pending", "replicas": int64(0)},
+	}})
+	cluster.lock.Unlock()
+
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPatch, "default", nil)
+	apiOp.Request = WithStatusSubresource(apiOp.Request)
+	apiOp.Request.Body = ioutil.NopCloser(strings.NewReader(`{"spec":{"replicas":5},"status":{"phase":"Running"}}`))
+	if _, err := store.Update(apiOp, schema, types.APIObject{Object: map[string]interface{}{}}, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	live := cluster.objects[clusterKey("default", "a")]
+	if status, _, _ := unstructured.NestedMap(live.Object, "status"); !reflect.DeepEqual(status, map[string]interface{}{"phase": "Running", "replicas": int64(0)}) {
+		t.Errorf("got status %v, want only the phase patched", status)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(live.Object, "spec", "replicas"); replicas != 1 {
+		t.Errorf("got %d replicas in the spec, want the spec untouched by a status patch", replicas)
+	}
+}
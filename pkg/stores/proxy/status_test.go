@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// statusResource answers every get with status, like an apiserver that returns a Status in place of the object.
+type statusResource struct {
+	*fakeResource
+	status map[string]interface{}
+}
+
+func (s *statusResource) Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return &unstructured.Unstructured{Object: s.status}, nil
+}
+
+type statusClusterGetter struct {
+	*fakeClusterGetter
+	status map[string]interface{}
+}
+
+func (s *statusClusterGetter) TableClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return &statusResource{fakeResource: &fakeResource{cluster: s.cluster, namespace: namespace}, status: s.status}, nil
+}
+
+func status(result string, code int, reason metav1.StatusReason) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Status",
+		"status":     result,
+		"code":       int64(code),
+		"reason":     string(reason),
+		"message":    "status of the test",
+	}
+}
+
+func statusStore(status map[string]interface{}) (types.Store, *fakeCluster) {
+	cluster := newFakeCluster()
+	return NewProxyStore(&statusClusterGetter{fakeClusterGetter: &fakeClusterGetter{cluster: cluster}, status: status}, nil, fakeAccessSetLookup{}), cluster
+}
+
+func TestStatusReturnedOnGetIsAnError(t *testing.T) {
+	schema := configMapSchema()
+	for _, test := range []struct {
+		name   string
+		status map[string]interface{}
+		code   int
+	}{
+		{name: "not found", status: status(metav1.StatusFailure, http.StatusNotFound, metav1.StatusReasonNotFound), code: http.StatusNotFound},
+		{name: "forbidden", status: status(metav1.StatusFailure, http.StatusForbidden, metav1.StatusReasonForbidden), code: http.StatusForbidden},
+		{name: "success", status: status(metav1.StatusSuccess, http.StatusOK, ""), code: http.StatusInternalServerError},
+	} {
+		store, _ := statusStore(test.status)
+		obj, err := store.ByID(conformance.DefaultRequest(schema)(context.Background(), http.MethodGet, "default", nil), schema, "a")
+		apiErr, ok := err.(*apierror.APIError)
+		if !ok || apiErr.Code.Status != test.code {
+			t.Errorf("%s: got %v %v, want a %d", test.name, obj.Object, err, test.code)
+		}
+	}
+}
+
+func TestSuccessStatusAfterDeleteMeansGone(t *testing.T) {
+	schema := configMapSchema()
+	store, cluster := statusStore(status(metav1.StatusSuccess, http.StatusOK, ""))
+	if _, err := createConfigMap(NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}), schema, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := store.Delete(conformance.DefaultRequest(schema)(context.Background(), http.MethodDelete, "default", nil), schema, "a")
+	if code, ok := err.(validation.ErrorCode); !ok || code.Status != http.StatusNoContent {
+		t.Errorf("got %v %v, want no content for the deleted object", obj.Object, err)
+	}
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var (
+	ErrNotAcceptable = validation.ErrorCode{
+		Code:   "NotAcceptable",
+		Status: http.StatusNotAcceptable,
+	}
+)
+
+// IsTableRequest returns true if the client asked for the apiserver's meta.k8s.io/v1 Table, as kubectl and other
+// client-go based clients do with Accept: application/json;as=Table;g=meta.k8s.io;v=v1.
+func IsTableRequest(req *http.Request) bool {
+	for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+		params := map[string]string{}
+		parts := strings.Split(accept, ";")
+		for _, part := range parts[1:] {
+			kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+			if len(kv) == 2 {
+				params[kv[0]] = kv[1]
+			}
+		}
+		if strings.TrimSpace(parts[0]) == "application/json" && params["as"] == "Table" &&
+			params["g"] == "meta.k8s.io" && params["v"] == "v1" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTable returns true if obj is a meta.k8s.io/v1 Table.
+func IsTable(obj map[string]interface{}) bool {
+	return obj["kind"] == "Table" && obj["apiVersion"] == "meta.k8s.io/v1"
+}
+
+// tableList returns the Table of a list request unmodified as the only object of the list, the rows are not
+// converted to objects and no formatter runs on them.
+func tableList(schema *types.APISchema, resultList *unstructured.UnstructuredList) (types.APIObjectList, error) {
+	if !IsTable(resultList.Object) {
+		return types.APIObjectList{}, apierror.NewAPIError(ErrNotAcceptable, schema.ID+" can not be listed as a Table")
+	}
+	return types.APIObjectList{
+		Revision: resultList.GetResourceVersion(),
+		Continue: resultList.GetContinue(),
+		Objects: []types.APIObject{
+			{
+				Type:   schema.ID,
+				Object: &unstructured.Unstructured{Object: resultList.Object},
+			},
+		},
+	}, nil
+}
+
+// checkTableSupport fails Table requests for schemas the apiserver doesn't serve as Tables.
+func checkTableSupport(apiOp *types.APIRequest, schema *types.APISchema) error {
+	if IsTableRequest(apiOp.Request) && !attributes.Table(schema) {
+		return apierror.NewAPIError(ErrNotAcceptable, schema.ID+" can not be listed as a Table")
+	}
+	return nil
+}
+
+// filterTableRows drops the rows of a Table for objects not in names.
+func filterTableRows(table map[string]interface{}, names sets.String) {
+	rows, _ := table["rows"].([]interface{})
+	filtered := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		m, _ := row.(map[string]interface{})
+		object, _ := m["object"].(map[string]interface{})
+		metadata, _ := object["metadata"].(map[string]interface{})
+		if name, _ := metadata["name"].(string); names.Has(name) {
+			filtered = append(filtered, row)
+		}
+	}
+	table["rows"] = filtered
+}
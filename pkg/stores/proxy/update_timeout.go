@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+var (
+	ErrUpdateTimeout = validation.ErrorCode{
+		Code:   "UpdateTimeout",
+		Status: http.StatusServiceUnavailable,
+	}
+)
+
+// Update runs the whole update, every request it makes to the apiserver included, within the update timeout
+// budget if one is configured.
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, params types.APIObject, id string) (types.APIObject, error) {
+	apiOp, addWarnings, err := s.withFieldValidation(apiOp)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	defer addWarnings()
+
+	req := apiOp
+	if s.updateTimeout > 0 {
+		ctx, cancel := context.WithTimeout(apiOp.Request.Context(), s.updateTimeout)
+		defer cancel()
+		req = apiOp.WithContext(ctx)
+	}
+
+	obj, err := s.update(req, schema, params, id)
+	if err != nil {
+		if req.Context().Err() == context.DeadlineExceeded && apiOp.Context().Err() == nil {
+			return types.APIObject{}, apierror.NewAPIError(ErrUpdateTimeout,
+				"update of "+schema.ID+" "+id+" did not complete within "+s.updateTimeout.String()+", the resource may be contended, retry the request")
+		}
+		return obj, err
+	}
+	s.confirmWrite(apiOp, schema, obj)
+	s.auditEvent(apiOp, schema, "Updated", obj.Object)
+	return obj, nil
+}
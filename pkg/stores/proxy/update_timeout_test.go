@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// contendedResource blocks every update until the context of the request ends, like an apiserver that can't get
+// the write through.
+type contendedResource struct {
+	*fakeResource
+}
+
+func (c *contendedResource) Update(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type contendedClusterGetter struct {
+	*fakeClusterGetter
+}
+
+func (c *contendedClusterGetter) TableClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return &contendedResource{fakeResource: &fakeResource{cluster: c.cluster, namespace: namespace}}, nil
+}
+
+func TestBlockedUpdateTimesOut(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	if _, err := createConfigMap(NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}), schema, "a"); err != nil {
+		t.Fatal(err)
+	}
+	resourceVersion := cluster.objects[clusterKey("default", "a")].GetResourceVersion()
+
+	store := NewProxyStore(&contendedClusterGetter{fakeClusterGetter: &fakeClusterGetter{cluster: cluster}}, nil,
+		fakeAccessSetLookup{}, WithUpdateTimeout(20*time.Millisecond))
+	apiOp := conformance.DefaultRequest(schema)(context.Background(), http.MethodPut, "default", nil)
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "a", "namespace": "default", "resourceVersion": resourceVersion},
+		"data":     map[string]interface{}{"key": "value"},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := store.Update(apiOp, schema, types.APIObject{Object: obj}, "a")
+		done <- err
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the blocked update did not end at the update timeout")
+	}
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok || apiErr.Code != ErrUpdateTimeout {
+		t.Fatalf("got %v, want an update timeout", err)
+	}
+	if apiErr.Code.Status != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want 503", apiErr.Code.Status)
+	}
+}
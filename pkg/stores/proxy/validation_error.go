@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var ErrInvalid = validation.ErrorCode{
+	Code:   string(metav1.StatusReasonInvalid),
+	Status: http.StatusUnprocessableEntity,
+}
+
+// FieldError is one cause of a 422 Unprocessable Entity from the apiserver, Field is the path of the rejected
+// field such as spec.containers[0].image.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ValidationError is a create or update the apiserver rejected as invalid, with every field error it reported.
+// The errors returned by the store wrap it, use AsValidationError or errors.As to get it.
+type ValidationError struct {
+	Message string
+	Fields  []FieldError
+}
+
+func (v *ValidationError) Error() string {
+	return v.Message
+}
+
+// AsValidationError returns the ValidationError err holds, if any.
+func AsValidationError(err error) (*ValidationError, bool) {
+	var result *ValidationError
+	if errors.As(err, &result) {
+		return result, true
+	}
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) && apiErr.Cause != nil {
+		return AsValidationError(apiErr.Cause)
+	}
+	return nil, false
+}
+
+// toValidationError returns the ValidationError of an Invalid status that lists causes, otherwise nil.
+func toValidationError(err error) *ValidationError {
+	if !apierrors.IsInvalid(err) {
+		return nil
+	}
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || status.Status().Details == nil || len(status.Status().Details.Causes) == 0 {
+		return nil
+	}
+
+	result := &ValidationError{
+		Message: status.Status().Message,
+	}
+	for _, cause := range status.Status().Details.Causes {
+		result.Fields = append(result.Fields, FieldError{
+			Field:   cause.Field,
+			Message: cause.Message,
+			Reason:  string(cause.Type),
+		})
+	}
+	return result
+}
+
+// translateValidationError turns an Invalid status with causes into a 422 that wraps the ValidationError and names
+// the first field, and records the field errors for the response when the request collects them.
+func translateValidationError(ctx context.Context, err error) error {
+	v := toValidationError(err)
+	if v == nil {
+		return nil
+	}
+	if collector, ok := ctx.Value(fieldErrorsKey{}).(*fieldErrors); ok {
+		collector.set(v.Fields)
+	}
+
+	var fieldName string
+	if len(v.Fields) > 0 {
+		fieldName = strings.TrimPrefix(v.Fields[0].Field, ".")
+	}
+	return &apierror.APIError{
+		Code:      ErrInvalid,
+		Message:   v.Message,
+		FieldName: fieldName,
+		Cause:     v,
+	}
+}
+
+type fieldErrorsKey struct{}
+
+type fieldErrors struct {
+	lock   sync.Mutex
+	fields []FieldError
+}
+
+func (f *fieldErrors) set(fields []FieldError) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.fields = fields
+}
+
+// WithFieldErrors returns a context that collects the field errors of a rejected create or update made with it,
+// so the handler writing the error response can include them, see FieldErrors.
+func WithFieldErrors(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fieldErrorsKey{}, &fieldErrors{})
+}
+
+// FieldErrors returns the field errors collected in a context from WithFieldErrors.
+func FieldErrors(ctx context.Context) []FieldError {
+	collector, ok := ctx.Value(fieldErrorsKey{}).(*fieldErrors)
+	if !ok {
+		return nil
+	}
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	return collector.fields
+}
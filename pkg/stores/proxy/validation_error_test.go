@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/dynamic"
+)
+
+// rejectingResource fails every create with err.
+type rejectingResource struct {
+	*fakeResource
+	err error
+}
+
+func (r *rejectingResource) Create(ctx context.Context, obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, r.err
+}
+
+type rejectingClusterGetter struct {
+	*fakeClusterGetter
+	err error
+}
+
+func (r *rejectingClusterGetter) TableClient(ctx *types.APIRequest, schema *types.APISchema, namespace string) (dynamic.ResourceInterface, error) {
+	return &rejectingResource{fakeResource: &fakeResource{cluster: r.cluster, namespace: namespace}, err: r.err}, nil
+}
+
+func invalidConfigMap() error {
+	return apierrors.NewInvalid(schema.GroupKind{Kind: "ConfigMap"}, "a", field.ErrorList{
+		field.Invalid(field.NewPath("metadata", "name"), "A", "must be lowercase"),
+		field.Required(field.NewPath("data", "key"), "key is required"),
+	})
+}
+
+func TestRejectedCreateHasFieldErrors(t *testing.T) {
+	schema := configMapSchema()
+	store := NewProxyStore(&rejectingClusterGetter{fakeClusterGetter: &fakeClusterGetter{cluster: newFakeCluster()}, err: invalidConfigMap()},
+		nil, fakeAccessSetLookup{})
+	apiOp := conformance.DefaultRequest(schema)(WithFieldErrors(context.Background()), http.MethodPost, "default", nil)
+	_, err := store.Create(apiOp, schema, types.APIObject{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "A", "namespace": "default"},
+	}})
+
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok || apiErr.Code != ErrInvalid || apiErr.Code.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("got %v, want a 422", err)
+	}
+	if apiErr.FieldName != "metadata.name" {
+		t.Errorf("got field name %q, want the first rejected field", apiErr.FieldName)
+	}
+
+	want := []FieldError{
+		{Field: "metadata.name", Message: `Invalid value: "A": must be lowercase`, Reason: string(metav1.CauseTypeFieldValueInvalid)},
+		{Field: "data.key", Message: "Required value: key is required", Reason: string(metav1.CauseTypeFieldValueRequired)},
+	}
+	var validationErr *ValidationError
+	if !errors.As(apiErr.Cause, &validationErr) || !reflect.DeepEqual(validationErr.Fields, want) {
+		t.Errorf("got %#v, want the field errors %v", apiErr.Cause, want)
+	}
+	if v, ok := AsValidationError(err); !ok || v != validationErr {
+		t.Errorf("got %v, want the validation error through AsValidationError", v)
+	}
+	if fields := FieldErrors(apiOp.Context()); !reflect.DeepEqual(fields, want) {
+		t.Errorf("got the collected field errors %v, want %v", fields, want)
+	}
+}
+
+func TestInvalidWithoutCausesIsNotAValidationError(t *testing.T) {
+	rejected := apierrors.NewInvalid(schema.GroupKind{Kind: "ConfigMap"}, "a", nil)
+	store := NewProxyStore(&rejectingClusterGetter{fakeClusterGetter: &fakeClusterGetter{cluster: newFakeCluster()}, err: rejected},
+		nil, fakeAccessSetLookup{})
+	_, err := createConfigMap(store, configMapSchema(), "a")
+	if _, ok := AsValidationError(err); ok {
+		t.Errorf("got a validation error from %v, want none without causes", err)
+	}
+	if apiErr, ok := err.(*apierror.APIError); !ok || apiErr.Code.Status != http.StatusUnprocessableEntity {
+		t.Errorf("got %v, want the 422 of the apiserver", err)
+	}
+}
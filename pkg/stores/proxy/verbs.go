@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/rancher/wrangler/pkg/slice"
+)
+
+var (
+	ErrMethodNotAllowed = validation.ErrorCode{
+		Code:   "MethodNotAllowed",
+		Status: http.StatusMethodNotAllowed,
+	}
+
+	// verbMethods are the HTTP methods each Kubernetes verb is served on
+	verbMethods = map[string][]string{
+		"get":    {http.MethodGet},
+		"list":   {http.MethodGet},
+		"watch":  {http.MethodGet},
+		"create": {http.MethodPost},
+		"update": {http.MethodPut},
+		"patch":  {http.MethodPatch},
+		"delete": {http.MethodDelete},
+	}
+)
+
+// verbStore rejects operations on verbs the resource does not support according to discovery, such as creating
+// componentstatuses, with a 405 and an Allow header listing the methods that are supported. Schemas without
+// discovery verbs are not checked.
+type verbStore struct {
+	types.Store
+}
+
+func checkVerb(apiOp *types.APIRequest, schema *types.APISchema, verb string) error {
+	verbs := attributes.Verbs(schema)
+	if len(verbs) == 0 || slice.ContainsString(verbs, verb) {
+		return nil
+	}
+
+	allowed := map[string]bool{}
+	for _, verb := range verbs {
+		for _, method := range verbMethods[verb] {
+			allowed[method] = true
+		}
+	}
+	var methods []string
+	for method := range allowed {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	if apiOp.Response != nil {
+		apiOp.Response.Header().Set("Allow", strings.Join(methods, ", "))
+	}
+	return apierror.NewAPIError(ErrMethodNotAllowed, schema.ID+" does not support "+verb)
+}
+
+func (v *verbStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	if err := checkVerb(apiOp, schema, "get"); err != nil {
+		return types.APIObject{}, err
+	}
+	return v.Store.ByID(apiOp, schema, id)
+}
+
+func (v *verbStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	if err := checkVerb(apiOp, schema, "list"); err != nil {
+		return types.APIObjectList{}, err
+	}
+	return v.Store.List(apiOp, schema)
+}
+
+func (v *verbStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	if err := checkVerb(apiOp, schema, "create"); err != nil {
+		return types.APIObject{}, err
+	}
+	return v.Store.Create(apiOp, schema, data)
+}
+
+func (v *verbStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	verb := "update"
+	if apiOp.Method == http.MethodPatch {
+		verb = "patch"
+	}
+	// the verbs of the status subresource are not in discovery of the resource
+	if isStatusSubresource(apiOp) {
+		return v.Store.Update(apiOp, schema, data, id)
+	}
+	if err := checkVerb(apiOp, schema, verb); err != nil {
+		return types.APIObject{}, err
+	}
+	return v.Store.Update(apiOp, schema, data, id)
+}
+
+func (v *verbStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	if err := checkVerb(apiOp, schema, "delete"); err != nil {
+		return types.APIObject{}, err
+	}
+	return v.Store.Delete(apiOp, schema, id)
+}
+
+func (v *verbStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	if err := checkVerb(apiOp, schema, "watch"); err != nil {
+		return nil, err
+	}
+	return v.Store.Watch(apiOp, schema, w)
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"time"
+)
+
+// watchTimeout is the timeout of the watches the store opens on the apiserver.
+const watchTimeout = 30 * time.Minute
+
+// DefaultWatchRenewMargin is how long before its timeout a watch is renewed by a WatchKeepaliveExtender that
+// doesn't set a margin.
+const DefaultWatchRenewMargin = 60 * time.Second
+
+type watchEnd int
+
+const (
+	watchClosed watchEnd = iota
+	watchExpired
+	watchRenew
+)
+
+// WatchKeepaliveExtender renews watches before the apiserver closes them at their timeout. When Margin is left
+// of a watch it is closed and a new one is opened from the last revision it sent, which bookmarks keep current,
+// and a watchevent.ResumedAPIEvent is sent. Clients that ignore the event see one uninterrupted stream. A watch
+// that sent no revision yet is not renewed and ends at its timeout as before.
+type WatchKeepaliveExtender struct {
+	Enabled bool
+	// Margin is the time before the timeout at which the watch is renewed, DefaultWatchRenewMargin when zero.
+	Margin time.Duration
+}
+
+// renewAfter returns a channel that fires when a watch with timeout should be renewed, or nil when it never is,
+// and a func to release the timer.
+func (k WatchKeepaliveExtender) renewAfter(timeout time.Duration) (<-chan time.Time, func()) {
+	margin := k.Margin
+	if margin <= 0 {
+		margin = DefaultWatchRenewMargin
+	}
+	if !k.Enabled || margin >= timeout {
+		return nil, func() {}
+	}
+	timer := time.NewTimer(timeout - margin)
+	return timer.C, func() { timer.Stop() }
+}
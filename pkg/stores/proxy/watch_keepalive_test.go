@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/conformance"
+	"github.com/rancher/steve/pkg/watchevent"
+)
+
+func TestRenewAfter(t *testing.T) {
+	if c, _ := (WatchKeepaliveExtender{}).renewAfter(watchTimeout); c != nil {
+		t.Error("got a renewal without the keepalive enabled")
+	}
+	if c, _ := (WatchKeepaliveExtender{Enabled: true, Margin: watchTimeout}).renewAfter(watchTimeout); c != nil {
+		t.Error("got a renewal for a margin as long as the timeout")
+	}
+	if c, _ := (WatchKeepaliveExtender{Enabled: true}).renewAfter(DefaultWatchRenewMargin); c != nil {
+		t.Error("got a renewal for a timeout within the default margin")
+	}
+
+	c, stop := (WatchKeepaliveExtender{Enabled: true, Margin: time.Minute - 10*time.Millisecond}).renewAfter(time.Minute)
+	defer stop()
+	select {
+	case <-c:
+	case <-time.After(5 * time.Second):
+		t.Error("the renewal did not fire margin before the timeout")
+	}
+}
+
+// nearTimeoutWatch opens a watch of the config maps of default that is renewed 200ms after it opened, as if it
+// had reached the margin before its timeout.
+func nearTimeoutWatch(t *testing.T, ctx context.Context, cluster *fakeCluster) (types.Store, chan types.APIEvent) {
+	t.Helper()
+	schema := configMapSchema()
+	store := NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{},
+		WithWatchKeepalive(watchTimeout-200*time.Millisecond))
+	apiOp := conformance.DefaultRequest(schema)(ctx, http.MethodGet, "default", nil)
+	c, err := store.Watch(apiOp, schema, types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, c
+}
+
+func nextWatchEvent(t *testing.T, c chan types.APIEvent) types.APIEvent {
+	t.Helper()
+	select {
+	case event, ok := <-c:
+		if !ok {
+			t.Fatal("the watch closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return types.APIEvent{}
+}
+
+func TestWatchIsRenewedBeforeItsTimeout(t *testing.T) {
+	cluster := newFakeCluster()
+	schema := configMapSchema()
+	if _, err := createConfigMap(NewProxyStore(&fakeClusterGetter{cluster: cluster}, nil, fakeAccessSetLookup{}), schema, "existing"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store, c := nearTimeoutWatch(t, ctx, cluster)
+
+	created := nextWatchEvent(t, c)
+	if created.Name != types.CreateAPIEvent || created.Object.ID != "default/existing" {
+		t.Fatalf("got %s of %s, want the existing config map", created.Name, created.Object.ID)
+	}
+
+	resumed := nextWatchEvent(t, c)
+	if resumed.Name != watchevent.ResumedAPIEvent || resumed.Revision != created.Revision {
+		t.Fatalf("got %s at %s, want %s at the revision of the last event %s", resumed.Name, resumed.Revision,
+			watchevent.ResumedAPIEvent, created.Revision)
+	}
+
+	// the new watch resumes from the revision, the existing config map is not sent again and later writes are
+	if _, err := createConfigMap(store, schema, "after"); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextWatchEvent(t, c); event.Name != types.CreateAPIEvent || event.Object.ID != "default/after" {
+		t.Errorf("got %s of %s, want the create after the renewal", event.Name, event.Object.ID)
+	}
+
+	cluster.lock.Lock()
+	watchers := len(cluster.watchers)
+	cluster.lock.Unlock()
+	if watchers != 1 {
+		t.Errorf("got %d open watches, want the renewed watch stopped", watchers)
+	}
+}
+
+func TestWatchWithoutARevisionIsNotRenewed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, c := nearTimeoutWatch(t, ctx, newFakeCluster())
+
+	select {
+	case event, ok := <-c:
+		if ok {
+			t.Errorf("got %s, want the watch closed at the renewal without an event", event.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the watch did not close")
+	}
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// webhookNameRegexp matches the name in the message of an apiserver that could not call an admission webhook, for
+// example: Internal error occurred: failed calling webhook "validate.example.com": Post "https://...": EOF
+var webhookNameRegexp = regexp.MustCompile(`failed calling (?:admission )?webhook "([^"]+)"`)
+
+// WebhookRetry retries writes that failed because an admission webhook could not be called or the apiserver was
+// unavailable. Both happen before the object is persisted and usually clear up within seconds, for example while a
+// webhook's pods are being rolled.
+type WebhookRetry struct {
+	// Attempts is the number of retries after the first attempt, zero disables retrying.
+	Attempts int
+	// Backoff is the wait before the first retry, it is doubled for every following retry.
+	Backoff time.Duration
+}
+
+// DefaultWebhookRetry is used by the Store unless WithWebhookRetry is given, and by the cluster apply action.
+var DefaultWebhookRetry = WebhookRetry{
+	Attempts: 3,
+	Backoff:  250 * time.Millisecond,
+}
+
+// Do calls write until it succeeds, fails with an error that is not retryable, the retries are used up or the
+// next wait would run past the deadline of ctx. When the last error came from an admission webhook it is
+// replaced with one that says so and names the webhook.
+func (w WebhookRetry) Do(ctx context.Context, write func() error) error {
+	return w.do(ctx, isWebhookRetryable, write)
+}
+
+func (w WebhookRetry) do(ctx context.Context, retryable func(error) bool, write func() error) error {
+	backoff := w.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = write()
+		if err == nil || !retryable(err) || attempt >= w.Attempts || !waitBackoff(ctx, backoff) {
+			break
+		}
+		backoff *= 2
+	}
+	return webhookError(err)
+}
+
+// waitBackoff waits for d and reports whether to try again, which it does not when ctx is done first or its
+// deadline is sooner than d.
+func waitBackoff(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// isWebhookRetryable is true for errors from calling an admission webhook and for 503 Service Unavailable. A
+// webhook that answered and denied the request is never retried.
+func isWebhookRetryable(err error) bool {
+	return isWebhookCallError(err) || apierrors.IsServiceUnavailable(err)
+}
+
+func isWebhookCallError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "failed calling webhook") || strings.Contains(msg, "failed calling admission webhook")
+}
+
+// webhookError rewrites the message of an error from calling an admission webhook to state the webhook as the
+// cause, keeping the status so the client still gets the code from the apiserver.
+func webhookError(err error) error {
+	if !isWebhookCallError(err) {
+		return err
+	}
+
+	cause := "an admission webhook"
+	if m := webhookNameRegexp.FindStringSubmatch(err.Error()); len(m) == 2 {
+		cause = fmt.Sprintf("admission webhook %q", m[1])
+	}
+	msg := fmt.Sprintf("request failed because %s could not be reached: %v", cause, err)
+
+	if status, ok := err.(apierrors.APIStatus); ok {
+		result := status.Status()
+		result.Message = msg
+		return &apierrors.StatusError{ErrStatus: result}
+	}
+	return errors.New(msg)
+}
+
+// createWithWebhookRetry sends a single create, retried by the webhook retry of the store. A 503 is only retried
+// when the object has a name, a create with generateName that failed after it was persisted would otherwise be
+// repeated under a new name.
+func (s *Store) createWithWebhookRetry(ctx context.Context, client dynamic.ResourceInterface, obj *unstructured.Unstructured, opts metav1.CreateOptions) (*unstructured.Unstructured, error) {
+	retryable := isWebhookRetryable
+	if obj.GetName() == "" {
+		retryable = isWebhookCallError
+	}
+
+	var resp *unstructured.Unstructured
+	err := s.webhookRetry.do(ctx, retryable, func() (err error) {
+		resp, err = client.Create(ctx, obj, opts)
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,49 @@
+// Package querydefaults applies the default query parameters of a schema to its lists and watches.
+package querydefaults
+
+import (
+	"net/url"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// Store adds every parameter of Defaults that the request does not set itself, so a client always overrides a
+// default by sending the parameter, even empty: ?labelSelector= lists everything.
+type Store struct {
+	types.Store
+	Defaults url.Values
+}
+
+func NewStore(store types.Store, defaults url.Values) types.Store {
+	return &Store{
+		Store:    store,
+		Defaults: defaults,
+	}
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	return s.Store.List(s.addDefaults(apiOp), schema)
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	return s.Store.Watch(s.addDefaults(apiOp), schema, w)
+}
+
+func (s *Store) addDefaults(apiOp *types.APIRequest) *types.APIRequest {
+	q := apiOp.Request.URL.Query()
+	changed := false
+	for key, values := range s.Defaults {
+		if _, ok := q[key]; !ok {
+			q[key] = values
+			changed = true
+		}
+	}
+	if !changed {
+		return apiOp
+	}
+
+	apiOp = apiOp.Clone()
+	apiOp.Request = apiOp.Request.Clone(apiOp.Context())
+	apiOp.Request.URL.RawQuery = q.Encode()
+	return apiOp
+}
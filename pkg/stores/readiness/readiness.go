@@ -0,0 +1,174 @@
+// Package readiness adds a uniform status.ready and status.readyMessage to objects whose kinds report their
+// effective state in different fields, such as the replica counts of a Deployment or the phase of a Pod.
+package readiness
+
+import (
+	"fmt"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Extractor reports whether the object is ready and why, from the fields its kind uses.
+type Extractor func(data map[string]interface{}) (ready bool, message string)
+
+// Store sets status.ready and status.readyMessage from the extractor on every object it returns. Both are virtual,
+// they are never sent to the apiserver because the apiserver drops unknown status fields on update.
+type Store struct {
+	types.Store
+	extractor Extractor
+}
+
+func NewStore(store types.Store, extractor Extractor) types.Store {
+	return &Store{
+		Store:     store,
+		extractor: extractor,
+	}
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	if proxy.IsRawRequest(apiOp) {
+		return obj, err
+	}
+	return s.add(obj), err
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := s.Store.List(apiOp, schema)
+	if proxy.IsRawRequest(apiOp) {
+		return list, err
+	}
+	for i := range list.Objects {
+		list.Objects[i] = s.add(list.Objects[i])
+	}
+	return list, err
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	obj, err := s.Store.Create(apiOp, schema, data)
+	return s.add(obj), err
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	obj, err := s.Store.Update(apiOp, schema, data, id)
+	return s.add(obj), err
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c, err := s.Store.Watch(apiOp, schema, w)
+	if err != nil || c == nil {
+		return c, err
+	}
+
+	result := make(chan types.APIEvent)
+	go func() {
+		defer close(result)
+		for event := range c {
+			if event.Error == nil {
+				event.Object = s.add(event.Object)
+			}
+			result <- event
+		}
+	}()
+	return result, nil
+}
+
+// add sets the readiness on a copy of the top level and status maps of obj, objects can be shared by stores.
+func (s *Store) add(obj types.APIObject) types.APIObject {
+	if obj.Object == nil {
+		return obj
+	}
+
+	original := obj.Data()
+	if original == nil {
+		return obj
+	}
+	ready, message := s.extractor(original)
+
+	copied := make(map[string]interface{}, len(original)+1)
+	for k, v := range original {
+		copied[k] = v
+	}
+	status := map[string]interface{}{}
+	for k, v := range data.Object(original).Map("status") {
+		status[k] = v
+	}
+	status["ready"] = ready
+	status["readyMessage"] = message
+	copied["status"] = status
+
+	if _, ok := obj.Object.(*unstructured.Unstructured); ok {
+		obj.Object = &unstructured.Unstructured{Object: copied}
+	} else {
+		obj.Object = copied
+	}
+	return obj
+}
+
+func condition(obj data.Object, conditionType string) (data.Object, bool) {
+	for _, c := range obj.Slice("status", "conditions") {
+		if c.String("type") == conditionType {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Deployment is ready once the latest generation was observed and every desired replica is updated and available.
+func Deployment(obj map[string]interface{}) (bool, string) {
+	d := data.Object(obj)
+	generation, _ := convert.ToNumber(d.Map("metadata")["generation"])
+	observed, _ := convert.ToNumber(d.Map("status")["observedGeneration"])
+	if observed < generation {
+		return false, "waiting for the deployment spec update to be observed"
+	}
+
+	replicas := int64(1)
+	if r, ok := d.Map("spec")["replicas"]; ok {
+		replicas, _ = convert.ToNumber(r)
+	}
+	updated, _ := convert.ToNumber(d.Map("status")["updatedReplicas"])
+	available, _ := convert.ToNumber(d.Map("status")["availableReplicas"])
+	if updated < replicas {
+		return false, fmt.Sprintf("%d of %d replicas updated", updated, replicas)
+	}
+	if available < replicas {
+		return false, fmt.Sprintf("%d of %d replicas available", available, replicas)
+	}
+	return true, fmt.Sprintf("%d of %d replicas available", available, replicas)
+}
+
+// Job is ready once it completed, a failed job is not ready and reports why.
+func Job(obj map[string]interface{}) (bool, string) {
+	d := data.Object(obj)
+	if c, ok := condition(d, "Complete"); ok && c.String("status") == "True" {
+		return true, "completed at " + d.String("status", "completionTime")
+	}
+	if c, ok := condition(d, "Failed"); ok && c.String("status") == "True" {
+		return false, "failed: " + c.String("message")
+	}
+	active, _ := convert.ToNumber(d.Map("status")["active"])
+	return false, fmt.Sprintf("running, %d active", active)
+}
+
+// Pod is ready when its Ready condition is true, or it succeeded. Otherwise the message is its phase and the
+// reason it is not ready.
+func Pod(obj map[string]interface{}) (bool, string) {
+	d := data.Object(obj)
+	phase := d.String("status", "phase")
+	if phase == "Succeeded" {
+		return true, phase
+	}
+	c, ok := condition(d, "Ready")
+	if ok && c.String("status") == "True" {
+		return true, phase
+	}
+	if ok && c.String("message") != "" {
+		return false, phase + ": " + c.String("message")
+	}
+	return false, phase
+}
@@ -0,0 +1,151 @@
+package readiness
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/jsonnumber"
+	"github.com/rancher/wrangler/pkg/data"
+)
+
+func object(t *testing.T, body string) map[string]interface{} {
+	t.Helper()
+	obj := map[string]interface{}{}
+	if err := jsonnumber.Unmarshal([]byte(body), &obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+type readinessTest struct {
+	name    string
+	obj     string
+	ready   bool
+	message string
+}
+
+func runExtractor(t *testing.T, extractor Extractor, tests []readinessTest) {
+	t.Helper()
+	for _, test := range tests {
+		ready, message := extractor(object(t, test.obj))
+		if ready != test.ready || message != test.message {
+			t.Errorf("%s: got %v %q, want %v %q", test.name, ready, message, test.ready, test.message)
+		}
+	}
+}
+
+func TestDeployment(t *testing.T) {
+	runExtractor(t, Deployment, []readinessTest{
+		{
+			name:    "available",
+			obj:     `{"metadata":{"generation":2},"spec":{"replicas":3},"status":{"observedGeneration":2,"updatedReplicas":3,"availableReplicas":3}}`,
+			ready:   true,
+			message: "3 of 3 replicas available",
+		},
+		{
+			name:    "spec not observed",
+			obj:     `{"metadata":{"generation":3},"spec":{"replicas":3},"status":{"observedGeneration":2,"updatedReplicas":3,"availableReplicas":3}}`,
+			message: "waiting for the deployment spec update to be observed",
+		},
+		{
+			name:    "rolling out",
+			obj:     `{"metadata":{"generation":2},"spec":{"replicas":3},"status":{"observedGeneration":2,"updatedReplicas":1,"availableReplicas":3}}`,
+			message: "1 of 3 replicas updated",
+		},
+		{
+			name:    "unavailable",
+			obj:     `{"metadata":{"generation":2},"spec":{"replicas":3},"status":{"observedGeneration":2,"updatedReplicas":3,"availableReplicas":2}}`,
+			message: "2 of 3 replicas available",
+		},
+		{
+			name:    "default replicas",
+			obj:     `{"metadata":{"generation":1},"status":{"observedGeneration":1,"updatedReplicas":1,"availableReplicas":1}}`,
+			ready:   true,
+			message: "1 of 1 replicas available",
+		},
+		{
+			name:    "scaled to zero",
+			obj:     `{"metadata":{"generation":1},"spec":{"replicas":0},"status":{"observedGeneration":1}}`,
+			ready:   true,
+			message: "0 of 0 replicas available",
+		},
+	})
+}
+
+func TestJob(t *testing.T) {
+	runExtractor(t, Job, []readinessTest{
+		{
+			name:    "complete",
+			obj:     `{"status":{"completionTime":"2021-09-01T10:00:00Z","conditions":[{"type":"Complete","status":"True"}]}}`,
+			ready:   true,
+			message: "completed at 2021-09-01T10:00:00Z",
+		},
+		{
+			name:    "failed",
+			obj:     `{"status":{"conditions":[{"type":"Failed","status":"True","message":"Job has reached the specified backoff limit"}]}}`,
+			message: "failed: Job has reached the specified backoff limit",
+		},
+		{
+			name:    "running",
+			obj:     `{"status":{"active":2}}`,
+			message: "running, 2 active",
+		},
+	})
+}
+
+func TestPod(t *testing.T) {
+	runExtractor(t, Pod, []readinessTest{
+		{
+			name:    "ready",
+			obj:     `{"status":{"phase":"Running","conditions":[{"type":"Ready","status":"True"}]}}`,
+			ready:   true,
+			message: "Running",
+		},
+		{
+			name:    "succeeded",
+			obj:     `{"status":{"phase":"Succeeded","conditions":[{"type":"Ready","status":"False"}]}}`,
+			ready:   true,
+			message: "Succeeded",
+		},
+		{
+			name:    "not ready",
+			obj:     `{"status":{"phase":"Running","conditions":[{"type":"Ready","status":"False","message":"containers with unready status: [web]"}]}}`,
+			message: "Running: containers with unready status: [web]",
+		},
+		{
+			name:    "pending",
+			obj:     `{"status":{"phase":"Pending"}}`,
+			message: "Pending",
+		},
+	})
+}
+
+// podStore returns one pod, shared by every call like a cache would.
+type podStore struct {
+	types.Store
+	obj map[string]interface{}
+}
+
+func (p *podStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	return types.APIObject{ID: id, Object: p.obj}, nil
+}
+
+func TestStoreAddsVirtualFields(t *testing.T) {
+	shared := object(t, `{"status":{"phase":"Pending"}}`)
+	store := NewStore(&podStore{obj: shared}, Pod)
+
+	apiOp := &types.APIRequest{Request: httptest.NewRequest(http.MethodGet, "/v1/pods/default/web", nil)}
+	obj, err := store.ByID(apiOp, nil, "default/web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := obj.Data().Map("status")
+	if status["ready"] != false || status["readyMessage"] != "Pending" || status["phase"] != "Pending" {
+		t.Errorf("got status %v, want the readiness next to the phase", status)
+	}
+	if _, ok := data.Object(shared).Map("status")["ready"]; ok {
+		t.Error("the readiness was added to the object of the wrapped store")
+	}
+}
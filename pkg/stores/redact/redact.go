@@ -0,0 +1,171 @@
+package redact
+
+import (
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Store applies the FieldRedactionPolicy of the schema to every object it returns. A rule applies unless the
+// access set of the request grants its verb on the object, a request without an access set is always redacted.
+// Tables are returned only when none of their rows needs redacting.
+type Store struct {
+	types.Store
+}
+
+func NewStore(store types.Store) types.Store {
+	return &Store{
+		Store: store,
+	}
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	if err != nil {
+		return obj, err
+	}
+	return redact(apiOp, schema, obj), nil
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := s.Store.List(apiOp, schema)
+	if err != nil {
+		return list, err
+	}
+	for i, obj := range list.Objects {
+		if proxy.IsTable(obj.Data()) {
+			if err := checkTable(apiOp, schema, obj); err != nil {
+				return types.APIObjectList{}, err
+			}
+			continue
+		}
+		list.Objects[i] = redact(apiOp, schema, obj)
+	}
+	return list, nil
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	obj, err := s.Store.Create(apiOp, schema, data)
+	if err != nil {
+		return obj, err
+	}
+	return redact(apiOp, schema, obj), nil
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	obj, err := s.Store.Update(apiOp, schema, data, id)
+	if err != nil {
+		return obj, err
+	}
+	return redact(apiOp, schema, obj), nil
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.Delete(apiOp, schema, id)
+	if err != nil {
+		return obj, err
+	}
+	return redact(apiOp, schema, obj), nil
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c, err := s.Store.Watch(apiOp, schema, w)
+	if err != nil || c == nil {
+		return c, err
+	}
+
+	result := make(chan types.APIEvent)
+	go func() {
+		defer close(result)
+		for event := range c {
+			if event.Error == nil {
+				event.Object = redact(apiOp, schema, event.Object)
+			}
+			result <- event
+		}
+	}()
+	return result, nil
+}
+
+// redact returns obj with the fields the user may not see replaced. obj is copied before it is changed because
+// stores can share the objects they return between requests.
+func redact(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject) types.APIObject {
+	if obj.Object == nil {
+		return obj
+	}
+
+	rules := hiddenRules(apiOp, schema, obj)
+	if len(rules) == 0 {
+		return obj
+	}
+
+	var m map[string]interface{}
+	if u, ok := obj.Object.(*unstructured.Unstructured); ok {
+		u = u.DeepCopy()
+		obj.Object, m = u, u.Object
+	} else {
+		copied, err := convert.EncodeToMap(obj.Object)
+		if err != nil {
+			// never return a field that should be hidden
+			obj.Object = map[string]interface{}{}
+			return obj
+		}
+		obj.Object, m = copied, copied
+	}
+
+	for _, rule := range rules {
+		path := strings.Split(rule.Path, ".")
+		if _, ok := data.GetValue(m, path...); ok {
+			data.PutValue(m, rule.Replacement, path...)
+		}
+	}
+	return obj
+}
+
+// checkTable fails a Table that has a row the user would see redacted. The cells of a row are rendered by the
+// apiserver and can't be mapped back to field paths, so such a Table is refused instead of returned redacted.
+func checkTable(apiOp *types.APIRequest, schema *types.APISchema, table types.APIObject) error {
+	if len(attributes.FieldRedactionPolicy(schema)) == 0 {
+		return nil
+	}
+
+	rows, _ := table.Data()["rows"].([]interface{})
+	for _, row := range rows {
+		m, _ := row.(map[string]interface{})
+		object, _ := m["object"].(map[string]interface{})
+		if len(hiddenRules(apiOp, schema, types.APIObject{Object: object})) > 0 {
+			return apierror.NewAPIError(proxy.ErrNotAcceptable, schema.ID+" can not be listed as a Table, it has redacted fields")
+		}
+	}
+	return nil
+}
+
+func hiddenRules(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject) (result []attributes.RedactionRule) {
+	rules := attributes.FieldRedactionPolicy(schema)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var accessSet *accesscontrol.AccessSet
+	if apiOp.Schemas != nil {
+		accessSet, _ = apiOp.Schemas.Attributes["accessSet"].(*accesscontrol.AccessSet)
+	}
+
+	meta := obj.Data()
+	gr := attributes.GR(schema)
+	namespace, name := meta.String("metadata", "namespace"), meta.String("metadata", "name")
+
+	for _, rule := range rules {
+		if accessSet == nil || !accessSet.Grants(rule.RequiredVerb, gr, namespace, name) {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
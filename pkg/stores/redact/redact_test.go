@@ -0,0 +1,206 @@
+package redact
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/fake"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newSchema() *types.APISchema {
+	s := &types.APISchema{Schema: &schemas.Schema{ID: "secret"}}
+	attributes.SetGVR(s, schema.GroupVersionResource{Version: "v1", Resource: "secrets"})
+	attributes.SetFieldRedactionPolicy(s, []attributes.RedactionRule{
+		{Path: "data.password", RequiredVerb: "update", Replacement: "***"},
+	})
+	return s
+}
+
+// newRequest returns a request for a user that is granted update on every secret if admin is true, and only
+// get otherwise.
+func newRequest(admin bool) *types.APIRequest {
+	accessSet := &accesscontrol.AccessSet{}
+	gr := schema.GroupResource{Resource: "secrets"}
+	accessSet.Add("get", gr, accesscontrol.Access{Namespace: accesscontrol.All, ResourceName: accesscontrol.All})
+	if admin {
+		accessSet.Add("update", gr, accesscontrol.Access{Namespace: accesscontrol.All, ResourceName: accesscontrol.All})
+	}
+	return &types.APIRequest{
+		Schemas: &types.APISchemas{
+			Attributes: map[string]interface{}{"accessSet": accessSet},
+		},
+	}
+}
+
+func secret(name, password string) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"namespace": "default", "name": name},
+		"data":     map[string]interface{}{"password": password},
+	}
+}
+
+func table(objects ...map[string]interface{}) types.APIObject {
+	var rows []interface{}
+	for _, object := range objects {
+		rows = append(rows, map[string]interface{}{
+			"cells":  []interface{}{object["metadata"].(map[string]interface{})["name"], object["data"]},
+			"object": object,
+		})
+	}
+	return types.APIObject{
+		Type: "secret",
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"kind":       "Table",
+			"apiVersion": "meta.k8s.io/v1",
+			"rows":       rows,
+		}},
+	}
+}
+
+func password(obj types.APIObject) string {
+	return obj.Data().String("data", "password")
+}
+
+func TestByID(t *testing.T) {
+	tests := []struct {
+		name  string
+		admin bool
+		want  string
+	}{
+		{name: "admin", admin: true, want: "hunter2"},
+		{name: "regular user", admin: false, want: "***"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: secret("s1", "hunter2")}
+			next := fake.NewFakeStore().OnByID("default/s1", types.APIObject{Type: "secret", Object: obj}, nil)
+
+			result, err := NewStore(next).ByID(newRequest(test.admin), newSchema(), "default/s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := password(result); got != test.want {
+				t.Errorf("password = %q, want %q", got, test.want)
+			}
+			if got := obj.Object["data"].(map[string]interface{})["password"]; got != "hunter2" {
+				t.Errorf("the object of the store was changed, password = %v", got)
+			}
+		})
+	}
+}
+
+func TestByIDWithoutAccessSet(t *testing.T) {
+	obj := types.APIObject{Type: "secret", Object: &unstructured.Unstructured{Object: secret("s1", "hunter2")}}
+	next := fake.NewFakeStore().OnByID("default/s1", obj, nil)
+
+	result, err := NewStore(next).ByID(&types.APIRequest{}, newSchema(), "default/s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := password(result); got != "***" {
+		t.Errorf("password = %q, want it redacted", got)
+	}
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name  string
+		admin bool
+		want  string
+	}{
+		{name: "admin", admin: true, want: "hunter2"},
+		{name: "regular user", admin: false, want: "***"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := fake.NewFakeStore().OnList(types.APIObjectList{
+				Objects: []types.APIObject{
+					{Type: "secret", Object: &unstructured.Unstructured{Object: secret("s1", "hunter2")}},
+					{Type: "secret", Object: &unstructured.Unstructured{Object: secret("s2", "hunter2")}},
+				},
+			}, nil)
+
+			list, err := NewStore(next).List(newRequest(test.admin), newSchema())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, obj := range list.Objects {
+				if got := password(obj); got != test.want {
+					t.Errorf("%s: password = %q, want %q", obj.Name(), got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListTable(t *testing.T) {
+	next := fake.NewFakeStore().OnList(types.APIObjectList{
+		Objects: []types.APIObject{table(secret("s1", "hunter2"), secret("s2", "hunter2"))},
+	}, nil)
+
+	list, err := NewStore(next).List(newRequest(true), newSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Objects) != 1 {
+		t.Fatalf("got %d objects, want the Table only", len(list.Objects))
+	}
+	rows, _ := list.Objects[0].Data()["rows"].([]interface{})
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+}
+
+func TestListTableRefusedForRegularUser(t *testing.T) {
+	next := fake.NewFakeStore().OnList(types.APIObjectList{
+		Objects: []types.APIObject{table(secret("s1", "hunter2"))},
+	}, nil)
+
+	list, err := NewStore(next).List(newRequest(false), newSchema())
+	if err == nil {
+		t.Fatalf("got %d objects, want the Table refused", len(list.Objects))
+	}
+	apiErr, ok := err.(*apierror.APIError)
+	if !ok || apiErr.Code.Status != http.StatusNotAcceptable {
+		t.Errorf("got error %v, want %d", err, http.StatusNotAcceptable)
+	}
+}
+
+func TestListTableWithoutPolicy(t *testing.T) {
+	s := newSchema()
+	attributes.SetFieldRedactionPolicy(s, nil)
+	next := fake.NewFakeStore().OnList(types.APIObjectList{
+		Objects: []types.APIObject{table(secret("s1", "hunter2"))},
+	}, nil)
+
+	if _, err := NewStore(next).List(newRequest(false), s); err != nil {
+		t.Errorf("got error %v for a schema without a redaction policy", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c := make(chan types.APIEvent, 1)
+	c <- types.APIEvent{
+		Name:   "resource.change",
+		Object: types.APIObject{Type: "secret", Object: &unstructured.Unstructured{Object: secret("s1", "hunter2")}},
+	}
+	close(c)
+	next := fake.NewFakeStore().OnWatch(c, nil)
+
+	result, err := NewStore(next).Watch(newRequest(false), newSchema(), types.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for event := range result {
+		if got := password(event.Object); got != "***" {
+			t.Errorf("password = %q, want it redacted", got)
+		}
+	}
+}
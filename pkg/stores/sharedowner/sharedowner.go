@@ -0,0 +1,166 @@
+// Package sharedowner lets the users named in an annotation of an object read and change it beyond what RBAC
+// grants them, for resources shared by several users in a tenant namespace.
+package sharedowner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/slice"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// Store retries a get, update, patch or delete that RBAC forbade with the access of steve when the object names the
+// user in the owner annotation of a policy that allows the verb. Lists and watches are not extended, owners only
+// reach the objects they don't have RBAC access to by name.
+type Store struct {
+	types.Store
+	policies []attributes.OwnerPolicy
+}
+
+func NewStore(store types.Store, policies []attributes.OwnerPolicy) types.Store {
+	return &Store{
+		Store:    store,
+		policies: policies,
+	}
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	if !isForbidden(err) {
+		return obj, err
+	}
+	owned, ok := s.owned(apiOp, schema, id, "get")
+	if !ok {
+		return obj, err
+	}
+	return owned, nil
+}
+
+// Update retries with the access of steve only for the object the ownership was checked on. The proxy store writes
+// to the namespace and name in the body, so a body naming another object is refused, it would otherwise let an owner
+// write any object as steve. The body of a PATCH is kept so the retry sends it again.
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	body, err := bufferBody(apiOp)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+
+	obj, err := s.Store.Update(apiOp, schema, data, id)
+	if !isForbidden(err) {
+		return obj, err
+	}
+	verb := "update"
+	if apiOp.Method == http.MethodPatch {
+		verb = "patch"
+	}
+	if !sameObject(apiOp, data, id) {
+		return obj, err
+	}
+	if _, ok := s.owned(apiOp, schema, id, verb); !ok {
+		return obj, err
+	}
+
+	retryOp := adminOp(apiOp)
+	if body != nil {
+		retryOp.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return s.Store.Update(retryOp, schema, data, id)
+}
+
+// sameObject is whether the namespace and name in data, where set, are the ones of the request.
+func sameObject(apiOp *types.APIRequest, data types.APIObject, id string) bool {
+	namespace, name := apiOp.Namespace, id
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		namespace, name = id[:i], id[i+1:]
+	}
+	input := data.Data()
+	if ns := types.Namespace(input); ns != "" && ns != namespace {
+		return false
+	}
+	if n := input.String("metadata", "name"); n != "" && n != name {
+		return false
+	}
+	return true
+}
+
+// bufferBody reads the body of a PATCH and puts it back for the first attempt, it returns nil for other requests.
+func bufferBody(apiOp *types.APIRequest) ([]byte, error) {
+	if apiOp.Method != http.MethodPatch || apiOp.Request == nil || apiOp.Request.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(apiOp.Request.Body)
+	apiOp.Request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	apiOp.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.Delete(apiOp, schema, id)
+	if !isForbidden(err) {
+		return obj, err
+	}
+	if _, ok := s.owned(apiOp, schema, id, "delete"); !ok {
+		return obj, err
+	}
+	return s.Store.Delete(adminOp(apiOp), schema, id)
+}
+
+// owned reads the object with the access of steve and returns it if a policy allowing verb names the user as
+// an owner.
+func (s *Store) owned(apiOp *types.APIRequest, schema *types.APISchema, id, verb string) (types.APIObject, bool) {
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok || !s.allows(verb) {
+		return types.APIObject{}, false
+	}
+
+	getOp := adminOp(apiOp)
+	getOp.Method = http.MethodGet
+	obj, err := s.Store.ByID(getOp, schema, id)
+	if err != nil {
+		return types.APIObject{}, false
+	}
+
+	annotations := obj.Data().Map("metadata").Map("annotations")
+	for _, policy := range s.policies {
+		if !slice.ContainsString(policy.AllowedVerbs, verb) {
+			continue
+		}
+		for _, owner := range strings.Split(annotations.String(policy.OwnerAnnotation), ",") {
+			if strings.TrimSpace(owner) == user.GetName() {
+				return obj, true
+			}
+		}
+	}
+	return types.APIObject{}, false
+}
+
+func (s *Store) allows(verb string) bool {
+	for _, policy := range s.policies {
+		if slice.ContainsString(policy.AllowedVerbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func adminOp(apiOp *types.APIRequest) *types.APIRequest {
+	return apiOp.WithContext(proxy.WithAdminAccess(apiOp.Context()))
+}
+
+func isForbidden(err error) bool {
+	if apiErr, ok := err.(*apierror.APIError); ok {
+		return apiErr.Code.Status == http.StatusForbidden
+	}
+	return apierrors.IsForbidden(err)
+}
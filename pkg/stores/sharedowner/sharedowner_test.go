@@ -0,0 +1,140 @@
+package sharedowner
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/fake"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+var widgets = &types.APISchema{
+	Schema: &schemas.Schema{ID: "widget"},
+}
+
+// rbacStore forbids every write made without admin access and records the body of the admin writes.
+type rbacStore struct {
+	*fake.FakeStore
+	adminUpdates []string
+	adminBodies  []string
+}
+
+func (r *rbacStore) Update(apiOp *types.APIRequest, s *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	var body []byte
+	if apiOp.Request.Body != nil {
+		body, _ = ioutil.ReadAll(apiOp.Request.Body)
+	}
+	if !proxy.HasAdminAccess(apiOp.Context()) {
+		return types.APIObject{}, apierrors.NewForbidden(schema.GroupResource{Resource: "widgets"}, id, nil)
+	}
+	r.adminUpdates = append(r.adminUpdates, id)
+	r.adminBodies = append(r.adminBodies, string(body))
+	return data, nil
+}
+
+func newStore(owner string) (*Store, *rbacStore) {
+	backing := &rbacStore{FakeStore: fake.NewFakeStore()}
+	backing.OnByID("foo", object("tenant-a", "foo", owner), nil)
+	store := NewStore(backing, []attributes.OwnerPolicy{{
+		OwnerAnnotation: "example.com/owners",
+		AllowedVerbs:    []string{"update", "patch"},
+	}}).(*Store)
+	return store, backing
+}
+
+func object(namespace, name, owners string) types.APIObject {
+	return types.APIObject{
+		ID: namespace + "/" + name,
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+				"annotations": map[string]interface{}{
+					"example.com/owners": owners,
+				},
+			},
+		}},
+	}
+}
+
+func newRequest(method, body string) *types.APIRequest {
+	req := httptest.NewRequest(method, "/v1/widgets/tenant-a/foo", strings.NewReader(body))
+	req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "alice"}))
+	return &types.APIRequest{
+		Method:    method,
+		Namespace: "tenant-a",
+		Request:   req,
+		Response:  httptest.NewRecorder(),
+	}
+}
+
+func TestUpdateRetriesAsAdminForOwner(t *testing.T) {
+	store, backing := newStore("bob, alice")
+
+	_, err := store.Update(newRequest(http.MethodPut, ""), widgets, object("tenant-a", "foo", "bob, alice"), "foo")
+	if err != nil {
+		t.Fatalf("update by owner: %v", err)
+	}
+	if len(backing.adminUpdates) != 1 {
+		t.Fatalf("expected one admin update, got %v", backing.adminUpdates)
+	}
+}
+
+func TestUpdateRefusesNonOwner(t *testing.T) {
+	store, backing := newStore("bob")
+
+	_, err := store.Update(newRequest(http.MethodPut, ""), widgets, object("tenant-a", "foo", "bob"), "foo")
+	if !apierrors.IsForbidden(err) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+	if len(backing.adminUpdates) != 0 {
+		t.Fatalf("expected no admin update, got %v", backing.adminUpdates)
+	}
+}
+
+func TestUpdateRefusesBodyForOtherObject(t *testing.T) {
+	tests := []struct {
+		name string
+		data types.APIObject
+	}{
+		{name: "other namespace", data: object("kube-system", "foo", "alice")},
+		{name: "other name", data: object("tenant-a", "bar", "alice")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, backing := newStore("alice")
+
+			_, err := store.Update(newRequest(http.MethodPut, ""), widgets, tt.data, "foo")
+			if !apierrors.IsForbidden(err) {
+				t.Fatalf("expected forbidden, got %v", err)
+			}
+			if len(backing.adminUpdates) != 0 {
+				t.Fatalf("expected no admin update, got %v", backing.adminUpdates)
+			}
+		})
+	}
+}
+
+func TestPatchRetrySendsBodyAgain(t *testing.T) {
+	store, backing := newStore("alice")
+	patch := `{"spec":{"size":"large"}}`
+
+	_, err := store.Update(newRequest(http.MethodPatch, patch), widgets, types.APIObject{Object: map[string]interface{}{}}, "foo")
+	if err != nil {
+		t.Fatalf("patch by owner: %v", err)
+	}
+	if len(backing.adminBodies) != 1 || backing.adminBodies[0] != patch {
+		t.Fatalf("expected the admin retry to send %s, got %q", patch, backing.adminBodies)
+	}
+}
@@ -0,0 +1,153 @@
+package tenant
+
+import (
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// NamespaceResolver returns the namespaces the requesting user is confined to. If ok is false the user is not a
+// tenant and requests are not restricted.
+type NamespaceResolver interface {
+	TenantNamespaces(apiOp *types.APIRequest) (namespaces []string, ok bool, err error)
+}
+
+type NamespaceResolverFunc func(apiOp *types.APIRequest) ([]string, bool, error)
+
+func (n NamespaceResolverFunc) TenantNamespaces(apiOp *types.APIRequest) ([]string, bool, error) {
+	return n(apiOp)
+}
+
+// UserExtraResolver confines users that have the given key in their authentication extras to the namespaces
+// listed as its values.
+func UserExtraResolver(key string) NamespaceResolver {
+	return NamespaceResolverFunc(func(apiOp *types.APIRequest) ([]string, bool, error) {
+		user, ok := request.UserFrom(apiOp.Context())
+		if !ok {
+			return nil, false, nil
+		}
+		namespaces, ok := user.GetExtra()[key]
+		return namespaces, ok, nil
+	})
+}
+
+// StoreFactory returns a function for Template.StoreFactory that confines the template's store to the tenant
+// namespaces returned by resolver.
+func StoreFactory(resolver NamespaceResolver) func(types.Store) types.Store {
+	return func(store types.Store) types.Store {
+		return NewStore(store, resolver)
+	}
+}
+
+// Store rejects any request for a namespaced schema that targets a namespace outside the tenant's namespaces and
+// limits lists and watches across all namespaces to the tenant's namespaces. This applies on top of RBAC, it
+// never grants access. Cluster scoped schemas are passed through.
+type Store struct {
+	types.Store
+	resolver NamespaceResolver
+}
+
+func NewStore(store types.Store, resolver NamespaceResolver) *Store {
+	return &Store{
+		Store:    store,
+		resolver: resolver,
+	}
+}
+
+func (s *Store) namespaces(apiOp *types.APIRequest, schema *types.APISchema) (sets.String, bool, error) {
+	if !attributes.Namespaced(schema) {
+		return nil, false, nil
+	}
+	namespaces, ok, err := s.resolver.TenantNamespaces(apiOp)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return sets.NewString(namespaces...), true, nil
+}
+
+func (s *Store) check(apiOp *types.APIRequest, schema *types.APISchema, namespace string) error {
+	allowed, restricted, err := s.namespaces(apiOp, schema)
+	if err != nil || !restricted {
+		return err
+	}
+	if !allowed.Has(namespace) {
+		return apierror.NewAPIError(validation.PermissionDenied, "namespace "+namespace+" is outside of the tenant's namespaces")
+	}
+	return nil
+}
+
+// constrain returns a copy of apiOp limited to the tenant's namespaces for requests across all namespaces.
+func (s *Store) constrain(apiOp *types.APIRequest, schema *types.APISchema) (*types.APIRequest, error) {
+	allowed, restricted, err := s.namespaces(apiOp, schema)
+	if err != nil || !restricted {
+		return apiOp, err
+	}
+
+	if apiOp.Namespace != "" {
+		if !allowed.Has(apiOp.Namespace) {
+			return nil, apierror.NewAPIError(validation.PermissionDenied, "namespace "+apiOp.Namespace+" is outside of the tenant's namespaces")
+		}
+		return apiOp, nil
+	}
+
+	apiOp = apiOp.Clone()
+	apiOp.Request = proxy.IntersectNamespaceConstraint(apiOp.Request, allowed.List()...)
+	return apiOp, nil
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	if err := s.check(apiOp, schema, apiOp.Namespace); err != nil {
+		return types.APIObject{}, err
+	}
+	return s.Store.ByID(apiOp, schema, id)
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	apiOp, err := s.constrain(apiOp, schema)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+	return s.Store.List(apiOp, schema)
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	apiOp, err := s.constrain(apiOp, schema)
+	if err != nil {
+		return nil, err
+	}
+	return s.Store.Watch(apiOp, schema, w)
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	ns := types.Namespace(data.Data())
+	if ns == "" {
+		ns = apiOp.Namespace
+	}
+	if err := s.check(apiOp, schema, ns); err != nil {
+		return types.APIObject{}, err
+	}
+	return s.Store.Create(apiOp, schema, data)
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	if err := s.check(apiOp, schema, apiOp.Namespace); err != nil {
+		return types.APIObject{}, err
+	}
+	if ns := types.Namespace(data.Data()); ns != "" && ns != apiOp.Namespace {
+		if err := s.check(apiOp, schema, ns); err != nil {
+			return types.APIObject{}, err
+		}
+	}
+	return s.Store.Update(apiOp, schema, data, id)
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	if err := s.check(apiOp, schema, apiOp.Namespace); err != nil {
+		return types.APIObject{}, err
+	}
+	return s.Store.Delete(apiOp, schema, id)
+}
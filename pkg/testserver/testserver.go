@@ -0,0 +1,305 @@
+// Package testserver starts the steve HTTP routes in process, backed by a memory store, so tests can exercise the
+// full HTTP layer, routing, request parsing and response writing, without a Kubernetes cluster.
+//
+//	func TestWidgets(t *testing.T) {
+//		ts, err := testserver.NewTestServer(t, widgetSchema)
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		defer ts.Close()
+//
+//		ts.AssertCreate("widget", map[string]interface{}{
+//			"metadata": map[string]interface{}{"name": "a", "namespace": "default"},
+//			"spec":     map[string]interface{}{"size": "large"},
+//		})
+//		ts.AssertGet("widget", "default/a", map[string]interface{}{
+//			"spec": map[string]interface{}{"size": "large"},
+//		})
+//	}
+package testserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/builtin"
+	apiserver "github.com/rancher/apiserver/pkg/server"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/server/router"
+	"github.com/rancher/steve/pkg/stores/memory"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// TestServer serves the schemas it was created with at URL. Requests are made as an admin user.
+type TestServer struct {
+	// URL is the base URL of the server, without a trailing slash.
+	URL string
+	// Client is the client the helpers send requests with.
+	Client *http.Client
+	// Store is the memory store serving the schemas that were created without a store.
+	Store types.Store
+
+	t      *testing.T
+	server *httptest.Server
+}
+
+// NewTestServer starts a server for schemas. Schemas without a store are served by a shared memory store, schemas
+// without methods get every collection and resource method. The schemas are copied, they can be reused. The
+// server must be closed with Close.
+func NewTestServer(t *testing.T, schemas ...*types.APISchema) (*TestServer, error) {
+	store := memory.NewMemoryStore()
+
+	apiSchemas := types.EmptyAPISchemas()
+	if err := apiSchemas.AddSchemas(builtin.Schemas); err != nil {
+		return nil, err
+	}
+	for _, schema := range schemas {
+		schema = schema.DeepCopy()
+		if schema.Store == nil {
+			schema.Store = store
+		}
+		if len(schema.CollectionMethods) == 0 && len(schema.ResourceMethods) == 0 {
+			schema.CollectionMethods = []string{http.MethodGet, http.MethodPost}
+			schema.ResourceMethods = []string{http.MethodGet, http.MethodPut, http.MethodDelete}
+		}
+		if err := apiSchemas.AddSchema(*schema); err != nil {
+			return nil, fmt.Errorf("adding schema %s: %w", schema.ID, err)
+		}
+	}
+
+	server := apiserver.DefaultAPIServer()
+	api := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{
+			Name:   "admin",
+			Groups: []string{user.SystemPrivilegedGroup, user.AllAuthenticated},
+		}))
+		urlBuilder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		apiOp := &types.APIRequest{
+			Schemas:    apiSchemas,
+			Request:    req,
+			Response:   rw,
+			URLBuilder: urlBuilder,
+		}
+		parseVars(apiOp)
+		server.Handle(apiOp)
+	})
+
+	httpServer := httptest.NewServer(router.Routes(router.Handlers{
+		K8sResource: api,
+		APIRoot:     api,
+		K8sProxy:    http.NotFoundHandler(),
+		Next:        http.NotFoundHandler(),
+	}))
+	return &TestServer{
+		URL:    httpServer.URL,
+		Client: httpServer.Client(),
+		Store:  store,
+		t:      t,
+		server: httpServer,
+	}, nil
+}
+
+// parseVars sets the type, namespace and name of apiOp from the route variables the way the steve handler does.
+func parseVars(apiOp *types.APIRequest) {
+	vars := mux.Vars(apiOp.Request)
+	apiOp.Type = vars["type"]
+	apiOp.Name = vars["name"]
+	apiOp.Namespace = vars["namespace"]
+	if nameOrNamespace := vars["nameorns"]; nameOrNamespace != "" {
+		if attributes.Namespaced(apiOp.Schemas.LookupSchema(apiOp.Type)) {
+			apiOp.Namespace = nameOrNamespace
+		} else {
+			apiOp.Name = nameOrNamespace
+		}
+	}
+}
+
+// Close stops the server.
+func (s *TestServer) Close() {
+	s.server.Close()
+}
+
+// ObjectURL returns the URL of the object of the schema with the given id, namespace/name for namespaced objects.
+// An empty id returns the URL of the collection.
+func (s *TestServer) ObjectURL(schemaID, id string) string {
+	if id == "" {
+		return s.URL + "/v1/" + schemaID
+	}
+	return s.URL + "/v1/" + schemaID + "/" + id
+}
+
+// Do sends a request with body, encoded as JSON unless it is nil, to url and returns the status code and the
+// decoded JSON response, nil when the response is empty.
+func (s *TestServer) Do(method, url string, body interface{}) (int, map[string]interface{}, error) {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return resp.StatusCode, nil, nil
+	}
+	result := map[string]interface{}{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("decoding response %q: %w", data, err)
+	}
+	return resp.StatusCode, result, nil
+}
+
+// expectStatus sends the request and fails the test unless the response has one of the statuses.
+func (s *TestServer) expectStatus(method, url string, body interface{}, statuses ...int) map[string]interface{} {
+	s.t.Helper()
+	code, result, err := s.Do(method, url, body)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, url, err)
+	}
+	for _, status := range statuses {
+		if code == status {
+			return result
+		}
+	}
+	s.t.Fatalf("%s %s: got status %d, want %v: %v", method, url, code, statuses, result)
+	return nil
+}
+
+// Get returns the object of the schema with the given id and fails the test unless it exists.
+func (s *TestServer) Get(schemaID, id string) map[string]interface{} {
+	s.t.Helper()
+	return s.expectStatus(http.MethodGet, s.ObjectURL(schemaID, id), nil, http.StatusOK)
+}
+
+// AssertGet fails the test unless the object of the schema with the given id exists and has every field of
+// expected. Maps in expected are compared by their keys, fields missing from expected are not checked.
+func (s *TestServer) AssertGet(schemaID, id string, expected map[string]interface{}) {
+	s.t.Helper()
+	s.assertFields(schemaID+" "+id, s.Get(schemaID, id), expected)
+}
+
+// AssertNotFound fails the test unless getting the object of the schema with the given id returns 404.
+func (s *TestServer) AssertNotFound(schemaID, id string) {
+	s.t.Helper()
+	s.expectStatus(http.MethodGet, s.ObjectURL(schemaID, id), nil, http.StatusNotFound)
+}
+
+// AssertCreate creates obj and fails the test unless it succeeds. It returns the created object.
+func (s *TestServer) AssertCreate(schemaID string, obj map[string]interface{}) map[string]interface{} {
+	s.t.Helper()
+	return s.expectStatus(http.MethodPost, s.ObjectURL(schemaID, ""), obj, http.StatusCreated, http.StatusOK)
+}
+
+// AssertUpdate replaces the object of the schema with the given id with obj and fails the test unless it
+// succeeds. It returns the updated object.
+func (s *TestServer) AssertUpdate(schemaID, id string, obj map[string]interface{}) map[string]interface{} {
+	s.t.Helper()
+	return s.expectStatus(http.MethodPut, s.ObjectURL(schemaID, id), obj, http.StatusOK)
+}
+
+// AssertDelete deletes the object of the schema with the given id and fails the test unless it succeeds.
+func (s *TestServer) AssertDelete(schemaID, id string) {
+	s.t.Helper()
+	s.expectStatus(http.MethodDelete, s.ObjectURL(schemaID, id), nil, http.StatusOK, http.StatusNoContent)
+}
+
+// AssertList fails the test unless listing the schema returns exactly the objects with the given ids, in any order.
+func (s *TestServer) AssertList(schemaID string, ids ...string) {
+	s.t.Helper()
+	result := s.expectStatus(http.MethodGet, s.ObjectURL(schemaID, ""), nil, http.StatusOK)
+
+	var got []string
+	items, _ := result["data"].([]interface{})
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			got = append(got, fmt.Sprint(obj["id"]))
+		}
+	}
+	want := append([]string(nil), ids...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		s.t.Errorf("list %s: got ids [%s], want [%s]", schemaID, strings.Join(got, ", "), strings.Join(want, ", "))
+	}
+}
+
+// assertFields reports every field of expected that differs in actual. expected is round tripped through JSON so
+// its numbers compare equal to the decoded response.
+func (s *TestServer) assertFields(name string, actual, expected map[string]interface{}) {
+	s.t.Helper()
+	data, err := json.Marshal(expected)
+	if err != nil {
+		s.t.Fatalf("%s: encoding expected fields: %v", name, err)
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		s.t.Fatalf("%s: decoding expected fields: %v", name, err)
+	}
+	for _, diff := range diffFields("", actual, normalized) {
+		s.t.Errorf("%s: %s", name, diff)
+	}
+}
+
+func diffFields(path string, actual, expected map[string]interface{}) []string {
+	var diffs []string
+	for key, want := range expected {
+		field := key
+		if path != "" {
+			field = path + "." + key
+		}
+		got, ok := actual[key]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s is missing, want %v", field, want))
+			continue
+		}
+		wantMap, wantIsMap := want.(map[string]interface{})
+		gotMap, gotIsMap := got.(map[string]interface{})
+		if wantIsMap && gotIsMap {
+			diffs = append(diffs, diffFields(field, gotMap, wantMap)...)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			diffs = append(diffs, fmt.Sprintf("%s is %v, want %v", field, got, want))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
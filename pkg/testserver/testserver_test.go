@@ -0,0 +1,122 @@
+package testserver
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/fake"
+	"github.com/rancher/wrangler/pkg/schemas"
+)
+
+func newWidgetSchema() *types.APISchema {
+	s := &types.APISchema{Schema: &schemas.Schema{ID: "widget"}}
+	attributes.SetNamespaced(s, true)
+	return s
+}
+
+func widget(size string) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "a", "namespace": "default"},
+		"spec":     map[string]interface{}{"size": size, "count": 3},
+	}
+}
+
+func TestCRUDRoundTrip(t *testing.T) {
+	ts, err := NewTestServer(t, newWidgetSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	created := ts.AssertCreate("widget", widget("large"))
+	if created["id"] != "default/a" {
+		t.Errorf("got id %v, want default/a", created["id"])
+	}
+	ts.AssertGet("widget", "default/a", widget("large"))
+	ts.AssertList("widget", "default/a")
+	if _, list, err := ts.Do(http.MethodGet, ts.ObjectURL("widget", "other"), nil); err != nil {
+		t.Fatal(err)
+	} else if data, _ := list["data"].([]interface{}); len(data) != 0 {
+		t.Errorf("got %v for the namespace other, want an empty list", data)
+	}
+
+	obj := ts.Get("widget", "default/a")
+	obj["spec"] = map[string]interface{}{"size": "small", "count": 3}
+	ts.AssertUpdate("widget", "default/a", obj)
+	ts.AssertGet("widget", "default/a", map[string]interface{}{
+		"spec": map[string]interface{}{"size": "small"},
+	})
+
+	ts.AssertDelete("widget", "default/a")
+	ts.AssertNotFound("widget", "default/a")
+	ts.AssertList("widget")
+}
+
+func TestDoReportsStatuses(t *testing.T) {
+	ts, err := NewTestServer(t, newWidgetSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	ts.AssertCreate("widget", widget("large"))
+	if code, _, err := ts.Do(http.MethodPost, ts.ObjectURL("widget", ""), widget("large")); err != nil || code != http.StatusConflict {
+		t.Errorf("got %d %v for a duplicate create, want 409", code, err)
+	}
+	if code, _, err := ts.Do(http.MethodGet, ts.ObjectURL("gadget", ""), nil); err != nil || code != http.StatusNotFound {
+		t.Errorf("got %d %v for an unknown schema, want 404", code, err)
+	}
+}
+
+func TestSchemasWithAStoreKeepIt(t *testing.T) {
+	store := fake.NewFakeStore().OnByID("a", types.APIObject{
+		Type:   "gadget",
+		ID:     "default/a",
+		Object: map[string]interface{}{"spec": map[string]interface{}{"from": "fake"}},
+	}, nil)
+	gadgetSchema := &types.APISchema{Schema: &schemas.Schema{ID: "gadget"}, Store: store}
+
+	widgetSchema := newWidgetSchema()
+	ts, err := NewTestServer(t, widgetSchema, gadgetSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	ts.AssertGet("gadget", "default/a", map[string]interface{}{
+		"spec": map[string]interface{}{"from": "fake"},
+	})
+	store.AssertCalled(t, fake.ByID, "a")
+	if len(widgetSchema.CollectionMethods) != 0 || widgetSchema.Store != nil {
+		t.Error("the schema passed to NewTestServer was changed")
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	actual := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "a", "labels": map[string]interface{}{"app": "web"}},
+		"spec":     map[string]interface{}{"size": "large", "count": float64(3)},
+	}
+
+	if diffs := diffFields("", actual, map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}},
+		"spec":     map[string]interface{}{"count": float64(3)},
+	}); len(diffs) != 0 {
+		t.Errorf("got %v for matching fields, want no differences", diffs)
+	}
+
+	diffs := diffFields("", actual, map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "db"}},
+		"spec":     map[string]interface{}{"size": "large", "color": "red"},
+	})
+	want := []string{
+		"metadata.labels.app is web, want db",
+		"spec.color is missing, want red",
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got %v, want %v", diffs, want)
+	}
+}
@@ -0,0 +1,125 @@
+// Package watchevent converts store watch events into an envelope with an explicit Kubernetes style event type
+// so they can be streamed as newline-delimited JSON.
+package watchevent
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// BookmarkAPIEvent is the name of an APIEvent that only advances the revision of the watch.
+	BookmarkAPIEvent = "resource.bookmark"
+	// ErrorAPIEvent is the name of an APIEvent that carries an error instead of an object.
+	ErrorAPIEvent = "resource.error"
+	// ResumedAPIEvent is the name of an APIEvent sent when a watch was reopened from Revision before the
+	// apiserver would have closed it, no events were missed.
+	ResumedAPIEvent = "resource.resumed"
+
+	// Resumed is the event type of a ResumedAPIEvent.
+	Resumed watch.EventType = "RESUMED"
+)
+
+type Event struct {
+	Type     watch.EventType `json:"type"`
+	Revision string          `json:"revision,omitempty"`
+	Object   interface{}     `json:"object,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Type returns the event type of an APIEvent, every event that is not recognized is reported as an error.
+func Type(event types.APIEvent) watch.EventType {
+	if event.Error != nil {
+		return watch.Error
+	}
+	switch event.Name {
+	case types.CreateAPIEvent:
+		return watch.Added
+	case types.ChangeAPIEvent:
+		return watch.Modified
+	case types.RemoveAPIEvent:
+		return watch.Deleted
+	case BookmarkAPIEvent:
+		return watch.Bookmark
+	case ResumedAPIEvent:
+		return Resumed
+	default:
+		return watch.Error
+	}
+}
+
+func FromAPIEvent(event types.APIEvent) Event {
+	result := Event{
+		Type:     Type(event),
+		Revision: event.Revision,
+	}
+
+	switch result.Type {
+	case watch.Error:
+		if event.Error != nil {
+			result.Error = event.Error.Error()
+		} else {
+			result.Error = "unknown event " + event.Name
+		}
+	case watch.Bookmark, Resumed:
+	default:
+		result.Object = event.Object.Object
+	}
+
+	return result
+}
+
+// FrameEncoder writes the events of a stream, one frame per event.
+type FrameEncoder interface {
+	Encode(event types.APIEvent) error
+}
+
+// Encoder writes one JSON event per line, flushing after every event when the writer supports it.
+type Encoder struct {
+	w       io.Writer
+	encoder *json.Encoder
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:       w,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (e *Encoder) Encode(event types.APIEvent) error {
+	// json.Encoder terminates every value with a newline
+	if err := e.encoder.Encode(FromAPIEvent(event)); err != nil {
+		return err
+	}
+	if f, ok := e.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// Stream writes every event from the channel as JSON lines until it is closed or writing fails.
+func Stream(rw http.ResponseWriter, events chan types.APIEvent) error {
+	return StreamWith(rw, events, func(w io.Writer) FrameEncoder {
+		return NewEncoder(w)
+	})
+}
+
+// StreamWith is Stream with the frames written by the encoder returned by newEncoder, for embedders that wrap
+// events in their own envelope.
+func StreamWith(rw http.ResponseWriter, events chan types.APIEvent, newEncoder func(io.Writer) FrameEncoder) error {
+	rw.Header().Set("Content-Type", "application/jsonl")
+	rw.WriteHeader(http.StatusOK)
+
+	encoder := newEncoder(rw)
+	for event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}